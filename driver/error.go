@@ -8,4 +8,7 @@ func (e Error) Error() string { return string(e) }
 // Constant errors
 const (
 	ErrConnectionClosed = Error("connection is closed")
+	// ErrUnsupported indicates, that the driver doesn't support an operation
+	// yet, such as executing statements.
+	ErrUnsupported = Error("unsupported")
 )
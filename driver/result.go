@@ -0,0 +1,36 @@
+package driver
+
+import (
+	"database/sql/driver"
+
+	"github.com/tomarrell/lbadd/internal/executor"
+)
+
+var _ driver.Result = (*Result)(nil)
+
+// Result is the result of an executed statement, that modified the database,
+// such as an insert, update or delete statement.
+type Result struct {
+	result executor.ExecResult
+}
+
+// newResult creates a new result from the result, that the executor returned
+// for an executed statement.
+func newResult(result executor.ExecResult) *Result {
+	return &Result{
+		result: result,
+	}
+}
+
+// LastInsertId returns the rowid of the last row that was inserted by the
+// statement. If the table that was inserted into has an INTEGER PRIMARY KEY
+// column, this is the value of that column.
+func (r *Result) LastInsertId() (int64, error) {
+	return r.result.LastInsertID(), nil
+}
+
+// RowsAffected returns the amount of rows that were inserted, updated or
+// deleted by the statement.
+func (r *Result) RowsAffected() (int64, error) {
+	return r.result.RowsAffected(), nil
+}
@@ -0,0 +1,34 @@
+package driver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testExecResult is an executor result with fixed values.
+type testExecResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r testExecResult) LastInsertID() int64 { return r.lastInsertID }
+
+func (r testExecResult) RowsAffected() int64 { return r.rowsAffected }
+
+func (r testExecResult) String() string { return fmt.Sprint(r.rowsAffected) }
+
+func TestResult(t *testing.T) {
+	assert := assert.New(t)
+
+	result := newResult(testExecResult{lastInsertID: 42, rowsAffected: 1})
+
+	lastInsertID, err := result.LastInsertId()
+	assert.NoError(err)
+	assert.EqualValues(42, lastInsertID)
+
+	rowsAffected, err := result.RowsAffected()
+	assert.NoError(err)
+	assert.EqualValues(1, rowsAffected)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql/driver"
 	"fmt"
-	"strconv"
+
+	"github.com/tomarrell/lbadd/internal/compiler"
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/parser"
 )
 
 var _ driver.Stmt = (*Stmt)(nil)
@@ -14,12 +17,40 @@ var _ driver.StmtQueryContext = (*Stmt)(nil)
 // Stmt is a prepared statement that can be executed. It does not remember
 // values that were passed in.
 type Stmt struct {
+	// cmd is the compiled statement, whose parameters are not bound.
+	cmd command.Command
+	// numInput is the amount of parameters of the statement.
+	numInput int
 }
 
 // parse attempts to parse the given query string. If the query string is valid
-// and supported sql, a statement and error=nil will be returned.
+// and supported sql, a statement and error=nil will be returned. The query
+// string must consist of a single statement.
 func parse(query string) (*Stmt, error) {
-	return nil, fmt.Errorf("unimplemented") // TODO(TimSatke): implement
+	p := parser.New(query)
+	stmt, errs, ok := p.Next()
+	if !ok {
+		return nil, fmt.Errorf("empty query")
+	}
+	if len(errs) != 0 {
+		return nil, fmt.Errorf("syntax: %v", errs[0])
+	}
+	if _, _, ok := p.Next(); ok {
+		return nil, fmt.Errorf("query must consist of a single statement")
+	}
+
+	cmd, err := compiler.New().Compile(stmt)
+	if err != nil {
+		return nil, fmt.Errorf("compile: %w", err)
+	}
+	var numInput int
+	if params := command.Parameters(cmd); len(params) != 0 {
+		numInput = params[len(params)-1].Index
+	}
+	return &Stmt{
+		cmd:      cmd,
+		numInput: numInput,
+	}, nil
 }
 
 // Close closes this statement, making it impossible to execute it again.
@@ -28,8 +59,21 @@ func (s *Stmt) Close() error {
 }
 
 // NumInput returns the amount of argument placeholders that the statement has.
+// Placeholders with the same name count as one, and gaps between numbered
+// placeholders count as placeholders.
 func (s *Stmt) NumInput() int {
-	return 0 // TODO(TimSatke): implement
+	return s.numInput
+}
+
+// bind binds the given arguments to the parameters of the statement, and
+// returns the resulting command. The statement itself is not modified, so
+// that it can be bound again with other arguments.
+func (s *Stmt) bind(args []driver.NamedValue) (command.Command, error) {
+	cmd, err := command.Bind(s.cmd, args)
+	if err != nil {
+		return nil, fmt.Errorf("bind: %w", err)
+	}
+	return cmd, nil
 }
 
 // Exec is discouraged. Don't use this, use ExecContext instead.
@@ -37,8 +81,7 @@ func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
 	namedValues := make([]driver.NamedValue, 0, len(args))
 	for i, arg := range args {
 		namedValues = append(namedValues, driver.NamedValue{
-			Name:    "param" + strconv.Itoa(i),
-			Ordinal: i,
+			Ordinal: i + 1,
 			Value:   arg,
 		})
 	}
@@ -50,8 +93,7 @@ func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
 	namedValues := make([]driver.NamedValue, 0, len(args))
 	for i, arg := range args {
 		namedValues = append(namedValues, driver.NamedValue{
-			Name:    "param" + strconv.Itoa(i),
-			Ordinal: i,
+			Ordinal: i + 1,
 			Value:   arg,
 		})
 	}
@@ -61,12 +103,22 @@ func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
 // ExecContext executes this statement with the given arguments as arguments,
 // with respect to the given context. This should be used for update statements only (alter, update, drop, delete etc.).
 func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	return nil, fmt.Errorf("unimplemented") // TODO(TimSatke): implement
+	if _, err := s.bind(args); err != nil {
+		return nil, err
+	}
+	// the connection has no executor yet, that could execute the bound
+	// command
+	return nil, fmt.Errorf("execute: %w", ErrUnsupported) // TODO(TimSatke): implement
 }
 
 // QueryContext executes this statement with the given arguments as arguments,
 // with respect to the given context. This should be used for query statements
 // only (select etc.).
 func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	return nil, fmt.Errorf("unimplemented") // TODO(TimSatke): implement
+	if _, err := s.bind(args); err != nil {
+		return nil, err
+	}
+	// the connection has no executor yet, that could execute the bound
+	// command
+	return nil, fmt.Errorf("execute: %w", ErrUnsupported) // TODO(TimSatke): implement
 }
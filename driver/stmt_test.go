@@ -0,0 +1,75 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStmtNumInput(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{"SELECT * FROM users", 0, false},
+		{"INSERT INTO users (name) VALUES (?)", 1, false},
+		{"SELECT :name, :name FROM users", 1, false},
+		{"SELECT ?, ?3 FROM users", 3, false},
+		{"SELECT * FROM", 0, true},
+		{"SELECT 1; SELECT 2", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert := assert.New(t)
+
+			stmt, err := parse(tt.query)
+			if tt.wantErr {
+				assert.Error(err)
+				return
+			}
+			if assert.NoError(err) {
+				assert.Equal(tt.want, stmt.NumInput())
+			}
+		})
+	}
+}
+
+func TestStmtBind(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := parse("SELECT * FROM users WHERE name = :name")
+	assert.NoError(err)
+
+	_, err = stmt.bind([]driver.NamedValue{{Ordinal: 1, Name: "name", Value: "jdoe"}})
+	assert.NoError(err)
+	_, err = stmt.bind([]driver.NamedValue{{Ordinal: 1, Value: "jdoe"}})
+	assert.NoError(err)
+	_, err = stmt.bind(nil)
+	assert.Error(err)
+	_, err = stmt.bind([]driver.NamedValue{{Ordinal: 1, Name: "name", Value: "jdoe"}, {Ordinal: 2, Name: "unknown", Value: "jane"}})
+	assert.EqualError(err, "bind: no parameter named unknown")
+	_, err = stmt.bind([]driver.NamedValue{{Ordinal: 1, Value: "jdoe"}, {Ordinal: 2, Value: "jane"}})
+	assert.EqualError(err, "bind: no parameter with index 2")
+}
+
+func TestStmtExecUnsupported(t *testing.T) {
+	assert := assert.New(t)
+
+	stmt, err := parse("SELECT * FROM users WHERE name = ?")
+	assert.NoError(err)
+
+	_, err = stmt.ExecContext(context.Background(), []driver.NamedValue{{Ordinal: 1, Value: "jdoe"}})
+	assert.True(errors.Is(err, ErrUnsupported), "%v", err)
+	_, err = stmt.QueryContext(context.Background(), []driver.NamedValue{{Ordinal: 1, Value: "jdoe"}})
+	assert.True(errors.Is(err, ErrUnsupported), "%v", err)
+
+	// bind errors are reported before execution
+	_, err = stmt.QueryContext(context.Background(), nil)
+	assert.Error(err)
+	assert.False(errors.Is(err, ErrUnsupported))
+}
@@ -0,0 +1,89 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// aggregateFunctions are the names of all known aggregate functions, in upper
+// case.
+var aggregateFunctions = map[string]struct{}{
+	"AVG":          {},
+	"COUNT":        {},
+	"GROUP_CONCAT": {},
+	"MAX":          {},
+	"MIN":          {},
+	"SUM":          {},
+	"TOTAL":        {},
+}
+
+// isAggregate determines whether the given function call is a call to an
+// aggregate function. MIN and MAX are only aggregate functions if they are
+// called with a single argument, otherwise they are scalar functions.
+func isAggregate(fn command.FunctionExpr) bool {
+	name := strings.ToUpper(fn.Name)
+	if _, ok := aggregateFunctions[name]; !ok {
+		return false
+	}
+	if (name == "MIN" || name == "MAX") && len(fn.Args) != 1 {
+		return false
+	}
+	return true
+}
+
+// collectAggregates appends all aggregate calls within the given expression to
+// the given aggregates, if they are not already contained. Aggregate calls
+// within nested queries belong to the nested query and are not collected, and
+// aggregate functions that are used as window functions are not aggregate
+// calls. An error is returned if an aggregate or window function call is
+// nested within another aggregate call.
+func collectAggregates(aggregates []command.FunctionExpr, expr command.Expr) ([]command.FunctionExpr, error) {
+	if expr == nil {
+		return aggregates, nil
+	}
+	if fn, ok := expr.(command.FunctionExpr); ok && fn.Over == nil && isAggregate(fn) {
+		for _, child := range childExprs(fn) {
+			if nested, _ := collectAggregates(nil, child); len(nested) != 0 {
+				return nil, fmt.Errorf("misuse of aggregate function %v", nested[0].Name)
+			}
+			if nested, _ := collectWindows(nil, child); len(nested) != 0 {
+				return nil, fmt.Errorf("misuse of window function %v", nested[0].Name)
+			}
+		}
+		for _, aggregate := range aggregates {
+			if aggregate.String() == fn.String() {
+				return aggregates, nil
+			}
+		}
+		return append(aggregates, fn), nil
+	}
+
+	var err error
+	for _, child := range childExprs(expr) {
+		aggregates, err = collectAggregates(aggregates, child)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return aggregates, nil
+}
+
+// childExprs returns the direct child expressions of the given expression.
+// Expressions within nested queries are not child expressions.
+func childExprs(expr command.Expr) []command.Expr {
+	var children []command.Expr
+	root := true
+	command.Walk(expr, func(node command.Node) bool {
+		if root {
+			root = false
+			return true
+		}
+		if child, ok := node.(command.Expr); ok {
+			children = append(children, child)
+		}
+		return false
+	})
+	return children
+}
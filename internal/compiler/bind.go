@@ -0,0 +1,496 @@
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/database"
+	"github.com/tomarrell/lbadd/internal/database/column"
+	"github.com/tomarrell/lbadd/internal/database/table"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// boundColumn is a column of a list, whose name is known from the catalog.
+type boundColumn struct {
+	// table is the name or the alias of the table that this column originates
+	// from. May be empty.
+	table string
+	// name is the name of this column.
+	name string
+	// typ is the inferred type of this column. May be column.Unknown.
+	typ column.BaseType
+	// coalesced indicates that this column is a column of the right input of
+	// a join, that is merged with the equally named column of the left input.
+	// A coalesced column is only part of the expansion of a qualified star,
+	// and can only be referenced by its qualified name.
+	coalesced bool
+}
+
+// OptionCatalog is used to bind compiled statements against the given
+// catalog. Binding resolves all table and column references, infers the types
+// of expressions and expands star result columns into the columns of the
+// tables that are selected from. All problems that are found while binding a
+// statement are reported together in a (*MultiError), where every problem is a
+// (BindError).
+func OptionCatalog(db database.DB) Option {
+	return func(c *simpleCompiler) {
+		c.catalog = db
+	}
+}
+
+// reportProblem records the given problem, that was found at the given token.
+// A problem that was already found at the same position, because the
+// respective part of the statement was compiled more than once, is not
+// recorded again.
+func (c *simpleCompiler) reportProblem(at token.Token, err error) {
+	problem := newBindError(at, err)
+	for _, other := range c.problems.errs {
+		if other.Error() == problem.Error() {
+			return
+		}
+	}
+	c.problems.Append(problem)
+}
+
+// bindTable adds the columns of the given table, that was declared at the
+// given token, to the innermost scope. If the table does not exist in the
+// catalog, a problem is reported. If the columns of the table can't be
+// determined, the scope is marked as unbound.
+func (c *simpleCompiler) bindTable(tbl command.Table, at token.Token) {
+	if c.catalog == nil || len(c.scopes) == 0 {
+		return
+	}
+	s := c.scopes[len(c.scopes)-1]
+	cols, err := c.bindTableColumns(tbl)
+	if err != nil {
+		if _, ok := tbl.(command.SimpleTable); ok && at != nil {
+			c.reportProblem(at, err)
+		}
+		s.unbound = true
+		return
+	}
+	s.cols = append(s.cols, cols...)
+}
+
+// bindResultScope adds the columns of the given list to the innermost scope,
+// so that they can be referenced by their name. If the columns of the list are
+// unknown, the scope is marked as unbound.
+func (c *simpleCompiler) bindResultScope(list command.List) {
+	s := c.scopes[len(c.scopes)-1]
+	cols, err := c.bindColumns(list)
+	if err != nil {
+		s.unbound = true
+		return
+	}
+	s.cols = append(s.cols, cols...)
+}
+
+// bindJoinConstraint coalesces the columns of the right table of a join, whose
+// columns start at the given index of the columns of the innermost scope, with
+// the equally named columns of the left tables. The coalesced columns are the
+// given USING columns, or all common columns if the join is a natural one.
+// USING columns, that are not present in both inputs, are reported as problem
+// at the respective token.
+func (c *simpleCompiler) bindJoinConstraint(rightStart int, using []token.Token, natural bool) {
+	if c.catalog == nil || len(c.scopes) == 0 {
+		return
+	}
+	s := c.scopes[len(c.scopes)-1]
+	if s.unbound {
+		return
+	}
+	if natural {
+		for _, name := range naturalColumns(s.cols, rightStart) {
+			_ = coalesceColumn(s.cols, rightStart, name)
+		}
+		return
+	}
+	for _, name := range using {
+		if err := coalesceColumn(s.cols, rightStart, name.Value()); err != nil {
+			c.reportProblem(name, err)
+		}
+	}
+}
+
+// bindInsert checks, that the given table, that was referenced at the given
+// token, exists in the catalog, and that it has columns with the names of the
+// given tokens, or that the names are names of the rowid.
+func (c *simpleCompiler) bindInsert(tbl command.SimpleTable, at token.Token, columnNames []token.Token) {
+	if c.catalog == nil {
+		return
+	}
+	cols, err := c.bindTableColumns(tbl)
+	if err != nil {
+		c.reportProblem(at, err)
+		return
+	}
+	for _, name := range columnNames {
+		if indexOfBoundColumn(cols, name.Value()) == -1 && !table.IsRowIDName(name.Value()) {
+			c.reportProblem(name, fmt.Errorf("%w: %v.%v", ErrNoSuchColumn, tbl.Table, name.Value()))
+		}
+	}
+}
+
+// bindUpsert adds the columns of the given table and of the given excluded
+// table, which is the same table under the alias "excluded", to the innermost
+// scope. Like coalesced columns, the excluded columns can only be referenced
+// by their qualified name. Problems with the table have already been reported
+// when binding the insert, and are not reported again.
+func (c *simpleCompiler) bindUpsert(tbl, excluded command.SimpleTable) {
+	if c.catalog == nil {
+		return
+	}
+	s := c.scopes[len(c.scopes)-1]
+	cols, err := c.bindTableColumns(tbl)
+	if err != nil {
+		s.unbound = true
+		return
+	}
+	s.cols = append(s.cols, cols...)
+	for _, col := range renameBoundColumns(cols, excluded.Alias) {
+		col.coalesced = true
+		s.cols = append(s.cols, col)
+	}
+}
+
+// bindColumnRef resolves the given column reference, that was found at the
+// given token, and reports a problem if that fails.
+func (c *simpleCompiler) bindColumnRef(ref command.ColumnRef, at token.Token) {
+	if c.catalog == nil {
+		return
+	}
+	if _, err := c.lookupColumn(ref); err != nil {
+		c.reportProblem(at, err)
+	}
+}
+
+// lookupColumn resolves the given column reference against the columns of
+// the visible tables. The scopes are searched from the innermost scope
+// outwards. Unqualified references may also refer to a result column alias,
+// while ordering terms are compiled.
+func (c *simpleCompiler) lookupColumn(ref command.ColumnRef) (boundColumn, error) {
+	if ref.Table == "" {
+		for _, alias := range c.resultAliases {
+			if strings.EqualFold(alias, ref.Column) {
+				return boundColumn{name: alias}, nil
+			}
+		}
+	}
+
+	for depth := 0; depth < len(c.scopes); depth++ {
+		s := c.scopes[len(c.scopes)-1-depth]
+		if _, ok := s.tables[strings.ToLower(ref.Table)]; ref.Table != "" && !ok {
+			continue
+		}
+		found, err := matchColumn(s.cols, ref)
+		switch {
+		case err != nil:
+			return boundColumn{}, err
+		case found != nil:
+			return *found, nil
+		case s.unbound:
+			return boundColumn{table: ref.Table, name: ref.Column}, nil
+		case ref.Table != "":
+			// the table is visible, so the reference can't be resolved in an
+			// outer scope
+			return c.lookupRowID(ref)
+		}
+	}
+	return c.lookupRowID(ref)
+}
+
+// lookupRowID resolves a reference to the rowid of a table, which is not a
+// declared column. Any other reference is reported as unknown column.
+func (c *simpleCompiler) lookupRowID(ref command.ColumnRef) (boundColumn, error) {
+	if table.IsRowIDName(ref.Column) {
+		return boundColumn{table: ref.Table, name: ref.Column, typ: column.Integer}, nil
+	}
+	if ref.Table != "" {
+		return boundColumn{}, fmt.Errorf("%w: %v.%v", ErrNoSuchColumn, ref.Table, ref.Column)
+	}
+	return boundColumn{}, fmt.Errorf("%w: %v", ErrNoSuchColumn, ref.Column)
+}
+
+// matchColumn returns the column of the given columns, that is referenced by
+// the given reference, or nil if there is none. An error is returned if more
+// than one column matches.
+func matchColumn(cols []boundColumn, ref command.ColumnRef) (*boundColumn, error) {
+	var found *boundColumn
+	for i, col := range cols {
+		if !strings.EqualFold(col.name, ref.Column) {
+			continue
+		}
+		if ref.Table == "" && col.coalesced {
+			continue
+		}
+		if ref.Table != "" && !strings.EqualFold(col.table, ref.Table) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%w: %v", ErrAmbiguousColumn, ref)
+		}
+		found = &cols[i]
+	}
+	return found, nil
+}
+
+// coalesceColumn marks the first right column with the given name as
+// coalesced. The right columns start at the given index of the given columns,
+// all columns before are left columns. An error is returned if the left or the
+// right columns don't have a column with the given name.
+func coalesceColumn(cols []boundColumn, rightStart int, name string) error {
+	leftIndex := indexOfBoundColumn(cols[:rightStart], name)
+	rightIndex := indexOfBoundColumn(cols[rightStart:], name)
+	if leftIndex == -1 || rightIndex == -1 {
+		return fmt.Errorf("cannot join using column %v - column not present in both tables", name)
+	}
+	cols[rightStart+rightIndex].coalesced = true
+	return nil
+}
+
+// naturalColumns returns the names of the right columns, that are also the
+// name of a left column. The right columns start at the given index of the
+// given columns, all columns before are left columns.
+func naturalColumns(cols []boundColumn, rightStart int) []string {
+	var names []string
+	for _, col := range cols[rightStart:] {
+		if !col.coalesced && indexOfBoundColumn(cols[:rightStart], col.name) != -1 {
+			names = append(names, col.name)
+		}
+	}
+	return names
+}
+
+// bindResultColumns expands all star columns of the given result columns into
+// the columns of the innermost scope, in the order of the tables that are
+// selected from. If the compiler has no catalog, or the columns of some table
+// are unknown, the columns are returned unchanged. Expanded columns, whose
+// name is already taken by a previous result column, are given a unique
+// alias.
+func (c *simpleCompiler) bindResultColumns(cols []command.Column) ([]command.Column, error) {
+	if c.catalog == nil || len(c.scopes) == 0 || !hasStar(cols) {
+		return cols, nil
+	}
+	s := c.scopes[len(c.scopes)-1]
+	if s.unbound {
+		return cols, nil
+	}
+
+	var bound []command.Column
+	taken := make(map[string]struct{})
+	for _, col := range cols {
+		if !isStar(col) {
+			taken[strings.ToLower(resultColumnName(col))] = struct{}{}
+			bound = append(bound, col)
+			continue
+		}
+
+		var found bool
+		for _, inputCol := range s.cols {
+			if col.Table == "" && inputCol.coalesced {
+				continue
+			}
+			if col.Table != "" && !strings.EqualFold(col.Table, inputCol.table) {
+				continue
+			}
+			found = true
+			expanded := command.Column{
+				Column: command.ColumnRef{
+					Table:  inputCol.table,
+					Column: inputCol.name,
+				},
+			}
+			name := inputCol.name
+			for i := 1; ; i++ {
+				if _, ok := taken[strings.ToLower(name)]; !ok {
+					break
+				}
+				name = inputCol.name + ":" + strconv.Itoa(i)
+				expanded.Alias = name
+			}
+			taken[strings.ToLower(name)] = struct{}{}
+			bound = append(bound, expanded)
+		}
+		if !found && col.Table != "" {
+			return nil, fmt.Errorf("%w: %v", ErrNoSuchTable, col.Table)
+		}
+		if !found {
+			return nil, fmt.Errorf("no tables specified")
+		}
+	}
+	return bound, nil
+}
+
+// bindColumns returns the columns, that the given list produces.
+func (c *simpleCompiler) bindColumns(list command.List) ([]boundColumn, error) {
+	switch l := list.(type) {
+	case command.Scan:
+		return c.bindTableColumns(l.Table)
+	case command.Values:
+		var cols []boundColumn
+		if len(l.Values) != 0 {
+			for i, expr := range l.Values[0] {
+				cols = append(cols, boundColumn{
+					name: "column" + strconv.Itoa(i+1),
+					typ:  c.typeOf(expr, nil),
+				})
+			}
+		}
+		return cols, nil
+	case command.Project:
+		inputCols, err := c.bindColumns(l.Input)
+		if err != nil {
+			return nil, err
+		}
+		resolve := func(ref command.ColumnRef) (boundColumn, error) {
+			found, err := matchColumn(inputCols, ref)
+			if err != nil || found == nil {
+				return boundColumn{}, err
+			}
+			return *found, nil
+		}
+		cols := make([]boundColumn, len(l.Cols))
+		for i, col := range l.Cols {
+			if isStar(col) {
+				return nil, fmt.Errorf("columns of unexpanded star are unknown")
+			}
+			cols[i].name = resultColumnName(col)
+			cols[i].typ = c.typeOf(col.Column, resolve)
+		}
+		return cols, nil
+	case command.Join:
+		left, err := c.bindColumns(l.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := c.bindColumns(l.Right)
+		if err != nil {
+			return nil, err
+		}
+		cols := append(left, right...)
+		names := l.Using
+		if l.Natural {
+			names = naturalColumns(cols, len(left))
+		}
+		for _, name := range names {
+			if err := coalesceColumn(cols, len(left), name); err != nil {
+				return nil, err
+			}
+		}
+		return cols, nil
+	case command.Select:
+		return c.bindColumns(l.Input)
+	case command.Aggregate:
+		return c.bindColumns(l.Input)
+	case command.Window:
+		return c.bindColumns(l.Input)
+	case command.Distinct:
+		return c.bindColumns(l.Input)
+	case command.Sort:
+		return c.bindColumns(l.Input)
+	case command.Limit:
+		return c.bindColumns(l.Input)
+	case command.Offset:
+		return c.bindColumns(l.Input)
+	case command.Union:
+		return c.bindColumns(l.Left)
+	case command.Intersect:
+		return c.bindColumns(l.Left)
+	case command.Except:
+		return c.bindColumns(l.Left)
+	case command.With:
+		return c.bindColumns(l.Input)
+	}
+	return nil, fmt.Errorf("bind columns of %T: %w", list, ErrUnsupported)
+}
+
+// bindTableColumns returns the columns of the given table, which are assigned
+// to the alias of the table, if it has one.
+func (c *simpleCompiler) bindTableColumns(tbl command.Table) ([]boundColumn, error) {
+	switch t := tbl.(type) {
+	case command.SimpleTable:
+		s, ok := c.catalog.Schema(t.Schema)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v.%v", ErrNoSuchTable, t.Schema, t.Table)
+		}
+		found, ok := s.Table(t.Table)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrNoSuchTable, t.Table)
+		}
+		name := t.Table
+		if t.Alias != "" {
+			name = t.Alias
+		}
+		var cols []boundColumn
+		for _, col := range found.Columns() {
+			typ := column.Unknown
+			if col.Type() != nil {
+				typ = col.Type().BaseType()
+			}
+			cols = append(cols, boundColumn{
+				table: name,
+				name:  col.Name(),
+				typ:   typ,
+			})
+		}
+		return cols, nil
+	case command.SubqueryTable:
+		cols, err := c.bindColumns(t.Input)
+		if err != nil {
+			return nil, err
+		}
+		return renameBoundColumns(cols, t.Alias), nil
+	case command.CommonTableRef:
+		info := c.lookupCommonTable(t.Name)
+		if info == nil || info.cols == nil {
+			return nil, fmt.Errorf("columns of table %v are unknown", t.Name)
+		}
+		name := t.Name
+		if t.Alias != "" {
+			name = t.Alias
+		}
+		return renameBoundColumns(info.cols, name), nil
+	}
+	return nil, fmt.Errorf("bind columns of table %T: %w", tbl, ErrUnsupported)
+}
+
+// indexOfBoundColumn returns the index of the first column with the given
+// name, that is not coalesced, or -1 if there is no such column.
+func indexOfBoundColumn(cols []boundColumn, name string) int {
+	for i, col := range cols {
+		if !col.coalesced && strings.EqualFold(col.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// renameBoundColumns returns a copy of the given columns, that are assigned to
+// the table with the given name.
+func renameBoundColumns(cols []boundColumn, tableName string) []boundColumn {
+	renamed := make([]boundColumn, len(cols))
+	for i, col := range cols {
+		col.table = tableName
+		renamed[i] = col
+	}
+	return renamed
+}
+
+// hasStar determines whether any of the given result columns is a star.
+func hasStar(cols []command.Column) bool {
+	for _, col := range cols {
+		if isStar(col) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStar determines whether the given result column is a star, that is
+// expanded into the columns of the input.
+func isStar(col command.Column) bool {
+	lit, ok := col.Column.(command.LiteralExpr)
+	return ok && lit.Value == "*"
+}
@@ -0,0 +1,319 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/column"
+	"github.com/tomarrell/lbadd/internal/database/schema"
+	"github.com/tomarrell/lbadd/internal/database/storage"
+	"github.com/tomarrell/lbadd/internal/database/table"
+	"github.com/tomarrell/lbadd/internal/parser"
+)
+
+// testCatalog is a catalog with a single schema with the empty name, whose
+// tables are given by their name in lower case.
+type testCatalog map[string]testTable
+
+func (c testCatalog) Schema(name string) (schema.Schema, bool) {
+	if name != "" {
+		return nil, false
+	}
+	return c, true
+}
+
+func (c testCatalog) Table(name string) (table.Table, bool) {
+	tbl, ok := c[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	tbl.name = name
+	return tbl, true
+}
+
+type testTable struct {
+	name string
+	cols []testColumn
+}
+
+func (t testTable) Schema() string { return "" }
+
+func (t testTable) Name() string { return t.name }
+
+func (t testTable) Columns() []column.Column {
+	cols := make([]column.Column, len(t.cols))
+	for i, col := range t.cols {
+		cols[i] = col
+	}
+	return cols
+}
+
+func (t testTable) Storage() storage.Storage { return nil }
+
+type testColumn struct {
+	name string
+	typ  column.BaseType
+}
+
+func (c testColumn) Name() string { return c.name }
+
+func (c testColumn) Type() column.Type { return c }
+
+func (c testColumn) IsNullable() bool { return true }
+
+func (c testColumn) IsPrimaryKey() bool { return false }
+
+func (c testColumn) ShouldAutoincrement() bool { return false }
+
+func (c testColumn) BaseType() column.BaseType { return c.typ }
+
+func (c testColumn) IsParameterized() bool { return false }
+
+func (c testColumn) FirstParameter() float64 { return 0 }
+
+func (c testColumn) SecondParameter() float64 { return 0 }
+
+func newTestCatalog() testCatalog {
+	return testCatalog{
+		"a": {cols: []testColumn{{"id", column.Integer}, {"name", column.Varchar}}},
+		"b": {cols: []testColumn{{"id", column.Integer}, {"price", column.Decimal}}},
+		"c": {cols: []testColumn{{"id", column.Integer}, {"name", column.Varchar}, {"price", column.Decimal}}},
+	}
+}
+
+func Test_simpleCompiler_Compile_Catalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			"star",
+			"SELECT * FROM a",
+			"Project[cols=a.id,a.name](Scan[table=a]())",
+			false,
+		},
+		{
+			"star with alias",
+			"SELECT * FROM a AS s WHERE s.id > 1",
+			"Project[cols=s.id,s.name](Select[filter=s.id > 1](Scan[table=a AS s]()))",
+			false,
+		},
+		{
+			"star of join with duplicate columns",
+			"SELECT * FROM a, b",
+			"Project[cols=a.id,a.name,b.id AS id:1,b.price](Join[type=JoinCross](Scan[table=a](),Scan[table=b]()))",
+			false,
+		},
+		{
+			"table star",
+			"SELECT b.*, a.id FROM a JOIN b ON a.id == b.id",
+			"Project[cols=b.id,b.price,a.id](Join[filter=a.id==b.id](Scan[table=a](),Scan[table=b]()))",
+			false,
+		},
+		{
+			"star of join using",
+			"SELECT * FROM a JOIN c USING (id, name)",
+			"Project[cols=a.id,a.name,c.price](Join[using=(id,name)](Scan[table=a](),Scan[table=c]()))",
+			false,
+		},
+		{
+			"table star of join using",
+			"SELECT c.* FROM a JOIN c USING (id)",
+			"Project[cols=c.id,c.name,c.price](Join[using=(id)](Scan[table=a](),Scan[table=c]()))",
+			false,
+		},
+		{
+			"star of natural join",
+			"SELECT * FROM b NATURAL JOIN c",
+			"Project[cols=b.id,b.price,c.name](Join[natural=true](Scan[table=b](),Scan[table=c]()))",
+			false,
+		},
+		{
+			"star of subquery",
+			"SELECT * FROM (SELECT name, id * 2 FROM a) AS s",
+			"Project[cols=s.name,s.id * 2](Scan[table=(Project[cols=name,id * 2](Scan[table=a]())) AS s]())",
+			false,
+		},
+		{
+			"star of common table",
+			"WITH t(k, v) AS (SELECT * FROM b) SELECT * FROM t",
+			"With[tables=t(k,v) AS (Project[cols=b.id,b.price](Scan[table=b]()))](Project[cols=t.k,t.v](Scan[table=t]()))",
+			false,
+		},
+		{
+			"star of recursive common table",
+			"WITH RECURSIVE r AS (SELECT id FROM a UNION SELECT * FROM r) SELECT * FROM r",
+			"With[tables=r AS RECURSIVE (Project[cols=id](Scan[table=a]())) UNION (Project[cols=r.id](Scan[table=r]()))](Project[cols=r.id](Scan[table=r]()))",
+			false,
+		},
+		{
+			"star of values",
+			"SELECT * FROM (VALUES (1, 2)) AS v",
+			"Project[cols=v.column1,v.column2](Scan[table=(Values[]((1,2))) AS v]())",
+			false,
+		},
+		{
+			"unknown table",
+			"SELECT * FROM d",
+			"",
+			true,
+		},
+		{
+			"unknown star table",
+			"SELECT d.* FROM a",
+			"",
+			true,
+		},
+		{
+			"unknown using column",
+			"SELECT * FROM a JOIN b USING (name)",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			stmt, errs, ok := parser.New(tt.input).Next()
+			require.Len(t, errs, 0)
+			require.True(t, ok)
+
+			got, err := New(OptionCatalog(newTestCatalog())).Compile(stmt)
+			if tt.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.want, got.String())
+		})
+	}
+}
+
+func Test_simpleCompiler_Compile_Bind(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantProblems []string
+	}{
+		{"column", "SELECT name FROM a WHERE id > 1", nil},
+		{"qualified column", "SELECT a.name FROM a JOIN b ON a.id == b.id", nil},
+		{"rowid", "SELECT rowid, a.oid FROM a", nil},
+		{"coalesced column", "SELECT id FROM a JOIN b USING (id)", nil},
+		{"natural join", "SELECT id, price FROM b NATURAL JOIN c", nil},
+		{"correlated column", "SELECT name FROM a WHERE EXISTS (SELECT * FROM b WHERE b.id == a.id)", nil},
+		{"ordering term alias", "SELECT name AS n FROM a ORDER BY n", nil},
+		{"compound ordering term", "SELECT id FROM a UNION SELECT id FROM b ORDER BY id", nil},
+		{"common table column", "WITH t(k) AS (SELECT price FROM b) SELECT k FROM t WHERE k > 1.5", nil},
+		{"table function", "SELECT anything FROM myFunction(1) AS f", nil},
+		{"upsert", "INSERT INTO a VALUES (1, 'a') ON CONFLICT (id) DO UPDATE SET name = excluded.name WHERE name < excluded.name", nil},
+		{
+			"unknown table",
+			"SELECT id FROM d",
+			[]string{"no such table: d at (1:16) offset 15 length 1"},
+		},
+		{
+			"unknown column",
+			"SELECT nope FROM a",
+			[]string{"no such column: nope at (1:8) offset 7 length 4"},
+		},
+		{
+			"all problems",
+			"SELECT nope, a.missing FROM a WHERE name > 1",
+			[]string{
+				"no such column: nope at (1:8) offset 7 length 4",
+				"no such column: a.missing at (1:14) offset 13 length 1",
+				"type mismatch: cannot compare VARCHAR with INTEGER at (1:42) offset 41 length 1",
+			},
+		},
+		{
+			"ambiguous column",
+			"SELECT id FROM a, b",
+			[]string{"ambiguous column name: id at (1:8) offset 7 length 2"},
+		},
+		{
+			"column of outer query",
+			"SELECT (SELECT name FROM b) FROM a WHERE price > 1",
+			[]string{"no such column: price at (1:42) offset 41 length 5"},
+		},
+		{
+			"arithmetic on text",
+			"SELECT name * 2 FROM a",
+			[]string{"type mismatch: cannot apply * to VARCHAR at (1:13) offset 12 length 1"},
+		},
+		{
+			"sum of text",
+			"SELECT SUM(name) FROM a",
+			[]string{"type mismatch: cannot compute SUM of VARCHAR at (1:8) offset 7 length 3"},
+		},
+		{
+			"type of subquery column",
+			"SELECT * FROM (SELECT name FROM a) AS s WHERE s.name == 1",
+			[]string{"type mismatch: cannot compare VARCHAR with INTEGER at (1:54) offset 53 length 2"},
+		},
+		{
+			"type of common table column",
+			"WITH t(k) AS (SELECT price FROM b) SELECT k FROM t WHERE k > 'a'",
+			[]string{"type mismatch: cannot compare DECIMAL with VARCHAR at (1:60) offset 59 length 1"},
+		},
+		{
+			"unknown insert column",
+			"INSERT INTO a (id, nope) VALUES (1, 2)",
+			[]string{"no such column: a.nope at (1:20) offset 19 length 4"},
+		},
+		{
+			"unknown insert table",
+			"INSERT INTO d VALUES (1)",
+			[]string{"no such table: d at (1:13) offset 12 length 1"},
+		},
+		{
+			"unknown update column",
+			"UPDATE a SET nope = 1 WHERE id == 1",
+			[]string{"no such column: nope at (1:14) offset 13 length 4"},
+		},
+		{
+			"unknown delete column",
+			"DELETE FROM a WHERE nope == 1",
+			[]string{"no such column: nope at (1:21) offset 20 length 4"},
+		},
+		{
+			"unknown upsert column",
+			"INSERT INTO a VALUES (1, 'a') ON CONFLICT (nope) DO UPDATE SET other = excluded.missing",
+			[]string{
+				"no such column: a.nope at (1:44) offset 43 length 4",
+				"no such column: a.other at (1:64) offset 63 length 5",
+				"no such column: excluded.missing at (1:72) offset 71 length 8",
+			},
+		},
+		{
+			"unknown using column",
+			"SELECT * FROM a JOIN b USING (name)",
+			[]string{"cannot join using column name - column not present in both tables at (1:31) offset 30 length 4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			stmt, errs, ok := parser.New(tt.input).Next()
+			require.Len(t, errs, 0)
+			require.True(t, ok)
+
+			_, err := New(OptionCatalog(newTestCatalog())).Compile(stmt)
+			if tt.wantProblems == nil {
+				assert.NoError(err)
+				return
+			}
+			require.IsType(t, &MultiError{}, err)
+			var problems []string
+			for _, problem := range err.(*MultiError).Errors() {
+				problems = append(problems, problem.Error())
+			}
+			assert.Equal(tt.wantProblems, problems)
+		})
+	}
+}
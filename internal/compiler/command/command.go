@@ -10,6 +10,7 @@ import (
 
 var _ Command = (*Explain)(nil)
 var _ Command = (*Scan)(nil)
+var _ Command = (*IndexScan)(nil)
 var _ Command = (*Select)(nil)
 var _ Command = (*Project)(nil)
 var _ Command = (*Delete)(nil)
@@ -17,10 +18,20 @@ var _ Command = (*DropTable)(nil)
 var _ Command = (*DropIndex)(nil)
 var _ Command = (*DropTrigger)(nil)
 var _ Command = (*DropView)(nil)
+var _ Command = (*Analyze)(nil)
 var _ Command = (*Update)(nil)
 var _ Command = (*Insert)(nil)
 var _ Command = (*Join)(nil)
 var _ Command = (*Limit)(nil)
+var _ Command = (*CreateTable)(nil)
+var _ Command = (*CreateIndex)(nil)
+var _ Command = (*Aggregate)(nil)
+var _ Command = (*Window)(nil)
+var _ Command = (*Sort)(nil)
+var _ Command = (*Union)(nil)
+var _ Command = (*Intersect)(nil)
+var _ Command = (*Except)(nil)
+var _ Command = (*With)(nil)
 
 // Command describes a structure that can be executed by the database executor.
 // Instead of using bytecode, we use a hierarchical structure for the executor.
@@ -41,6 +52,17 @@ const (
 	JoinLeftOuter
 	JoinInner
 	JoinCross
+	// JoinSemi produces every row of the left input, for which the right
+	// input has at least one row, for which the filter is true. Only the
+	// columns of the left input are produced. The filter is evaluated like
+	// the filter of a subquery, whose enclosing query produces the row of the
+	// left input, so a column reference with a depth of 1 references a column
+	// of the left input.
+	JoinSemi
+	// JoinAnti produces every row of the left input, for which the right
+	// input has no row, for which the filter is true. Apart from that, it
+	// behaves like JoinSemi.
+	JoinAnti
 )
 
 //go:generate stringer -type=UpdateOr
@@ -81,6 +103,9 @@ type (
 	Explain struct {
 		// Command is the command that will be explained, but not executed.
 		Command Command
+		// Optimizations are the names of the optimization rules that rewrote
+		// the command, in the order they were applied first.
+		Optimizations []string
 	}
 
 	// List is a marker interface that facilitates creating a type hierarchy for
@@ -96,6 +121,48 @@ type (
 	Scan struct {
 		// Table is the table whose contents will be used.
 		Table Table
+		// Cols are the names of the columns of a simple table, that are used.
+		// The rows of the scan only consist of these columns, in the order of
+		// the table, and the rowid. Names, that are not columns of the table,
+		// are ignored. If this is nil, all columns are used.
+		Cols []string
+	}
+
+	// IndexScan instructs the executor to use the rows of a table, whose key
+	// in an index of the table is in any of the key ranges. The key of a row
+	// consists of its values in the indexed columns, in the order of the
+	// index. The rows are produced in the order of their keys.
+	IndexScan struct {
+		// Table is the table whose rows will be used.
+		Table SimpleTable
+		// Index is the name of the used index, which is an index of the table.
+		Index string
+		// Ranges are the key ranges, that the keys of the used rows are in. A
+		// row is used once, even if its key is in multiple ranges.
+		Ranges []KeyRange
+		// Covering indicates, that the index holds all values that are needed
+		// from the table, so that the table itself is not read. The rows then
+		// only consist of the indexed columns, and the rowid.
+		Covering bool
+	}
+
+	// KeyRange is a range of keys of an index. The bounds of a range are
+	// prefixes of keys, and a key is compared to a bound by comparing its
+	// prefix of the same length. Keys, that have a NULL value in any of the
+	// columns that are covered by a bound, are never in a range, and if any
+	// value of a bound is NULL, the range is empty. Values are compared with
+	// the BINARY collation.
+	KeyRange struct {
+		// Lo is the lower bound of the range. If it is empty, the range has no
+		// lower bound.
+		Lo []Expr
+		// LoExclusive indicates, that keys equal to Lo are not in the range.
+		LoExclusive bool
+		// Hi is the upper bound of the range. If it is empty, the range has no
+		// upper bound.
+		Hi []Expr
+		// HiExclusive indicates, that keys equal to Hi are not in the range.
+		HiExclusive bool
 	}
 
 	// Table is a marker interface, allowing for different specifications of
@@ -127,6 +194,39 @@ type (
 		Index string
 	}
 
+	// SubqueryTable is a table, whose rows are the result of a nested query.
+	//
+	// SubqueryTable represents the second grammar production of
+	// table-or-subquery.
+	SubqueryTable struct {
+		// Input is the query that produces the rows of this table.
+		Input List
+		// Alias name of this table. May be empty.
+		Alias string
+	}
+
+	// TableFunction is a table, whose rows are the result of a table-valued
+	// function.
+	TableFunction struct {
+		// Schema name of the function. May be empty.
+		Schema string
+		// Name is the name of the table-valued function.
+		Name string
+		// Args are the function argument expressions.
+		Args []Expr
+		// Alias name of this table. May be empty.
+		Alias string
+	}
+
+	// CommonTableRef is a reference to a common table, that is defined by an
+	// enclosing With command.
+	CommonTableRef struct {
+		// Name is the name of the referenced common table.
+		Name string
+		// Alias name of this table. May be empty.
+		Alias string
+	}
+
 	// Select represents a selection that should be performed by the executor
 	// over the nested input. Additionally, a filter can be specified which must
 	// be respected by the executor.
@@ -185,6 +285,17 @@ type (
 	// schema defined in this command.
 	DropTrigger drop
 
+	// Analyze instructs the executor to collect statistics about the data of
+	// tables, that are used to estimate the costs of commands.
+	Analyze struct {
+		// Schema is the schema of the analyzed table or index. May be empty.
+		Schema string
+		// Name is the name of the analyzed table or index. If Schema is
+		// empty, this may also be the name of a schema, whose tables are
+		// analyzed. If Name is empty, all tables are analyzed.
+		Name string
+	}
+
 	// Update instructs the executor to update all datasets, for which the
 	// filter expression evaluates to true, with the defined updates.
 	Update struct {
@@ -225,13 +336,19 @@ type (
 	// Join instructs the executor to produce a list from the left and right
 	// input list. Lists are merged with respect to the given filter.
 	Join struct {
-		// Natural indicates whether this join is a natural one.
+		// Natural indicates whether this join is a natural one. A natural
+		// join behaves like a join with a USING clause, that names all columns
+		// that the left and right list have in common.
 		Natural bool
 		// Type is the type of join that this join is.
 		Type JoinType
 		// Filter defines the condition that has to apply to two datasets from
 		// the left and right list in order to be merged.
 		Filter Expr
+		// Using are the names of the columns, whose values must be equal in
+		// two datasets from the left and right list in order to be merged.
+		// Each of these columns appears only once in the merged dataset.
+		Using []string
 		// Left is the left input list.
 		Left List
 		// Right is the right input list.
@@ -274,6 +391,151 @@ type (
 		Input List
 	}
 
+	// Aggregate instructs the executor to partition the datasets of the input
+	// list into groups, and to compute the aggregate functions for every group.
+	// Every group results in a single dataset, consisting of the values of a
+	// dataset of the group, the group keys and the aggregate results. Group
+	// keys and aggregate results can be referenced by any expression above
+	// this aggregate, that is equal to the group key or the aggregate call.
+	Aggregate struct {
+		// GroupBy are the expressions that the datasets are grouped by. If
+		// this is empty, all datasets form a single group, which also exists
+		// if the input list is empty.
+		GroupBy []Expr
+		// Aggregates are the aggregate function calls that are computed for
+		// every group.
+		Aggregates []FunctionExpr
+		// Having is an expression that a group has to match, in order to be
+		// part of the result. May be nil.
+		Having Expr
+		// Input is the input list of datasets.
+		Input List
+	}
+
+	// Window instructs the executor to compute the window functions for every
+	// dataset of the input list. Every dataset of the input list results in a
+	// single dataset, consisting of the values of the input dataset and the
+	// window function results. Window function results can be referenced by
+	// any expression above this window, that is equal to the window function
+	// call.
+	Window struct {
+		// Functions are the window function calls that are computed for every
+		// dataset.
+		Functions []FunctionExpr
+		// Input is the input list of datasets.
+		Input List
+	}
+
+	// Sort instructs the executor to order the datasets of the input list by
+	// the sort terms. The first term has the highest precedence, and datasets
+	// that are equal with respect to all terms keep their input order.
+	Sort struct {
+		// Terms are the sort terms. There is at least one term.
+		Terms []SortTerm
+		// Limit is the amount of datasets that are required from the sorted
+		// list (top to bottom). If it is not nil, only the first Limit
+		// datasets are returned, so that the executor only has to determine
+		// those. A negative value means that all datasets are returned.
+		Limit Expr
+		// Input is the input list of datasets.
+		Input List
+	}
+
+	// SortTerm is a single expression that datasets are sorted by.
+	SortTerm struct {
+		// Expr is the expression whose result is compared.
+		Expr Expr
+		// Desc indicates that the datasets are sorted in descending order.
+		Desc bool
+		// NullsFirst indicates that NULL values are sorted before all other
+		// values, independent of the sort direction.
+		NullsFirst bool
+		// Collation is the name of the collating sequence that texts are
+		// compared with. May be empty, in which case the BINARY collation is
+		// used.
+		Collation string
+	}
+
+	// Union instructs the executor to produce a list of all datasets from the
+	// left and the right input list. Both lists must have the same amount of
+	// columns. Unless All is set, duplicate datasets are only returned once.
+	Union struct {
+		// All indicates that duplicate datasets are kept.
+		All bool
+		// Left is the left input list.
+		Left List
+		// Right is the right input list.
+		Right List
+	}
+
+	// Intersect instructs the executor to produce a list of the datasets from
+	// the left input list, that are also contained in the right input list.
+	// Both lists must have the same amount of columns. Unless All is set,
+	// duplicate datasets are only returned once. Otherwise, a dataset is
+	// returned as often as it is contained in both lists.
+	Intersect struct {
+		// All indicates that duplicate datasets are kept.
+		All bool
+		// Left is the left input list.
+		Left List
+		// Right is the right input list.
+		Right List
+	}
+
+	// Except instructs the executor to produce a list of the datasets from the
+	// left input list, that are not contained in the right input list. Both
+	// lists must have the same amount of columns. Unless All is set, duplicate
+	// datasets are only returned once. Otherwise, every occurrence of a
+	// dataset in the right list removes one occurrence from the left list.
+	Except struct {
+		// All indicates that duplicate datasets are kept.
+		All bool
+		// Left is the left input list.
+		Left List
+		// Right is the right input list.
+		Right List
+	}
+
+	// With defines common tables, that can be referenced by the input list
+	// and by all common tables that are defined after them. A recursive common
+	// table can also reference itself.
+	With struct {
+		// Tables are the common tables, in the order of their definition.
+		Tables []CommonTable
+		// Input is the list that references the common tables.
+		Input List
+	}
+
+	// CommonTable is a named list of datasets, that is defined by a With
+	// command. A recursive common table is computed by evaluating the
+	// recursive list repeatedly, where a self-reference yields the datasets
+	// that were produced by the previous evaluation, until no new datasets are
+	// produced.
+	CommonTable struct {
+		// Name is the name of the common table.
+		Name string
+		// Cols are the names of the columns of the common table. If this is
+		// empty, the names of the columns of the input are used.
+		Cols []string
+		// Input is the list of datasets of this table. If this table is
+		// recursive, it is the list of the initial datasets.
+		Input List
+		// Recursive is the list that is evaluated repeatedly for recursive
+		// tables. It is nil if this table is not recursive.
+		Recursive List
+		// All indicates that datasets produced by Recursive are kept, even if
+		// they were already produced. Otherwise, the evaluation terminates if
+		// only known datasets are produced, which makes it safe for cyclic
+		// data.
+		All bool
+		// Limit is the maximum amount of datasets of a recursive table. May
+		// be nil.
+		Limit Expr
+		// Materialize indicates that this table is computed only once, because
+		// it is referenced more than once.
+		Materialize bool
+	}
+
 	// Values returns a list of datasets from the evaluated expressions.
 	Values struct {
 		// Values are the values that represent the datasets in this list. Each
@@ -299,28 +561,177 @@ type (
 		DefaultValues bool
 		// Input is the input list of datasets, that will be inserted.
 		Input List
+		// OnConflict determines what happens to a dataset, that can not be
+		// inserted because it conflicts with an existing row. May be nil, in
+		// which case the InsertOr fallback is performed.
+		OnConflict *OnConflict
+	}
+
+	// OnConflict is the upsert clause of an insert. Each conflicting dataset
+	// is either dropped, or used to update the existing row that it conflicts
+	// with.
+	OnConflict struct {
+		// Target are the columns, whose uniqueness constraint is handled. If
+		// empty, conflicts with any uniqueness constraint are handled.
+		Target []string
+		// TargetFilter is the filter expression of a partial index, that
+		// the target refers to. May be nil.
+		TargetFilter Expr
+		// Updates are applied to the existing row, if a dataset conflicts
+		// with it. The values of the dataset can be referenced as columns of
+		// the table "excluded". If there are no updates, the dataset is
+		// dropped and the existing row is left unchanged (DO NOTHING).
+		Updates []UpdateSetter
+		// Filter determines, whether the existing row is updated. If it
+		// doesn't pass the filter, the dataset is dropped. May be nil.
+		Filter Expr
+	}
+
+	// CreateTable instructs the executor to create a table with the given name
+	// and column definitions in the given schema.
+	CreateTable struct {
+		// IfNotExists determines whether the executor should ignore an error
+		// that occurs if a table with the given name already exists.
+		IfNotExists bool
+		// Schema is the schema the table is created in. May be empty.
+		Schema string
+		// Name is the name of the created table.
+		Name string
+		// Columns are the column definitions of the created table, in the order
+		// they were declared.
+		Columns []ColumnDef
+		// WithoutRowID indicates, that the created table must not have an
+		// implicit rowid column.
+		WithoutRowID bool
+	}
+
+	// CreateIndex instructs the executor to create an index with the given
+	// name on the given columns of a table.
+	CreateIndex struct {
+		// Unique indicates, that no two rows of the table may have the same
+		// values in all indexed columns, unless any of them is NULL.
+		Unique bool
+		// IfNotExists determines whether the executor should ignore an error
+		// that occurs if an index with the given name already exists.
+		IfNotExists bool
+		// Schema is the schema of the index and the table. May be empty.
+		Schema string
+		// Name is the name of the created index.
+		Name string
+		// Table is the name of the indexed table.
+		Table string
+		// Columns are the names of the indexed columns, in the order of the
+		// index. The sort order of the columns is not recorded, because it
+		// doesn't affect which rows are found through the index.
+		Columns []string
+	}
+
+	// ColumnDef is the definition of a single column of a table, as it is used
+	// when creating a table.
+	ColumnDef struct {
+		// Name is the name of the column.
+		Name string
+		// Type is the declared type of the column, e.g. INTEGER or VARCHAR(25).
+		// May be empty, if no type was declared.
+		Type string
+		// PrimaryKey indicates, whether this column is part of the primary key
+		// of the table. If a table has a single primary key column with the
+		// type INTEGER, that column is an alias for the rowid of the table.
+		PrimaryKey bool
+		// Autoincrement indicates, whether the rowid that this column is an
+		// alias for, must never be re-used, not even for deleted rows. Only
+		// valid on a single INTEGER PRIMARY KEY column.
+		Autoincrement bool
+		// NotNull indicates, that this column must not hold NULL values.
+		NotNull bool
+		// Unique indicates, that all values in this column must be distinct.
+		Unique bool
+		// Default is the default value of this column, that is used if no
+		// value is specified upon insertion. May be nil.
+		Default Expr
 	}
 )
 
-func (Scan) _list()     {}
-func (Select) _list()   {}
-func (Project) _list()  {}
-func (Join) _list()     {}
-func (Limit) _list()    {}
-func (Offset) _list()   {}
-func (Distinct) _list() {}
-func (Values) _list()   {}
+func (Scan) _list()      {}
+func (IndexScan) _list() {}
+func (Select) _list()    {}
+func (Project) _list()   {}
+func (Join) _list()      {}
+func (Limit) _list()     {}
+func (Offset) _list()    {}
+func (Distinct) _list()  {}
+func (Aggregate) _list() {}
+func (Window) _list()    {}
+func (Sort) _list()      {}
+func (Union) _list()     {}
+func (Intersect) _list() {}
+func (Except) _list()    {}
+func (With) _list()      {}
+func (Values) _list()    {}
+func (Empty) _list()     {}
 
-func (SimpleTable) _table() {}
+func (SimpleTable) _table()    {}
+func (SubqueryTable) _table()  {}
+func (CommonTableRef) _table() {}
+func (TableFunction) _table()  {}
 
 func (e Explain) String() string {
+	if len(e.Optimizations) != 0 {
+		return fmt.Sprintf("explanation[optimizations=%v]: %v", strings.Join(e.Optimizations, ","), e.Command)
+	}
 	return fmt.Sprintf("explanation: %v", e.Command)
 }
 
 func (s Scan) String() string {
+	if s.Cols != nil {
+		return fmt.Sprintf("Scan[table=%v,cols=%v]()", s.Table, strings.Join(s.Cols, ","))
+	}
 	return fmt.Sprintf("Scan[table=%v]()", s.Table)
 }
 
+func (s IndexScan) String() string {
+	ranges := make([]string, len(s.Ranges))
+	for i, r := range s.Ranges {
+		ranges[i] = r.String()
+	}
+	if s.Covering {
+		return fmt.Sprintf("IndexScan[table=%v,index=%v,ranges=(%v),covering]()", s.Table, s.Index, strings.Join(ranges, ","))
+	}
+	return fmt.Sprintf("IndexScan[table=%v,index=%v,ranges=(%v)]()", s.Table, s.Index, strings.Join(ranges, ","))
+}
+
+func (r KeyRange) String() string {
+	key := func(exprs []Expr) string {
+		if len(exprs) == 1 {
+			return fmt.Sprint(exprs[0])
+		}
+		strs := make([]string, len(exprs))
+		for i, expr := range exprs {
+			strs[i] = fmt.Sprint(expr)
+		}
+		return "(" + strings.Join(strs, ",") + ")"
+	}
+	var buf strings.Builder
+	if len(r.Lo) == 0 || r.LoExclusive {
+		buf.WriteString("(")
+	} else {
+		buf.WriteString("[")
+	}
+	if len(r.Lo) != 0 {
+		buf.WriteString(key(r.Lo))
+	}
+	buf.WriteString("..")
+	if len(r.Hi) != 0 {
+		buf.WriteString(key(r.Hi))
+	}
+	if len(r.Hi) == 0 || r.HiExclusive {
+		buf.WriteString(")")
+	} else {
+		buf.WriteString("]")
+	}
+	return buf.String()
+}
+
 func (s Select) String() string {
 	return fmt.Sprintf("Select[filter=%v](%v)", s.Filter, s.Input)
 }
@@ -345,6 +756,14 @@ func (d DropTable) String() string {
 	return fmt.Sprintf("DropTable[table=%v,ifexists=%v]()", table, d.IfExists)
 }
 
+func (a Analyze) String() string {
+	name := a.Name
+	if a.Schema != "" {
+		name = a.Schema + "." + name
+	}
+	return fmt.Sprintf("Analyze[name=%v]()", name)
+}
+
 func (d DropIndex) String() string {
 	index := d.Name
 	if d.Schema != "" {
@@ -395,6 +814,9 @@ func (j Join) String() string {
 	if j.Filter != nil {
 		cfg = append(cfg, fmt.Sprintf("filter=%v", j.Filter))
 	}
+	if len(j.Using) != 0 {
+		cfg = append(cfg, fmt.Sprintf("using=(%v)", strings.Join(j.Using, ",")))
+	}
 	if j.Natural {
 		cfg = append(cfg, fmt.Sprintf("natural=%v", j.Natural))
 	}
@@ -426,6 +848,112 @@ func (d Distinct) String() string {
 	return fmt.Sprintf("Distinct[](%v)", d.Input.String())
 }
 
+func (a Aggregate) String() string {
+	groupBy := make([]string, len(a.GroupBy))
+	for i, expr := range a.GroupBy {
+		groupBy[i] = expr.String()
+	}
+	aggregates := make([]string, len(a.Aggregates))
+	for i, fn := range a.Aggregates {
+		aggregates[i] = fn.String()
+	}
+	if a.Having != nil {
+		return fmt.Sprintf("Aggregate[groupby=%v,aggregates=%v,having=%v](%v)", strings.Join(groupBy, ","), strings.Join(aggregates, ","), a.Having, a.Input)
+	}
+	return fmt.Sprintf("Aggregate[groupby=%v,aggregates=%v](%v)", strings.Join(groupBy, ","), strings.Join(aggregates, ","), a.Input)
+}
+
+func (w Window) String() string {
+	functions := make([]string, len(w.Functions))
+	for i, fn := range w.Functions {
+		functions[i] = fn.String()
+	}
+	return fmt.Sprintf("Window[functions=%v](%v)", strings.Join(functions, ","), w.Input)
+}
+
+func (s Sort) String() string {
+	terms := make([]string, len(s.Terms))
+	for i, term := range s.Terms {
+		terms[i] = term.String()
+	}
+	if s.Limit != nil {
+		return fmt.Sprintf("Sort[by=%v,limit=%v](%v)", strings.Join(terms, ","), s.Limit, s.Input)
+	}
+	return fmt.Sprintf("Sort[by=%v](%v)", strings.Join(terms, ","), s.Input)
+}
+
+func (u Union) String() string {
+	return fmt.Sprintf("Union[%v](%v,%v)", setOperationConfig(u.All), u.Left, u.Right)
+}
+
+func (i Intersect) String() string {
+	return fmt.Sprintf("Intersect[%v](%v,%v)", setOperationConfig(i.All), i.Left, i.Right)
+}
+
+func (e Except) String() string {
+	return fmt.Sprintf("Except[%v](%v,%v)", setOperationConfig(e.All), e.Left, e.Right)
+}
+
+func setOperationConfig(all bool) string {
+	if all {
+		return "all"
+	}
+	return ""
+}
+
+func (w With) String() string {
+	tables := make([]string, len(w.Tables))
+	for i, table := range w.Tables {
+		tables[i] = table.String()
+	}
+	return fmt.Sprintf("With[tables=%v](%v)", strings.Join(tables, ","), w.Input)
+}
+
+func (t CommonTable) String() string {
+	var buf strings.Builder
+	buf.WriteString(t.Name)
+	if len(t.Cols) != 0 {
+		buf.WriteString("(" + strings.Join(t.Cols, ",") + ")")
+	}
+	buf.WriteString(" AS ")
+	if t.Materialize {
+		buf.WriteString("MATERIALIZED ")
+	}
+	if t.Recursive == nil {
+		buf.WriteString(fmt.Sprintf("(%v)", t.Input))
+		return buf.String()
+	}
+	buf.WriteString(fmt.Sprintf("RECURSIVE (%v) UNION ", t.Input))
+	if t.All {
+		buf.WriteString("ALL ")
+	}
+	buf.WriteString(fmt.Sprintf("(%v)", t.Recursive))
+	if t.Limit != nil {
+		buf.WriteString(fmt.Sprintf(" LIMIT %v", t.Limit))
+	}
+	return buf.String()
+}
+
+func (t SortTerm) String() string {
+	var buf strings.Builder
+	buf.WriteString(t.Expr.String())
+	if t.Collation != "" {
+		buf.WriteString(" COLLATE ")
+		buf.WriteString(t.Collation)
+	}
+	if t.Desc {
+		buf.WriteString(" DESC")
+	} else {
+		buf.WriteString(" ASC")
+	}
+	if t.NullsFirst {
+		buf.WriteString(" NULLS FIRST")
+	} else {
+		buf.WriteString(" NULLS LAST")
+	}
+	return buf.String()
+}
+
 func (t SimpleTable) String() string {
 	var buf strings.Builder
 	if t.Schema != "" {
@@ -438,6 +966,36 @@ func (t SimpleTable) String() string {
 	return buf.String()
 }
 
+func (t SubqueryTable) String() string {
+	if t.Alias != "" {
+		return fmt.Sprintf("(%v) AS %v", t.Input, t.Alias)
+	}
+	return fmt.Sprintf("(%v)", t.Input)
+}
+
+func (t CommonTableRef) String() string {
+	if t.Alias != "" {
+		return t.Name + " AS " + t.Alias
+	}
+	return t.Name
+}
+
+func (t TableFunction) String() string {
+	var buf strings.Builder
+	if t.Schema != "" {
+		buf.WriteString(t.Schema + ".")
+	}
+	var args []string
+	for _, arg := range t.Args {
+		args = append(args, arg.String())
+	}
+	buf.WriteString(t.Name + "(" + strings.Join(args, ",") + ")")
+	if t.Alias != "" {
+		buf.WriteString(" AS " + t.Alias)
+	}
+	return buf.String()
+}
+
 func (v Values) String() string {
 	var values []string
 	for _, val := range v.Values {
@@ -455,5 +1013,84 @@ func (i Insert) String() string {
 	for _, col := range i.Cols {
 		cols = append(cols, col.String())
 	}
-	return fmt.Sprintf("Insert[table=%v,cols=%v](%v)", i.Table, strings.Join(cols, ","), i.Input)
+	// configuration
+	cfg := []string{
+		fmt.Sprintf("table=%v", i.Table),
+		fmt.Sprintf("cols=%v", strings.Join(cols, ",")),
+	}
+	if i.InsertOr != InsertOrUnknown {
+		cfg = append([]string{fmt.Sprintf("or=%v", i.InsertOr)}, cfg...)
+	}
+	if i.OnConflict != nil {
+		cfg = append(cfg, fmt.Sprintf("onConflict=%v", i.OnConflict))
+	}
+	return fmt.Sprintf("Insert[%v](%v)", strings.Join(cfg, ","), i.Input)
+}
+
+func (o OnConflict) String() string {
+	var buf strings.Builder
+	if len(o.Target) != 0 {
+		buf.WriteString(fmt.Sprintf("(%v)", strings.Join(o.Target, ",")))
+		if o.TargetFilter != nil {
+			buf.WriteString(fmt.Sprintf(" WHERE %v", o.TargetFilter))
+		}
+		buf.WriteString(" ")
+	}
+	if len(o.Updates) == 0 {
+		buf.WriteString("DO NOTHING")
+		return buf.String()
+	}
+	var sets []string
+	for _, set := range o.Updates {
+		sets = append(sets, set.String())
+	}
+	buf.WriteString(fmt.Sprintf("DO UPDATE SET %v", strings.Join(sets, ",")))
+	if o.Filter != nil {
+		buf.WriteString(fmt.Sprintf(" WHERE %v", o.Filter))
+	}
+	return buf.String()
+}
+
+func (c CreateTable) String() string {
+	table := c.Name
+	if c.Schema != "" {
+		table = c.Schema + "." + table
+	}
+	var cols []string
+	for _, col := range c.Columns {
+		cols = append(cols, col.String())
+	}
+	return fmt.Sprintf("CreateTable[table=%v,ifnotexists=%v,withoutrowid=%v](%v)", table, c.IfNotExists, c.WithoutRowID, strings.Join(cols, ","))
+}
+
+func (c CreateIndex) String() string {
+	index := c.Name
+	if c.Schema != "" {
+		index = c.Schema + "." + index
+	}
+	return fmt.Sprintf("CreateIndex[index=%v,table=%v,unique=%v,ifnotexists=%v](%v)", index, c.Table, c.Unique, c.IfNotExists, strings.Join(c.Columns, ","))
+}
+
+func (d ColumnDef) String() string {
+	var buf strings.Builder
+	buf.WriteString(d.Name)
+	if d.Type != "" {
+		buf.WriteString(" " + d.Type)
+	}
+	if d.PrimaryKey {
+		buf.WriteString(" PRIMARY KEY")
+	}
+	if d.Autoincrement {
+		buf.WriteString(" AUTOINCREMENT")
+	}
+	if d.NotNull {
+		buf.WriteString(" NOT NULL")
+	}
+	if d.Unique {
+		buf.WriteString(" UNIQUE")
+	}
+	if d.Default != nil {
+		buf.WriteString(fmt.Sprintf(" DEFAULT %v", d.Default))
+	}
+	return buf.String()
 }
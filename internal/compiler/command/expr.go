@@ -6,6 +6,105 @@ import (
 	"strings"
 )
 
+// FrameUnit is the unit in which the bounds of a frame are measured.
+type FrameUnit uint8
+
+// Known frame units.
+const (
+	FrameUnknown FrameUnit = iota
+	// FrameRows measures bounds in datasets.
+	FrameRows
+	// FrameRange measures bounds in the value of the single ordering term.
+	FrameRange
+	// FrameGroups measures bounds in groups of peers.
+	FrameGroups
+)
+
+func (u FrameUnit) String() string {
+	switch u {
+	case FrameRows:
+		return "ROWS"
+	case FrameRange:
+		return "RANGE"
+	case FrameGroups:
+		return "GROUPS"
+	}
+	return "FrameUnit(" + strconv.Itoa(int(u)) + ")"
+}
+
+// FrameBoundType is the type of a frame bound.
+type FrameBoundType uint8
+
+// Known frame bound types.
+const (
+	BoundUnknown FrameBoundType = iota
+	BoundUnboundedPreceding
+	BoundPreceding
+	BoundCurrentRow
+	BoundFollowing
+	BoundUnboundedFollowing
+)
+
+func (t FrameBoundType) String() string {
+	switch t {
+	case BoundUnboundedPreceding:
+		return "UNBOUNDED PRECEDING"
+	case BoundPreceding:
+		return "PRECEDING"
+	case BoundCurrentRow:
+		return "CURRENT ROW"
+	case BoundFollowing:
+		return "FOLLOWING"
+	case BoundUnboundedFollowing:
+		return "UNBOUNDED FOLLOWING"
+	}
+	return "FrameBoundType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// FrameExclude determines the datasets, that are excluded from a frame.
+type FrameExclude uint8
+
+// Known frame exclusions.
+const (
+	// ExcludeNoOthers excludes no datasets.
+	ExcludeNoOthers FrameExclude = iota
+	// ExcludeCurrentRow excludes the current dataset.
+	ExcludeCurrentRow
+	// ExcludeGroup excludes the current dataset and its peers.
+	ExcludeGroup
+	// ExcludeTies excludes the peers of the current dataset, but not the
+	// current dataset itself.
+	ExcludeTies
+)
+
+func (e FrameExclude) String() string {
+	switch e {
+	case ExcludeNoOthers:
+		return "NO OTHERS"
+	case ExcludeCurrentRow:
+		return "CURRENT ROW"
+	case ExcludeGroup:
+		return "GROUP"
+	case ExcludeTies:
+		return "TIES"
+	}
+	return "FrameExclude(" + strconv.Itoa(int(e)) + ")"
+}
+
+//go:generate stringer -type=RaiseAction
+
+// RaiseAction is the action that is performed by a RAISE expression.
+type RaiseAction uint8
+
+// Known RaiseActions
+const (
+	RaiseUnknown RaiseAction = iota
+	RaiseIgnore
+	RaiseRollback
+	RaiseAbort
+	RaiseFail
+)
+
 type (
 	// Expr is a marker interface for anything that is an expression. Different
 	// implementations of this interface represent different productions of the
@@ -22,6 +121,24 @@ type (
 		Value string
 	}
 
+	// ColumnRef is a reference to a column of a table. The table name and the
+	// schema name are optional. If the table name is empty, the executor has to
+	// resolve the column from all tables that are available in the execution
+	// context.
+	ColumnRef struct {
+		// Schema is the name of the schema of the referenced table. May be
+		// empty. If this is not empty, Table must not be empty either.
+		Schema string
+		// Table is the name or alias of the referenced table. May be empty.
+		Table string
+		// Column is the name of the referenced column.
+		Column string
+		// Depth is the number of enclosing queries, that have to be ascended
+		// to resolve this reference. A reference with a depth greater than
+		// zero is a correlated reference to a column of an outer query.
+		Depth int
+	}
+
 	// ConstantBooleanExpr is a simple expression that represents a boolean
 	// value. It is rarely emitted by the compiler and rather used by
 	// optimizations.
@@ -58,6 +175,50 @@ type (
 		Distinct bool
 		// Args are the function argument expressions.
 		Args []Expr
+		// Filter is the condition of a FILTER clause. Only datasets that match
+		// the filter are passed to an aggregate function. May be nil.
+		Filter Expr
+		// Over is the window, over which this function is computed. If Over is
+		// not nil, this function is a window function.
+		Over *WindowSpec
+	}
+
+	// WindowSpec is the window of a window function. The datasets are divided
+	// into partitions, and the function is computed for every dataset over a
+	// frame of datasets within its partition.
+	WindowSpec struct {
+		// Partition are the expressions, by whose values the datasets are
+		// divided into partitions.
+		Partition []Expr
+		// Order are the terms, by which the datasets within a partition are
+		// ordered. Datasets with equal values for all terms are peers.
+		Order []SortTerm
+		// Frame is the frame of datasets, over which the function is computed
+		// for the current dataset.
+		Frame Frame
+	}
+
+	// Frame is the frame of datasets within a partition, that is considered
+	// for the current dataset. The frame starts and ends at the given bounds,
+	// and the excluded datasets are removed from it.
+	Frame struct {
+		// Unit is the unit in which the bounds are measured.
+		Unit FrameUnit
+		// Start is the start bound of the frame.
+		Start FrameBound
+		// End is the end bound of the frame.
+		End FrameBound
+		// Exclude determines the datasets that are excluded from the frame.
+		Exclude FrameExclude
+	}
+
+	// FrameBound is the start or the end bound of a frame.
+	FrameBound struct {
+		// Type is the type of this bound.
+		Type FrameBoundType
+		// Offset is the offset of a PRECEDING or FOLLOWING bound, in the unit
+		// of the frame. Offset is nil for all other bound types.
+		Offset Expr
 	}
 
 	// EqualityExpr is an expression with a left and right side expression, and
@@ -88,20 +249,178 @@ type (
 		// of this range.
 		Invert bool
 	}
+
+	// CastExpr represents a conversion of a value to a type, of the form
+	// CAST(<Value> AS <Type>).
+	CastExpr struct {
+		// Value is the expression that is converted.
+		Value Expr
+		// Type is the name of the type that the value is converted to.
+		Type string
+	}
+
+	// CollateExpr assigns a collating sequence to an expression, that must be
+	// used when the value is compared.
+	CollateExpr struct {
+		// Value is the expression that the collation is assigned to.
+		Value Expr
+		// Collation is the name of the collating sequence.
+		Collation string
+	}
+
+	// PatternExpr is an expression that matches a value against a pattern,
+	// such as LIKE, GLOB, REGEXP or MATCH.
+	PatternExpr struct {
+		// Operator is the upper case pattern operator, one of LIKE, GLOB,
+		// REGEXP and MATCH.
+		Operator string
+		// Value is the value that is matched against the pattern.
+		Value Expr
+		// Pattern is the pattern that the value is matched against.
+		Pattern Expr
+		// Escape is the escape character of a LIKE pattern. May be nil.
+		Escape Expr
+		// Invert determines whether the value must not match the pattern.
+		Invert bool
+	}
+
+	// IsExpr represents the condition that the left and right side expression
+	// are equal, where NULL is equal to NULL. If this expression is inverted,
+	// the condition is, that both sides are not equal. ISNULL and NOTNULL are
+	// represented by an IsExpr with a NULL literal on the right side.
+	IsExpr struct {
+		// Left is the left hand side expression.
+		Left Expr
+		// Right is the right hand side expression.
+		Right Expr
+		// Invert determines whether this is an IS NOT expression.
+		Invert bool
+	}
+
+	// InExpr represents the condition that a value is contained in a list of
+	// values, or in the result of a query. Exactly one of Values and Input is
+	// set.
+	InExpr struct {
+		// Value is the value that is searched for.
+		Value Expr
+		// Values is the list of expressions that the value is searched in.
+		Values []Expr
+		// Input is the query, that the value is searched in. The query must
+		// have exactly one column.
+		Input List
+		// Invert determines whether the value must not be contained.
+		Invert bool
+	}
+
+	// ExistsExpr represents the condition that a query returns at least one
+	// row.
+	ExistsExpr struct {
+		// Input is the query that is checked for rows.
+		Input List
+		// Invert determines whether the query must not return any rows.
+		Invert bool
+	}
+
+	// CaseExpr is a conditional expression. If Value is set, the result is the
+	// Then expression of the first When expression that is equal to Value.
+	// Otherwise, the result is the Then expression of the first When
+	// expression that evaluates to true. If no case applies, the result is
+	// Else.
+	CaseExpr struct {
+		// Value is the base expression, that is compared to the When
+		// expressions. May be nil.
+		Value Expr
+		// Cases are the cases of this expression, in the order in which they
+		// are evaluated.
+		Cases []WhenThen
+		// Else is the result, if no case applies. May be nil, in which case
+		// the result is NULL.
+		Else Expr
+	}
+
+	// WhenThen is a single case of a case expression.
+	WhenThen struct {
+		// When is the condition of this case.
+		When Expr
+		// Then is the result of this case.
+		Then Expr
+	}
+
+	// SubqueryExpr is a scalar subquery. It evaluates to the first column of
+	// the first row of the nested query, or to NULL if the query has no rows.
+	SubqueryExpr struct {
+		// Input is the nested query.
+		Input List
+	}
+
+	// ParameterExpr is a placeholder for a bind parameter, whose value is
+	// supplied when the command is executed. Parameters are numbered like in
+	// SQLite: a numbered parameter (?NNN) has the given index, and every other
+	// parameter has an index one larger than the largest index before it,
+	// except for a named parameter whose name already occurred, which has the
+	// index of its first occurrence.
+	ParameterExpr struct {
+		// Name is the name of a named parameter, including its prefix (:, @
+		// or $). It is empty for unnamed parameters.
+		Name string
+		// Index is the index of the parameter, starting at 1.
+		Index int
+		// Bound indicates, that the parameter has been bound to a value, and
+		// that Value holds that value.
+		Bound bool
+		// Value is the value of a bound parameter. A NULL value is represented
+		// by nil.
+		Value interface{}
+	}
+
+	// RaiseExpr represents the RAISE function, which can only be used within
+	// triggers.
+	RaiseExpr struct {
+		// Action is the action that is performed when the expression is
+		// evaluated.
+		Action RaiseAction
+		// Message is the error message, as string literal including the
+		// quotes. It is empty if the action is RaiseIgnore.
+		Message string
+	}
 )
 
 func (LiteralExpr) _expr()         {}
+func (ColumnRef) _expr()           {}
 func (ConstantBooleanExpr) _expr() {}
 func (EqualityExpr) _expr()        {}
 func (RangeExpr) _expr()           {}
 func (UnaryExpr) _expr()           {}
 func (BinaryExpr) _expr()          {}
 func (FunctionExpr) _expr()        {}
+func (CastExpr) _expr()            {}
+func (CollateExpr) _expr()         {}
+func (PatternExpr) _expr()         {}
+func (IsExpr) _expr()              {}
+func (InExpr) _expr()              {}
+func (ExistsExpr) _expr()          {}
+func (CaseExpr) _expr()            {}
+func (SubqueryExpr) _expr()        {}
+func (RaiseExpr) _expr()           {}
+func (ParameterExpr) _expr()       {}
 
 func (l LiteralExpr) String() string {
 	return l.Value
 }
 
+func (r ColumnRef) String() string {
+	var buf strings.Builder
+	buf.WriteString(strings.Repeat("^", r.Depth))
+	if r.Schema != "" {
+		buf.WriteString(r.Schema + ".")
+	}
+	if r.Table != "" {
+		buf.WriteString(r.Table + ".")
+	}
+	buf.WriteString(r.Column)
+	return buf.String()
+}
+
 func (b ConstantBooleanExpr) String() string {
 	return strconv.FormatBool(b.Value)
 }
@@ -115,9 +434,9 @@ func (e EqualityExpr) String() string {
 
 func (r RangeExpr) String() string {
 	if r.Invert {
-		return fmt.Sprintf("![%v;%v]", r.Lo, r.Hi)
+		return fmt.Sprintf("%v ![%v;%v]", r.Needle, r.Lo, r.Hi)
 	}
-	return fmt.Sprintf("[%v;%v]", r.Lo, r.Hi)
+	return fmt.Sprintf("%v [%v;%v]", r.Needle, r.Lo, r.Hi)
 }
 
 func (e UnaryExpr) String() string {
@@ -133,8 +452,139 @@ func (f FunctionExpr) String() string {
 	for _, arg := range f.Args {
 		args = append(args, arg.String())
 	}
+	var buf strings.Builder
 	if f.Distinct {
-		return fmt.Sprintf("%s(DISTINCT %s)", f.Name, strings.Join(args, ","))
+		buf.WriteString(fmt.Sprintf("%s(DISTINCT %s)", f.Name, strings.Join(args, ",")))
+	} else {
+		buf.WriteString(fmt.Sprintf("%s(%s)", f.Name, strings.Join(args, ",")))
+	}
+	if f.Filter != nil {
+		buf.WriteString(fmt.Sprintf(" FILTER (WHERE %v)", f.Filter))
+	}
+	if f.Over != nil {
+		buf.WriteString(fmt.Sprintf(" OVER %v", f.Over))
+	}
+	return buf.String()
+}
+
+func (w WindowSpec) String() string {
+	var parts []string
+	if len(w.Partition) != 0 {
+		partition := make([]string, len(w.Partition))
+		for i, expr := range w.Partition {
+			partition[i] = expr.String()
+		}
+		parts = append(parts, "PARTITION BY "+strings.Join(partition, ","))
+	}
+	if len(w.Order) != 0 {
+		order := make([]string, len(w.Order))
+		for i, term := range w.Order {
+			order[i] = term.String()
+		}
+		parts = append(parts, "ORDER BY "+strings.Join(order, ","))
+	}
+	parts = append(parts, w.Frame.String())
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func (f Frame) String() string {
+	frame := fmt.Sprintf("%v BETWEEN %v AND %v", f.Unit, f.Start, f.End)
+	if f.Exclude != ExcludeNoOthers {
+		frame += " EXCLUDE " + f.Exclude.String()
+	}
+	return frame
+}
+
+func (b FrameBound) String() string {
+	if b.Offset != nil {
+		return fmt.Sprintf("%v %v", b.Offset, b.Type)
+	}
+	return b.Type.String()
+}
+
+func (c CastExpr) String() string {
+	return fmt.Sprintf("CAST(%v AS %v)", c.Value, c.Type)
+}
+
+func (c CollateExpr) String() string {
+	return fmt.Sprintf("%v COLLATE %v", c.Value, c.Collation)
+}
+
+func (p PatternExpr) String() string {
+	operator := p.Operator
+	if p.Invert {
+		operator = "NOT " + operator
+	}
+	if p.Escape != nil {
+		return fmt.Sprintf("%v %v %v ESCAPE %v", p.Value, operator, p.Pattern, p.Escape)
+	}
+	return fmt.Sprintf("%v %v %v", p.Value, operator, p.Pattern)
+}
+
+func (i IsExpr) String() string {
+	if i.Invert {
+		return fmt.Sprintf("%v IS NOT %v", i.Left, i.Right)
+	}
+	return fmt.Sprintf("%v IS %v", i.Left, i.Right)
+}
+
+func (i InExpr) String() string {
+	operator := "IN"
+	if i.Invert {
+		operator = "NOT IN"
+	}
+	if i.Input != nil {
+		return fmt.Sprintf("%v %v (%v)", i.Value, operator, i.Input)
+	}
+	var values []string
+	for _, value := range i.Values {
+		values = append(values, value.String())
+	}
+	return fmt.Sprintf("%v %v (%v)", i.Value, operator, strings.Join(values, ","))
+}
+
+func (e ExistsExpr) String() string {
+	if e.Invert {
+		return fmt.Sprintf("NOT EXISTS (%v)", e.Input)
+	}
+	return fmt.Sprintf("EXISTS (%v)", e.Input)
+}
+
+func (c CaseExpr) String() string {
+	var buf strings.Builder
+	buf.WriteString("CASE")
+	if c.Value != nil {
+		buf.WriteString(" " + c.Value.String())
+	}
+	for _, whenThen := range c.Cases {
+		buf.WriteString(fmt.Sprintf(" WHEN %v THEN %v", whenThen.When, whenThen.Then))
+	}
+	if c.Else != nil {
+		buf.WriteString(" ELSE " + c.Else.String())
+	}
+	buf.WriteString(" END")
+	return buf.String()
+}
+
+func (s SubqueryExpr) String() string {
+	return fmt.Sprintf("(%v)", s.Input)
+}
+
+func (p ParameterExpr) String() string {
+	name := p.Name
+	if name == "" {
+		name = "?" + strconv.Itoa(p.Index)
+	}
+	if p.Bound {
+		return fmt.Sprintf("%v=%#v", name, p.Value)
+	}
+	return name
+}
+
+func (r RaiseExpr) String() string {
+	action := strings.ToUpper(strings.TrimPrefix(r.Action.String(), "Raise"))
+	if r.Action == RaiseIgnore {
+		return fmt.Sprintf("RAISE(%v)", action)
 	}
-	return fmt.Sprintf("%s(%s)", f.Name, strings.Join(args, ","))
+	return fmt.Sprintf("RAISE(%v,%v)", action, r.Message)
 }
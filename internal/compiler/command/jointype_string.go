@@ -13,11 +13,13 @@ func _() {
 	_ = x[JoinLeftOuter-2]
 	_ = x[JoinInner-3]
 	_ = x[JoinCross-4]
+	_ = x[JoinSemi-5]
+	_ = x[JoinAnti-6]
 }
 
-const _JoinType_name = "JoinUnknownJoinLeftJoinLeftOuterJoinInnerJoinCross"
+const _JoinType_name = "JoinUnknownJoinLeftJoinLeftOuterJoinInnerJoinCrossJoinSemiJoinAnti"
 
-var _JoinType_index = [...]uint8{0, 11, 19, 32, 41, 50}
+var _JoinType_index = [...]uint8{0, 11, 19, 32, 41, 50, 58, 66}
 
 func (i JoinType) String() string {
 	if i >= JoinType(len(_JoinType_index)-1) {
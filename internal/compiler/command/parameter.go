@@ -0,0 +1,70 @@
+package command
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Parameters returns the bind parameters of the given command, ordered by
+// their index. A parameter that occurs more than once is only returned once.
+// The number of parameters, that have to be bound before the command can be
+// executed, is the index of the last parameter, because SQLite allows gaps in
+// the parameter indices.
+func Parameters(cmd Command) []ParameterExpr {
+	byIndex := make(map[int]ParameterExpr)
+	Walk(cmd, func(node Node) bool {
+		if param, ok := node.(ParameterExpr); ok {
+			byIndex[param.Index] = param
+		}
+		return true
+	})
+
+	params := make([]ParameterExpr, 0, len(byIndex))
+	for _, param := range byIndex {
+		params = append(params, param)
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Index < params[j].Index
+	})
+	return params
+}
+
+// Bind returns a copy of the given command, in which all bind parameters are
+// bound to the given values. A value with a name is bound to the parameter
+// with that name, where the prefix of the parameter name may be omitted. A
+// value without a name is bound to the parameter whose index is the ordinal of
+// the value. An error is returned if a parameter remains without a value, or
+// if a value doesn't belong to any parameter.
+func Bind(cmd Command, args []driver.NamedValue) (Command, error) {
+	used := make([]bool, len(args))
+	bound, err := Rewrite(cmd, nil, func(node Node) (Node, error) {
+		param, ok := node.(ParameterExpr)
+		if !ok {
+			return node, nil
+		}
+		for i, arg := range args {
+			if arg.Name != "" && param.Name != "" && strings.TrimLeft(param.Name, ":@$") == strings.TrimLeft(arg.Name, ":@$") ||
+				arg.Name == "" && arg.Ordinal == param.Index {
+				used[i] = true
+				param.Bound = true
+				param.Value = arg.Value
+				return param, nil
+			}
+		}
+		return nil, fmt.Errorf("no value for parameter %v", param)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, arg := range args {
+		if !used[i] {
+			if arg.Name != "" {
+				return nil, fmt.Errorf("no parameter named %v", arg.Name)
+			}
+			return nil, fmt.Errorf("no parameter with index %v", arg.Ordinal)
+		}
+	}
+	return bound, nil
+}
@@ -0,0 +1,437 @@
+package command
+
+import "fmt"
+
+// Node is a node of a command tree, which is a Command, a List, a Table or an
+// Expr. Parts of nodes, such as columns, sort terms and common tables, are not
+// nodes themselves, but the nodes that they hold are children of the node
+// that holds them.
+type Node interface {
+	fmt.Stringer
+}
+
+// Walk traverses the given command tree in depth-first order, like
+// ast.Inspect. It calls fn for the given node, and if fn returns true, it
+// walks every child of the node, in the order of the fields that hold them.
+// Nil children are not visited.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	_, _ = children(node, func(child Node) (Node, error) {
+		Walk(child, fn)
+		return child, nil
+	})
+}
+
+// Rewrite returns a copy of the given command tree, in which nodes are
+// replaced, like astutil.Apply. For every node, pre is called before the
+// children of the node are rewritten. If it returns false, the node is kept as
+// it is, and neither its children are rewritten, nor is post called for it.
+// Otherwise, post is called with a copy of the node, that holds the rewritten
+// children, and the node is replaced by the result of post. A node may be
+// replaced by nil, which removes it from the node that holds it. Nil children
+// are not visited, and either function may be nil.
+//
+// If post returns an error, the rewrite is aborted, and the error is
+// returned. An error is also returned, if a node is replaced by a node, that
+// can't take its place, such as a list, that is replaced by an expression. The
+// input command tree is not modified.
+func Rewrite(node Node, pre func(Node) bool, post func(Node) (Node, error)) (Node, error) {
+	if node == nil || (pre != nil && !pre(node)) {
+		return node, nil
+	}
+	node, err := children(node, func(child Node) (Node, error) {
+		return Rewrite(child, pre, post)
+	})
+	if err != nil || post == nil {
+		return node, err
+	}
+	return post(node)
+}
+
+// children returns a copy of the given node, in which every child is replaced
+// by the result of fn. This is the only place, that knows the children of the
+// nodes.
+func children(node Node, fn func(Node) (Node, error)) (Node, error) {
+	r := &replacer{fn: fn}
+	switch n := node.(type) {
+	case Explain:
+		n.Command = r.command(n.Command)
+		node = n
+	case Delete:
+		n.Table = r.table(n.Table)
+		n.Filter = r.expr(n.Filter)
+		node = n
+	case Update:
+		n.Table = r.table(n.Table)
+		n.Updates = r.setters(n.Updates)
+		n.Filter = r.expr(n.Filter)
+		node = n
+	case Insert:
+		n.Table = r.table(n.Table)
+		n.Cols = r.columns(n.Cols)
+		n.Input = r.list(n.Input)
+		if n.OnConflict != nil {
+			onConflict := *n.OnConflict
+			onConflict.TargetFilter = r.expr(onConflict.TargetFilter)
+			onConflict.Updates = r.setters(onConflict.Updates)
+			onConflict.Filter = r.expr(onConflict.Filter)
+			n.OnConflict = &onConflict
+		}
+		node = n
+	case CreateTable:
+		cols := make([]ColumnDef, len(n.Columns))
+		for i, col := range n.Columns {
+			col.Default = r.expr(col.Default)
+			cols[i] = col
+		}
+		n.Columns = cols
+		node = n
+
+	case Scan:
+		n.Table = r.table(n.Table)
+		node = n
+	case IndexScan:
+		n.Table = r.simpleTable(n.Table)
+		ranges := make([]KeyRange, len(n.Ranges))
+		for i, keyRange := range n.Ranges {
+			keyRange.Lo = r.exprs(keyRange.Lo)
+			keyRange.Hi = r.exprs(keyRange.Hi)
+			ranges[i] = keyRange
+		}
+		n.Ranges = ranges
+		node = n
+	case Select:
+		n.Filter = r.expr(n.Filter)
+		n.Input = r.list(n.Input)
+		node = n
+	case Project:
+		n.Cols = r.columns(n.Cols)
+		n.Input = r.list(n.Input)
+		node = n
+	case Join:
+		n.Filter = r.expr(n.Filter)
+		n.Left = r.list(n.Left)
+		n.Right = r.list(n.Right)
+		node = n
+	case Limit:
+		n.Limit = r.expr(n.Limit)
+		n.Input = r.list(n.Input)
+		node = n
+	case Offset:
+		n.Offset = r.expr(n.Offset)
+		n.Input = r.list(n.Input)
+		node = n
+	case Empty:
+		n.Cols = r.columns(n.Cols)
+		node = n
+	case Distinct:
+		n.Input = r.list(n.Input)
+		node = n
+	case Aggregate:
+		n.GroupBy = r.exprs(n.GroupBy)
+		n.Aggregates = r.functions(n.Aggregates)
+		n.Having = r.expr(n.Having)
+		n.Input = r.list(n.Input)
+		node = n
+	case Window:
+		n.Functions = r.functions(n.Functions)
+		n.Input = r.list(n.Input)
+		node = n
+	case Sort:
+		n.Terms = r.sortTerms(n.Terms)
+		n.Limit = r.expr(n.Limit)
+		n.Input = r.list(n.Input)
+		node = n
+	case Union:
+		n.Left = r.list(n.Left)
+		n.Right = r.list(n.Right)
+		node = n
+	case Intersect:
+		n.Left = r.list(n.Left)
+		n.Right = r.list(n.Right)
+		node = n
+	case Except:
+		n.Left = r.list(n.Left)
+		n.Right = r.list(n.Right)
+		node = n
+	case With:
+		tables := make([]CommonTable, len(n.Tables))
+		for i, table := range n.Tables {
+			table.Input = r.list(table.Input)
+			table.Recursive = r.list(table.Recursive)
+			table.Limit = r.expr(table.Limit)
+			tables[i] = table
+		}
+		n.Tables = tables
+		n.Input = r.list(n.Input)
+		node = n
+	case Values:
+		values := make([][]Expr, len(n.Values))
+		for i, exprs := range n.Values {
+			values[i] = r.exprs(exprs)
+		}
+		n.Values = values
+		node = n
+
+	case SubqueryTable:
+		n.Input = r.list(n.Input)
+		node = n
+	case TableFunction:
+		n.Args = r.exprs(n.Args)
+		node = n
+
+	case UnaryExpr:
+		n.Value = r.expr(n.Value)
+		node = n
+	case BinaryExpr:
+		n.Left = r.expr(n.Left)
+		n.Right = r.expr(n.Right)
+		node = n
+	case FunctionExpr:
+		n.Args = r.exprs(n.Args)
+		n.Filter = r.expr(n.Filter)
+		if n.Over != nil {
+			over := *n.Over
+			over.Partition = r.exprs(over.Partition)
+			over.Order = r.sortTerms(over.Order)
+			over.Frame.Start.Offset = r.expr(over.Frame.Start.Offset)
+			over.Frame.End.Offset = r.expr(over.Frame.End.Offset)
+			n.Over = &over
+		}
+		node = n
+	case EqualityExpr:
+		n.Left = r.expr(n.Left)
+		n.Right = r.expr(n.Right)
+		node = n
+	case RangeExpr:
+		n.Needle = r.expr(n.Needle)
+		n.Lo = r.expr(n.Lo)
+		n.Hi = r.expr(n.Hi)
+		node = n
+	case CastExpr:
+		n.Value = r.expr(n.Value)
+		node = n
+	case CollateExpr:
+		n.Value = r.expr(n.Value)
+		node = n
+	case PatternExpr:
+		n.Value = r.expr(n.Value)
+		n.Pattern = r.expr(n.Pattern)
+		n.Escape = r.expr(n.Escape)
+		node = n
+	case IsExpr:
+		n.Left = r.expr(n.Left)
+		n.Right = r.expr(n.Right)
+		node = n
+	case InExpr:
+		n.Value = r.expr(n.Value)
+		n.Values = r.exprs(n.Values)
+		n.Input = r.list(n.Input)
+		node = n
+	case ExistsExpr:
+		n.Input = r.list(n.Input)
+		node = n
+	case CaseExpr:
+		n.Value = r.expr(n.Value)
+		cases := make([]WhenThen, len(n.Cases))
+		for i, whenThen := range n.Cases {
+			whenThen.When = r.expr(whenThen.When)
+			whenThen.Then = r.expr(whenThen.Then)
+			cases[i] = whenThen
+		}
+		n.Cases = cases
+		n.Else = r.expr(n.Else)
+		node = n
+	case SubqueryExpr:
+		n.Input = r.list(n.Input)
+		node = n
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return node, nil
+}
+
+// replacer replaces the children of a node with the result of fn, and checks
+// that every replacement can take the place of the child. Once fn returned an
+// error, no more children are replaced.
+type replacer struct {
+	fn  func(Node) (Node, error)
+	err error
+}
+
+// replace returns the replacement of the given child, which is not nil. If
+// there is no replacement, ok=false is returned.
+func (r *replacer) replace(child Node) (replacement Node, ok bool) {
+	if r.err != nil {
+		return nil, false
+	}
+	replacement, r.err = r.fn(child)
+	return replacement, r.err == nil
+}
+
+func (r *replacer) mismatch(child, replacement Node) {
+	r.err = fmt.Errorf("cannot replace %T %v with %T", child, child, replacement)
+}
+
+func (r *replacer) command(cmd Command) Command {
+	if cmd == nil {
+		return nil
+	}
+	replacement, ok := r.replace(cmd)
+	if !ok {
+		return cmd
+	}
+	return replacement
+}
+
+func (r *replacer) list(list List) List {
+	if list == nil {
+		return nil
+	}
+	replacement, ok := r.replace(list)
+	if !ok {
+		return list
+	}
+	if replacement == nil {
+		return nil
+	}
+	replaced, ok := replacement.(List)
+	if !ok {
+		r.mismatch(list, replacement)
+		return list
+	}
+	return replaced
+}
+
+func (r *replacer) table(table Table) Table {
+	if table == nil {
+		return nil
+	}
+	child, ok := table.(Node)
+	if !ok {
+		return table
+	}
+	replacement, ok := r.replace(child)
+	if !ok {
+		return table
+	}
+	if replacement == nil {
+		return nil
+	}
+	replaced, ok := replacement.(Table)
+	if !ok {
+		r.mismatch(child, replacement)
+		return table
+	}
+	return replaced
+}
+
+// simpleTable replaces a simple table, that must remain a simple table, such
+// as the table of an index scan.
+func (r *replacer) simpleTable(table SimpleTable) SimpleTable {
+	replacement, ok := r.replace(table)
+	if !ok {
+		return table
+	}
+	replaced, ok := replacement.(SimpleTable)
+	if !ok {
+		r.mismatch(table, replacement)
+		return table
+	}
+	return replaced
+}
+
+func (r *replacer) expr(expr Expr) Expr {
+	if expr == nil {
+		return nil
+	}
+	replacement, ok := r.replace(expr)
+	if !ok {
+		return expr
+	}
+	if replacement == nil {
+		return nil
+	}
+	replaced, ok := replacement.(Expr)
+	if !ok {
+		r.mismatch(expr, replacement)
+		return expr
+	}
+	return replaced
+}
+
+func (r *replacer) exprs(exprs []Expr) []Expr {
+	if exprs == nil {
+		return nil
+	}
+	replaced := make([]Expr, len(exprs))
+	for i, expr := range exprs {
+		replaced[i] = r.expr(expr)
+	}
+	return replaced
+}
+
+// function replaces a function call, that must remain a function call, such
+// as an aggregate or a window function.
+func (r *replacer) function(fn FunctionExpr) FunctionExpr {
+	replacement, ok := r.replace(fn)
+	if !ok {
+		return fn
+	}
+	replaced, ok := replacement.(FunctionExpr)
+	if !ok {
+		r.mismatch(fn, replacement)
+		return fn
+	}
+	return replaced
+}
+
+func (r *replacer) functions(fns []FunctionExpr) []FunctionExpr {
+	if fns == nil {
+		return nil
+	}
+	replaced := make([]FunctionExpr, len(fns))
+	for i, fn := range fns {
+		replaced[i] = r.function(fn)
+	}
+	return replaced
+}
+
+func (r *replacer) columns(cols []Column) []Column {
+	if cols == nil {
+		return nil
+	}
+	replaced := make([]Column, len(cols))
+	for i, col := range cols {
+		col.Column = r.expr(col.Column)
+		replaced[i] = col
+	}
+	return replaced
+}
+
+func (r *replacer) sortTerms(terms []SortTerm) []SortTerm {
+	if terms == nil {
+		return nil
+	}
+	replaced := make([]SortTerm, len(terms))
+	for i, term := range terms {
+		term.Expr = r.expr(term.Expr)
+		replaced[i] = term
+	}
+	return replaced
+}
+
+func (r *replacer) setters(setters []UpdateSetter) []UpdateSetter {
+	if setters == nil {
+		return nil
+	}
+	replaced := make([]UpdateSetter, len(setters))
+	for i, setter := range setters {
+		setter.Value = r.expr(setter.Value)
+		replaced[i] = setter
+	}
+	return replaced
+}
@@ -0,0 +1,130 @@
+package command
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	cmd := Project{
+		Cols: []Column{{Column: ColumnRef{Column: "v"}}},
+		Input: Select{
+			Filter: InExpr{
+				Value: ColumnRef{Column: "id"},
+				Input: Project{
+					Cols:  []Column{{Column: ColumnRef{Column: "id"}}},
+					Input: Scan{Table: SimpleTable{Table: "b"}},
+				},
+			},
+			Input: Scan{Table: SubqueryTable{Input: Values{Values: [][]Expr{{LiteralExpr{Value: "1"}}}}}},
+		},
+	}
+
+	var visited []string
+	Walk(cmd, func(node Node) bool {
+		visited = append(visited, fmt.Sprintf("%T", node))
+		return true
+	})
+	assert.Equal(t, []string{
+		"command.Project", "command.ColumnRef", "command.Select", "command.InExpr", "command.ColumnRef",
+		"command.Project", "command.ColumnRef", "command.Scan", "command.SimpleTable",
+		"command.Scan", "command.SubqueryTable", "command.Values", "command.LiteralExpr",
+	}, visited)
+
+	visited = nil
+	Walk(cmd, func(node Node) bool {
+		if _, ok := node.(Expr); ok {
+			visited = append(visited, node.String())
+			return false
+		}
+		return true
+	})
+	assert.Equal(t, []string{"v", "id IN (Project[cols=id](Scan[table=b]()))", "1"}, visited)
+
+	visited = nil
+	Walk(IndexScan{
+		Table:  SimpleTable{Table: "a"},
+		Index:  "a_v",
+		Ranges: []KeyRange{{Lo: []Expr{LiteralExpr{Value: "1"}}, Hi: []Expr{LiteralExpr{Value: "2"}}}},
+	}, func(node Node) bool {
+		visited = append(visited, fmt.Sprintf("%T", node))
+		return true
+	})
+	assert.Equal(t, []string{"command.IndexScan", "command.SimpleTable", "command.LiteralExpr", "command.LiteralExpr"}, visited)
+}
+
+func TestRewrite(t *testing.T) {
+	cmd := Join{
+		Filter: BinaryExpr{
+			Operator: "==",
+			Left:     ColumnRef{Table: "a", Column: "id"},
+			Right:    SubqueryExpr{Input: Project{Cols: []Column{{Column: ColumnRef{Table: "a", Column: "id"}}}, Input: Scan{Table: SimpleTable{Table: "c"}}}},
+		},
+		Left:  Scan{Table: SimpleTable{Table: "a"}},
+		Right: Scan{Table: SimpleTable{Table: "b"}},
+	}
+	original := cmd.String()
+
+	t.Run("post", func(t *testing.T) {
+		rewritten, err := Rewrite(cmd, func(node Node) bool {
+			_, isSubquery := node.(SubqueryExpr)
+			return !isSubquery
+		}, func(node Node) (Node, error) {
+			if ref, ok := node.(ColumnRef); ok {
+				ref.Table = "b"
+				return ref, nil
+			}
+			return node, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Join[filter=b.id == (Project[cols=a.id](Scan[table=c]()))](Scan[table=a](),Scan[table=b]())", rewritten.String())
+		assert.Equal(t, original, cmd.String())
+	})
+	t.Run("remove", func(t *testing.T) {
+		rewritten, err := Rewrite(cmd, nil, func(node Node) (Node, error) {
+			if scan, ok := node.(Scan); ok && scan.Table.(SimpleTable).Table == "a" {
+				return nil, nil
+			}
+			return node, nil
+		})
+		require.NoError(t, err)
+		assert.Nil(t, rewritten.(Join).Left)
+		assert.Equal(t, original, cmd.String())
+	})
+	t.Run("table", func(t *testing.T) {
+		cmd := Union{
+			Left:  Scan{Table: SimpleTable{Table: "a"}},
+			Right: IndexScan{Table: SimpleTable{Table: "b"}, Index: "b_v"},
+		}
+		qualify := func(node Node) (Node, error) {
+			if table, ok := node.(SimpleTable); ok {
+				table.Schema = "main"
+				return table, nil
+			}
+			return node, nil
+		}
+		rewritten, err := Rewrite(cmd, nil, qualify)
+		require.NoError(t, err)
+		assert.Equal(t, "Union[](Scan[table=main.a](),IndexScan[table=main.b,index=b_v,ranges=()]())", rewritten.String())
+
+		_, err = Rewrite(cmd, nil, func(node Node) (Node, error) {
+			if _, ok := node.(SimpleTable); ok {
+				return SubqueryTable{Input: Scan{Table: SimpleTable{Table: "c"}}}, nil
+			}
+			return node, nil
+		})
+		assert.Error(t, err, "the table of an index scan must remain a simple table")
+	})
+	t.Run("mismatch", func(t *testing.T) {
+		_, err := Rewrite(cmd, nil, func(node Node) (Node, error) {
+			if _, ok := node.(Scan); ok {
+				return LiteralExpr{Value: "1"}, nil
+			}
+			return node, nil
+		})
+		assert.Error(t, err)
+	})
+}
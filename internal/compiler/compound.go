@@ -0,0 +1,153 @@
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+)
+
+// compileCompound compiles the given select cores, which are connected by
+// their compound operators, to a set operation. Set operations are evaluated
+// from left to right. The given ordering terms are applied to the result of
+// the set operation.
+func (c *simpleCompiler) compileCompound(cores []*ast.SelectCore, order []*ast.OrderingTerm) (command.List, error) {
+	var list, first command.List
+	var cols []command.Column
+	var count int
+	for i, core := range cores {
+		compiled, err := c.compileSelectCore(core, nil)
+		if err != nil {
+			return nil, fmt.Errorf("core: %w", err)
+		}
+		right := compiled.(command.List)
+		rightCols, rightCount := resultColumns(right)
+		if i == 0 {
+			list, first, cols, count = right, right, rightCols, rightCount
+			continue
+		}
+
+		operator := cores[i-1].CompoundOperator
+		if operator == nil {
+			return nil, fmt.Errorf("missing compound operator")
+		}
+		if count != -1 && rightCount != -1 && count != rightCount {
+			return nil, fmt.Errorf("SELECTs to the left and right of %v do not have the same number of result columns", compoundOperatorName(operator))
+		}
+
+		switch {
+		case operator.Union != nil:
+			list = command.Union{All: operator.All != nil, Left: list, Right: right}
+		case operator.Intersect != nil:
+			list = command.Intersect{Left: list, Right: right}
+		case operator.Except != nil:
+			list = command.Except{Left: list, Right: right}
+		default:
+			return nil, fmt.Errorf("compound operator: %w", ErrUnsupported)
+		}
+	}
+
+	if len(order) == 0 {
+		return list, nil
+	}
+	if c.catalog != nil {
+		// the ordering terms reference the result columns of the first select
+		c.pushScope()
+		defer c.popScope()
+		c.bindResultScope(first)
+	}
+	terms, err := c.compileCompoundOrderingTerms(order, cols)
+	if err != nil {
+		return nil, fmt.Errorf("order: %w", err)
+	}
+	return command.Sort{
+		Terms: terms,
+		Input: list,
+	}, nil
+}
+
+// compileCompoundOrderingTerms compiles the ordering terms of a compound
+// select, whose result columns are the given columns of the first select. Every
+// term must refer to a result column, either by its position, by its name or
+// by an equal expression. The terms are compiled to references to the result
+// column by name.
+func (c *simpleCompiler) compileCompoundOrderingTerms(order []*ast.OrderingTerm, cols []command.Column) ([]command.SortTerm, error) {
+	terms, err := c.compileOrderingTerms(order, cols)
+	if err != nil {
+		return nil, err
+	}
+	for i, term := range terms {
+		position := -1
+		for j, col := range cols {
+			if ref, ok := term.Expr.(command.ColumnRef); ok && ref.Table == "" && strings.EqualFold(ref.Column, resultColumnName(col)) {
+				position = j
+				break
+			}
+			if term.Expr.String() == col.Column.String() {
+				position = j
+				break
+			}
+		}
+		if position == -1 {
+			return nil, fmt.Errorf("term %v does not match any column in the result set", i+1)
+		}
+		terms[i].Expr = command.ColumnRef{Column: resultColumnName(cols[position])}
+	}
+	return terms, nil
+}
+
+// resultColumns returns the result columns of the given list, and their
+// amount. If the amount can't be determined, because the list contains a
+// star, the amount is -1.
+func resultColumns(list command.List) ([]command.Column, int) {
+	switch l := list.(type) {
+	case command.Values:
+		var cols []command.Column
+		if len(l.Values) != 0 {
+			for i := range l.Values[0] {
+				cols = append(cols, command.Column{
+					Column: command.ColumnRef{Column: "column" + strconv.Itoa(i+1)},
+				})
+			}
+		}
+		return cols, len(cols)
+	case command.Project:
+		for _, col := range l.Cols {
+			if lit, ok := col.Column.(command.LiteralExpr); ok && lit.Value == "*" {
+				return l.Cols, -1
+			}
+		}
+		return l.Cols, len(l.Cols)
+	case command.Distinct:
+		return resultColumns(l.Input)
+	case command.Sort:
+		return resultColumns(l.Input)
+	}
+	return nil, -1
+}
+
+// resultColumnName returns the name of the given result column, which is its
+// alias, the name of the referenced column or the expression.
+func resultColumnName(col command.Column) string {
+	if col.Alias != "" {
+		return col.Alias
+	}
+	if ref, ok := col.Column.(command.ColumnRef); ok {
+		return ref.Column
+	}
+	return col.Column.String()
+}
+
+func compoundOperatorName(operator *ast.CompoundOperator) string {
+	switch {
+	case operator.Union != nil && operator.All != nil:
+		return "UNION ALL"
+	case operator.Union != nil:
+		return "UNION"
+	case operator.Intersect != nil:
+		return "INTERSECT"
+	}
+	return "EXCEPT"
+}
@@ -1,5 +1,11 @@
 package compiler
 
+import (
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
 // Error is a helper type for creating constant errors.
 type Error string
 
@@ -9,4 +15,43 @@ const (
 	// ErrUnsupported indicates that something is not supported. What exactly is
 	// unsupported, must be indicated by a wrapping error.
 	ErrUnsupported Error = "unsupported"
+	// ErrNoSuchTable indicates that a referenced table does not exist in the
+	// catalog.
+	ErrNoSuchTable Error = "no such table"
+	// ErrNoSuchColumn indicates that a referenced column does not exist in any
+	// of the visible tables.
+	ErrNoSuchColumn Error = "no such column"
+	// ErrAmbiguousColumn indicates that an unqualified column reference
+	// matches columns of more than one visible table.
+	ErrAmbiguousColumn Error = "ambiguous column name"
+	// ErrTypeMismatch indicates that an operator or a function is applied to
+	// a value of a type, that it can't be applied to.
+	ErrTypeMismatch Error = "type mismatch"
 )
+
+// BindError is a problem, that was found while binding a statement against the
+// catalog. It holds the position of the token, at which the problem was found.
+type BindError struct {
+	// Line, Col, Offset and Length describe the position of the token, at
+	// which the problem was found.
+	Line, Col, Offset, Length int
+	// Err is the problem.
+	Err error
+}
+
+func newBindError(at token.Token, err error) BindError {
+	return BindError{
+		Line:   at.Line(),
+		Col:    at.Col(),
+		Offset: at.Offset(),
+		Length: at.Length(),
+		Err:    err,
+	}
+}
+
+func (e BindError) Error() string {
+	return fmt.Sprintf("%v at (%d:%d) offset %d length %d", e.Err, e.Line, e.Col, e.Offset, e.Length)
+}
+
+// Unwrap returns the problem, that this error holds.
+func (e BindError) Unwrap() error { return e.Err }
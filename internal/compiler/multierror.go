@@ -15,6 +15,12 @@ func (e *MultiError) Append(err error) {
 	e.errs = append(e.errs, err)
 }
 
+// Errors returns all errors, that were appended to the multi error, in the
+// order in which they were appended.
+func (e *MultiError) Errors() []error {
+	return e.errs
+}
+
 func (e *MultiError) Error() string {
 	if len(e.errs) == 0 {
 		return ""
@@ -0,0 +1,237 @@
+package optimization
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// PruneColumns is the rule that narrows the scans below a projection to the
+// columns, that are referenced by the projection and the lists between them,
+// so that rows only hold the values, that are actually used. Columns of a
+// projection in a subquery, that are not referenced by the enclosing query,
+// are removed, but at least one column is kept.
+//
+// Unqualified column references are required from all tables. Lists, that
+// depend on all columns of their input, such as distinct lists, compound
+// lists and joins with a USING clause, are not narrowed. If any expression
+// contains a subquery, which may reference columns of the enclosing query,
+// nothing is narrowed.
+var PruneColumns = Rule{
+	Name:  "PruneColumns",
+	Apply: pruneColumns,
+}
+
+// requiredColumns are the column references, that a list has to provide
+// columns for. If all is set, the list has to provide all of its columns.
+type requiredColumns struct {
+	all  bool
+	refs []command.ColumnRef
+}
+
+func pruneColumns(cmd command.Command) (command.Command, bool) {
+	project, ok := cmd.(command.Project)
+	if !ok || hasStar(project.Cols) {
+		return nil, false
+	}
+	var exprs []command.Expr
+	for _, col := range project.Cols {
+		exprs = append(exprs, col.Column)
+	}
+	input, ok := prune(project.Input, requiredColumns{}.with(exprs...))
+	if !ok {
+		return nil, false
+	}
+	project.Input = input
+	return project, true
+}
+
+// prune narrows the scans of the given list to the required columns. The
+// second return value indicates whether any scan was changed.
+func prune(list command.List, required requiredColumns) (command.List, bool) {
+	var changed bool
+	input := func(list command.List, required requiredColumns) command.List {
+		pruned, ok := prune(list, required)
+		changed = changed || ok
+		return pruned
+	}
+	switch l := list.(type) {
+	case command.Scan:
+		return pruneScan(l, required)
+	case command.Select:
+		l.Input = input(l.Input, required.with(l.Filter))
+		return l, changed
+	case command.Join:
+		if isSemiOrAntiJoin(l) {
+			// the filter references columns of the left side like a
+			// correlated subquery, and unqualified references may resolve to
+			// either side
+			l.Left = input(l.Left, required.with(l.Filter).withDepth(1, l.Filter))
+			l.Right = input(l.Right, requiredColumns{}.with(l.Filter))
+			return l, changed
+		}
+		if l.Natural || len(l.Using) != 0 {
+			required = requiredColumns{all: true}
+		}
+		required = required.with(l.Filter)
+		l.Left = input(l.Left, required)
+		l.Right = input(l.Right, required)
+		return l, changed
+	case command.Sort:
+		exprs := []command.Expr{l.Limit}
+		for _, term := range l.Terms {
+			exprs = append(exprs, term.Expr)
+		}
+		l.Input = input(l.Input, required.with(exprs...))
+		return l, changed
+	case command.Limit:
+		l.Input = input(l.Input, required.with(l.Limit))
+		return l, changed
+	case command.Offset:
+		l.Input = input(l.Input, required.with(l.Offset))
+		return l, changed
+	case command.Aggregate:
+		exprs := append([]command.Expr{l.Having}, l.GroupBy...)
+		for _, fn := range l.Aggregates {
+			exprs = append(exprs, fn)
+		}
+		l.Input = input(l.Input, required.with(exprs...))
+		return l, changed
+	case command.Window:
+		var exprs []command.Expr
+		for _, fn := range l.Functions {
+			exprs = append(exprs, fn)
+		}
+		l.Input = input(l.Input, required.with(exprs...))
+		return l, changed
+	}
+	return list, false
+}
+
+// pruneScan narrows the given scan to the required columns. A scan of a
+// simple table is narrowed to the required columns of the table, and the
+// projection of a subquery is narrowed to the required columns of the
+// subquery.
+func pruneScan(scan command.Scan, required requiredColumns) (command.List, bool) {
+	switch table := scan.Table.(type) {
+	case command.SimpleTable:
+		var cols []string
+		if !required.all {
+			cols = required.columns(tableName(table))
+		}
+		if (cols == nil) == (scan.Cols == nil) && equalNames(cols, scan.Cols) {
+			return scan, false
+		}
+		scan.Cols = cols
+		return scan, true
+	case command.SubqueryTable:
+		project, ok := table.Input.(command.Project)
+		if !ok || required.all || hasStar(project.Cols) {
+			return scan, false
+		}
+		names := required.columns(table.Alias)
+		var cols []command.Column
+		for _, col := range project.Cols {
+			if name, ok := outputName(col); !ok || containsName(names, strings.ToLower(name)) {
+				cols = append(cols, col)
+			}
+		}
+		if len(cols) == 0 {
+			cols = project.Cols[:1]
+		}
+		if len(cols) == len(project.Cols) {
+			return scan, false
+		}
+		project.Cols = cols
+		table.Input = project
+		scan.Table = table
+		return scan, true
+	}
+	return scan, false
+}
+
+// outputName returns the name of the given column of a projection, by which
+// it can be referenced. If the name is not known, ok=false is returned.
+func outputName(col command.Column) (string, bool) {
+	if col.Alias != "" {
+		return col.Alias, true
+	}
+	if ref, ok := col.Column.(command.ColumnRef); ok {
+		return ref.Column, true
+	}
+	return "", false
+}
+
+// with returns the required columns, that additionally include the columns
+// referenced by the given expressions. Nil expressions are ignored.
+func (r requiredColumns) with(exprs ...command.Expr) requiredColumns {
+	return r.withDepth(0, exprs...)
+}
+
+// withDepth returns the required columns, that additionally include the
+// columns referenced with the given depth by the given expressions.
+func (r requiredColumns) withDepth(depth int, exprs ...command.Expr) requiredColumns {
+	if r.all {
+		return r
+	}
+	refs := append([]command.ColumnRef(nil), r.refs...)
+	for _, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+		if hasSubquery(expr) {
+			return requiredColumns{all: true}
+		}
+		inspectExpr(expr, func(expr command.Expr) {
+			if ref, ok := expr.(command.ColumnRef); ok && ref.Depth == depth {
+				ref.Depth = 0
+				refs = append(refs, ref)
+			}
+		})
+	}
+	return requiredColumns{refs: refs}
+}
+
+// columns returns the sorted lower case names of the required columns of the
+// table with the given name, which includes all unqualified references. The
+// result is never nil.
+func (r requiredColumns) columns(tableName string) []string {
+	cols := []string{}
+	for _, ref := range r.refs {
+		if ref.Table != "" && (tableName == "" || !strings.EqualFold(ref.Table, tableName)) {
+			continue
+		}
+		if name := strings.ToLower(ref.Column); !containsName(cols, name) {
+			cols = append(cols, name)
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+// hasSubquery determines whether the given expression contains a subquery.
+func hasSubquery(expr command.Expr) bool {
+	var found bool
+	inspectExpr(expr, func(expr command.Expr) {
+		switch e := expr.(type) {
+		case command.InExpr:
+			found = found || e.Input != nil
+		case command.ExistsExpr, command.SubqueryExpr:
+			found = true
+		}
+	})
+	return found
+}
+
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package optimization
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+func TestPruneColumns(t *testing.T) {
+	project := func(input command.List, cols ...command.Expr) command.Project {
+		project := command.Project{Input: input}
+		for _, col := range cols {
+			project.Cols = append(project.Cols, command.Column{Column: col})
+		}
+		return project
+	}
+	join := func(filter command.Expr, left, right command.List) command.Join {
+		return command.Join{
+			Type:   command.JoinInner,
+			Filter: filter,
+			Left:   left,
+			Right:  right,
+		}
+	}
+
+	runGolden(t, []Rule{PruneColumns}, []goldenTestcase{
+		{
+			"projection",
+			project(scan("a"), col("a", "v"), col("", "W")),
+		},
+		{
+			"no columns",
+			project(scan("a"), lit("1")),
+		},
+		{
+			"star",
+			project(scan("a"), lit("*")),
+		},
+		{
+			"qualified star",
+			command.Project{
+				Cols:  []command.Column{{Table: "a", Column: lit("*")}},
+				Input: scan("a"),
+			},
+		},
+		{
+			"select",
+			project(command.Select{
+				Filter: eq(col("a", "w"), lit("1")),
+				Input:  scan("a"),
+			}, col("a", "v")),
+		},
+		{
+			"join",
+			project(join(
+				eq(col("a", "id"), col("b", "id")),
+				scan("a"),
+				command.Scan{Table: command.SimpleTable{Table: "b", Alias: "x"}},
+			), col("a", "v"), col("x", "w"), col("", "u")),
+		},
+		{
+			"join with using",
+			project(command.Join{
+				Type:  command.JoinInner,
+				Using: []string{"id"},
+				Left:  scan("a"),
+				Right: scan("b"),
+			}, col("a", "v")),
+		},
+		{
+			"sort and limit",
+			project(command.Limit{
+				Limit: lit("1"),
+				Input: command.Sort{
+					Terms: []command.SortTerm{{Expr: col("a", "w"), Desc: true}},
+					Input: scan("a"),
+				},
+			}, col("a", "v")),
+		},
+		{
+			"aggregate",
+			project(command.Aggregate{
+				GroupBy: []command.Expr{col("a", "g")},
+				Aggregates: []command.FunctionExpr{
+					{Name: "SUM", Args: []command.Expr{col("a", "v")}},
+				},
+				Having: eq(col("a", "g"), lit("1")),
+				Input:  scan("a"),
+			}, col("a", "g"), command.FunctionExpr{Name: "SUM", Args: []command.Expr{col("a", "v")}}),
+		},
+		{
+			"distinct",
+			project(command.Distinct{Input: scan("a")}, col("a", "v")),
+		},
+		{
+			"subquery",
+			project(command.Select{
+				Filter: command.ExistsExpr{Input: project(scan("b"), col("b", "id"))},
+				Input:  scan("a"),
+			}, col("a", "v")),
+		},
+		{
+			"correlated reference",
+			project(scan("a"), col("a", "v"), command.ColumnRef{Table: "b", Column: "w", Depth: 1}),
+		},
+		{
+			"subquery table",
+			project(command.Scan{Table: command.SubqueryTable{
+				Alias: "s",
+				Input: command.Project{
+					Cols: []command.Column{
+						{Column: col("a", "v")},
+						{Column: col("a", "w"), Alias: "x"},
+						{Column: lit("1"), Alias: "y"},
+						{Column: command.FunctionExpr{Name: "ABS", Args: []command.Expr{col("a", "z")}}},
+					},
+					Input: scan("a"),
+				},
+			}}, col("s", "x")),
+		},
+		{
+			"subquery table without referenced columns",
+			project(command.Scan{Table: command.SubqueryTable{
+				Alias: "s",
+				Input: project(scan("a"), col("a", "v"), col("a", "w")),
+			}}, lit("1")),
+		},
+	})
+}
@@ -0,0 +1,614 @@
+package optimization
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// FoldConstants is the rule that evaluates all expressions, whose operands are
+// constant, and simplifies boolean expressions with a constant operand, such
+// as x AND false. Only integers, texts, booleans and NULL are considered
+// constant, and only calls to deterministic scalar functions are evaluated. A
+// selection, whose filter folds to true, is removed, and a selection, whose
+// filter folds to false or NULL, is replaced by an empty list, if the columns
+// of the list are known. Otherwise, the filter is replaced by false.
+var FoldConstants = Rule{
+	Name:  "FoldConstants",
+	Apply: foldConstants,
+}
+
+func foldConstants(cmd command.Command) (command.Command, bool) {
+	f := &folder{}
+	switch c := cmd.(type) {
+	case command.Select:
+		filter := f.fold(c.Filter)
+		if truth, ok := constantTruth(filter); ok {
+			if truth {
+				return c.Input, true
+			}
+			if empty, ok := emptyOf(c.Input); ok {
+				return empty, true
+			}
+			if _, isFalse := c.Filter.(command.ConstantBooleanExpr); isFalse {
+				return nil, false
+			}
+			c.Filter = command.ConstantBooleanExpr{Value: false}
+			return c, true
+		}
+		c.Filter = filter
+		return c, f.changed
+	case command.Project:
+		if isEmptyList(c.Input) && !hasStar(c.Cols) {
+			return command.Empty{Cols: c.Cols}, true
+		}
+		c.Cols = f.foldColumns(c.Cols)
+		return c, f.changed
+	case command.Distinct:
+		if empty, ok := c.Input.(command.Empty); ok {
+			return empty, true
+		}
+	case command.Sort:
+		if empty, ok := c.Input.(command.Empty); ok {
+			return empty, true
+		}
+		c.Terms = f.foldSortTerms(c.Terms)
+		c.Limit = f.fold(c.Limit)
+		return c, f.changed
+	case command.Limit:
+		if empty, ok := c.Input.(command.Empty); ok {
+			return empty, true
+		}
+		c.Limit = f.fold(c.Limit)
+		return c, f.changed
+	case command.Offset:
+		if empty, ok := c.Input.(command.Empty); ok {
+			return empty, true
+		}
+		c.Offset = f.fold(c.Offset)
+		return c, f.changed
+	case command.Join:
+		c.Filter = f.fold(c.Filter)
+		if truth, ok := constantTruth(c.Filter); ok && truth {
+			c.Filter = nil
+			return c, true
+		}
+		return c, f.changed
+	case command.Aggregate:
+		c.GroupBy = f.foldExprs(c.GroupBy)
+		c.Aggregates = f.foldFunctions(c.Aggregates)
+		c.Having = f.fold(c.Having)
+		if truth, ok := constantTruth(c.Having); ok && truth {
+			c.Having = nil
+			return c, true
+		}
+		return c, f.changed
+	case command.Window:
+		c.Functions = f.foldFunctions(c.Functions)
+		return c, f.changed
+	case command.Values:
+		values := make([][]command.Expr, len(c.Values))
+		for i, exprs := range c.Values {
+			values[i] = f.foldExprs(exprs)
+		}
+		c.Values = values
+		return c, f.changed
+	case command.Delete:
+		c.Filter = f.fold(c.Filter)
+		return c, f.changed
+	case command.Update:
+		c.Filter = f.fold(c.Filter)
+		updates := make([]command.UpdateSetter, len(c.Updates))
+		for i, update := range c.Updates {
+			update.Value = f.fold(update.Value)
+			updates[i] = update
+		}
+		c.Updates = updates
+		return c, f.changed
+	}
+	return nil, false
+}
+
+// emptyOf returns an empty list with the columns of the given list, if the
+// given list is known to be empty, or if its columns are known.
+func emptyOf(list command.List) (command.Empty, bool) {
+	switch l := list.(type) {
+	case command.Empty:
+		return l, true
+	case command.Project:
+		if !hasStar(l.Cols) {
+			return command.Empty{Cols: l.Cols}, true
+		}
+	case command.Select:
+		return emptyOf(l.Input)
+	case command.Distinct:
+		return emptyOf(l.Input)
+	case command.Sort:
+		return emptyOf(l.Input)
+	case command.Limit:
+		return emptyOf(l.Input)
+	case command.Offset:
+		return emptyOf(l.Input)
+	}
+	return command.Empty{}, false
+}
+
+// isEmptyList determines whether the given list is known to have no datasets.
+func isEmptyList(list command.List) bool {
+	switch l := list.(type) {
+	case command.Empty:
+		return true
+	case command.Select:
+		truth, ok := constantTruth(l.Filter)
+		return ok && !truth
+	}
+	return false
+}
+
+// hasStar determines whether any of the given columns is a star column.
+func hasStar(cols []command.Column) bool {
+	for _, col := range cols {
+		if lit, ok := col.Column.(command.LiteralExpr); ok && lit.Value == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// folder folds expressions, and records whether any expression changed.
+type folder struct {
+	changed bool
+}
+
+func (f *folder) fold(expr command.Expr) command.Expr {
+	return mapExpr(expr, func(expr command.Expr) command.Expr {
+		if folded, ok := simplify(expr); ok {
+			f.changed = true
+			return folded
+		}
+		return expr
+	})
+}
+
+func (f *folder) foldExprs(exprs []command.Expr) []command.Expr {
+	if exprs == nil {
+		return nil
+	}
+	folded := make([]command.Expr, len(exprs))
+	for i, expr := range exprs {
+		folded[i] = f.fold(expr)
+	}
+	return folded
+}
+
+// foldColumns folds the expressions of the given projected columns. A folded
+// column without an alias is aliased with its original expression, so that the
+// name of the column doesn't change.
+func (f *folder) foldColumns(cols []command.Column) []command.Column {
+	folded := make([]command.Column, len(cols))
+	for i, col := range cols {
+		colFolder := &folder{}
+		if foldedExpr := colFolder.fold(col.Column); colFolder.changed {
+			if col.Alias == "" {
+				col.Alias = col.Column.String()
+			}
+			col.Column = foldedExpr
+			f.changed = true
+		}
+		folded[i] = col
+	}
+	return folded
+}
+
+func (f *folder) foldSortTerms(terms []command.SortTerm) []command.SortTerm {
+	folded := make([]command.SortTerm, len(terms))
+	for i, term := range terms {
+		term.Expr = f.fold(term.Expr)
+		folded[i] = term
+	}
+	return folded
+}
+
+// foldFunctions folds the arguments of the given aggregate or window
+// function calls. The calls themselves are never folded.
+func (f *folder) foldFunctions(fns []command.FunctionExpr) []command.FunctionExpr {
+	if fns == nil {
+		return nil
+	}
+	folded := make([]command.FunctionExpr, len(fns))
+	for i, fn := range fns {
+		if foldedFn, ok := f.fold(fn).(command.FunctionExpr); ok {
+			fn = foldedFn
+		}
+		folded[i] = fn
+	}
+	return folded
+}
+
+// simplify returns the simplified version of the given expression, whose
+// operands are already simplified. If the expression can't be simplified,
+// ok=false is returned.
+func simplify(expr command.Expr) (command.Expr, bool) {
+	switch e := expr.(type) {
+	case command.UnaryExpr:
+		return simplifyUnary(e)
+	case command.BinaryExpr:
+		return simplifyBinary(e)
+	case command.EqualityExpr:
+		left, leftOk := constantValue(e.Left)
+		right, rightOk := constantValue(e.Right)
+		if !leftOk || !rightOk {
+			return nil, false
+		}
+		result, ok := compareConstants("=", left, right)
+		if !ok {
+			return nil, false
+		}
+		if b, isBool := result.(bool); isBool && e.Invert {
+			result = !b
+		}
+		return constantExpr(result), true
+	case command.FunctionExpr:
+		return simplifyFunction(e)
+	}
+	return nil, false
+}
+
+func simplifyUnary(e command.UnaryExpr) (command.Expr, bool) {
+	val, ok := constantValue(e.Value)
+	operator := strings.ToUpper(e.Operator)
+	if !ok {
+		// NOT NOT x is x, if x is a boolean
+		if inner, isUnary := e.Value.(command.UnaryExpr); isUnary && operator == "NOT" &&
+			strings.EqualFold(inner.Operator, "NOT") && isBoolean(inner.Value) {
+			return inner.Value, true
+		}
+		return nil, false
+	}
+	if val == nil {
+		return constantExpr(nil), true
+	}
+	switch operator {
+	case "+":
+		return e.Value, true
+	case "NOT":
+		if truth, ok := truthOf(val); ok {
+			return constantExpr(!truth), true
+		}
+	case "-":
+		if i, isInt := val.(int64); isInt && i != math.MinInt64 {
+			return constantExpr(-i), true
+		}
+	case "~":
+		if i, isInt := val.(int64); isInt {
+			return constantExpr(^i), true
+		}
+	}
+	return nil, false
+}
+
+func simplifyBinary(e command.BinaryExpr) (command.Expr, bool) {
+	left, leftOk := constantValue(e.Left)
+	right, rightOk := constantValue(e.Right)
+	operator := strings.ToUpper(e.Operator)
+
+	switch operator {
+	case "AND", "OR":
+		// the value, that determines the result of the operator on its own
+		dominant := operator == "OR"
+		leftTruth, leftKnown := knownTruth(left, leftOk)
+		rightTruth, rightKnown := knownTruth(right, rightOk)
+		switch {
+		case leftKnown && leftTruth == dominant, rightKnown && rightTruth == dominant:
+			return constantExpr(dominant), true
+		case leftOk && rightOk:
+			if !(leftKnown || left == nil) || !(rightKnown || right == nil) {
+				return nil, false
+			}
+			// none of the operands is dominant
+			if left == nil || right == nil {
+				return constantExpr(nil), true
+			}
+			return constantExpr(!dominant), true
+		case leftKnown && isBoolean(e.Right):
+			return e.Right, true
+		case rightKnown && isBoolean(e.Left):
+			return e.Left, true
+		}
+		return nil, false
+	}
+
+	if !leftOk || !rightOk {
+		return nil, false
+	}
+	switch operator {
+	case "<", "<=", ">", ">=", "=", "==", "!=", "<>":
+		if result, ok := compareConstants(operator, left, right); ok {
+			return constantExpr(result), true
+		}
+		return nil, false
+	}
+	if left == nil || right == nil {
+		return constantExpr(nil), true
+	}
+	switch operator {
+	case "||":
+		leftText, leftIsText := textOf(left)
+		rightText, rightIsText := textOf(right)
+		if leftIsText && rightIsText {
+			return constantExpr(leftText + rightText), true
+		}
+	case "+", "-", "*", "/", "%":
+		a, aIsInt := left.(int64)
+		b, bIsInt := right.(int64)
+		if aIsInt && bIsInt {
+			if result, ok := integerArithmetic(operator, a, b); ok {
+				return constantExpr(result), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func simplifyFunction(e command.FunctionExpr) (command.Expr, bool) {
+	if e.Over != nil || e.Filter != nil || e.Distinct {
+		return nil, false
+	}
+	name := strings.ToUpper(e.Name)
+	switch name {
+	case "COALESCE", "IFNULL":
+		if len(e.Args) < 2 || name == "IFNULL" && len(e.Args) != 2 {
+			return nil, false
+		}
+		args := e.Args
+		for len(args) > 1 {
+			if val, ok := constantValue(args[0]); !ok || val != nil {
+				break
+			}
+			args = args[1:]
+		}
+		if val, ok := constantValue(args[0]); ok && val != nil || len(args) == 1 {
+			return args[0], true
+		}
+		if len(args) == len(e.Args) {
+			return nil, false
+		}
+		e.Args = args
+		return e, true
+	}
+
+	if len(e.Args) != 1 {
+		return nil, false
+	}
+	val, ok := constantValue(e.Args[0])
+	if !ok {
+		return nil, false
+	}
+	if name == "TYPEOF" {
+		switch val.(type) {
+		case nil:
+			return constantExpr("null"), true
+		case int64, bool:
+			return constantExpr("integer"), true
+		case string:
+			return constantExpr("text"), true
+		}
+		return nil, false
+	}
+	if val == nil {
+		switch name {
+		case "ABS", "LENGTH", "LOWER", "UPPER":
+			return constantExpr(nil), true
+		}
+		return nil, false
+	}
+	switch name {
+	case "ABS":
+		if i, isInt := val.(int64); isInt && i != math.MinInt64 {
+			if i < 0 {
+				i = -i
+			}
+			return constantExpr(i), true
+		}
+	case "LENGTH":
+		if text, isText := textOf(val); isText {
+			return constantExpr(int64(utf8.RuneCountInString(text))), true
+		}
+	case "LOWER":
+		if text, isText := textOf(val); isText {
+			return constantExpr(strings.ToLower(text)), true
+		}
+	case "UPPER":
+		if text, isText := textOf(val); isText {
+			return constantExpr(strings.ToUpper(text)), true
+		}
+	}
+	return nil, false
+}
+
+// constantValue returns the value of the given expression, if the expression
+// is a constant. The value is either nil for NULL, an int64, a string or a
+// bool.
+func constantValue(expr command.Expr) (interface{}, bool) {
+	switch e := expr.(type) {
+	case command.ConstantBooleanExpr:
+		return e.Value, true
+	case command.LiteralExpr:
+		literal := e.Value
+		if literal == "" {
+			return nil, false
+		}
+		if quote := literal[0]; quote == '\'' && len(literal) > 1 && literal[len(literal)-1] == quote {
+			return unquote(literal[1:len(literal)-1], quote), true
+		}
+		if strings.EqualFold(literal, "NULL") {
+			return nil, true
+		}
+		if i, err := strconv.ParseInt(literal, 10, 64); err == nil {
+			return i, true
+		}
+	}
+	return nil, false
+}
+
+// constantExpr returns an expression, that evaluates to the given value. The
+// value must be nil, an int64, a string or a bool.
+func constantExpr(val interface{}) command.Expr {
+	switch v := val.(type) {
+	case bool:
+		return command.ConstantBooleanExpr{Value: v}
+	case int64:
+		return command.LiteralExpr{Value: strconv.FormatInt(v, 10)}
+	case string:
+		quoted := strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(v)
+		return command.LiteralExpr{Value: "'" + quoted + "'"}
+	}
+	return command.LiteralExpr{Value: "NULL"}
+}
+
+// unquote removes escape characters from the content of a quoted literal, like
+// the executor does. Both a doubled quote and a backslash escape the character
+// that follows.
+func unquote(content string, quote byte) string {
+	var buf strings.Builder
+	for i := 0; i < len(content); i++ {
+		if (content[i] == '\\' || content[i] == quote) && i+1 < len(content) {
+			i++
+		}
+		buf.WriteByte(content[i])
+	}
+	return buf.String()
+}
+
+// constantTruth returns whether the given expression is a constant, that is
+// true in a boolean context. NULL is not true.
+func constantTruth(expr command.Expr) (truth bool, ok bool) {
+	val, ok := constantValue(expr)
+	if !ok {
+		return false, false
+	}
+	if val == nil {
+		return false, true
+	}
+	return truthOf(val)
+}
+
+// knownTruth returns the truth of the given constant value, if the value is
+// constant, not NULL, and its truth is known.
+func knownTruth(val interface{}, constant bool) (truth bool, ok bool) {
+	if !constant || val == nil {
+		return false, false
+	}
+	return truthOf(val)
+}
+
+// truthOf returns the truth of the given value, which must not be NULL. The
+// truth of texts is not known, because it depends on their numeric prefix.
+func truthOf(val interface{}) (bool, bool) {
+	switch v := val.(type) {
+	case bool:
+		return v, true
+	case int64:
+		return v != 0, true
+	}
+	return false, false
+}
+
+// textOf returns the text representation of the given value, if it is an
+// integer or a text.
+func textOf(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case string:
+		return v, true
+	}
+	return "", false
+}
+
+// compareConstants compares the given constants with the given comparison
+// operator. Only integers and NULL values can be compared, because the
+// comparison of other values depends on affinities and collations.
+func compareConstants(operator string, left, right interface{}) (interface{}, bool) {
+	if left == nil || right == nil {
+		return nil, true
+	}
+	a, aIsInt := left.(int64)
+	b, bIsInt := right.(int64)
+	if !aIsInt || !bIsInt {
+		return nil, false
+	}
+	switch operator {
+	case "<":
+		return a < b, true
+	case "<=":
+		return a <= b, true
+	case ">":
+		return a > b, true
+	case ">=":
+		return a >= b, true
+	case "!=", "<>":
+		return a != b, true
+	}
+	return a == b, true
+}
+
+// integerArithmetic applies the given arithmetic operator to the given
+// integers. If the operation overflows, ok=false is returned, because the
+// executor performs it on reals instead. Division by zero results in NULL.
+func integerArithmetic(operator string, a, b int64) (interface{}, bool) {
+	switch operator {
+	case "+":
+		if sum := a + b; (sum > a) == (b > 0) {
+			return sum, true
+		}
+	case "-":
+		if diff := a - b; (diff < a) == (b > 0) {
+			return diff, true
+		}
+	case "*":
+		if a == 0 || b == 0 {
+			return int64(0), true
+		}
+		if product := a * b; product/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64) {
+			return product, true
+		}
+	case "/":
+		if b == 0 {
+			return nil, true
+		}
+		if !(a == math.MinInt64 && b == -1) {
+			return a / b, true
+		}
+	case "%":
+		if b == 0 {
+			return nil, true
+		}
+		if b == -1 {
+			return int64(0), true
+		}
+		return a % b, true
+	}
+	return nil, false
+}
+
+// isBoolean determines whether the given expression always evaluates to a
+// boolean or NULL.
+func isBoolean(expr command.Expr) bool {
+	switch e := expr.(type) {
+	case command.ConstantBooleanExpr, command.EqualityExpr, command.RangeExpr, command.IsExpr,
+		command.InExpr, command.ExistsExpr, command.PatternExpr:
+		return true
+	case command.UnaryExpr:
+		return strings.EqualFold(e.Operator, "NOT")
+	case command.BinaryExpr:
+		switch strings.ToUpper(e.Operator) {
+		case "AND", "OR", "<", "<=", ">", ">=", "=", "==", "!=", "<>":
+			return true
+		}
+	}
+	return false
+}
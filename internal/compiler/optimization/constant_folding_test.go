@@ -0,0 +1,175 @@
+package optimization
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+func TestFoldConstants(t *testing.T) {
+	binary := func(operator string, left, right command.Expr) command.BinaryExpr {
+		return command.BinaryExpr{Operator: operator, Left: left, Right: right}
+	}
+	unary := func(operator string, value command.Expr) command.UnaryExpr {
+		return command.UnaryExpr{Operator: operator, Value: value}
+	}
+	fn := func(name string, args ...command.Expr) command.FunctionExpr {
+		return command.FunctionExpr{Name: name, Args: args}
+	}
+	project := func(input command.List, exprs ...command.Expr) command.Project {
+		cols := make([]command.Column, len(exprs))
+		for i, expr := range exprs {
+			cols[i] = command.Column{Column: expr}
+		}
+		return command.Project{Cols: cols, Input: input}
+	}
+	truth := func(value bool) command.ConstantBooleanExpr {
+		return command.ConstantBooleanExpr{Value: value}
+	}
+
+	runGolden(t, []Rule{FoldConstants}, []goldenTestcase{
+		{
+			"arithmetic",
+			project(scan("t"),
+				binary("+", lit("1"), binary("*", lit("2"), lit("3"))),
+				binary("-", unary("-", lit("5")), lit("2")),
+				binary("/", lit("7"), lit("0")),
+				binary("%", lit("7"), lit("-1")),
+				binary("+", lit("9223372036854775807"), lit("1")),
+				binary("+", lit("1.5"), lit("1")),
+				binary("+", col("", "a"), binary("+", lit("1"), lit("1"))),
+				binary("||", lit("'it'"), binary("||", lit("'''s '"), lit("1"))),
+				unary("~", lit("0")),
+			),
+		},
+		{
+			"null",
+			project(scan("t"),
+				binary("+", col("", "a"), lit("NULL")),
+				binary("*", lit("NULL"), lit("2")),
+				unary("-", lit("NULL")),
+				binary("<", lit("NULL"), lit("1")),
+				eq(lit("NULL"), lit("NULL")),
+			),
+		},
+		{
+			"comparisons",
+			project(scan("t"),
+				binary("<", lit("1"), lit("2")),
+				binary(">=", lit("1"), lit("2")),
+				binary("<>", lit("1"), lit("2")),
+				eq(lit("3"), lit("3")),
+				command.EqualityExpr{Left: lit("3"), Right: lit("3"), Invert: true},
+				eq(lit("'a'"), lit("'A'")),
+			),
+		},
+		{
+			"boolean identities",
+			project(scan("t"),
+				unary("NOT", truth(true)),
+				unary("NOT", lit("0")),
+				binary("AND", col("", "a"), truth(false)),
+				binary("AND", eq(col("", "a"), lit("1")), truth(true)),
+				binary("AND", col("", "a"), truth(true)),
+				binary("OR", truth(true), col("", "a")),
+				binary("OR", lit("0"), eq(col("", "a"), lit("1"))),
+				binary("OR", lit("NULL"), truth(false)),
+				binary("AND", lit("NULL"), truth(true)),
+				unary("NOT", unary("NOT", eq(col("", "a"), lit("1")))),
+				unary("NOT", unary("NOT", col("", "a"))),
+			),
+		},
+		{
+			"functions",
+			project(scan("t"),
+				fn("ABS", lit("-3")),
+				fn("LENGTH", lit("'héllo'")),
+				fn("UPPER", lit("'abc'")),
+				fn("LOWER", lit("NULL")),
+				fn("TYPEOF", lit("1")),
+				fn("COALESCE", lit("NULL"), lit("NULL"), col("", "a"), lit("1")),
+				fn("COALESCE", lit("NULL"), col("", "a")),
+				fn("IFNULL", lit("2"), col("", "a")),
+				fn("RANDOM"),
+				fn("ABS", col("", "a")),
+			),
+		},
+		{
+			"aliases",
+			command.Project{
+				Cols: []command.Column{
+					{Column: binary("+", lit("1"), lit("2")), Alias: "three"},
+					{Column: binary("+", lit("1"), lit("2"))},
+					{Column: col("", "a")},
+				},
+				Input: scan("t"),
+			},
+		},
+		{
+			"true filter",
+			project(command.Select{
+				Filter: binary("OR", eq(col("", "a"), lit("1")), binary("<", lit("1"), lit("2"))),
+				Input:  scan("t"),
+			}, col("", "a")),
+		},
+		{
+			"false filter",
+			project(command.Select{
+				Filter: binary("AND", eq(col("", "a"), lit("1")), binary(">", lit("1"), lit("2"))),
+				Input:  scan("t"),
+			}, col("", "a"), col("", "b")),
+		},
+		{
+			"false filter with star",
+			command.Project{
+				Cols: []command.Column{{Column: lit("*")}},
+				Input: command.Select{
+					Filter: lit("NULL"),
+					Input:  scan("t"),
+				},
+			},
+		},
+		{
+			"false filter above projection",
+			command.Sort{
+				Terms: []command.SortTerm{{Expr: col("", "a")}},
+				Input: command.Select{
+					Filter: lit("0"),
+					Input:  project(scan("t"), col("", "a")),
+				},
+			},
+		},
+		{
+			"false filter below aggregate",
+			project(command.Aggregate{
+				Aggregates: []command.FunctionExpr{fn("COUNT", lit("*"))},
+				Input: command.Select{
+					Filter: truth(false),
+					Input:  scan("t"),
+				},
+			}, fn("COUNT", lit("*"))),
+		},
+		{
+			"join and having",
+			command.Aggregate{
+				GroupBy: []command.Expr{binary("+", col("", "a"), binary("-", lit("2"), lit("1")))},
+				Having:  binary(">", lit("2"), lit("1")),
+				Input: command.Join{
+					Filter: eq(lit("1"), lit("1")),
+					Left:   scan("a"),
+					Right:  scan("b"),
+				},
+			},
+		},
+		{
+			"limit and offset",
+			command.Limit{
+				Limit: binary("*", lit("2"), lit("5")),
+				Input: command.Offset{
+					Offset: binary("+", lit("1"), lit("1")),
+					Input:  scan("t"),
+				},
+			},
+		},
+	})
+}
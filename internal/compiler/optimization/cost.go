@@ -0,0 +1,310 @@
+package optimization
+
+import (
+	"math"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+const (
+	// DefaultRows is the estimated amount of rows of a table, if no
+	// statistics are known about it.
+	DefaultRows = 1000
+
+	// defaultEqualitySelectivity is the estimated fraction of rows, that pass
+	// an equality, if the distinct values of the compared columns are not
+	// known.
+	defaultEqualitySelectivity = 0.1
+	// defaultRangeSelectivity is the estimated fraction of rows, whose value
+	// is between two bounds.
+	defaultRangeSelectivity = 0.25
+	// defaultSelectivity is the estimated fraction of rows, that pass any
+	// other filter.
+	defaultSelectivity = 1.0 / 3
+)
+
+// costModel estimates the amount of rows of lists, and the fraction of rows
+// that pass filters, from the statistics of the scanned tables. If no
+// statistics are known, fixed estimates are used.
+type costModel struct {
+	stats Statistics
+}
+
+// tableStatistics returns the statistics of the given table. If the table is
+// not a simple table, or no statistics are known, ok=false is returned.
+func (m costModel) tableStatistics(table command.Table) (TableStatistics, bool) {
+	simple, ok := table.(command.SimpleTable)
+	if !ok || m.stats == nil {
+		return TableStatistics{}, false
+	}
+	return m.stats.TableStatistics(simple.Schema, simple.Table)
+}
+
+// tables returns the statistics of all tables, whose columns can be
+// referenced by qualified column references above the given list, by the
+// lower case name that the columns are qualified with.
+func (m costModel) tables(list command.List) map[string]TableStatistics {
+	tables := make(map[string]TableStatistics)
+	var collect func(command.List)
+	collect = func(list command.List) {
+		switch l := list.(type) {
+		case command.Scan:
+			if stats, ok := m.tableStatistics(l.Table); ok {
+				tables[strings.ToLower(tableName(l.Table))] = stats
+			}
+		case command.IndexScan:
+			if stats, ok := m.tableStatistics(l.Table); ok {
+				tables[strings.ToLower(tableName(l.Table))] = stats
+			}
+		case command.Select:
+			collect(l.Input)
+		case command.Join:
+			collect(l.Left)
+			if !isSemiOrAntiJoin(l) {
+				collect(l.Right)
+			}
+		}
+	}
+	collect(list)
+	return tables
+}
+
+// rows estimates the amount of rows of the given list.
+func (m costModel) rows(list command.List) float64 {
+	switch l := list.(type) {
+	case command.Scan:
+		if table, ok := l.Table.(command.SubqueryTable); ok {
+			return m.rows(table.Input)
+		}
+		if stats, ok := m.tableStatistics(l.Table); ok {
+			return stats.Rows
+		}
+		return DefaultRows
+	case command.IndexScan:
+		rows := float64(DefaultRows)
+		if stats, ok := m.tableStatistics(l.Table); ok {
+			rows = stats.Rows
+		}
+		return rows * keyRangeSelectivity(l.Ranges)
+	case command.Select:
+		return m.rows(l.Input) * m.selectivity(l.Filter, m.tables(l.Input))
+	case command.Join:
+		left, right := m.rows(l.Left), m.rows(l.Right)
+		if isSemiOrAntiJoin(l) {
+			// the fraction of the left rows, that have a matching right row
+			matched := math.Min(1, right*m.selectivity(l.Filter, m.tables(l.Right)))
+			if l.Type == command.JoinAnti {
+				return left * (1 - matched)
+			}
+			return left * matched
+		}
+		rows := left * right * m.selectivity(l.Filter, m.tables(l))
+		if isOuterJoin(l) {
+			return math.Max(rows, left)
+		}
+		return rows
+	case command.Project:
+		return m.rows(l.Input)
+	case command.Sort:
+		return m.rows(l.Input)
+	case command.Distinct:
+		return m.rows(l.Input)
+	case command.Window:
+		return m.rows(l.Input)
+	case command.Aggregate:
+		if len(l.GroupBy) == 0 {
+			return 1
+		}
+		return m.rows(l.Input)
+	case command.Limit:
+		return m.rows(l.Input)
+	case command.Offset:
+		return m.rows(l.Input)
+	case command.Union:
+		return m.rows(l.Left) + m.rows(l.Right)
+	case command.Intersect:
+		return math.Min(m.rows(l.Left), m.rows(l.Right))
+	case command.Except:
+		return m.rows(l.Left)
+	case command.Values:
+		return float64(len(l.Values))
+	case command.Empty:
+		return 0
+	}
+	return DefaultRows
+}
+
+// selectivity estimates the fraction of rows, that pass the given filter. The
+// given tables are the statistics of the tables, whose columns are referenced
+// by the filter, as returned by tables. A nil filter passes all rows.
+func (m costModel) selectivity(filter command.Expr, tables map[string]TableStatistics) float64 {
+	switch e := filter.(type) {
+	case nil:
+		return 1
+	case command.ConstantBooleanExpr:
+		if e.Value {
+			return 1
+		}
+		return 0
+	case command.UnaryExpr:
+		if strings.EqualFold(e.Operator, "NOT") {
+			return 1 - m.selectivity(e.Value, tables)
+		}
+	case command.BinaryExpr:
+		switch operator := strings.ToUpper(e.Operator); operator {
+		case "AND":
+			return m.selectivity(e.Left, tables) * m.selectivity(e.Right, tables)
+		case "OR":
+			left, right := m.selectivity(e.Left, tables), m.selectivity(e.Right, tables)
+			return left + right - left*right
+		case "<", "<=", ">", ">=":
+			return m.comparisonSelectivity(operator, e.Left, e.Right, tables)
+		}
+	case command.EqualityExpr:
+		selectivity := m.equalitySelectivity(e.Left, e.Right, tables)
+		if e.Invert {
+			return 1 - selectivity
+		}
+		return selectivity
+	case command.RangeExpr:
+		col, colOk := m.column(e.Needle, tables)
+		lo, loOk := histogramValue(e.Lo)
+		hi, hiOk := histogramValue(e.Hi)
+		selectivity := defaultRangeSelectivity
+		if colOk && loOk && hiOk && len(col.Histogram) != 0 {
+			selectivity = math.Max(fractionBelow(col, hi, true)-fractionBelow(col, lo, false), 0)
+			if e.Invert {
+				return math.Max(1-col.NullFraction-selectivity, 0)
+			}
+			return selectivity
+		}
+		if e.Invert {
+			return 1 - selectivity
+		}
+		return selectivity
+	case command.IsExpr:
+		col, ok := m.column(e.Left, tables)
+		if lit, isLit := e.Right.(command.LiteralExpr); ok && isLit && strings.EqualFold(lit.Value, "NULL") {
+			if e.Invert {
+				return 1 - col.NullFraction
+			}
+			return col.NullFraction
+		}
+	}
+	return defaultSelectivity
+}
+
+// equalitySelectivity estimates the fraction of rows, for which the given
+// expressions are equal. Every value of a column is assumed to occur equally
+// often, so that a column with n distinct values is equal to a constant in a
+// fraction of 1/n of the rows, and equal to another column with m distinct
+// values in a fraction of 1/max(n,m) of the rows. If a column is compared to a
+// constant and has a histogram, the fraction is estimated from the bucket,
+// that contains the constant. NULL values are never equal.
+func (m costModel) equalitySelectivity(left, right command.Expr, tables map[string]TableStatistics) float64 {
+	leftCol, leftOk := m.column(left, tables)
+	rightCol, rightOk := m.column(right, tables)
+	switch {
+	case leftOk && rightOk:
+		if leftCol.Distinct > 0 && rightCol.Distinct > 0 {
+			return (1 - leftCol.NullFraction) * (1 - rightCol.NullFraction) / math.Max(math.Max(leftCol.Distinct, rightCol.Distinct), 1)
+		}
+	case leftOk:
+		return columnEqualitySelectivity(leftCol, right)
+	case rightOk:
+		return columnEqualitySelectivity(rightCol, left)
+	}
+	return defaultEqualitySelectivity
+}
+
+// columnEqualitySelectivity estimates the fraction of rows, whose value in the
+// column with the given statistics is equal to the given expression.
+func columnEqualitySelectivity(col ColumnStatistics, expr command.Expr) float64 {
+	if col.NullFraction >= 1 {
+		return 0
+	}
+	if val, ok := histogramValue(expr); ok && len(col.Histogram) != 0 {
+		return fractionEqual(col, val)
+	}
+	if col.Distinct > 0 {
+		return (1 - col.NullFraction) / math.Max(col.Distinct, 1)
+	}
+	return defaultEqualitySelectivity
+}
+
+// comparisonSelectivity estimates the fraction of rows, for which the given
+// comparison of the given expressions is true. If a column is compared to a
+// constant and has a histogram, the fraction is estimated from the histogram.
+func (m costModel) comparisonSelectivity(operator string, left, right command.Expr, tables map[string]TableStatistics) float64 {
+	col, ok := m.column(left, tables)
+	val, valOk := histogramValue(right)
+	if !ok || !valOk {
+		// swap the sides, so that the column is on the left side
+		col, ok = m.column(right, tables)
+		val, valOk = histogramValue(left)
+		operator = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<="}[operator]
+	}
+	if !ok || !valOk || len(col.Histogram) == 0 {
+		return defaultSelectivity
+	}
+
+	nonNull := 1 - col.NullFraction
+	var selectivity float64
+	switch operator {
+	case "<":
+		selectivity = fractionBelow(col, val, false)
+	case "<=":
+		selectivity = fractionBelow(col, val, true)
+	case ">":
+		selectivity = nonNull - fractionBelow(col, val, true)
+	case ">=":
+		selectivity = nonNull - fractionBelow(col, val, false)
+	}
+	return math.Min(math.Max(selectivity, 0), nonNull)
+}
+
+// keyRangeSelectivity estimates the fraction of the rows of a table, whose
+// keys are in the given key ranges of an index. Since the indexed columns are
+// not known, fixed estimates are used for ranges, whose bounds are equal, and
+// for all other ranges.
+func keyRangeSelectivity(ranges []command.KeyRange) float64 {
+	var selectivity float64
+	for _, r := range ranges {
+		if isEqualityRange(r) {
+			selectivity += defaultEqualitySelectivity
+		} else {
+			selectivity += defaultRangeSelectivity
+		}
+	}
+	return math.Min(selectivity, 1)
+}
+
+// isEqualityRange determines whether the given key range only contains keys,
+// that are equal to its bounds.
+func isEqualityRange(r command.KeyRange) bool {
+	if r.LoExclusive || r.HiExclusive || len(r.Lo) == 0 || len(r.Lo) != len(r.Hi) {
+		return false
+	}
+	for i := range r.Lo {
+		if r.Lo[i].String() != r.Hi[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+// column returns the statistics of the column, that is referenced by the
+// given expression, if they are known.
+func (m costModel) column(expr command.Expr, tables map[string]TableStatistics) (ColumnStatistics, bool) {
+	ref, ok := expr.(command.ColumnRef)
+	if !ok || ref.Depth != 0 || ref.Table == "" {
+		return ColumnStatistics{}, false
+	}
+	stats, ok := tables[strings.ToLower(ref.Table)]
+	if !ok {
+		return ColumnStatistics{}, false
+	}
+	col, ok := stats.Columns[strings.ToLower(ref.Column)]
+	return col, ok
+}
@@ -0,0 +1,122 @@
+package optimization
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+func Test_costModel_selectivity(t *testing.T) {
+	model := costModel{stats: joinOrderStatistics}
+	tables := model.tables(command.Join{Left: scan("a"), Right: scan("b")})
+	tests := []struct {
+		filter command.Expr
+		want   float64
+	}{
+		{nil, 1},
+		{command.ConstantBooleanExpr{Value: false}, 0},
+		{eq(col("a", "v"), lit("1")), 0.01},
+		{eq(col("a", "id"), col("b", "id")), 0.0001},
+		{eq(col("b", "id"), lit("1")), 0.1},
+		{command.EqualityExpr{Left: col("a", "v"), Right: lit("1"), Invert: true}, 0.99},
+		{eq(col("a", "w"), lit("1")), defaultEqualitySelectivity},
+		{eq(col("c", "id"), lit("1")), defaultEqualitySelectivity},
+		{and(eq(col("a", "v"), lit("1")), eq(col("b", "id"), lit("1"))), 0.001},
+		{command.BinaryExpr{Operator: "OR", Left: eq(col("a", "v"), lit("1")), Right: eq(col("b", "id"), lit("1"))}, 0.109},
+		{command.UnaryExpr{Operator: "NOT", Value: eq(col("b", "id"), lit("1"))}, 0.9},
+		{command.RangeExpr{Needle: col("a", "v"), Lo: lit("1"), Hi: lit("2")}, defaultRangeSelectivity},
+		{command.BinaryExpr{Operator: "<", Left: col("a", "v"), Right: lit("1")}, defaultSelectivity},
+	}
+	for _, tt := range tests {
+		name := "<nil>"
+		if tt.filter != nil {
+			name = strings.ReplaceAll(tt.filter.String(), " ", "")
+		}
+		t.Run(name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, model.selectivity(tt.filter, tables), 1e-9)
+		})
+	}
+}
+
+func Test_costModel_histogram(t *testing.T) {
+	model := costModel{stats: testStatistics{
+		"h": {
+			Rows: 100,
+			Columns: map[string]ColumnStatistics{
+				"n": {
+					Distinct:     40,
+					NullFraction: 0.2,
+					Histogram: []Bucket{
+						{Upper: int64(10), Fraction: 0.2, Distinct: 10},
+						{Upper: int64(20), Fraction: 0.2, Distinct: 2},
+						{Upper: 40.0, Fraction: 0.2, Distinct: 20},
+						{Upper: "m", Fraction: 0.2, Distinct: 8},
+					},
+				},
+				"e": {NullFraction: 1},
+			},
+		},
+	}}
+	tables := model.tables(scan("h"))
+	binary := func(operator string, left, right command.Expr) command.BinaryExpr {
+		return command.BinaryExpr{Operator: operator, Left: left, Right: right}
+	}
+	tests := []struct {
+		filter command.Expr
+		want   float64
+	}{
+		{eq(col("h", "n"), lit("5")), 0.02},
+		{eq(lit("15"), col("h", "n")), 0.1},
+		{eq(col("h", "n"), lit("'c'")), 0.025},
+		{eq(col("h", "n"), lit("'z'")), 0},
+		{eq(col("h", "n"), lit("NULL")), 0.02},
+		{eq(col("h", "n"), col("h", "x")), 0.02},
+		{eq(col("h", "e"), lit("1")), 0},
+		{binary("<", col("h", "n"), lit("10")), 0.18},
+		{binary("<=", col("h", "n"), lit("10")), 0.2},
+		{binary("<", col("h", "n"), lit("15")), 0.3},
+		{binary("<", col("h", "n"), lit("30.0")), 0.5},
+		{binary(">", col("h", "n"), lit("20")), 0.4},
+		{binary(">=", col("h", "n"), lit("20")), 0.5},
+		{binary(">", lit("20"), col("h", "n")), 0.3},
+		{binary("<", col("h", "n"), lit("'a'")), 0.7},
+		{binary(">", col("h", "n"), lit("'z'")), 0},
+		{binary("<", col("h", "n"), col("h", "x")), defaultSelectivity},
+		{command.RangeExpr{Needle: col("h", "n"), Lo: lit("11"), Hi: lit("20")}, 0.18},
+		{command.RangeExpr{Needle: col("h", "n"), Lo: lit("11"), Hi: lit("20"), Invert: true}, 0.62},
+		{command.IsExpr{Left: col("h", "n"), Right: lit("NULL")}, 0.2},
+		{command.IsExpr{Left: col("h", "n"), Right: lit("NULL"), Invert: true}, 0.8},
+		{command.IsExpr{Left: col("h", "x"), Right: lit("NULL")}, defaultSelectivity},
+	}
+	for _, tt := range tests {
+		t.Run(strings.ReplaceAll(tt.filter.String(), " ", ""), func(t *testing.T) {
+			assert.InDelta(t, tt.want, model.selectivity(tt.filter, tables), 1e-9)
+		})
+	}
+}
+
+func Test_costModel_rows(t *testing.T) {
+	model := costModel{stats: joinOrderStatistics}
+	semi := func(typ command.JoinType, filter command.Expr) command.Join {
+		return command.Join{Type: typ, Filter: filter, Left: scan("a"), Right: scan("c")}
+	}
+	correlated := eq(col("c", "id"), command.ColumnRef{Table: "a", Column: "id", Depth: 1})
+	tests := []struct {
+		list command.List
+		want float64
+	}{
+		{scan("a"), 10000},
+		{command.Join{Type: command.JoinInner, Filter: eq(col("a", "id"), col("c", "id")), Left: scan("a"), Right: scan("c")}, 20},
+		{semi(command.JoinSemi, nil), 10000},
+		{semi(command.JoinAnti, nil), 0},
+		{semi(command.JoinSemi, and(correlated, eq(col("c", "id"), lit("1")))), 500},
+		{semi(command.JoinAnti, and(correlated, eq(col("c", "id"), lit("1")))), 9500},
+	}
+	for _, tt := range tests {
+		t.Run(strings.ReplaceAll(tt.list.String(), " ", ""), func(t *testing.T) {
+			assert.InDelta(t, tt.want, model.rows(tt.list), 1e-9)
+		})
+	}
+}
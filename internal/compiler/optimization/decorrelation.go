@@ -0,0 +1,520 @@
+package optimization
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// DecorrelateSubqueries is the rule that replaces subqueries, which are
+// evaluated for every row of the enclosing query, with joins, so that the
+// rows of the subquery are only produced once.
+//
+// A conjunct of a selection, that is an EXISTS or IN expression with a
+// subquery, is replaced with a semi join of the input of the selection and the
+// subquery, and a NOT EXISTS or NOT IN expression is replaced with an anti
+// join. The conjuncts of the filters of the subquery, that may reference
+// columns of the enclosing query, become the filter of the join, which is
+// evaluated like the filter of the subquery. A NOT IN expression is not true,
+// if the value or a value of the subquery is NULL, so the filter of the anti
+// join also passes for such rows.
+//
+// A scalar subquery in the columns of a projection, or in a selection below
+// it, that aggregates the rows, whose values are equal to values of the
+// enclosing query, is replaced with a left join of the input with a subquery,
+// that aggregates the rows grouped by these values. If there are no such rows,
+// the aggregates over no rows are used, such as 0 for COUNT.
+//
+// Only subqueries, that are projections of selections of scans and joins of
+// simple tables, are decorrelated. Column references of scalar subqueries,
+// outside of the conjuncts, that are equal to values of the enclosing query,
+// must be qualified with the tables of the subquery, because unqualified
+// references may reference columns of the enclosing query.
+var DecorrelateSubqueries = Rule{
+	Name:  "DecorrelateSubqueries",
+	Apply: decorrelateSubqueries,
+}
+
+// subquery is the input of a subquery, whose conjuncts, that may reference
+// columns of the enclosing query, are separated.
+type subquery struct {
+	// input is the input of the subquery, filtered by the conjuncts, that
+	// only reference columns of the subquery.
+	input command.List
+	// names are the lower case names of the tables of the subquery.
+	names []string
+	// correlated are the conjuncts, that may reference columns of the
+	// enclosing query.
+	correlated []command.Expr
+}
+
+func decorrelateSubqueries(cmd command.Command) (command.Command, bool) {
+	switch c := cmd.(type) {
+	case command.Select:
+		return decorrelateConjuncts(c)
+	case command.Project:
+		return decorrelateScalars(c)
+	}
+	return nil, false
+}
+
+// decorrelateConjuncts replaces the conjuncts of the given selection, that are
+// EXISTS or IN expressions with a subquery, with semi and anti joins.
+func decorrelateConjuncts(sel command.Select) (command.Command, bool) {
+	input := sel.Input
+	var remaining []command.Expr
+	var changed bool
+	for _, conjunct := range conjuncts(sel.Filter) {
+		if join, ok := semiJoin(input, conjunct); ok {
+			input = join
+			changed = true
+			continue
+		}
+		remaining = append(remaining, conjunct)
+	}
+	if !changed {
+		return nil, false
+	}
+	return filtered(input, remaining), true
+}
+
+// semiJoin returns the semi or anti join of the given list and the subquery
+// of the given EXISTS or IN expression. If the expression can't be replaced
+// with a join, ok=false is returned.
+func semiJoin(left command.List, expr command.Expr) (command.Join, bool) {
+	join := command.Join{
+		Type: command.JoinSemi,
+		Left: left,
+	}
+	switch e := expr.(type) {
+	case command.ExistsExpr:
+		project, ok := e.Input.(command.Project)
+		if !ok {
+			return command.Join{}, false
+		}
+		sq, ok := splitSubquery(project.Input)
+		if !ok {
+			return command.Join{}, false
+		}
+		if e.Invert {
+			join.Type = command.JoinAnti
+		}
+		join.Filter = conjunction(sq.correlated)
+		join.Right = sq.input
+		return join, true
+	case command.InExpr:
+		if e.Input == nil || hasSubquery(e.Value) {
+			return command.Join{}, false
+		}
+		project, ok := e.Input.(command.Project)
+		if !ok || len(project.Cols) != 1 || hasStar(project.Cols) || hasSubquery(project.Cols[0].Column) {
+			return command.Join{}, false
+		}
+		sq, ok := splitSubquery(project.Input)
+		if !ok {
+			return command.Join{}, false
+		}
+		// the value is evaluated in the enclosing query, and only the
+		// collation of the value is used for the comparison
+		value := enclosed(e.Value)
+		col := project.Cols[0].Column
+		if collate, ok := col.(command.CollateExpr); ok {
+			col = collate.Value
+		}
+		cmp := command.Expr(command.EqualityExpr{Left: value, Right: col})
+		if e.Invert {
+			join.Type = command.JoinAnti
+			cmp = disjunction(cmp, isNull(value), isNull(col))
+		}
+		join.Filter = conjunction(append(sq.correlated, cmp))
+		join.Right = sq.input
+		return join, true
+	}
+	return command.Join{}, false
+}
+
+// decorrelateScalars replaces the scalar subqueries in the columns of the
+// given projection, and in the selections below it, with left joins.
+func decorrelateScalars(project command.Project) (command.Command, bool) {
+	if hasStar(project.Cols) {
+		return nil, false
+	}
+	var sels []command.Select
+	input := project.Input
+	for {
+		sel, ok := input.(command.Select)
+		if !ok {
+			break
+		}
+		sels = append(sels, sel)
+		input = sel.Input
+	}
+	names, ok := tableNames(input)
+	if !ok {
+		return nil, false
+	}
+
+	var joins []command.Join
+	replace := func(expr command.Expr) command.Expr {
+		return mapExpr(expr, func(expr command.Expr) command.Expr {
+			sub, ok := expr.(command.SubqueryExpr)
+			if !ok {
+				return expr
+			}
+			alias := unusedName(names, "subquery")
+			join, value, ok := scalarJoin(sub, alias)
+			if !ok {
+				return expr
+			}
+			names = append(names, alias)
+			joins = append(joins, join)
+			return value
+		})
+	}
+	cols := make([]command.Column, len(project.Cols))
+	for i, col := range project.Cols {
+		joined := len(joins)
+		replaced := replace(col.Column)
+		if len(joins) != joined {
+			// the column keeps its name
+			if col.Alias == "" {
+				col.Alias = col.Column.String()
+			}
+			col.Column = replaced
+		}
+		cols[i] = col
+	}
+	for i := range sels {
+		sels[i].Filter = replace(sels[i].Filter)
+	}
+	if len(joins) == 0 {
+		return nil, false
+	}
+
+	for _, join := range joins {
+		join.Left = input
+		input = join
+	}
+	for i := len(sels) - 1; i >= 0; i-- {
+		sels[i].Input = input
+		input = sels[i]
+	}
+	project.Cols = cols
+	project.Input = input
+	return project, true
+}
+
+// scalarJoin returns the left join, that produces the value of the given
+// scalar subquery for every row of its input, and the expression for the
+// value. The joined table is aliased with the given name. The left side of the
+// join is not set. If the subquery can't be replaced with a join, ok=false is
+// returned.
+func scalarJoin(sub command.SubqueryExpr, alias string) (join command.Join, value command.Expr, ok bool) {
+	project, ok := sub.Input.(command.Project)
+	if !ok || len(project.Cols) != 1 || hasStar(project.Cols) {
+		return command.Join{}, nil, false
+	}
+	agg, ok := project.Input.(command.Aggregate)
+	if !ok || len(agg.GroupBy) != 0 || agg.Having != nil {
+		return command.Join{}, nil, false
+	}
+	sq, ok := splitSubquery(agg.Input)
+	if !ok || len(sq.correlated) == 0 || !isClosedExpr(project.Cols[0].Column, sq.names) {
+		return command.Join{}, nil, false
+	}
+	for _, fn := range agg.Aggregates {
+		if !isClosedExpr(fn, sq.names) {
+			return command.Join{}, nil, false
+		}
+	}
+
+	// every correlated conjunct must compare a value of the subquery, by which
+	// the rows are grouped, with a value of the enclosing query
+	var keys, filters []command.Expr
+	cols := make([]command.Column, 0, len(sq.correlated)+1)
+	for i, conjunct := range sq.correlated {
+		key, enclosing, ok := correlatedEquality(conjunct, sq.names)
+		if !ok {
+			return command.Join{}, nil, false
+		}
+		name := alias + "_key" + strconv.Itoa(i+1)
+		keys = append(keys, key)
+		cols = append(cols, command.Column{Column: key, Alias: name})
+		filters = append(filters, command.EqualityExpr{
+			Left:  command.ColumnRef{Table: alias, Column: name},
+			Right: enclosing,
+		})
+	}
+	name := alias + "_value"
+	cols = append(cols, command.Column{Column: project.Cols[0].Column, Alias: name})
+
+	join = command.Join{
+		Type:   command.JoinLeft,
+		Filter: conjunction(filters),
+		Right: command.Scan{
+			Table: command.SubqueryTable{
+				Input: command.Project{
+					Cols: cols,
+					Input: command.Aggregate{
+						GroupBy:    keys,
+						Aggregates: agg.Aggregates,
+						Input:      sq.input,
+					},
+				},
+				Alias: alias,
+			},
+		},
+	}
+	value = command.ColumnRef{Table: alias, Column: name}
+	// without a matching group, the subquery aggregates no rows
+	if empty := emptyAggregate(project.Cols[0].Column, agg.Aggregates); !isNullLiteral(empty) {
+		value = command.CaseExpr{
+			Cases: []command.WhenThen{
+				{
+					When: isNull(command.ColumnRef{Table: alias, Column: cols[0].Alias}),
+					Then: empty,
+				},
+			},
+			Else: value,
+		}
+	}
+	return join, value, true
+}
+
+// correlatedEquality splits the given conjunct of a subquery into a value of
+// the subquery and a value of the enclosing query, if it is an equality of
+// the two. The value of the enclosing query is returned, as it is evaluated
+// in the enclosing query.
+func correlatedEquality(conjunct command.Expr, names []string) (inner, enclosing command.Expr, ok bool) {
+	eq, ok := conjunct.(command.EqualityExpr)
+	if !ok || eq.Invert {
+		return nil, nil, false
+	}
+	for _, sides := range [][2]command.Expr{{eq.Left, eq.Right}, {eq.Right, eq.Left}} {
+		inner, enclosing := sides[0], sides[1]
+		if isClosedExpr(inner, names) && !isPinned(inner) && isEnclosingExpr(enclosing) {
+			if _, ok := inner.(command.CollateExpr); ok {
+				return nil, nil, false
+			}
+			if _, ok := enclosing.(command.CollateExpr); ok {
+				return nil, nil, false
+			}
+			return inner, shallower(enclosing), true
+		}
+	}
+	return nil, nil, false
+}
+
+// splitSubquery splits the conjuncts of the selections of the given input of
+// a subquery into the conjuncts, that only reference columns of the subquery,
+// and the correlated conjuncts. If the input is not a selection of scans and
+// joins of simple tables, or any other part of it may reference columns of the
+// enclosing query, ok=false is returned.
+func splitSubquery(list command.List) (subquery, bool) {
+	var filters []command.Expr
+	for {
+		sel, ok := list.(command.Select)
+		if !ok {
+			break
+		}
+		filters = append(filters, conjuncts(sel.Filter)...)
+		list = sel.Input
+	}
+	names, ok := tableNames(list)
+	if !ok || !isClosed(list, names) {
+		return subquery{}, false
+	}
+
+	sq := subquery{names: names}
+	var closed []command.Expr
+	for _, conjunct := range filters {
+		if isClosedExpr(conjunct, names) {
+			closed = append(closed, conjunct)
+		} else {
+			sq.correlated = append(sq.correlated, conjunct)
+		}
+	}
+	sq.input = filtered(list, closed)
+	return sq, true
+}
+
+// isClosed determines whether the given list is a scan of a simple table, or
+// a selection or join of such lists, whose expressions only reference columns
+// of the tables with the given names.
+func isClosed(list command.List, names []string) bool {
+	switch l := list.(type) {
+	case command.Scan:
+		_, ok := l.Table.(command.SimpleTable)
+		return ok
+	case command.Select:
+		return isClosedExpr(l.Filter, names) && isClosed(l.Input, names)
+	case command.Join:
+		if isSemiOrAntiJoin(l) {
+			return isClosedSemiJoin(l, names)
+		}
+		return (l.Filter == nil || isClosedExpr(l.Filter, names)) &&
+			isClosed(l.Left, names) && isClosed(l.Right, names)
+	}
+	return false
+}
+
+// isClosedSemiJoin determines whether both sides of the given semi or anti
+// join are closed, and its filter, which is evaluated like the filter of a
+// correlated subquery, only references columns of the right side, and of the
+// left side, that provides the tables with the given names.
+func isClosedSemiJoin(join command.Join, names []string) bool {
+	right, ok := tableNames(join.Right)
+	if !ok || !isClosed(join.Left, names) || !isClosed(join.Right, right) {
+		return false
+	}
+	if join.Filter == nil {
+		return true
+	}
+	if hasSubquery(join.Filter) {
+		return false
+	}
+	closed := true
+	inspectExpr(join.Filter, func(expr command.Expr) {
+		ref, ok := expr.(command.ColumnRef)
+		if !ok {
+			return
+		}
+		switch {
+		case ref.Schema != "":
+			closed = false
+		case ref.Depth == 0:
+			closed = closed && containsName(right, strings.ToLower(ref.Table))
+		case ref.Depth == 1:
+			closed = closed && containsName(names, strings.ToLower(ref.Table))
+		default:
+			closed = false
+		}
+	})
+	return closed
+}
+
+// isClosedExpr determines whether the given expression only references
+// columns, that are qualified with one of the given lower case table names,
+// and doesn't contain a subquery.
+func isClosedExpr(expr command.Expr, names []string) bool {
+	if hasSubquery(expr) {
+		return false
+	}
+	closed := true
+	inspectExpr(expr, func(expr command.Expr) {
+		if ref, ok := expr.(command.ColumnRef); ok {
+			closed = closed && ref.Depth == 0 && ref.Schema == "" && containsName(names, strings.ToLower(ref.Table))
+		}
+	})
+	return closed
+}
+
+// isEnclosingExpr determines whether the given expression only references
+// columns of enclosing queries, and at least one of them.
+func isEnclosingExpr(expr command.Expr) bool {
+	if isPinned(expr) {
+		return false
+	}
+	var refs int
+	enclosing := true
+	inspectExpr(expr, func(expr command.Expr) {
+		if ref, ok := expr.(command.ColumnRef); ok {
+			refs++
+			enclosing = enclosing && ref.Depth > 0
+		}
+	})
+	return enclosing && refs != 0
+}
+
+// emptyAggregate returns the given expression over the given aggregates, as it
+// is evaluated for no rows. COUNT and TOTAL are 0 for no rows, all other
+// aggregates and all column values are NULL.
+func emptyAggregate(expr command.Expr, aggregates []command.FunctionExpr) command.Expr {
+	// aggregates are replaced first, so that their arguments are unchanged
+	// when they are compared
+	expr = mapExpr(expr, func(expr command.Expr) command.Expr {
+		call, ok := expr.(command.FunctionExpr)
+		if !ok {
+			return expr
+		}
+		for _, fn := range aggregates {
+			if fn.String() != call.String() {
+				continue
+			}
+			switch strings.ToUpper(fn.Name) {
+			case "COUNT":
+				return command.LiteralExpr{Value: "0"}
+			case "TOTAL":
+				return command.LiteralExpr{Value: "0.0"}
+			}
+			return command.LiteralExpr{Value: "NULL"}
+		}
+		return expr
+	})
+	return mapExpr(expr, func(expr command.Expr) command.Expr {
+		if _, ok := expr.(command.ColumnRef); ok {
+			return command.LiteralExpr{Value: "NULL"}
+		}
+		return expr
+	})
+}
+
+// enclosed returns the given expression, as it is evaluated in a subquery of
+// the query, that it was evaluated in before.
+func enclosed(expr command.Expr) command.Expr {
+	return mapExpr(expr, func(expr command.Expr) command.Expr {
+		if ref, ok := expr.(command.ColumnRef); ok {
+			ref.Depth++
+			return ref
+		}
+		return expr
+	})
+}
+
+// shallower returns the given expression, which is evaluated in a subquery,
+// as it is evaluated in the enclosing query.
+func shallower(expr command.Expr) command.Expr {
+	return mapExpr(expr, func(expr command.Expr) command.Expr {
+		if ref, ok := expr.(command.ColumnRef); ok && ref.Depth > 0 {
+			ref.Depth--
+			return ref
+		}
+		return expr
+	})
+}
+
+// unusedName returns the given prefix, followed by the smallest positive
+// number, so that the result is not contained in the given lower case names.
+func unusedName(names []string, prefix string) string {
+	for i := 1; ; i++ {
+		if name := prefix + strconv.Itoa(i); !containsName(names, name) {
+			return name
+		}
+	}
+}
+
+// disjunction returns the disjunction of the given expressions.
+func disjunction(exprs ...command.Expr) command.Expr {
+	expr := exprs[0]
+	for _, disjunct := range exprs[1:] {
+		expr = command.BinaryExpr{
+			Operator: "OR",
+			Left:     expr,
+			Right:    disjunct,
+		}
+	}
+	return expr
+}
+
+func isNull(expr command.Expr) command.IsExpr {
+	return command.IsExpr{
+		Left:  expr,
+		Right: command.LiteralExpr{Value: "NULL"},
+	}
+}
+
+func isNullLiteral(expr command.Expr) bool {
+	lit, ok := expr.(command.LiteralExpr)
+	return ok && strings.EqualFold(lit.Value, "NULL")
+}
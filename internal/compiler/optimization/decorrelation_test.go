@@ -0,0 +1,138 @@
+package optimization
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+func TestDecorrelateSubqueries(t *testing.T) {
+	project := func(input command.List, cols ...command.Expr) command.Project {
+		project := command.Project{Input: input}
+		for _, col := range cols {
+			project.Cols = append(project.Cols, command.Column{Column: col})
+		}
+		return project
+	}
+	sel := func(filter command.Expr, input command.List) command.Select {
+		return command.Select{Filter: filter, Input: input}
+	}
+	outer := func(table, column string) command.ColumnRef {
+		return command.ColumnRef{Table: table, Column: column, Depth: 1}
+	}
+	count := command.FunctionExpr{Name: "COUNT", Args: []command.Expr{lit("*")}}
+	max := command.FunctionExpr{Name: "MAX", Args: []command.Expr{col("b", "w")}}
+	aggregate := func(value command.Expr, filter command.Expr, fns ...command.FunctionExpr) command.SubqueryExpr {
+		return command.SubqueryExpr{
+			Input: project(command.Aggregate{
+				Aggregates: fns,
+				Input:      sel(filter, scan("b")),
+			}, value),
+		}
+	}
+
+	runGolden(t, []Rule{DecorrelateSubqueries}, []goldenTestcase{
+		{
+			"exists",
+			sel(and(
+				eq(col("a", "v"), lit("1")),
+				command.ExistsExpr{Input: project(sel(and(
+					eq(col("b", "id"), outer("a", "id")),
+					eq(col("b", "w"), lit("2")),
+				), scan("b")), lit("*"))},
+			), scan("a")),
+		},
+		{
+			"not exists",
+			sel(command.ExistsExpr{
+				Input:  project(sel(eq(col("", "id"), outer("a", "id")), scan("b")), col("", "id")),
+				Invert: true,
+			}, scan("a")),
+		},
+		{
+			"uncorrelated exists",
+			sel(command.ExistsExpr{Input: project(scan("b"), col("b", "id"))}, scan("a")),
+		},
+		{
+			"in",
+			sel(command.InExpr{
+				Value: col("", "v"),
+				Input: project(sel(eq(col("b", "id"), outer("a", "id")), scan("b")), col("", "w")),
+			}, scan("a")),
+		},
+		{
+			"not in",
+			sel(command.InExpr{
+				Value:  col("a", "v"),
+				Input:  project(scan("b"), command.CollateExpr{Value: col("b", "w"), Collation: "NOCASE"}),
+				Invert: true,
+			}, scan("a")),
+		},
+		{
+			"in values",
+			sel(command.InExpr{Value: col("a", "v"), Values: []command.Expr{lit("1")}}, scan("a")),
+		},
+		{
+			"nested subquery",
+			sel(command.ExistsExpr{Input: project(sel(
+				command.ExistsExpr{Input: project(scan("c"), lit("1"))},
+				scan("b"),
+			), lit("1"))}, scan("a")),
+		},
+		{
+			"aggregating subquery",
+			sel(command.ExistsExpr{Input: project(command.Aggregate{
+				Aggregates: []command.FunctionExpr{count},
+				Input:      scan("b"),
+			}, count)}, scan("a")),
+		},
+		{
+			"subquery of subquery table",
+			sel(command.ExistsExpr{Input: project(
+				command.Scan{Table: command.SubqueryTable{Input: project(scan("b"), col("b", "w")), Alias: "s"}},
+				lit("1"),
+			)}, scan("a")),
+		},
+		{
+			"scalar count",
+			project(scan("a"), col("a", "id"), aggregate(count, eq(col("b", "id"), outer("a", "id")), count)),
+		},
+		{
+			"scalar max in selection",
+			project(sel(
+				command.BinaryExpr{
+					Operator: ">",
+					Left:     col("a", "v"),
+					Right:    aggregate(max, and(eq(outer("a", "id"), col("b", "id")), eq(col("b", "w"), lit("1"))), max),
+				},
+				scan("a"),
+			), col("a", "id")),
+		},
+		{
+			"two scalar subqueries",
+			command.Project{
+				Cols: []command.Column{
+					{Column: aggregate(count, eq(col("b", "id"), outer("a", "id")), count), Alias: "n"},
+					{Column: aggregate(max, eq(col("b", "id"), outer("a", "v")), max)},
+				},
+				Input: scan("a"),
+			},
+		},
+		{
+			"scalar subquery with unqualified reference",
+			project(scan("a"), aggregate(count, eq(col("", "id"), outer("a", "id")), count)),
+		},
+		{
+			"scalar subquery with inequality",
+			project(scan("a"), aggregate(count, command.BinaryExpr{Operator: "<", Left: col("b", "id"), Right: outer("a", "id")}, count)),
+		},
+		{
+			"uncorrelated scalar subquery",
+			project(scan("a"), aggregate(count, eq(col("b", "id"), lit("1")), count)),
+		},
+		{
+			"scalar subquery below star",
+			project(sel(eq(col("a", "v"), aggregate(count, eq(col("b", "id"), outer("a", "id")), count)), scan("a")), lit("*")),
+		},
+	})
+}
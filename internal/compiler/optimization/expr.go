@@ -0,0 +1,110 @@
+package optimization
+
+import (
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// deterministicFunctions are the names of all functions, that always return
+// the same result for the same arguments, in upper case.
+var deterministicFunctions = map[string]bool{
+	"ABS":          true,
+	"COALESCE":     true,
+	"IFNULL":       true,
+	"NULLIF":       true,
+	"LENGTH":       true,
+	"LOWER":        true,
+	"UPPER":        true,
+	"TYPEOF":       true,
+	"MIN":          true,
+	"MAX":          true,
+	"SUBSTR":       true,
+	"ROUND":        true,
+	"AVG":          true,
+	"COUNT":        true,
+	"GROUP_CONCAT": true,
+	"SUM":          true,
+	"TOTAL":        true,
+}
+
+// mapExpr replaces every node of the given expression tree with the result of
+// the given function, bottom-up. Lists that are nested in expressions, such as
+// subqueries, are not mapped. Expressions are copied, so that the input
+// expression is not modified. A nil expression is not passed to the function.
+func mapExpr(expr command.Expr, fn func(command.Expr) command.Expr) command.Expr {
+	mapped, _ := command.Rewrite(expr, func(node command.Node) bool {
+		_, isList := node.(command.List)
+		return !isList
+	}, func(node command.Node) (command.Node, error) {
+		return fn(node.(command.Expr)), nil
+	})
+	if mapped == nil {
+		return nil
+	}
+	return mapped.(command.Expr)
+}
+
+// inspectExpr calls the given function for every node of the given expression
+// tree, bottom-up. Lists that are nested in expressions are not inspected.
+func inspectExpr(expr command.Expr, fn func(command.Expr)) {
+	_ = mapExpr(expr, func(expr command.Expr) command.Expr {
+		fn(expr)
+		return expr
+	})
+}
+
+// isPinned determines whether the given expression must be evaluated exactly
+// where it is, because it contains a subquery or a call to a function, that is
+// not deterministic.
+func isPinned(expr command.Expr) bool {
+	var pinned bool
+	inspectExpr(expr, func(expr command.Expr) {
+		switch e := expr.(type) {
+		case command.FunctionExpr:
+			pinned = pinned || e.Over != nil || !deterministicFunctions[strings.ToUpper(e.Name)]
+		case command.InExpr:
+			pinned = pinned || e.Input != nil
+		case command.ExistsExpr, command.SubqueryExpr, command.RaiseExpr:
+			pinned = true
+		}
+	})
+	return pinned
+}
+
+// isAnd determines whether the given expression is a conjunction of two
+// expressions.
+func isAnd(expr command.Expr) (command.BinaryExpr, bool) {
+	binary, ok := expr.(command.BinaryExpr)
+	return binary, ok && strings.EqualFold(binary.Operator, "AND")
+}
+
+// conjuncts splits the given expression into the expressions, whose
+// conjunction it is. A nil expression has no conjuncts.
+func conjuncts(expr command.Expr) []command.Expr {
+	if expr == nil {
+		return nil
+	}
+	if and, ok := isAnd(expr); ok {
+		return append(conjuncts(and.Left), conjuncts(and.Right)...)
+	}
+	return []command.Expr{expr}
+}
+
+// conjunction returns the conjunction of the given expressions, or nil if
+// there are no expressions.
+func conjunction(exprs []command.Expr) command.Expr {
+	var expr command.Expr
+	for _, conjunct := range exprs {
+		if expr == nil {
+			expr = conjunct
+			continue
+		}
+		expr = command.BinaryExpr{
+			Operator: "AND",
+			Left:     expr,
+			Right:    conjunct,
+		}
+	}
+	return expr
+}
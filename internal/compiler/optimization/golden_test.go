@@ -0,0 +1,57 @@
+package optimization
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+var (
+	record = flag.Bool("record", false, "record golden tests")
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	os.Exit(m.Run())
+}
+
+// goldenTestcase is a command, that is optimized by the rules of a golden
+// test. The optimized command is compared to the recorded one.
+type goldenTestcase struct {
+	name string
+	cmd  command.Command
+}
+
+// runGolden optimizes the command of every given testcase with the given
+// rules, and compares the result to the recorded golden file of the testcase.
+func runGolden(t *testing.T, rules []Rule, tests []goldenTestcase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			got, _ := Optimizer{Rules: rules}.Optimize(tt.cmd)
+			gotString := got.String()
+			testFilePath := filepath.Join("testdata", t.Name()+".golden")
+
+			if *record {
+				t.Logf("overwriting golden file %v", testFilePath)
+				err := os.MkdirAll(filepath.Dir(testFilePath), 0777)
+				require.NoError(err)
+				err = ioutil.WriteFile(testFilePath, []byte(gotString), 0666)
+				require.NoError(err)
+				t.Fail()
+			} else {
+				data, err := ioutil.ReadFile(testFilePath)
+				require.NoError(err)
+				require.Equal(string(data), gotString)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package optimization
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// histogramValue returns the value of the given constant expression, so that
+// it can be compared to the bounds of a histogram. If the expression is not a
+// constant, or is NULL, ok=false is returned.
+func histogramValue(expr command.Expr) (interface{}, bool) {
+	val, ok := constantValue(expr)
+	if !ok {
+		lit, isLit := expr.(command.LiteralExpr)
+		if !isLit {
+			return nil, false
+		}
+		f, err := strconv.ParseFloat(lit.Value, 64)
+		if err != nil {
+			return nil, false
+		}
+		return f, true
+	}
+	switch v := val.(type) {
+	case nil:
+		return nil, false
+	case bool:
+		if v {
+			return int64(1), true
+		}
+		return int64(0), true
+	}
+	return val, true
+}
+
+// compareHistogramValues compares two values the way the executor does, where
+// numeric values are smaller than texts, which are smaller than blobs. Texts
+// are compared byte-wise.
+func compareHistogramValues(a, b interface{}) int {
+	classA, classB := histogramClass(a), histogramClass(b)
+	if classA != classB {
+		return classA - classB
+	}
+	switch classA {
+	case 0:
+		intA, aIsInt := a.(int64)
+		intB, bIsInt := b.(int64)
+		if aIsInt && bIsInt {
+			switch {
+			case intA < intB:
+				return -1
+			case intA > intB:
+				return 1
+			}
+			return 0
+		}
+		floatA, _ := numericValue(a)
+		floatB, _ := numericValue(b)
+		switch {
+		case floatA < floatB:
+			return -1
+		case floatA > floatB:
+			return 1
+		}
+		return 0
+	case 1:
+		return strings.Compare(a.(string), b.(string))
+	case 2:
+		return bytes.Compare(a.([]byte), b.([]byte))
+	}
+	return 0
+}
+
+// histogramClass returns the order of the class of the given value, which is
+// 0 for numeric values, 1 for texts and 2 for blobs.
+func histogramClass(val interface{}) int {
+	switch val.(type) {
+	case string:
+		return 1
+	case []byte:
+		return 2
+	}
+	return 0
+}
+
+// numericValue returns the given value as float64, if it is numeric.
+func numericValue(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// fractionEqual estimates the fraction of the rows of a table, whose value
+// in the column with the given statistics is equal to the given value. The
+// rows of a bucket are assumed to be distributed equally among its distinct
+// values.
+func fractionEqual(col ColumnStatistics, val interface{}) float64 {
+	for _, bucket := range col.Histogram {
+		if compareHistogramValues(val, bucket.Upper) <= 0 {
+			return bucket.Fraction / math.Max(bucket.Distinct, 1)
+		}
+	}
+	return 0
+}
+
+// fractionBelow estimates the fraction of the rows of a table, whose value in
+// the column with the given statistics is smaller than the given value, or
+// equal to it, if inclusive is set. Within a bucket, numeric values are
+// assumed to be distributed uniformly between the bounds of the bucket. For
+// other values, and in the first bucket, whose lower bound is not known, half
+// of the bucket is assumed to be below the value.
+func fractionBelow(col ColumnStatistics, val interface{}, inclusive bool) float64 {
+	var fraction float64
+	for i, bucket := range col.Histogram {
+		cmp := compareHistogramValues(val, bucket.Upper)
+		if cmp > 0 {
+			fraction += bucket.Fraction
+			continue
+		}
+		if cmp == 0 {
+			if inclusive {
+				return fraction + bucket.Fraction
+			}
+			return fraction + bucket.Fraction - bucket.Fraction/math.Max(bucket.Distinct, 1)
+		}
+		if i > 0 {
+			lower, lowerOk := numericValue(col.Histogram[i-1].Upper)
+			upper, upperOk := numericValue(bucket.Upper)
+			value, valueOk := numericValue(val)
+			if lowerOk && upperOk && valueOk && upper > lower {
+				return fraction + bucket.Fraction*math.Max(value-lower, 0)/(upper-lower)
+			}
+		}
+		return fraction + bucket.Fraction/2
+	}
+	return fraction
+}
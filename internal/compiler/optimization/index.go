@@ -0,0 +1,22 @@
+package optimization
+
+// Indexes provides the indexes of tables, that rules use to replace scans of
+// whole tables by scans of parts of an index.
+type Indexes interface {
+	// TableIndexes returns the indexes of the table with the given schema and
+	// name. If the table has no indexes, or is not known, nil is returned.
+	TableIndexes(schema, table string) []Index
+}
+
+// Index describes an index of a table.
+type Index struct {
+	// Name is the name of the index.
+	Name string
+	// Columns are the names of the indexed columns, in the order of the
+	// index. The values of a row in these columns are the key of the row in
+	// the index.
+	Columns []string
+	// Unique indicates, that no two rows have the same key, unless any value
+	// of the key is NULL.
+	Unique bool
+}
@@ -0,0 +1,448 @@
+package optimization
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// UseIndexes returns the rule that replaces a scan of a table, whose rows are
+// filtered by comparisons of indexed columns with values, by a scan of the key
+// ranges of an index, that contain the rows which pass the comparisons. The
+// indexes of the tables are looked up in the given indexes. Of all usable
+// indexes, the one with the smallest estimated fraction of the rows of the
+// table is used, which is estimated from the given statistics, which may be
+// nil. If the amount of distinct key prefixes of an index is known, the
+// fraction of the rows, whose keys have the prefix, that the equalities
+// select, is estimated from it, instead of from the single columns. If a table
+// is indexed by a named index, only that index is considered.
+//
+// Equalities, comparisons and ranges are used, that compare a column with a
+// value, which doesn't reference columns of the scanned table, without an
+// explicit collation. The key ranges are built from the equalities on a prefix
+// of the indexed columns, followed by the comparisons or the range on the next
+// indexed column. An inverted equality or range results in two key ranges. The
+// used conjuncts are removed from the filter, because the index scan only
+// produces rows, that pass them.
+//
+// If a projection only references indexed columns of the rows of an index
+// scan, the index scan is marked as covering, so that the table is not read.
+func UseIndexes(indexes Indexes, stats Statistics) Rule {
+	return Rule{
+		Name: "UseIndexes",
+		Apply: indexSelector{
+			indexes: indexes,
+			model:   costModel{stats: stats},
+		}.apply,
+	}
+}
+
+// indexSelector selects indexes with a cost model.
+type indexSelector struct {
+	indexes Indexes
+	model   costModel
+}
+
+// keyConstraint is a conjunct of a filter, that restricts the values of a
+// single column to the union of one or more ranges of values.
+type keyConstraint struct {
+	// conjunct is the position of the conjunct in the filter.
+	conjunct int
+	// column is the lower case name of the restricted column.
+	column string
+	// ranges are the ranges of values, that pass the conjunct.
+	ranges []valueRange
+	// equal indicates, that the conjunct is an equality, whose only range
+	// consists of a single value.
+	equal bool
+}
+
+// valueRange is a range of values of a single column. A nil bound means, that
+// the range is not bounded on that side.
+type valueRange struct {
+	lo, hi                   command.Expr
+	loExclusive, hiExclusive bool
+}
+
+// indexPlan is a scan of the key ranges of an index, that replaces the used
+// conjuncts of a filter.
+type indexPlan struct {
+	index  Index
+	ranges []command.KeyRange
+	// used are the positions of the used conjuncts in the filter. The first
+	// equal conjuncts are the equalities on a prefix of the indexed columns.
+	used        []int
+	equal       int
+	selectivity float64
+}
+
+// mirrored are the comparison operators, that are equivalent to a comparison
+// operator, if the sides of the comparison are swapped.
+var mirrored = map[string]string{
+	"<":  ">",
+	"<=": ">=",
+	">":  "<",
+	">=": "<=",
+	"=":  "=",
+	"==": "==",
+	"!=": "!=",
+	"<>": "<>",
+}
+
+func (s indexSelector) apply(cmd command.Command) (command.Command, bool) {
+	switch c := cmd.(type) {
+	case command.Select:
+		if scan, ok := c.Input.(command.Scan); ok {
+			return s.selectIndex(c, scan)
+		}
+	case command.Project:
+		return s.cover(c)
+	}
+	return nil, false
+}
+
+// selectIndex replaces the given scan, which is the input of the given
+// selection, by a scan of the cheapest usable index.
+func (s indexSelector) selectIndex(sel command.Select, scan command.Scan) (command.Command, bool) {
+	table, ok := scan.Table.(command.SimpleTable)
+	if !ok {
+		return nil, false
+	}
+
+	exprs := conjuncts(sel.Filter)
+	var constraints []keyConstraint
+	for i, expr := range exprs {
+		if constraint, ok := keyConstraintOf(expr, table); ok {
+			constraint.conjunct = i
+			constraints = append(constraints, constraint)
+		}
+	}
+	if len(constraints) == 0 {
+		return nil, false
+	}
+
+	tables := s.model.tables(scan)
+	stats, _ := s.model.tableStatistics(table)
+	var best *indexPlan
+	for _, index := range s.tableIndexes(table) {
+		plan, ok := planIndex(index, constraints)
+		if !ok {
+			continue
+		}
+		plan.selectivity = 1
+		used := plan.used
+		if distinct := stats.Indexes[strings.ToLower(index.Name)].Distinct; plan.equal != 0 && len(distinct) >= plan.equal && distinct[plan.equal-1] > 0 {
+			plan.selectivity = 1 / distinct[plan.equal-1]
+			used = used[plan.equal:]
+		}
+		for _, i := range used {
+			plan.selectivity *= s.model.selectivity(exprs[i], tables)
+		}
+		if best == nil || plan.selectivity < best.selectivity ||
+			plan.selectivity == best.selectivity && len(plan.used) > len(best.used) {
+			best = plan
+		}
+	}
+	if best == nil || best.selectivity >= 1 {
+		return nil, false
+	}
+
+	var remaining []command.Expr
+	for i, expr := range exprs {
+		if !containsConjunct(best.used, i) {
+			remaining = append(remaining, expr)
+		}
+	}
+	return filtered(command.IndexScan{
+		Table:  table,
+		Index:  best.index.Name,
+		Ranges: best.ranges,
+	}, remaining), true
+}
+
+// tableIndexes returns the indexes of the given table, ordered by their name.
+// If the table is indexed by a named index, only that index is returned.
+func (s indexSelector) tableIndexes(table command.SimpleTable) []Index {
+	if s.indexes == nil {
+		return nil
+	}
+	var indexes []Index
+	for _, index := range s.indexes.TableIndexes(table.Schema, table.Table) {
+		if !table.Indexed || strings.EqualFold(index.Name, table.Index) {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+	return indexes
+}
+
+// planIndex builds the key ranges of the given index from the given
+// constraints. Equalities are used on a prefix of the indexed columns, and
+// the next indexed column may be restricted by a lower and an upper bound, or
+// by a single constraint with multiple ranges. If no constraint restricts the
+// first indexed column, ok=false is returned.
+func planIndex(index Index, constraints []keyConstraint) (*indexPlan, bool) {
+	plan := &indexPlan{index: index}
+	var prefix []command.Expr
+	var ranges []valueRange
+	for _, column := range index.Columns {
+		column = strings.ToLower(column)
+		if equal, ok := findConstraint(constraints, column, func(c keyConstraint) bool { return c.equal }); ok {
+			prefix = append(prefix, equal.ranges[0].lo)
+			plan.used = append(plan.used, equal.conjunct)
+			continue
+		}
+
+		var bounds valueRange
+		if lo, ok := findConstraint(constraints, column, func(c keyConstraint) bool { return len(c.ranges) == 1 && c.ranges[0].lo != nil }); ok {
+			bounds.lo, bounds.loExclusive = lo.ranges[0].lo, lo.ranges[0].loExclusive
+			plan.used = append(plan.used, lo.conjunct)
+			if lo.ranges[0].hi != nil {
+				bounds.hi, bounds.hiExclusive = lo.ranges[0].hi, lo.ranges[0].hiExclusive
+			}
+		}
+		if hi, ok := findConstraint(constraints, column, func(c keyConstraint) bool { return len(c.ranges) == 1 && c.ranges[0].lo == nil }); ok && bounds.hi == nil {
+			bounds.hi, bounds.hiExclusive = hi.ranges[0].hi, hi.ranges[0].hiExclusive
+			plan.used = append(plan.used, hi.conjunct)
+		}
+		if bounds.lo != nil || bounds.hi != nil {
+			ranges = []valueRange{bounds}
+		} else if multiple, ok := findConstraint(constraints, column, func(c keyConstraint) bool { return len(c.ranges) > 1 }); ok {
+			ranges = multiple.ranges
+			plan.used = append(plan.used, multiple.conjunct)
+		}
+		break
+	}
+	if len(plan.used) == 0 {
+		return nil, false
+	}
+	plan.equal = len(prefix)
+
+	if len(ranges) == 0 {
+		plan.ranges = []command.KeyRange{{Lo: prefix, Hi: prefix}}
+		return plan, true
+	}
+	for _, r := range ranges {
+		keyRange := command.KeyRange{
+			Lo: prefix,
+			Hi: prefix,
+		}
+		if r.lo != nil {
+			keyRange.Lo = append(append([]command.Expr{}, prefix...), r.lo)
+			keyRange.LoExclusive = r.loExclusive
+		}
+		if r.hi != nil {
+			keyRange.Hi = append(append([]command.Expr{}, prefix...), r.hi)
+			keyRange.HiExclusive = r.hiExclusive
+		}
+		plan.ranges = append(plan.ranges, keyRange)
+	}
+	return plan, true
+}
+
+// findConstraint returns the first of the given constraints, that restricts
+// the given lower case column and matches the given predicate.
+func findConstraint(constraints []keyConstraint, column string, match func(keyConstraint) bool) (keyConstraint, bool) {
+	for _, constraint := range constraints {
+		if constraint.column == column && match(constraint) {
+			return constraint, true
+		}
+	}
+	return keyConstraint{}, false
+}
+
+func containsConjunct(used []int, conjunct int) bool {
+	for _, i := range used {
+		if i == conjunct {
+			return true
+		}
+	}
+	return false
+}
+
+// keyConstraintOf returns the constraint, that the given conjunct of a filter
+// above a scan of the given table imposes on a column of the table. If the
+// conjunct doesn't restrict a column to ranges of values, ok=false is
+// returned.
+func keyConstraintOf(expr command.Expr, table command.SimpleTable) (keyConstraint, bool) {
+	var operator string
+	var left, right command.Expr
+	switch e := expr.(type) {
+	case command.EqualityExpr:
+		operator, left, right = "=", e.Left, e.Right
+		if e.Invert {
+			operator = "!="
+		}
+	case command.BinaryExpr:
+		operator, left, right = e.Operator, e.Left, e.Right
+	case command.RangeExpr:
+		column, ok := tableColumn(e.Needle, table)
+		if !ok || !isKeyValue(e.Lo) || !isKeyValue(e.Hi) {
+			return keyConstraint{}, false
+		}
+		if e.Invert {
+			return keyConstraint{
+				column: column,
+				ranges: []valueRange{
+					{hi: e.Lo, hiExclusive: true},
+					{lo: e.Hi, loExclusive: true},
+				},
+			}, true
+		}
+		return keyConstraint{
+			column: column,
+			ranges: []valueRange{{lo: e.Lo, hi: e.Hi}},
+		}, true
+	default:
+		return keyConstraint{}, false
+	}
+
+	column, ok := tableColumn(left, table)
+	if !ok || !isKeyValue(right) {
+		// swap the sides, so that the column is on the left side
+		column, ok = tableColumn(right, table)
+		if !ok || !isKeyValue(left) {
+			return keyConstraint{}, false
+		}
+		left, right = right, left
+		operator = mirrored[operator]
+	}
+
+	constraint := keyConstraint{column: column}
+	switch operator {
+	case "=", "==":
+		constraint.ranges = []valueRange{{lo: right, hi: right}}
+		constraint.equal = true
+	case "!=", "<>":
+		constraint.ranges = []valueRange{
+			{hi: right, hiExclusive: true},
+			{lo: right, loExclusive: true},
+		}
+	case "<":
+		constraint.ranges = []valueRange{{hi: right, hiExclusive: true}}
+	case "<=":
+		constraint.ranges = []valueRange{{hi: right}}
+	case ">":
+		constraint.ranges = []valueRange{{lo: right, loExclusive: true}}
+	case ">=":
+		constraint.ranges = []valueRange{{lo: right}}
+	default:
+		return keyConstraint{}, false
+	}
+	return constraint, true
+}
+
+// tableColumn returns the lower case name of the column of the given table,
+// that is referenced by the given expression. If the expression is not a
+// reference of a column of the table, ok=false is returned.
+func tableColumn(expr command.Expr, table command.SimpleTable) (string, bool) {
+	ref, ok := expr.(command.ColumnRef)
+	if !ok || ref.Depth != 0 {
+		return "", false
+	}
+	if ref.Schema != "" && (table.Alias != "" || !strings.EqualFold(ref.Schema, table.Schema)) {
+		return "", false
+	}
+	if ref.Table != "" && !strings.EqualFold(ref.Table, tableName(table)) {
+		return "", false
+	}
+	return strings.ToLower(ref.Column), true
+}
+
+// isKeyValue determines whether the given expression can be evaluated once,
+// before an index is scanned, and compared to the keys of the index. This is
+// the case, if the expression doesn't reference columns of the current
+// query, is not pinned and doesn't specify a collation.
+func isKeyValue(expr command.Expr) bool {
+	if _, ok := expr.(command.CollateExpr); ok || isPinned(expr) {
+		return false
+	}
+	independent := true
+	inspectExpr(expr, func(expr command.Expr) {
+		if ref, ok := expr.(command.ColumnRef); ok && ref.Depth == 0 {
+			independent = false
+		}
+	})
+	return independent
+}
+
+// cover marks the index scan below the given projection as covering, if the
+// projection and all selections between them only reference indexed columns.
+func (s indexSelector) cover(project command.Project) (command.Command, bool) {
+	if hasStar(project.Cols) {
+		return nil, false
+	}
+
+	var filters []command.Expr
+	input := project.Input
+	for {
+		sel, ok := input.(command.Select)
+		if !ok {
+			break
+		}
+		filters = append(filters, sel.Filter)
+		input = sel.Input
+	}
+	scan, ok := input.(command.IndexScan)
+	if !ok || scan.Covering {
+		return nil, false
+	}
+	var index Index
+	for _, candidate := range s.tableIndexes(scan.Table) {
+		if strings.EqualFold(candidate.Name, scan.Index) {
+			index = candidate
+		}
+	}
+
+	exprs := filters
+	for _, col := range project.Cols {
+		exprs = append(exprs, col.Column)
+	}
+	for _, expr := range exprs {
+		if !coveredBy(expr, scan.Table, index) {
+			return nil, false
+		}
+	}
+
+	scan.Covering = true
+	var list command.List = scan
+	for i := len(filters) - 1; i >= 0; i-- {
+		list = command.Select{
+			Filter: filters[i],
+			Input:  list,
+		}
+	}
+	project.Input = list
+	return project, true
+}
+
+// coveredBy determines whether the given expression only references columns
+// of the given table, that are indexed by the given index.
+func coveredBy(expr command.Expr, table command.SimpleTable, index Index) bool {
+	if isPinned(expr) {
+		return false
+	}
+	covered := true
+	inspectExpr(expr, func(expr command.Expr) {
+		ref, ok := expr.(command.ColumnRef)
+		if !ok || ref.Depth != 0 {
+			return
+		}
+		column, ok := tableColumn(ref, table)
+		if !ok || !containsName(lowerNames(index.Columns), column) {
+			covered = false
+		}
+	})
+	return covered
+}
+
+func lowerNames(names []string) []string {
+	lower := make([]string, len(names))
+	for i, name := range names {
+		lower[i] = strings.ToLower(name)
+	}
+	return lower
+}
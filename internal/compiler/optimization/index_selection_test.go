@@ -0,0 +1,182 @@
+package optimization
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// testIndexes are indexes for testing, by table name.
+type testIndexes map[string][]Index
+
+func (i testIndexes) TableIndexes(schema, table string) []Index {
+	return i[table]
+}
+
+var indexSelectionIndexes = testIndexes{
+	"a": {
+		{Name: "a_v", Columns: []string{"v"}},
+		{Name: "a_v_w", Columns: []string{"v", "w"}},
+		{Name: "a_id", Columns: []string{"id"}, Unique: true},
+	},
+	"b": {
+		{Name: "b_w", Columns: []string{"w"}},
+	},
+	"c": {
+		{Name: "c_u", Columns: []string{"u"}},
+		{Name: "c_v_w", Columns: []string{"v", "w"}},
+	},
+	"d": {
+		{Name: "d_u", Columns: []string{"u"}},
+		{Name: "d_v_w", Columns: []string{"v", "w"}},
+	},
+}
+
+var indexSelectionStatistics = testStatistics{
+	"a": {
+		Rows: 10000,
+		Columns: map[string]ColumnStatistics{
+			"id": {Distinct: 10000},
+			"v":  {Distinct: 10},
+		},
+	},
+	// the values of w are almost determined by the values of v, which is only
+	// known from the statistics of the index of c
+	"c": {
+		Rows: 10000,
+		Columns: map[string]ColumnStatistics{
+			"u": {Distinct: 50},
+			"v": {Distinct: 10},
+			"w": {Distinct: 10},
+		},
+		Indexes: map[string]IndexStatistics{
+			"c_v_w": {Distinct: []float64{10, 12}},
+		},
+	},
+	"d": {
+		Rows: 10000,
+		Columns: map[string]ColumnStatistics{
+			"u": {Distinct: 50},
+			"v": {Distinct: 10},
+			"w": {Distinct: 10},
+		},
+	},
+}
+
+func TestUseIndexes(t *testing.T) {
+	sel := func(filter command.Expr, input command.List) command.Select {
+		return command.Select{Filter: filter, Input: input}
+	}
+	project := func(input command.List, cols ...command.Expr) command.Project {
+		project := command.Project{Input: input}
+		for _, col := range cols {
+			project.Cols = append(project.Cols, command.Column{Column: col})
+		}
+		return project
+	}
+	cmp := func(operator string, left, right command.Expr) command.BinaryExpr {
+		return command.BinaryExpr{Operator: operator, Left: left, Right: right}
+	}
+
+	runGolden(t, []Rule{UseIndexes(indexSelectionIndexes, indexSelectionStatistics)}, []goldenTestcase{
+		{
+			"equality",
+			sel(eq(col("a", "id"), lit("5")), scan("a")),
+		},
+		{
+			"mirrored comparison",
+			sel(cmp("<", lit("5"), col("", "v")), scan("a")),
+		},
+		{
+			"equality prefix and range",
+			sel(and(
+				eq(col("a", "v"), lit("1")),
+				cmp(">", col("a", "w"), lit("2")),
+				cmp("<=", col("a", "w"), lit("8")),
+				eq(col("a", "x"), lit("3")),
+			), scan("a")),
+		},
+		{
+			"range",
+			sel(command.RangeExpr{Needle: col("a", "v"), Lo: lit("1"), Hi: lit("3")}, scan("a")),
+		},
+		{
+			"inverted range",
+			sel(command.RangeExpr{Needle: col("a", "v"), Lo: lit("1"), Hi: lit("3"), Invert: true}, scan("a")),
+		},
+		{
+			"inequality",
+			sel(command.EqualityExpr{Left: col("b", "w"), Right: lit("1"), Invert: true}, scan("b")),
+		},
+		{
+			"most selective index",
+			sel(and(
+				eq(col("a", "v"), lit("1")),
+				eq(col("a", "id"), lit("2")),
+			), scan("a")),
+		},
+		{
+			"indexed by",
+			sel(and(
+				eq(col("a", "v"), lit("1")),
+				eq(col("a", "id"), lit("2")),
+			), command.Scan{Table: command.SimpleTable{Table: "a", Indexed: true, Index: "a_v"}}),
+		},
+		{
+			"alias",
+			sel(eq(col("x", "w"), lit("1")), command.Scan{Table: command.SimpleTable{Table: "b", Alias: "x"}}),
+		},
+		{
+			"correlated value",
+			sel(eq(col("b", "w"), command.ColumnRef{Table: "c", Column: "w", Depth: 1}), scan("b")),
+		},
+		{
+			"dependent value",
+			sel(eq(col("b", "w"), col("b", "v")), scan("b")),
+		},
+		{
+			"collation",
+			sel(eq(col("b", "w"), command.CollateExpr{Value: lit("'x'"), Collation: "NOCASE"}), scan("b")),
+		},
+		{
+			"pinned value",
+			sel(eq(col("b", "w"), command.FunctionExpr{Name: "RANDOM"}), scan("b")),
+		},
+		{
+			"unindexed column",
+			sel(eq(col("b", "v"), lit("1")), scan("b")),
+		},
+		{
+			"second column only",
+			sel(eq(col("a", "w"), lit("1")), scan("a")),
+		},
+		{
+			"covering",
+			project(sel(eq(col("a", "v"), lit("1")), scan("a")), col("a", "v")),
+		},
+		{
+			"covering with remaining filter",
+			project(sel(and(
+				eq(col("a", "v"), lit("1")),
+				cmp(">", col("a", "w"), lit("1")),
+				cmp("<", col("", "w"), col("a", "v")),
+			), scan("a")), col("", "w")),
+		},
+		{
+			"not covering",
+			project(sel(eq(col("a", "v"), lit("1")), scan("a")), col("a", "v"), col("a", "x")),
+		},
+		{
+			"rowid is not covered",
+			project(sel(eq(col("b", "w"), lit("1")), scan("b")), col("b", "rowid")),
+		},
+		{
+			"distinct key prefixes",
+			sel(and(eq(col("c", "v"), lit("1")), eq(col("c", "w"), lit("2")), eq(col("c", "u"), lit("3"))), scan("c")),
+		},
+		{
+			"no distinct key prefixes",
+			sel(and(eq(col("d", "v"), lit("1")), eq(col("d", "w"), lit("2")), eq(col("d", "u"), lit("3"))), scan("d")),
+		},
+	})
+}
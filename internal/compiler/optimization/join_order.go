@@ -0,0 +1,402 @@
+package optimization
+
+import (
+	"math/bits"
+	"sort"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+const (
+	// MaxExhaustiveRelations is the maximum amount of joined relations, for
+	// which all join orders are considered. The order of more relations is
+	// chosen greedily.
+	MaxExhaustiveRelations = 8
+
+	// maxJoinedRelations is the maximum amount of joined relations, that are
+	// reordered at all.
+	maxJoinedRelations = 64
+)
+
+// ReorderJoins returns the rule that reorders chains of inner and cross joins,
+// so that the estimated cost of executing them is minimal. Costs are
+// estimated from the given statistics, which may be nil.
+//
+// The joined relations and all conjuncts of the selections and join filters
+// between them are collected, and the joins are rebuilt in the cheapest
+// order. Every conjunct is placed on the lowest join, that provides all tables
+// that it references. For up to MaxExhaustiveRelations relations, the
+// cheapest of all join trees is found by dynamic programming over the subsets
+// of the relations. For more relations, the two cheapest joinable trees are
+// joined repeatedly, starting with the relations.
+//
+// The cost of a join is the cost of its inputs, plus the amount of row
+// combinations that are evaluated, plus the rows of the right input, which are
+// held in memory. Since reordering changes the order of the columns of a join,
+// joins are only reordered below a projection without star columns. Joins
+// with outer types or join constraints are not reordered, but their sides
+// can be.
+func ReorderJoins(stats Statistics) Rule {
+	return Rule{
+		Name:  "ReorderJoins",
+		Apply: joinOrderer{model: costModel{stats: stats}}.apply,
+	}
+}
+
+// joinOrderer reorders joins with a cost model.
+type joinOrderer struct {
+	model costModel
+}
+
+// joinGraph consists of the relations of a chain of joins, and the
+// conjuncts of the filters between them.
+type joinGraph struct {
+	// relations are the joined lists, that are not reordered themselves.
+	relations []command.List
+	// names are the names of the tables, that are provided by each relation.
+	names [][]string
+	// predicates are the conjuncts, that can be placed anywhere, where the
+	// referenced relations are joined, with the set of those relations.
+	predicates []joinPredicate
+	// pinned are the conjuncts, that can't be attributed to relations. They
+	// are evaluated above all joins.
+	pinned []command.Expr
+}
+
+// joinPredicate is a conjunct, that references the set of relations, where
+// the i-th bit of the set denotes the i-th relation of a joinGraph.
+type joinPredicate struct {
+	expr        command.Expr
+	relations   uint64
+	selectivity float64
+}
+
+// joinPlan is a join tree, whose leaves are the relations of a joinGraph.
+type joinPlan struct {
+	relations   uint64
+	left, right *joinPlan
+	rows        float64
+	cost        float64
+}
+
+func (o joinOrderer) apply(cmd command.Command) (command.Command, bool) {
+	project, ok := cmd.(command.Project)
+	if !ok || hasStar(project.Cols) {
+		return nil, false
+	}
+	input, ok := o.reorderInput(project.Input)
+	if !ok {
+		return nil, false
+	}
+	project.Input = input
+	return project, true
+}
+
+// reorderInput reorders the joins of the given list, or of its input, if the
+// list doesn't depend on the order of the columns and rows of its input.
+func (o joinOrderer) reorderInput(list command.List) (command.List, bool) {
+	var input command.List
+	var ok bool
+	switch l := list.(type) {
+	case command.Select, command.Join:
+		return o.reorder(l)
+	case command.Sort:
+		if l.Input, ok = o.reorderInput(l.Input); ok {
+			input = l
+		}
+	case command.Aggregate:
+		if l.Input, ok = o.reorderInput(l.Input); ok {
+			input = l
+		}
+	case command.Window:
+		if l.Input, ok = o.reorderInput(l.Input); ok {
+			input = l
+		}
+	case command.Distinct:
+		if l.Input, ok = o.reorderInput(l.Input); ok {
+			input = l
+		}
+	}
+	return input, ok
+}
+
+// reorder returns the cheapest join tree of the relations of the given
+// list. If it is not cheaper than the given list, ok=false is returned.
+func (o joinOrderer) reorder(list command.List) (command.List, bool) {
+	graph, ok := o.graph(list)
+	if !ok {
+		return nil, false
+	}
+	var plan *joinPlan
+	if len(graph.relations) <= MaxExhaustiveRelations {
+		plan = o.exhaustive(graph)
+	} else {
+		plan = o.greedy(graph)
+	}
+	if plan.cost >= o.plan(graph, list).cost {
+		return nil, false
+	}
+	return filtered(graph.build(plan), graph.pinned), true
+}
+
+// plan returns the plan of the given list, whose relations are the relations
+// of the given graph.
+func (o joinOrderer) plan(graph joinGraph, list command.List) *joinPlan {
+	switch l := list.(type) {
+	case command.Select:
+		return o.plan(graph, l.Input)
+	case command.Join:
+		if isReorderable(l) {
+			return o.join(graph, o.plan(graph, l.Left), o.plan(graph, l.Right))
+		}
+	}
+	for i, relation := range graph.relations {
+		if relation.String() == list.String() {
+			return o.leaf(graph, i)
+		}
+	}
+	return nil
+}
+
+// graph collects the relations and predicates of the given chain of joins. If
+// the list contains less than two relations, or the relations can't be
+// told apart by their table names, ok=false is returned.
+func (o joinOrderer) graph(list command.List) (joinGraph, bool) {
+	var graph joinGraph
+	var exprs []command.Expr
+	var collect func(command.List)
+	collect = func(list command.List) {
+		switch l := list.(type) {
+		case command.Select:
+			exprs = append(exprs, conjuncts(l.Filter)...)
+			collect(l.Input)
+			return
+		case command.Join:
+			if isReorderable(l) {
+				exprs = append(exprs, conjuncts(l.Filter)...)
+				collect(l.Left)
+				collect(l.Right)
+				return
+			}
+		}
+		graph.relations = append(graph.relations, list)
+	}
+	collect(list)
+	if len(graph.relations) < 2 || len(graph.relations) > maxJoinedRelations {
+		return joinGraph{}, false
+	}
+
+	// order the relations and predicates independently of the given join
+	// tree, so that reordering the result again yields the same join tree
+	sort.SliceStable(graph.relations, func(i, j int) bool {
+		return graph.relations[i].String() < graph.relations[j].String()
+	})
+	sort.SliceStable(exprs, func(i, j int) bool {
+		return exprs[i].String() < exprs[j].String()
+	})
+
+	seen := make(map[string]bool)
+	for _, relation := range graph.relations {
+		names, ok := tableNames(relation)
+		if !ok {
+			return joinGraph{}, false
+		}
+		for _, name := range names {
+			if seen[name] {
+				return joinGraph{}, false
+			}
+			seen[name] = true
+		}
+		graph.names = append(graph.names, names)
+	}
+
+	tables := o.model.tables(list)
+	for _, expr := range exprs {
+		relations, ok := graph.referencedRelations(expr)
+		if !ok {
+			graph.pinned = append(graph.pinned, expr)
+			continue
+		}
+		graph.predicates = append(graph.predicates, joinPredicate{
+			expr:        expr,
+			relations:   relations,
+			selectivity: o.model.selectivity(expr, tables),
+		})
+	}
+	return graph, true
+}
+
+// isReorderable determines whether the sides of the given join can be
+// swapped and regrouped with other joins.
+func isReorderable(join command.Join) bool {
+	if join.Left == nil || join.Right == nil || join.Natural || len(join.Using) != 0 {
+		return false
+	}
+	switch join.Type {
+	case command.JoinUnknown, command.JoinInner, command.JoinCross:
+		return true
+	}
+	return false
+}
+
+// referencedRelations returns the set of relations, whose tables are
+// referenced by the given expression. If the expression can't be attributed
+// to relations, ok=false is returned.
+func (g joinGraph) referencedRelations(expr command.Expr) (relations uint64, ok bool) {
+	tables, movable := referencedTables(expr)
+	if !movable {
+		return 0, false
+	}
+	for _, table := range tables {
+		found := false
+		for i, names := range g.names {
+			if containsName(names, table) {
+				relations |= 1 << uint(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return relations, true
+}
+
+// rows estimates the amount of rows of the join of the given set of
+// relations, which is independent of the order in which they are joined.
+func (o joinOrderer) rows(graph joinGraph, relations uint64) float64 {
+	rows := 1.0
+	for i, relation := range graph.relations {
+		if relations&(1<<uint(i)) != 0 {
+			rows *= o.model.rows(relation)
+		}
+	}
+	for _, predicate := range graph.predicates {
+		if predicate.relations&^relations == 0 {
+			rows *= predicate.selectivity
+		}
+	}
+	return rows
+}
+
+// leaf returns the plan that consists of the i-th relation only.
+func (o joinOrderer) leaf(graph joinGraph, i int) *joinPlan {
+	rows := o.rows(graph, 1<<uint(i))
+	return &joinPlan{
+		relations: 1 << uint(i),
+		rows:      rows,
+		cost:      rows,
+	}
+}
+
+// join returns the plan that joins the given plans.
+func (o joinOrderer) join(graph joinGraph, left, right *joinPlan) *joinPlan {
+	relations := left.relations | right.relations
+	return &joinPlan{
+		relations: relations,
+		left:      left,
+		right:     right,
+		rows:      o.rows(graph, relations),
+		cost:      left.cost + right.cost + left.rows*right.rows + right.rows,
+	}
+}
+
+// exhaustive returns the cheapest plan, that joins all relations of the given
+// graph. The cheapest plan of every set of relations is computed from the
+// cheapest plans of all of its partitions into two sets.
+func (o joinOrderer) exhaustive(graph joinGraph) *joinPlan {
+	all := uint64(1)<<uint(len(graph.relations)) - 1
+	best := make([]*joinPlan, all+1)
+	for i := range graph.relations {
+		best[1<<uint(i)] = o.leaf(graph, i)
+	}
+	// every proper subset of a set is smaller than the set itself, so the
+	// plans of the subsets are known
+	for set := uint64(1); set <= all; set++ {
+		if bits.OnesCount64(set) < 2 {
+			continue
+		}
+		for left := (set - 1) & set; left != 0; left = (left - 1) & set {
+			plan := o.join(graph, best[left], best[set&^left])
+			if best[set] == nil || plan.cost < best[set].cost {
+				best[set] = plan
+			}
+		}
+	}
+	return best[all]
+}
+
+// greedy returns a plan, that joins all relations of the given graph, by
+// repeatedly joining the two plans, whose join is the cheapest. Plans, that
+// are not connected by a predicate, are only joined, if no connected plans
+// are left.
+func (o joinOrderer) greedy(graph joinGraph) *joinPlan {
+	plans := make([]*joinPlan, len(graph.relations))
+	for i := range graph.relations {
+		plans[i] = o.leaf(graph, i)
+	}
+	for len(plans) > 1 {
+		var cheapest *joinPlan
+		var cheapestConnected bool
+		var left, right int
+		for i := range plans {
+			for j := range plans {
+				if i == j {
+					continue
+				}
+				plan := o.join(graph, plans[i], plans[j])
+				connected := graph.connected(plans[i].relations, plans[j].relations)
+				if cheapest == nil ||
+					connected && !cheapestConnected ||
+					connected == cheapestConnected && plan.cost < cheapest.cost {
+					cheapest, cheapestConnected, left, right = plan, connected, i, j
+				}
+			}
+		}
+		plans[left] = cheapest
+		plans = append(plans[:right], plans[right+1:]...)
+	}
+	return plans[0]
+}
+
+// connected determines whether a predicate references relations of both
+// given sets, and no other relations.
+func (g joinGraph) connected(left, right uint64) bool {
+	for _, predicate := range g.predicates {
+		if predicate.relations&^(left|right) == 0 && predicate.relations&left != 0 && predicate.relations&right != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// build returns the join tree of the given plan, where every predicate is
+// evaluated by the lowest join or relation, that provides all referenced
+// relations.
+func (g joinGraph) build(plan *joinPlan) command.List {
+	var exprs []command.Expr
+	for _, predicate := range g.predicates {
+		if predicate.relations&^plan.relations != 0 {
+			continue
+		}
+		if plan.left != nil && (predicate.relations&^plan.left.relations == 0 || predicate.relations&^plan.right.relations == 0) {
+			continue
+		}
+		exprs = append(exprs, predicate.expr)
+	}
+
+	if plan.left == nil {
+		return filtered(g.relations[bits.TrailingZeros64(plan.relations)], exprs)
+	}
+	join := command.Join{
+		Type:   command.JoinCross,
+		Filter: conjunction(exprs),
+		Left:   g.build(plan.left),
+		Right:  g.build(plan.right),
+	}
+	if join.Filter != nil {
+		join.Type = command.JoinInner
+	}
+	return join
+}
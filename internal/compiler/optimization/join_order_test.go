@@ -0,0 +1,180 @@
+package optimization
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// testStatistics are statistics for testing, by table name.
+type testStatistics map[string]TableStatistics
+
+func (s testStatistics) TableStatistics(schema, table string) (TableStatistics, bool) {
+	stats, ok := s[table]
+	return stats, ok
+}
+
+func joinOrderTestcases() []goldenTestcase {
+	project := func(input command.List) command.Project {
+		return command.Project{
+			Cols:  []command.Column{{Column: col("a", "v")}},
+			Input: input,
+		}
+	}
+	cross := func(left, right command.List) command.Join {
+		return command.Join{Type: command.JoinCross, Left: left, Right: right}
+	}
+	inner := func(filter command.Expr, left, right command.List) command.Join {
+		return command.Join{Type: command.JoinInner, Filter: filter, Left: left, Right: right}
+	}
+
+	var chain command.List = scan("t0")
+	var chainFilter []command.Expr
+	for i := 1; i < 10; i++ {
+		chain = cross(chain, scan(fmt.Sprintf("t%d", i)))
+		chainFilter = append(chainFilter, eq(col(fmt.Sprintf("t%d", i-1), "id"), col(fmt.Sprintf("t%d", i), "id")))
+	}
+
+	return []goldenTestcase{
+		{
+			"small tables first",
+			project(command.Select{
+				Filter: and(
+					eq(col("a", "id"), col("b", "id")),
+					eq(col("a", "id"), col("c", "id")),
+				),
+				Input: cross(cross(scan("a"), scan("b")), scan("c")),
+			}),
+		},
+		{
+			"avoid cross product",
+			project(inner(eq(col("b", "id"), col("c", "id")),
+				inner(eq(col("a", "id"), col("b", "id")), scan("a"), scan("c")),
+				scan("b"),
+			)),
+		},
+		{
+			"filtered relations",
+			project(command.Select{
+				Filter: and(
+					eq(col("a", "id"), col("d", "id")),
+					eq(col("a", "v"), lit("1")),
+					eq(col("d", "id"), lit("2")),
+				),
+				Input: cross(scan("d"), scan("a")),
+			}),
+		},
+		{
+			"unknown statistics",
+			project(inner(eq(col("x", "id"), col("y", "id")), scan("x"), scan("y"))),
+		},
+		{
+			"pinned",
+			project(command.Select{
+				Filter: and(
+					eq(col("", "v"), lit("1")),
+					eq(col("a", "id"), command.FunctionExpr{Name: "RANDOM"}),
+					eq(col("a", "id"), col("b", "id")),
+				),
+				Input: cross(scan("b"), scan("a")),
+			}),
+		},
+		{
+			"outer join relation",
+			project(command.Select{
+				Filter: eq(col("a", "id"), col("c", "id")),
+				Input: cross(
+					scan("c"),
+					command.Join{Type: command.JoinLeft, Filter: eq(col("a", "id"), col("b", "id")), Left: scan("a"), Right: scan("b")},
+				),
+			}),
+		},
+		{
+			"below sort and aggregate",
+			project(command.Sort{
+				Terms: []command.SortTerm{{Expr: col("a", "v")}},
+				Input: command.Aggregate{
+					GroupBy: []command.Expr{col("a", "v")},
+					Input:   inner(eq(col("a", "id"), col("b", "id")), scan("a"), scan("b")),
+				},
+			}),
+		},
+		{
+			"greedy",
+			project(command.Select{
+				Filter: conjunction(chainFilter),
+				Input:  chain,
+			}),
+		},
+		{
+			"star",
+			command.Project{
+				Cols:  []command.Column{{Column: lit("*")}},
+				Input: inner(eq(col("a", "id"), col("b", "id")), scan("a"), scan("b")),
+			},
+		},
+		{
+			"ambiguous self join",
+			project(inner(eq(col("a", "id"), col("a", "v")), scan("a"), scan("a"))),
+		},
+		{
+			"using",
+			project(command.Join{Using: []string{"id"}, Left: scan("a"), Right: scan("b")}),
+		},
+	}
+}
+
+var joinOrderStatistics = testStatistics{
+	"a": {
+		Rows: 10000,
+		Columns: map[string]ColumnStatistics{
+			"id": {Distinct: 10000},
+			"v":  {Distinct: 100},
+		},
+	},
+	"b": {
+		Rows: 10,
+		Columns: map[string]ColumnStatistics{
+			"id": {Distinct: 10},
+		},
+	},
+	"c": {
+		Rows: 20,
+		Columns: map[string]ColumnStatistics{
+			"id": {Distinct: 20},
+		},
+	},
+	"d": {
+		Rows: 5000,
+		Columns: map[string]ColumnStatistics{
+			"id": {Distinct: 5000},
+		},
+	},
+	"t0": {Rows: 1000},
+	"t1": {Rows: 10},
+	"t2": {Rows: 1000},
+	"t3": {Rows: 10},
+	"t4": {Rows: 1000},
+	"t5": {Rows: 10},
+	"t6": {Rows: 1000},
+	"t7": {Rows: 10},
+	"t8": {Rows: 1000},
+	"t9": {Rows: 10},
+}
+
+func TestReorderJoins(t *testing.T) {
+	runGolden(t, []Rule{ReorderJoins(joinOrderStatistics)}, joinOrderTestcases())
+}
+
+func TestReorderJoins_FixedPoint(t *testing.T) {
+	rule := ReorderJoins(joinOrderStatistics)
+	for _, tt := range joinOrderTestcases() {
+		t.Run(tt.name, func(t *testing.T) {
+			optimized, _ := Optimizer{Rules: []Rule{rule}, MaxIterations: 1}.Optimize(tt.cmd)
+			_, fired := Optimizer{Rules: []Rule{rule}}.Optimize(optimized)
+			assert.Empty(t, fired, "reordering is not stable")
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package optimization
+
+import "github.com/tomarrell/lbadd/internal/compiler/command"
+
+// PushDownLimits is the rule that moves limits and offsets as close to the
+// lists that produce the datasets as possible, so that fewer datasets have to
+// be produced.
+//
+// A limit or an offset is moved below a projection, because a projection
+// produces exactly one dataset for every dataset of its input. A limit of a
+// union that keeps duplicates is copied into both sides of the union, and a
+// limit of a sort is moved into the sort, which then only has to determine
+// the first datasets. If the limit is above an offset, the sort has to
+// determine the first limit+offset datasets.
+//
+// Constant chains of limits and offsets are folded. Since the composition
+// (Limit ∘ Offset)(x) is not commutative, an offset above a limit is rewritten
+// into a limit above an offset, that returns the same datasets, so that at
+// most a single limit above a single offset remains. Negative limits and
+// offsets that are not positive are removed, and a limit of zero is replaced
+// by an empty list, if the columns of the list are known.
+var PushDownLimits = Rule{
+	Name:  "PushDownLimits",
+	Apply: pushDownLimits,
+}
+
+func pushDownLimits(cmd command.Command) (command.Command, bool) {
+	switch c := cmd.(type) {
+	case command.Limit:
+		return pushDownLimit(c)
+	case command.Offset:
+		return pushDownOffset(c)
+	}
+	return nil, false
+}
+
+// pushDownLimit folds the given limit with the limit below it, or moves it
+// below its input.
+func pushDownLimit(limit command.Limit) (command.Command, bool) {
+	if n, ok := constantCount(limit.Limit); ok {
+		if n < 0 {
+			return limit.Input, true
+		}
+		if n == 0 {
+			if empty, ok := emptyOf(limit.Input); ok {
+				return empty, true
+			}
+		}
+	}
+
+	switch input := limit.Input.(type) {
+	case command.Limit:
+		n, ok := minCount(limit.Limit, input.Limit)
+		if !ok {
+			return nil, false
+		}
+		input.Limit = n
+		return input, true
+	case command.Project:
+		if input.Input == nil {
+			return nil, false
+		}
+		limit.Input = input.Input
+		input.Input = limit
+		return input, true
+	case command.Union:
+		if !input.All || isPinned(limit.Limit) {
+			return nil, false
+		}
+		if isLimited(input.Left, limit.Limit) && isLimited(input.Right, limit.Limit) {
+			return nil, false
+		}
+		input.Left = limitedTo(input.Left, limit.Limit)
+		input.Right = limitedTo(input.Right, limit.Limit)
+		limit.Input = input
+		return limit, true
+	case command.Sort:
+		sorted, ok := sortLimitedTo(input, limit.Limit)
+		if !ok {
+			return nil, false
+		}
+		return sorted, true
+	case command.Offset:
+		// the sort below the offset has to determine the skipped datasets as
+		// well
+		sort, ok := input.Input.(command.Sort)
+		if !ok {
+			return nil, false
+		}
+		n, ok := addCounts(limit.Limit, input.Offset)
+		if !ok || isLimited(sort, n) {
+			return nil, false
+		}
+		if input.Input, ok = sortLimitedTo(sort, n); !ok {
+			return nil, false
+		}
+		limit.Input = input
+		return limit, true
+	}
+	return nil, false
+}
+
+// pushDownOffset folds the given offset with the offset below it, or moves it
+// below its input. An offset above a limit is rewritten into a limit above an
+// offset.
+func pushDownOffset(offset command.Offset) (command.Command, bool) {
+	if n, ok := constantCount(offset.Offset); ok && n <= 0 {
+		return offset.Input, true
+	}
+
+	switch input := offset.Input.(type) {
+	case command.Offset:
+		n, ok := addCounts(offset.Offset, input.Offset)
+		if !ok {
+			return nil, false
+		}
+		input.Offset = n
+		return input, true
+	case command.Limit:
+		// skipping o of the first l datasets leaves the datasets o to l-1,
+		// which are the first l-o datasets after skipping o datasets
+		l, lok := constantCount(input.Limit)
+		o, ook := constantCount(offset.Offset)
+		if !lok || !ook || l < 0 {
+			return nil, false
+		}
+		remaining := l - o
+		if remaining < 0 {
+			remaining = 0
+		}
+		offset.Input = input.Input
+		return command.Limit{
+			Limit: constantExpr(remaining),
+			Input: offset,
+		}, true
+	case command.Project:
+		if input.Input == nil {
+			return nil, false
+		}
+		offset.Input = input.Input
+		input.Input = offset
+		return input, true
+	}
+	return nil, false
+}
+
+// sortLimitedTo moves the given limit into the given sort. If the sort already
+// has a limit, the smaller one of both limits is kept, which must be known.
+func sortLimitedTo(sort command.Sort, limit command.Expr) (command.Sort, bool) {
+	switch {
+	case sort.Limit == nil, isAtMost(limit, sort.Limit):
+		sort.Limit = limit
+	case !isAtMost(sort.Limit, limit):
+		return sort, false
+	}
+	return sort, true
+}
+
+// limitedTo wraps the given list into the given limit, unless the list is
+// already limited to it.
+func limitedTo(list command.List, limit command.Expr) command.List {
+	if isLimited(list, limit) {
+		return list
+	}
+	return command.Limit{
+		Limit: limit,
+		Input: list,
+	}
+}
+
+// isLimited determines whether the given list is known to produce at most as
+// many datasets as the given limit allows.
+func isLimited(list command.List, limit command.Expr) bool {
+	switch l := list.(type) {
+	case command.Limit:
+		return isAtMost(l.Limit, limit)
+	case command.Sort:
+		return l.Limit != nil && isAtMost(l.Limit, limit)
+	case command.Project:
+		return l.Input != nil && isLimited(l.Input, limit)
+	case command.Offset:
+		return isLimited(l.Input, limit)
+	case command.Select:
+		return isLimited(l.Input, limit)
+	case command.Empty:
+		return true
+	}
+	return false
+}
+
+// isAtMost determines whether the count a is known to be at most the count b.
+func isAtMost(a, b command.Expr) bool {
+	if a.String() == b.String() {
+		return true
+	}
+	n, aok := constantCount(a)
+	m, bok := constantCount(b)
+	return aok && bok && n >= 0 && (m < 0 || n <= m)
+}
+
+// minCount returns the smaller one of the given constant counts, of which a
+// negative count is unlimited.
+func minCount(a, b command.Expr) (command.Expr, bool) {
+	n, aok := constantCount(a)
+	m, bok := constantCount(b)
+	if !aok || !bok {
+		return nil, false
+	}
+	if n < 0 || (m >= 0 && m < n) {
+		return b, true
+	}
+	return a, true
+}
+
+// addCounts returns the sum of the given constant counts, which must not be
+// negative.
+func addCounts(a, b command.Expr) (command.Expr, bool) {
+	n, aok := constantCount(a)
+	m, bok := constantCount(b)
+	if !aok || !bok || n < 0 || m < 0 || n+m < 0 {
+		return nil, false
+	}
+	return constantExpr(n + m), true
+}
+
+// constantCount returns the value of the given expression, if it is a constant
+// integer. Like in the executor, all negative values are returned as -1.
+func constantCount(expr command.Expr) (int64, bool) {
+	val, ok := constantValue(expr)
+	if !ok {
+		return 0, false
+	}
+	n, ok := val.(int64)
+	if !ok {
+		return 0, false
+	}
+	if n < 0 {
+		return -1, true
+	}
+	return n, true
+}
@@ -0,0 +1,110 @@
+package optimization
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+func TestPushDownLimits(t *testing.T) {
+	project := func(input command.List, cols ...command.Expr) command.Project {
+		project := command.Project{Input: input}
+		for _, col := range cols {
+			project.Cols = append(project.Cols, command.Column{Column: col})
+		}
+		return project
+	}
+	limit := func(n string, input command.List) command.Limit {
+		return command.Limit{Limit: lit(n), Input: input}
+	}
+	offset := func(n string, input command.List) command.Offset {
+		return command.Offset{Offset: lit(n), Input: input}
+	}
+	sort := func(input command.List) command.Sort {
+		return command.Sort{
+			Terms: []command.SortTerm{{Expr: col("a", "v"), Desc: true}},
+			Input: input,
+		}
+	}
+	unionAll := func(left, right command.List) command.Union {
+		return command.Union{All: true, Left: left, Right: right}
+	}
+
+	runGolden(t, []Rule{PushDownLimits}, []goldenTestcase{
+		{
+			"projection",
+			limit("10", project(scan("a"), col("a", "v"))),
+		},
+		{
+			"offset below projection",
+			limit("10", offset("5", project(scan("a"), col("a", "v")))),
+		},
+		{
+			"union all",
+			limit("10", unionAll(project(scan("a"), col("a", "v")), project(scan("b"), col("b", "w")))),
+		},
+		{
+			"union all with limited side",
+			limit("10", unionAll(limit("5", scan("a")), scan("b"))),
+		},
+		{
+			"union",
+			limit("10", command.Union{Left: scan("a"), Right: scan("b")}),
+		},
+		{
+			"union all with non-deterministic limit",
+			command.Limit{
+				Limit: command.FunctionExpr{Name: "RANDOM"},
+				Input: unionAll(scan("a"), scan("b")),
+			},
+		},
+		{
+			"sort",
+			limit("3", project(sort(scan("a")), col("a", "v"))),
+		},
+		{
+			"sort below offset",
+			limit("3", offset("2", project(sort(scan("a")), col("a", "v")))),
+		},
+		{
+			"sort with smaller limit",
+			limit("5", command.Sort{
+				Terms: []command.SortTerm{{Expr: col("a", "v")}},
+				Limit: lit("3"),
+				Input: scan("a"),
+			}),
+		},
+		{
+			"limit of limit",
+			limit("10", limit("5", scan("a"))),
+		},
+		{
+			"offset of offset",
+			offset("2", offset("3", scan("a"))),
+		},
+		{
+			"limit of offset",
+			limit("3", offset("2", scan("a"))),
+		},
+		{
+			"offset of limit",
+			offset("2", limit("3", scan("a"))),
+		},
+		{
+			"offset of smaller limit",
+			offset("5", limit("3", project(scan("a"), col("a", "v")))),
+		},
+		{
+			"limit of offset of limit",
+			limit("2", offset("1", limit("5", scan("a")))),
+		},
+		{
+			"negative limit",
+			limit("-1", offset("0", scan("a"))),
+		},
+		{
+			"zero limit",
+			limit("0", project(scan("a"), col("a", "v"))),
+		},
+	})
+}
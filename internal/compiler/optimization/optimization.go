@@ -2,7 +2,77 @@ package optimization
 
 import "github.com/tomarrell/lbadd/internal/compiler/command"
 
+// DefaultMaxIterations is the amount of times that an optimizer applies its
+// rules to a command at most, if no other limit is specified.
+const DefaultMaxIterations = 32
+
 // Optimization defines a process that optimizes an input command and outputs a
 // modified, optimized version of that command, if the optimization is
 // applicable to the input command. If not, ok=false will be returned.
 type Optimization func(command.Command) (optimized command.Command, ok bool)
+
+// Rule is a named optimization, that rewrites a single node of a command tree.
+// A rule must not descend into the inputs of the node, because the optimizer
+// applies it to every node of the tree.
+type Rule struct {
+	// Name is the name of the rule, which is recorded if the rule fired.
+	Name string
+	// Apply rewrites a single node. If the rule is not applicable to the
+	// node, ok=false is returned.
+	Apply Optimization
+}
+
+// Optimizer applies rules to command trees, until none of the rules is
+// applicable anymore.
+type Optimizer struct {
+	// Rules are the rules that are applied, in order.
+	Rules []Rule
+	// MaxIterations is the maximum amount of times that all rules are applied
+	// to the command tree. If this is not positive, DefaultMaxIterations is
+	// used. If the command tree is still rewritten by the last iteration, the
+	// result of that iteration is used.
+	MaxIterations int
+}
+
+// Optimize applies all rules of the optimizer to every node of the given
+// command tree, until a fixed point is reached, and returns the optimized
+// command. Every rule is applied to the nodes bottom-up, so that it sees
+// the rewritten inputs of a node. The second return value are the names of the
+// rules that fired, in the order they fired first. A rule only fires, if it
+// rewrites a node into a different node. The input command is not modified.
+func (o Optimizer) Optimize(cmd command.Command) (command.Command, []string) {
+	maxIterations := o.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+
+	var fired []string
+	firedRules := make(map[string]bool)
+	for i := 0; i < maxIterations; i++ {
+		var changed bool
+		for _, rule := range o.Rules {
+			optimized, ok := rewrite(cmd, rule.Apply)
+			if !ok {
+				continue
+			}
+			cmd = optimized
+			changed = true
+			if !firedRules[rule.Name] {
+				firedRules[rule.Name] = true
+				fired = append(fired, rule.Name)
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return cmd, fired
+}
+
+// Optimization returns an optimization that applies the rule to every node of
+// a command tree once, bottom-up.
+func (r Rule) Optimization() Optimization {
+	return func(cmd command.Command) (command.Command, bool) {
+		return rewrite(cmd, r.Apply)
+	}
+}
@@ -0,0 +1,167 @@
+package optimization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// collapseDistinct is a rule for testing, that reduces Distinct(Distinct(any))
+// to Distinct(any).
+var collapseDistinct = Rule{
+	Name: "CollapseDistinct",
+	Apply: func(cmd command.Command) (command.Command, bool) {
+		if distinct, ok := cmd.(command.Distinct); ok {
+			if _, ok := distinct.Input.(command.Distinct); ok {
+				return distinct.Input, true
+			}
+		}
+		return nil, false
+	},
+}
+
+func scan(table string) command.Scan {
+	return command.Scan{
+		Table: command.SimpleTable{Table: table},
+	}
+}
+
+func TestOptimizer_Optimize(t *testing.T) {
+	tests := []struct {
+		name      string
+		optimizer Optimizer
+		cmd       command.Command
+		want      command.Command
+		wantFired []string
+	}{
+		{
+			"no rules",
+			Optimizer{},
+			command.Join{Right: scan("a")},
+			command.Join{Right: scan("a")},
+			nil,
+		},
+		{
+			"not applicable",
+			Optimizer{Rules: []Rule{HalfJoin, collapseDistinct}},
+			command.Distinct{Input: scan("a")},
+			command.Distinct{Input: scan("a")},
+			nil,
+		},
+		{
+			"fixed point",
+			Optimizer{Rules: []Rule{collapseDistinct, HalfJoin}},
+			command.Distinct{
+				Input: command.Join{
+					Left: command.Distinct{
+						Input: command.Distinct{Input: scan("a")},
+					},
+				},
+			},
+			command.Distinct{Input: scan("a")},
+			[]string{"CollapseDistinct", "HalfJoin"},
+		},
+		{
+			"nested commands",
+			Optimizer{Rules: []Rule{HalfJoin}},
+			command.Explain{
+				Command: command.Insert{
+					Table: command.SimpleTable{Table: "a"},
+					Input: command.With{
+						Tables: []command.CommonTable{
+							{
+								Name:  "b",
+								Input: command.Join{Left: scan("b")},
+							},
+						},
+						Input: command.Scan{
+							Table: command.SubqueryTable{
+								Input: command.Join{Right: scan("c")},
+							},
+						},
+					},
+				},
+			},
+			command.Explain{
+				Command: command.Insert{
+					Table: command.SimpleTable{Table: "a"},
+					Input: command.With{
+						Tables: []command.CommonTable{
+							{
+								Name:  "b",
+								Input: scan("b"),
+							},
+						},
+						Input: command.Scan{
+							Table: command.SubqueryTable{
+								Input: scan("c"),
+							},
+						},
+					},
+				},
+			},
+			[]string{"HalfJoin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, gotFired := tt.optimizer.Optimize(tt.cmd)
+			assert.Equal(tt.want, got)
+			assert.Equal(tt.wantFired, gotFired)
+		})
+	}
+}
+
+func TestOptimizer_Optimize_MaxIterations(t *testing.T) {
+	assert := assert.New(t)
+
+	// the rule never reaches a fixed point, because it always wraps the list
+	// into another distinct
+	var applied int
+	wrap := Rule{
+		Name: "Wrap",
+		Apply: func(cmd command.Command) (command.Command, bool) {
+			if _, ok := cmd.(command.Scan); !ok {
+				return nil, false
+			}
+			applied++
+			return command.Distinct{Input: cmd.(command.List)}, true
+		},
+	}
+	cmd := scan("a")
+
+	got, fired := Optimizer{Rules: []Rule{wrap}, MaxIterations: 3}.Optimize(cmd)
+	assert.Equal(3, applied)
+	assert.Equal([]string{"Wrap"}, fired)
+	assert.Equal(command.Distinct{Input: command.Distinct{Input: command.Distinct{Input: cmd}}}, got)
+
+	applied = 0
+	_, _ = Optimizer{Rules: []Rule{wrap}}.Optimize(cmd)
+	assert.Equal(DefaultMaxIterations, applied)
+}
+
+func TestOptimizer_Optimize_Unchanged(t *testing.T) {
+	assert := assert.New(t)
+
+	// the rule reports every node as rewritten, but returns it unchanged
+	var applied int
+	identity := Rule{
+		Name: "Identity",
+		Apply: func(cmd command.Command) (command.Command, bool) {
+			applied++
+			return cmd, true
+		},
+	}
+	cmd := command.Distinct{Input: scan("a")}
+
+	got, fired := Optimizer{Rules: []Rule{identity}}.Optimize(cmd)
+	assert.Equal(2, applied, "the optimizer must stop after a single pass")
+	assert.Empty(fired)
+	assert.Equal(cmd, got)
+
+	_, ok := identity.Optimization()(cmd)
+	assert.False(ok)
+}
@@ -0,0 +1,401 @@
+package optimization
+
+import (
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// PushDownPredicates is the rule that splits filters into their conjuncts, and
+// moves every conjunct as close to the scans as possible, so that datasets
+// are discarded before they are joined or projected.
+//
+// A conjunct of a selection is moved through a projection, if all column
+// references can be replaced by the projected expressions, and into the
+// subquery of a scan, if that subquery is a projection. A conjunct of a
+// selection above a join is moved into the join side, that provides all tables
+// that the conjunct references. If both sides are referenced, it is moved into
+// the filter of the join. A conjunct of the filter of a join, that references
+// only one side, is moved into that side. The null-extended right side of an
+// outer join only receives conjuncts of the join filter, and the left side
+// only receives conjuncts of selections above the join.
+//
+// A semi or anti join only receives conjuncts for its left side, and the
+// conjuncts of its filter, which is evaluated like the filter of a correlated
+// subquery, are not moved.
+//
+// Conjuncts with unqualified column references can't be moved into a join
+// side, because the columns of the tables are not known. Conjuncts that
+// contain subqueries or calls to functions, that are not deterministic, are
+// never moved.
+var PushDownPredicates = Rule{
+	Name:  "PushDownPredicates",
+	Apply: pushDownPredicates,
+}
+
+func pushDownPredicates(cmd command.Command) (command.Command, bool) {
+	switch c := cmd.(type) {
+	case command.Select:
+		switch input := c.Input.(type) {
+		case command.Select:
+			return filtered(input, conjuncts(c.Filter)), true
+		case command.Project:
+			return pushThroughProject(c, input)
+		case command.Join:
+			return pushIntoJoin(c, input)
+		case command.Scan:
+			return pushIntoSubquery(c, input)
+		}
+	case command.Join:
+		return pushDownJoinFilter(c)
+	}
+	return nil, false
+}
+
+// pushThroughProject moves the conjuncts of the given selection below the
+// given projection, which is the input of the selection.
+func pushThroughProject(sel command.Select, project command.Project) (command.Command, bool) {
+	var pushed, remaining []command.Expr
+	for _, conjunct := range conjuncts(sel.Filter) {
+		if !isPinned(conjunct) {
+			if substituted, ok := substituteColumns(conjunct, project.Cols); ok {
+				pushed = append(pushed, substituted)
+				continue
+			}
+		}
+		remaining = append(remaining, conjunct)
+	}
+	if len(pushed) == 0 {
+		return nil, false
+	}
+	project.Input = filtered(project.Input, pushed)
+	return filtered(project, remaining), true
+}
+
+// pushIntoSubquery moves the conjuncts of the given selection into the
+// subquery of the given scan, which is the input of the selection, if that
+// subquery is a projection.
+func pushIntoSubquery(sel command.Select, scan command.Scan) (command.Command, bool) {
+	table, ok := scan.Table.(command.SubqueryTable)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := table.Input.(command.Project); !ok {
+		return nil, false
+	}
+
+	var pushed, remaining []command.Expr
+	for _, conjunct := range conjuncts(sel.Filter) {
+		if !isPinned(conjunct) {
+			if unqualified, ok := unqualifyColumns(conjunct, table.Alias); ok {
+				pushed = append(pushed, unqualified)
+				continue
+			}
+		}
+		remaining = append(remaining, conjunct)
+	}
+	if len(pushed) == 0 {
+		return nil, false
+	}
+	table.Input = filtered(table.Input, pushed)
+	scan.Table = table
+	return filtered(scan, remaining), true
+}
+
+// pushIntoJoin moves the conjuncts of the given selection into the sides or
+// the filter of the given join, which is the input of the selection.
+func pushIntoJoin(sel command.Select, join command.Join) (command.Command, bool) {
+	left, right, ok := joinTableNames(join)
+	if !ok {
+		return nil, false
+	}
+
+	var toLeft, toRight, toJoin, remaining []command.Expr
+	for _, conjunct := range conjuncts(sel.Filter) {
+		tables, movable := referencedTables(conjunct)
+		switch {
+		case !movable:
+			remaining = append(remaining, conjunct)
+		case provides(left, right, tables):
+			toLeft = append(toLeft, conjunct)
+		case isOuterJoin(join), isSemiOrAntiJoin(join):
+			remaining = append(remaining, conjunct)
+		case provides(right, left, tables):
+			toRight = append(toRight, conjunct)
+		case providesAll(left, right, tables):
+			toJoin = append(toJoin, conjunct)
+		default:
+			remaining = append(remaining, conjunct)
+		}
+	}
+	if len(toLeft) == 0 && len(toRight) == 0 && len(toJoin) == 0 {
+		return nil, false
+	}
+	join.Left = filtered(join.Left, toLeft)
+	join.Right = filtered(join.Right, toRight)
+	join.Filter = conjunction(append(conjuncts(join.Filter), toJoin...))
+	return filtered(join, remaining), true
+}
+
+// pushDownJoinFilter moves the conjuncts of the filter of the given join into
+// the sides of the join.
+func pushDownJoinFilter(join command.Join) (command.Command, bool) {
+	if join.Filter == nil || isSemiOrAntiJoin(join) {
+		return nil, false
+	}
+	left, right, ok := joinTableNames(join)
+	if !ok {
+		return nil, false
+	}
+
+	var toLeft, toRight, remaining []command.Expr
+	for _, conjunct := range conjuncts(join.Filter) {
+		tables, movable := referencedTables(conjunct)
+		switch {
+		case !movable:
+			remaining = append(remaining, conjunct)
+		case !isOuterJoin(join) && provides(left, right, tables):
+			toLeft = append(toLeft, conjunct)
+		case provides(right, left, tables):
+			toRight = append(toRight, conjunct)
+		default:
+			remaining = append(remaining, conjunct)
+		}
+	}
+	if len(toLeft) == 0 && len(toRight) == 0 {
+		return nil, false
+	}
+	join.Left = filtered(join.Left, toLeft)
+	join.Right = filtered(join.Right, toRight)
+	join.Filter = conjunction(remaining)
+	return join, true
+}
+
+// filtered returns the given list, filtered by the conjunction of the given
+// expressions. If the list is a selection, the expressions are added to its
+// filter.
+func filtered(list command.List, exprs []command.Expr) command.List {
+	if len(exprs) == 0 {
+		return list
+	}
+	if sel, ok := list.(command.Select); ok {
+		sel.Filter = conjunction(append(conjuncts(sel.Filter), exprs...))
+		return sel
+	}
+	return command.Select{
+		Filter: conjunction(exprs),
+		Input:  list,
+	}
+}
+
+// substituteColumns replaces all column references of the given expression,
+// which is evaluated above a projection with the given columns, with the
+// projected expressions, so that the expression can be evaluated below the
+// projection. If a column reference can't be replaced unambiguously, ok=false
+// is returned.
+func substituteColumns(expr command.Expr, cols []command.Column) (command.Expr, bool) {
+	ok := true
+	substituted := mapExpr(expr, func(expr command.Expr) command.Expr {
+		ref, isRef := expr.(command.ColumnRef)
+		if !isRef || ref.Depth != 0 {
+			return expr
+		}
+		projected, found := projectedColumn(cols, ref)
+		if !found || isPinned(projected) {
+			ok = false
+			return expr
+		}
+		return projected
+	})
+	return substituted, ok
+}
+
+// projectedColumn returns the expression of the projected column, that is
+// referenced by the given column reference. A reference, that is passed
+// through by a star column, is returned unchanged.
+func projectedColumn(cols []command.Column, ref command.ColumnRef) (command.Expr, bool) {
+	var match command.Expr
+	var matches int
+	var star bool
+	for _, col := range cols {
+		if lit, ok := col.Column.(command.LiteralExpr); ok && lit.Value == "*" {
+			star = true
+			continue
+		}
+		if col.Alias != "" {
+			if ref.Table == "" && strings.EqualFold(col.Alias, ref.Column) {
+				match = col.Column
+				matches++
+			}
+			continue
+		}
+		if colRef, ok := col.Column.(command.ColumnRef); ok && colRef.Depth == 0 &&
+			strings.EqualFold(colRef.Column, ref.Column) &&
+			(ref.Table == "" || ref.Schema == "" && strings.EqualFold(colRef.Table, ref.Table)) {
+			match = colRef
+			matches++
+		}
+	}
+	switch {
+	case matches == 1 && !star:
+		return match, true
+	case matches == 0 && star:
+		return ref, true
+	}
+	return nil, false
+}
+
+// unqualifyColumns removes the given table name from all column references of
+// the given expression, which is evaluated above a scan of a subquery with the
+// given name, so that the expression can be evaluated inside the subquery. If
+// the expression references columns of other tables or of enclosing queries,
+// ok=false is returned.
+func unqualifyColumns(expr command.Expr, tableName string) (command.Expr, bool) {
+	ok := true
+	unqualified := mapExpr(expr, func(expr command.Expr) command.Expr {
+		ref, isRef := expr.(command.ColumnRef)
+		if !isRef {
+			return expr
+		}
+		switch {
+		case ref.Depth != 0:
+			ok = false
+		case ref.Table == "":
+		case tableName != "" && ref.Schema == "" && strings.EqualFold(ref.Table, tableName):
+			ref.Table = ""
+		default:
+			ok = false
+		}
+		return ref
+	})
+	return unqualified, ok
+}
+
+// referencedTables returns the lower case names of the tables, that the
+// column references of the given expression are qualified with. References
+// of columns of enclosing queries are ignored. If the expression can't be
+// moved to the tables, because it has unqualified column references, doesn't
+// reference any table or is pinned, movable=false is returned.
+func referencedTables(expr command.Expr) (tables []string, movable bool) {
+	if isPinned(expr) {
+		return nil, false
+	}
+	movable = true
+	inspectExpr(expr, func(expr command.Expr) {
+		ref, ok := expr.(command.ColumnRef)
+		if !ok || ref.Depth != 0 {
+			return
+		}
+		if ref.Table == "" {
+			movable = false
+			return
+		}
+		tables = append(tables, strings.ToLower(ref.Table))
+	})
+	return tables, movable && len(tables) != 0
+}
+
+// joinTableNames returns the names of the tables, that are provided by the
+// left and the right side of the given join. If they are not known, ok=false is
+// returned.
+func joinTableNames(join command.Join) (left, right []string, ok bool) {
+	if join.Left == nil || join.Right == nil {
+		return nil, nil, false
+	}
+	if left, ok = tableNames(join.Left); !ok {
+		return nil, nil, false
+	}
+	if right, ok = tableNames(join.Right); !ok {
+		return nil, nil, false
+	}
+	return left, right, true
+}
+
+// tableNames returns the lower case names of the tables, whose columns can be
+// referenced by qualified column references above the given list. If the
+// names are not known, ok=false is returned.
+func tableNames(list command.List) (names []string, ok bool) {
+	switch l := list.(type) {
+	case command.Scan:
+		return []string{strings.ToLower(tableName(l.Table))}, true
+	case command.IndexScan:
+		return []string{strings.ToLower(tableName(l.Table))}, true
+	case command.Select:
+		return tableNames(l.Input)
+	case command.Join:
+		if isSemiOrAntiJoin(l) {
+			return tableNames(l.Left)
+		}
+		left, right, ok := joinTableNames(l)
+		return append(left, right...), ok
+	}
+	return nil, false
+}
+
+// tableName returns the name, that columns of the given table are qualified
+// with.
+func tableName(table command.Table) string {
+	switch t := table.(type) {
+	case command.SimpleTable:
+		if t.Alias != "" {
+			return t.Alias
+		}
+		return t.Table
+	case command.SubqueryTable:
+		return t.Alias
+	case command.CommonTableRef:
+		if t.Alias != "" {
+			return t.Alias
+		}
+		return t.Name
+	case command.TableFunction:
+		if t.Alias != "" {
+			return t.Alias
+		}
+		return t.Name
+	}
+	return ""
+}
+
+// provides determines whether all of the given tables are provided by the
+// given side of a join, and not by the other side.
+func provides(side, other, tables []string) bool {
+	for _, table := range tables {
+		if !containsName(side, table) || containsName(other, table) {
+			return false
+		}
+	}
+	return true
+}
+
+// providesAll determines whether every one of the given tables is provided
+// by exactly one side of a join.
+func providesAll(left, right, tables []string) bool {
+	for _, table := range tables {
+		if containsName(left, table) == containsName(right, table) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsName(names []string, name string) bool {
+	for _, other := range names {
+		if other == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isOuterJoin determines whether the right side of the given join is null
+// extended for datasets of the left side without a match.
+func isOuterJoin(join command.Join) bool {
+	return join.Type == command.JoinLeft || join.Type == command.JoinLeftOuter
+}
+
+// isSemiOrAntiJoin determines whether the given join only produces the
+// datasets of its left side.
+func isSemiOrAntiJoin(join command.Join) bool {
+	return join.Type == command.JoinSemi || join.Type == command.JoinAnti
+}
@@ -0,0 +1,231 @@
+package optimization
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+func col(table, column string) command.ColumnRef {
+	return command.ColumnRef{Table: table, Column: column}
+}
+
+func lit(value string) command.LiteralExpr {
+	return command.LiteralExpr{Value: value}
+}
+
+func eq(left, right command.Expr) command.EqualityExpr {
+	return command.EqualityExpr{Left: left, Right: right}
+}
+
+func and(exprs ...command.Expr) command.Expr {
+	return conjunction(exprs)
+}
+
+func TestPushDownPredicates(t *testing.T) {
+	join := func(typ command.JoinType, filter command.Expr, left, right command.List) command.Join {
+		return command.Join{
+			Type:   typ,
+			Filter: filter,
+			Left:   left,
+			Right:  right,
+		}
+	}
+
+	runGolden(t, []Rule{PushDownPredicates}, []goldenTestcase{
+		{
+			"select over select",
+			command.Select{
+				Filter: eq(col("", "a"), lit("1")),
+				Input: command.Select{
+					Filter: eq(col("", "b"), lit("2")),
+					Input:  scan("t"),
+				},
+			},
+		},
+		{
+			"inner join",
+			command.Project{
+				Cols: []command.Column{{Column: lit("*")}},
+				Input: command.Select{
+					Filter: and(
+						eq(col("a", "v"), lit("1")),
+						eq(col("b", "w"), lit("2")),
+						eq(col("a", "z"), col("b", "z")),
+						eq(col("", "u"), lit("3")),
+					),
+					Input: join(command.JoinInner, eq(col("a", "id"), col("b", "id")), scan("a"), scan("b")),
+				},
+			},
+		},
+		{
+			"cross join chain",
+			command.Select{
+				Filter: and(
+					eq(col("a", "v"), lit("1")),
+					eq(col("c", "w"), col("b", "w")),
+				),
+				Input: join(command.JoinCross, nil,
+					join(command.JoinCross, nil, scan("a"), scan("b")),
+					scan("c"),
+				),
+			},
+		},
+		{
+			"left join",
+			command.Select{
+				Filter: and(
+					eq(col("a", "v"), lit("1")),
+					command.IsExpr{Left: col("b", "w"), Right: lit("NULL")},
+				),
+				Input: join(command.JoinLeft, and(
+					eq(col("a", "id"), col("b", "id")),
+					eq(col("a", "x"), lit("1")),
+					eq(col("b", "y"), lit("2")),
+				), scan("a"), scan("b")),
+			},
+		},
+		{
+			"join filter",
+			join(command.JoinUnknown, and(
+				eq(col("a", "id"), col("b", "id")),
+				eq(col("b", "y"), lit("2")),
+			), scan("a"), scan("b")),
+		},
+		{
+			"aliased self join",
+			command.Select{
+				Filter: and(
+					eq(col("x", "v"), lit("1")),
+					eq(col("y", "v"), lit("2")),
+				),
+				Input: join(command.JoinCross, nil,
+					command.Scan{Table: command.SimpleTable{Table: "t", Alias: "x"}},
+					command.Scan{Table: command.SimpleTable{Table: "t", Alias: "y"}},
+				),
+			},
+		},
+		{
+			"ambiguous self join",
+			command.Select{
+				Filter: eq(col("t", "v"), lit("1")),
+				Input:  join(command.JoinCross, nil, scan("t"), scan("t")),
+			},
+		},
+		{
+			"pinned",
+			command.Select{
+				Filter: and(
+					eq(col("a", "v"), command.FunctionExpr{Name: "RANDOM"}),
+					command.ExistsExpr{Input: scan("c")},
+					eq(col("a", "v"), command.ColumnRef{Table: "o", Column: "v", Depth: 1}),
+				),
+				Input: join(command.JoinCross, nil, scan("a"), scan("b")),
+			},
+		},
+		{
+			"through project",
+			command.Select{
+				Filter: and(
+					eq(col("", "y"), lit("1")),
+					eq(col("", "b"), lit("2")),
+					eq(col("", "r"), lit("3")),
+					eq(col("", "missing"), lit("4")),
+				),
+				Input: command.Project{
+					Cols: []command.Column{
+						{Column: col("t", "a"), Alias: "y"},
+						{Column: col("t", "b")},
+						{Column: command.FunctionExpr{Name: "RANDOM"}, Alias: "r"},
+					},
+					Input: scan("t"),
+				},
+			},
+		},
+		{
+			"through project with star",
+			command.Select{
+				Filter: and(
+					eq(col("a", "v"), lit("1")),
+					eq(col("", "v"), lit("2")),
+				),
+				Input: command.Project{
+					Cols: []command.Column{
+						{Column: lit("*")},
+						{Column: col("a", "w"), Alias: "v"},
+					},
+					Input: join(command.JoinInner, nil, scan("a"), scan("b")),
+				},
+			},
+		},
+		{
+			"into subquery",
+			command.Project{
+				Cols: []command.Column{{Column: lit("*")}},
+				Input: command.Select{
+					Filter: and(
+						eq(col("s", "y"), lit("1")),
+						eq(col("", "b"), col("s", "y")),
+						eq(col("t", "b"), lit("2")),
+					),
+					Input: command.Scan{
+						Table: command.SubqueryTable{
+							Alias: "s",
+							Input: command.Project{
+								Cols: []command.Column{
+									{Column: col("", "a"), Alias: "y"},
+									{Column: col("", "b")},
+								},
+								Input: scan("t"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"semi join",
+			command.Select{
+				Filter: and(
+					eq(col("a", "v"), lit("1")),
+					eq(col("b", "w"), lit("2")),
+				),
+				Input: join(command.JoinSemi,
+					and(
+						eq(col("b", "id"), command.ColumnRef{Table: "a", Column: "id", Depth: 1}),
+						eq(col("b", "u"), lit("3")),
+					),
+					scan("a"), scan("b"),
+				),
+			},
+		},
+		{
+			"subquery",
+			command.Select{
+				Filter: command.ExistsExpr{
+					Input: command.Project{
+						Cols: []command.Column{{Column: lit("1")}},
+						Input: command.Select{
+							Filter: and(
+								eq(col("b", "w"), lit("2")),
+								eq(col("b", "id"), command.ColumnRef{Table: "a", Column: "id", Depth: 1}),
+							),
+							Input: join(command.JoinCross, nil, scan("b"), scan("c")),
+						},
+					},
+				},
+				Input: scan("a"),
+			},
+		},
+		{
+			"not applicable",
+			command.Select{
+				Filter: eq(col("", "v"), lit("1")),
+				Input: command.Aggregate{
+					GroupBy: []command.Expr{col("", "v")},
+					Input:   scan("t"),
+				},
+			},
+		},
+	})
+}
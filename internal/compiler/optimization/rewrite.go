@@ -0,0 +1,40 @@
+package optimization
+
+import (
+	"reflect"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// rewrite applies the given optimization to every node of the given command
+// tree, that is a command or a list, bottom-up. Lists that are nested in
+// expressions, such as subqueries, are rewritten as well, but the expressions
+// themselves are not passed to the optimization. The second return value
+// indicates whether any node was rewritten into a different node. If not, the
+// input command is returned. Nodes are copied, so that the input command is not modified. If
+// the optimization rewrites a list into a command that is not a list, that
+// rewrite is ignored.
+func rewrite(cmd command.Command, opt Optimization) (command.Command, bool) {
+	var changed bool
+	rewritten, _ := command.Rewrite(cmd, nil, func(node command.Node) (command.Node, error) {
+		switch node.(type) {
+		case command.Table, command.Expr:
+			return node, nil
+		}
+		optimized, ok := opt(node)
+		if !ok || reflect.DeepEqual(optimized, node) {
+			return node, nil
+		}
+		if _, isList := node.(command.List); isList && optimized != nil {
+			if _, ok := optimized.(command.List); !ok {
+				return node, nil
+			}
+		}
+		changed = true
+		return optimized, nil
+	})
+	if !changed {
+		return cmd, false
+	}
+	return rewritten, true
+}
@@ -0,0 +1,62 @@
+package optimization
+
+// Statistics provides estimates about the data of tables, that rules use to
+// compare the costs of equivalent commands.
+type Statistics interface {
+	// TableStatistics returns the statistics of the table with the given
+	// schema and name. If nothing is known about the table, ok=false is
+	// returned.
+	TableStatistics(schema, table string) (stats TableStatistics, ok bool)
+}
+
+// TableStatistics are the statistics of a single table.
+type TableStatistics struct {
+	// Rows is the amount of rows of the table.
+	Rows float64
+	// Columns are the statistics of the columns of the table, by the lower
+	// case name of the column. Columns, about which nothing is known, may be
+	// missing.
+	Columns map[string]ColumnStatistics
+	// Indexes are the statistics of the indexes of the table, by the lower
+	// case name of the index. Indexes, about which nothing is known, may be
+	// missing.
+	Indexes map[string]IndexStatistics
+}
+
+// IndexStatistics are the statistics of a single index of a table.
+type IndexStatistics struct {
+	// Distinct are the amounts of distinct prefixes of the keys of the index,
+	// that have no NULL values, by the length of the prefix minus one. The
+	// amount of distinct values of the first indexed column is the first
+	// element.
+	Distinct []float64
+}
+
+// ColumnStatistics are the statistics of a single column of a table.
+type ColumnStatistics struct {
+	// Distinct is the amount of distinct values, that are not NULL, in the
+	// column. If this is not positive, the amount is not known.
+	Distinct float64
+	// NullFraction is the fraction of the rows of the table, whose value in
+	// the column is NULL.
+	NullFraction float64
+	// Histogram is the equi-depth histogram of the values of the column, that
+	// are not NULL. May be empty, if it is not known.
+	Histogram []Bucket
+}
+
+// Bucket is a bucket of an equi-depth histogram. A bucket covers all values,
+// that are larger than the upper bound of the previous bucket, and smaller
+// than or equal to its own upper bound. All buckets of a histogram cover
+// about the same amount of rows, but a value is never split across two
+// buckets.
+type Bucket struct {
+	// Upper is the largest value in the bucket, which is an int64, a float64,
+	// a string or a []byte.
+	Upper interface{}
+	// Fraction is the fraction of the rows of the table, whose value is in
+	// the bucket.
+	Fraction float64
+	// Distinct is the amount of distinct values in the bucket.
+	Distinct float64
+}
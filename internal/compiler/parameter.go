@@ -0,0 +1,61 @@
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// maxParameterIndex is the largest index of a bind parameter. It is the same
+// as the default limit of SQLite.
+const maxParameterIndex = 32766
+
+// isParameter determines whether the value of a literal token is a placeholder
+// for a bind parameter.
+func isParameter(val string) bool {
+	return val != "" && strings.ContainsRune("?:@$", rune(val[0]))
+}
+
+// compileParameter compiles the given placeholder to a bind parameter, and
+// assigns the parameter its index. Since parts of a statement may be compiled
+// more than once, a placeholder is only assigned a new index the first time
+// it is compiled.
+func (c *simpleCompiler) compileParameter(placeholder token.Token) (command.ParameterExpr, error) {
+	if c.parameters == nil {
+		c.parameters = make(map[string]int)
+	}
+
+	val := placeholder.Value()
+	param := command.ParameterExpr{}
+	if val[0] != '?' {
+		param.Name = val
+	}
+	// named parameters are assigned an index by name, all other parameters by
+	// their position
+	key := val
+	if param.Name == "" {
+		key = strconv.Itoa(placeholder.Offset())
+	}
+	if index, ok := c.parameters[key]; ok {
+		param.Index = index
+		return param, nil
+	}
+
+	if val[0] == '?' && len(val) > 1 {
+		index, err := strconv.Atoi(val[1:])
+		if err != nil || index < 1 || index > maxParameterIndex {
+			return command.ParameterExpr{}, fmt.Errorf("parameter %v: index must be between 1 and %d", val, maxParameterIndex)
+		}
+		param.Index = index
+	} else {
+		param.Index = c.maxParameter + 1
+	}
+	if param.Index > c.maxParameter {
+		c.maxParameter = param.Index
+	}
+	c.parameters[key] = param.Index
+	return param, nil
+}
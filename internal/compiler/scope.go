@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// scope holds the tables, that are visible in a single query level.
+type scope struct {
+	// tables is the set of names of the visible tables. A table is visible
+	// under its alias, or under its name if it has no alias. Names are stored
+	// in lower case.
+	tables map[string]struct{}
+	// cols are the columns of the visible tables, in the order in which the
+	// tables were declared. Columns are only bound if the compiler has a
+	// catalog.
+	cols []boundColumn
+	// unbound indicates that the columns of at least one of the tables are
+	// unknown, so that a reference to an unknown column can not be reported.
+	unbound bool
+}
+
+// pushScope opens a new, empty scope for a nested query level.
+func (c *simpleCompiler) pushScope() {
+	c.scopes = append(c.scopes, &scope{
+		tables: make(map[string]struct{}),
+	})
+}
+
+// popScope closes the innermost scope.
+func (c *simpleCompiler) popScope() {
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// declareTable makes the given table visible in the innermost scope. If there
+// is no open scope, this is a no-op.
+func (c *simpleCompiler) declareTable(table command.Table) {
+	if len(c.scopes) == 0 {
+		return
+	}
+
+	var name string
+	switch t := table.(type) {
+	case command.SimpleTable:
+		name = t.Table
+		if t.Alias != "" {
+			name = t.Alias
+		}
+	case command.CommonTableRef:
+		name = t.Name
+		if t.Alias != "" {
+			name = t.Alias
+		}
+	case command.SubqueryTable:
+		name = t.Alias
+	case command.TableFunction:
+		name = t.Name
+		if t.Alias != "" {
+			name = t.Alias
+		}
+	}
+	if name == "" {
+		return
+	}
+	c.scopes[len(c.scopes)-1].tables[strings.ToLower(name)] = struct{}{}
+}
+
+// scopeDepth returns the number of scopes that have to be ascended from the
+// innermost scope, to find a scope in which the given table name is visible.
+// If the name is not visible in any scope, the depth is 0.
+func (c *simpleCompiler) scopeDepth(table string) int {
+	table = strings.ToLower(table)
+	for depth := 0; depth < len(c.scopes); depth++ {
+		if _, ok := c.scopes[len(c.scopes)-1-depth].tables[table]; ok {
+			return depth
+		}
+	}
+	return 0
+}
@@ -2,15 +2,47 @@ package compiler
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/tomarrell/lbadd/internal/compiler/command"
 	"github.com/tomarrell/lbadd/internal/compiler/optimization"
+	"github.com/tomarrell/lbadd/internal/database"
 	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
 )
 
 type simpleCompiler struct {
 	optimizations []optimization.Optimization
+	// optimizer applies the enabled optimization rules to compiled commands,
+	// after all optimizations were applied.
+	optimizer optimization.Optimizer
+	// catalog is the catalog that compiled statements are bound against. If
+	// it is nil, statements are not bound.
+	catalog database.DB
+
+	// scopes are the scopes of all query levels that enclose the currently
+	// compiled expression, with the innermost scope last.
+	scopes []*scope
+	// commonTables are the common tables of all WITH clauses that enclose the
+	// currently compiled statement, with the innermost table last.
+	commonTables []*commonTable
+	// windows are the named windows of the currently compiled select core,
+	// by their name in lower case.
+	windows map[string]*ast.WindowDefn
+	// resultAliases are the aliases of the result columns of the select core,
+	// whose ordering terms are currently compiled.
+	resultAliases []string
+	// problems are the problems, that were found while binding the currently
+	// compiled statement.
+	problems MultiError
+	// parameters are the indices of the bind parameters of the currently
+	// compiled statement, by the name of named parameters, and by the offset
+	// of the placeholder for all other parameters.
+	parameters map[string]int
+	// maxParameter is the largest index of a bind parameter of the currently
+	// compiled statement.
+	maxParameter int
 }
 
 // OptionEnableOptimization is used to enable the given optimization in a
@@ -21,6 +53,23 @@ func OptionEnableOptimization(opt optimization.Optimization) Option {
 	}
 }
 
+// OptionEnableRules is used to enable the given optimization rules in a
+// compiler. The rules of a compiler are applied repeatedly, until none of them
+// is applicable anymore.
+func OptionEnableRules(rules ...optimization.Rule) Option {
+	return func(c *simpleCompiler) {
+		c.optimizer.Rules = append(c.optimizer.Rules, rules...)
+	}
+}
+
+// OptionMaxOptimizerIterations is used to limit the amount of times that the
+// optimization rules are applied to a compiled command.
+func OptionMaxOptimizerIterations(maxIterations int) Option {
+	return func(c *simpleCompiler) {
+		c.optimizer.MaxIterations = maxIterations
+	}
+}
+
 // New creates a new, ready to use compiler with the given options applied.
 func New(opts ...Option) Compiler {
 	c := &simpleCompiler{}
@@ -31,8 +80,16 @@ func New(opts ...Option) Compiler {
 }
 
 func (c *simpleCompiler) Compile(ast *ast.SQLStmt) (command.Command, error) {
-	// compile the ast
-	cmd, err := c.compileInternal(ast)
+	// compile the ast on a copy of the compiler, so that the compilation state
+	// is not shared between multiple compilations
+	state := *c
+	cmd, err := state.compileInternal(ast)
+	if len(state.problems.errs) != 0 {
+		if err != nil {
+			state.problems.Append(err)
+		}
+		return nil, &state.problems
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -42,9 +99,14 @@ func (c *simpleCompiler) Compile(ast *ast.SQLStmt) (command.Command, error) {
 			cmd = optimized
 		}
 	}
+	var fired []string
+	if len(c.optimizer.Rules) != 0 {
+		cmd, fired = c.optimizer.Optimize(cmd)
+	}
 	if ast.Explain != nil {
 		return command.Explain{
-			Command: cmd,
+			Command:       cmd,
+			Optimizations: fired,
 		}, nil
 	}
 	return cmd, nil
@@ -100,15 +162,182 @@ func (c *simpleCompiler) compileInternal(ast *ast.SQLStmt) (command.Command, err
 			return nil, fmt.Errorf("insert: %w", err)
 		}
 		return cmd, nil
+	case ast.CreateTableStmt != nil:
+		cmd, err := c.compileCreateTable(ast.CreateTableStmt)
+		if err != nil {
+			return nil, fmt.Errorf("create table: %w", err)
+		}
+		return cmd, nil
+	case ast.CreateIndexStmt != nil:
+		cmd, err := c.compileCreateIndex(ast.CreateIndexStmt)
+		if err != nil {
+			return nil, fmt.Errorf("create index: %w", err)
+		}
+		return cmd, nil
+	case ast.AnalyzeStmt != nil:
+		return c.compileAnalyze(ast.AnalyzeStmt), nil
 	}
 	return nil, fmt.Errorf("statement type: %w", ErrUnsupported)
 }
 
-func (c *simpleCompiler) compileInsert(stmt *ast.InsertStmt) (command.Insert, error) {
-	if stmt.Replace != nil {
-		return command.Insert{}, fmt.Errorf("replace: %w", ErrUnsupported)
+func (c *simpleCompiler) compileCreateTable(stmt *ast.CreateTableStmt) (command.CreateTable, error) {
+	if stmt.Temp != nil || stmt.Temporary != nil {
+		return command.CreateTable{}, fmt.Errorf("temporary: %w", ErrUnsupported)
+	}
+	if stmt.SelectStmt != nil {
+		return command.CreateTable{}, fmt.Errorf("as select: %w", ErrUnsupported)
 	}
 
+	cmd := command.CreateTable{
+		IfNotExists:  stmt.If != nil,
+		Name:         stmt.TableName.Value(),
+		WithoutRowID: stmt.Without != nil,
+	}
+	if stmt.SchemaName != nil {
+		cmd.Schema = stmt.SchemaName.Value()
+	}
+
+	for _, def := range stmt.ColumnDef {
+		col, err := c.compileColumnDef(def)
+		if err != nil {
+			return command.CreateTable{}, fmt.Errorf("column %v: %w", def.ColumnName.Value(), err)
+		}
+		cmd.Columns = append(cmd.Columns, col)
+	}
+
+	for _, constraint := range stmt.TableConstraint {
+		if err := c.applyTableConstraint(cmd.Columns, constraint); err != nil {
+			return command.CreateTable{}, fmt.Errorf("table constraint: %w", err)
+		}
+	}
+
+	// AUTOINCREMENT is only allowed on a column that is the only INTEGER
+	// PRIMARY KEY column, because only such a column is an alias for the rowid
+	var pks int
+	for _, col := range cmd.Columns {
+		if col.PrimaryKey {
+			pks++
+		}
+	}
+	for _, col := range cmd.Columns {
+		if col.Autoincrement && !(pks == 1 && col.PrimaryKey && strings.EqualFold(col.Type, "INTEGER")) {
+			return command.CreateTable{}, fmt.Errorf("column %v: autoincrement is only allowed on an integer primary key", col.Name)
+		}
+	}
+
+	return cmd, nil
+}
+
+func (c *simpleCompiler) compileCreateIndex(stmt *ast.CreateIndexStmt) (command.CreateIndex, error) {
+	if stmt.Where != nil {
+		return command.CreateIndex{}, fmt.Errorf("partial index: %w", ErrUnsupported)
+	}
+
+	cmd := command.CreateIndex{
+		Unique:      stmt.Unique != nil,
+		IfNotExists: stmt.If != nil,
+		Name:        stmt.IndexName.Value(),
+		Table:       stmt.TableName.Value(),
+	}
+	if stmt.SchemaName != nil {
+		cmd.Schema = stmt.SchemaName.Value()
+	}
+	for _, col := range stmt.IndexedColumns {
+		if col.ColumnName == nil {
+			return command.CreateIndex{}, fmt.Errorf("indexed expression: %w", ErrUnsupported)
+		}
+		if col.Collate != nil {
+			return command.CreateIndex{}, fmt.Errorf("column %v: collation: %w", col.ColumnName.Value(), ErrUnsupported)
+		}
+		cmd.Columns = append(cmd.Columns, col.ColumnName.Value())
+	}
+	return cmd, nil
+}
+
+func (c *simpleCompiler) compileColumnDef(def *ast.ColumnDef) (command.ColumnDef, error) {
+	col := command.ColumnDef{
+		Name: def.ColumnName.Value(),
+	}
+	if def.TypeName != nil {
+		col.Type = compileTypeName(def.TypeName)
+	}
+
+	for _, constraint := range def.ColumnConstraint {
+		switch {
+		case constraint.Primary != nil:
+			col.PrimaryKey = true
+			col.Autoincrement = constraint.Autoincrement != nil
+		case constraint.Not != nil:
+			col.NotNull = true
+		case constraint.Unique != nil:
+			col.Unique = true
+		case constraint.Default != nil:
+			var dflt command.Expr
+			switch {
+			case constraint.SignedNumber != nil:
+				dflt = command.LiteralExpr{Value: compileSignedNumber(constraint.SignedNumber)}
+			case constraint.LiteralValue != nil:
+				compiled, err := c.compileExpr(&ast.Expr{LiteralValue: constraint.LiteralValue})
+				if err != nil {
+					return command.ColumnDef{}, fmt.Errorf("default: %w", err)
+				}
+				dflt = compiled
+			default:
+				compiled, err := c.compileExpr(constraint.Expr)
+				if err != nil {
+					return command.ColumnDef{}, fmt.Errorf("default: %w", err)
+				}
+				dflt = compiled
+			}
+			col.Default = dflt
+		case constraint.Check != nil:
+			return command.ColumnDef{}, fmt.Errorf("check: %w", ErrUnsupported)
+		case constraint.Collate != nil:
+			return command.ColumnDef{}, fmt.Errorf("collate: %w", ErrUnsupported)
+		case constraint.ForeignKeyClause != nil:
+			return command.ColumnDef{}, fmt.Errorf("foreign key: %w", ErrUnsupported)
+		default:
+			return command.ColumnDef{}, fmt.Errorf("generated: %w", ErrUnsupported)
+		}
+	}
+	return col, nil
+}
+
+func (c *simpleCompiler) applyTableConstraint(cols []command.ColumnDef, constraint *ast.TableConstraint) error {
+	if constraint.Primary == nil && constraint.Unique == nil {
+		if constraint.Check != nil {
+			return fmt.Errorf("check: %w", ErrUnsupported)
+		}
+		return fmt.Errorf("foreign key: %w", ErrUnsupported)
+	}
+	if constraint.Unique != nil && len(constraint.IndexedColumn) != 1 {
+		return fmt.Errorf("unique over multiple columns: %w", ErrUnsupported)
+	}
+
+	for _, indexed := range constraint.IndexedColumn {
+		if indexed.ColumnName == nil {
+			return fmt.Errorf("indexed expression: %w", ErrUnsupported)
+		}
+		name := indexed.ColumnName.Value()
+		found := false
+		for i := range cols {
+			if strings.EqualFold(cols[i].Name, name) {
+				found = true
+				if constraint.Primary != nil {
+					cols[i].PrimaryKey = true
+				} else {
+					cols[i].Unique = true
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("no such column: %v", name)
+		}
+	}
+	return nil
+}
+
+func (c *simpleCompiler) compileInsert(stmt *ast.InsertStmt) (command.Insert, error) {
 	// compile insertOr
 	var insertOr command.InsertOr
 	switch {
@@ -134,6 +363,7 @@ func (c *simpleCompiler) compileInsert(stmt *ast.InsertStmt) (command.Insert, er
 	if stmt.As != nil {
 		table.Alias = stmt.Alias.Value()
 	}
+	c.bindInsert(table, stmt.TableName, stmt.ColumnName)
 
 	// compile column names
 	var cols []command.Column
@@ -167,15 +397,90 @@ func (c *simpleCompiler) compileInsert(stmt *ast.InsertStmt) (command.Insert, er
 		}
 	}
 
+	// compile the upsert clause
+	var onConflict *command.OnConflict
+	if stmt.UpsertClause != nil {
+		compiled, err := c.compileUpsert(table, stmt.UpsertClause)
+		if err != nil {
+			return command.Insert{}, fmt.Errorf("upsert: %w", err)
+		}
+		onConflict = &compiled
+	}
+
 	return command.Insert{
 		InsertOr:      insertOr,
 		Table:         table,
 		Cols:          cols,
 		DefaultValues: stmt.Default != nil,
 		Input:         vals,
+		OnConflict:    onConflict,
 	}, nil
 }
 
+// compileUpsert compiles the upsert clause of an insert into the given table.
+// Within the clause, the existing row is visible under the name of the table,
+// and the dataset that could not be inserted is visible as table "excluded".
+func (c *simpleCompiler) compileUpsert(table command.SimpleTable, upsert *ast.UpsertClause) (command.OnConflict, error) {
+	c.pushScope()
+	defer c.popScope()
+
+	excluded := table
+	excluded.Alias = "excluded"
+	c.declareTable(table)
+	c.declareTable(excluded)
+	c.bindUpsert(table, excluded)
+
+	name := table.Table
+	if table.Alias != "" {
+		name = table.Alias
+	}
+
+	var onConflict command.OnConflict
+	for _, col := range upsert.IndexedColumn {
+		if col.ColumnName == nil {
+			return command.OnConflict{}, fmt.Errorf("expression in conflict target: %w", ErrUnsupported)
+		}
+		if col.Collate != nil {
+			return command.OnConflict{}, fmt.Errorf("collation in conflict target: %w", ErrUnsupported)
+		}
+		c.bindColumnRef(command.ColumnRef{Table: name, Column: col.ColumnName.Value()}, col.ColumnName)
+		onConflict.Target = append(onConflict.Target, col.ColumnName.Value())
+	}
+	if upsert.Where1 != nil {
+		filter, err := c.compileExpr(upsert.Expr1)
+		if err != nil {
+			return command.OnConflict{}, fmt.Errorf("target filter: %w", err)
+		}
+		onConflict.TargetFilter = filter
+	}
+
+	if upsert.Nothing != nil {
+		return onConflict, nil
+	}
+	for _, set := range upsert.UpdateSetter {
+		columnNames := []token.Token{set.ColumnName}
+		if set.ColumnName == nil {
+			columnNames = set.ColumnNameList.ColumnName
+		}
+		for _, col := range columnNames {
+			c.bindColumnRef(command.ColumnRef{Table: name, Column: col.Value()}, col)
+		}
+		compiledSet, err := c.compileUpdateSetter(set)
+		if err != nil {
+			return command.OnConflict{}, fmt.Errorf("update setter: %w", err)
+		}
+		onConflict.Updates = append(onConflict.Updates, compiledSet)
+	}
+	if upsert.Where2 != nil {
+		filter, err := c.compileExpr(upsert.Expr2)
+		if err != nil {
+			return command.OnConflict{}, fmt.Errorf("filter: %w", err)
+		}
+		onConflict.Filter = filter
+	}
+	return onConflict, nil
+}
+
 func (c *simpleCompiler) compileUpdate(stmt *ast.UpdateStmt) (command.Update, error) {
 	updateOr := command.UpdateOrIgnore // ignore as default or
 	switch {
@@ -191,13 +496,25 @@ func (c *simpleCompiler) compileUpdate(stmt *ast.UpdateStmt) (command.Update, er
 		updateOr = command.UpdateOrIgnore
 	}
 
+	c.pushScope()
+	defer c.popScope()
+
 	qtn, err := c.compileQualifiedTableName(stmt.QualifiedTableName)
 	if err != nil {
 		return command.Update{}, fmt.Errorf("qualified table name: %w", err)
 	}
+	c.declareTable(qtn)
+	c.bindTable(qtn, stmt.QualifiedTableName.TableName)
 
 	var sets []command.UpdateSetter
 	for _, set := range stmt.UpdateSetter {
+		columnNames := []token.Token{set.ColumnName}
+		if set.ColumnName == nil {
+			columnNames = set.ColumnNameList.ColumnName
+		}
+		for _, name := range columnNames {
+			c.bindColumnRef(command.ColumnRef{Column: name.Value()}, name)
+		}
 		compiledSet, err := c.compileUpdateSetter(set)
 		if err != nil {
 			return command.Update{}, fmt.Errorf("update setter: %w", err)
@@ -254,6 +571,17 @@ func (c *simpleCompiler) compileDropTable(stmt *ast.DropTableStmt) (command.Drop
 	return cmd, nil
 }
 
+func (c *simpleCompiler) compileAnalyze(stmt *ast.AnalyzeStmt) command.Analyze {
+	var cmd command.Analyze
+	if stmt.TableOrIndexName != nil {
+		cmd.Name = stmt.TableOrIndexName.Value()
+	}
+	if stmt.Period != nil {
+		cmd.Schema = stmt.SchemaName.Value()
+	}
+	return cmd
+}
+
 func (c *simpleCompiler) compileDropIndex(stmt *ast.DropIndexStmt) (command.DropIndex, error) {
 	cmd := command.DropIndex{
 		IfExists: stmt.If != nil,
@@ -292,6 +620,16 @@ func (c *simpleCompiler) compileDelete(stmt *ast.DeleteStmt) (command.Delete, er
 		return command.Delete{}, fmt.Errorf("with: %w", ErrUnsupported)
 	}
 
+	c.pushScope()
+	defer c.popScope()
+
+	table, err := c.compileQualifiedTableName(stmt.QualifiedTableName)
+	if err != nil {
+		return command.Delete{}, fmt.Errorf("qualified table name: %w", err)
+	}
+	c.declareTable(table)
+	c.bindTable(table, stmt.QualifiedTableName.TableName)
+
 	var filter command.Expr
 	if stmt.Where != nil {
 		compiled, err := c.compileExpr(stmt.Expr)
@@ -302,11 +640,6 @@ func (c *simpleCompiler) compileDelete(stmt *ast.DeleteStmt) (command.Delete, er
 	} else {
 		filter = command.ConstantBooleanExpr{Value: true} // constant true
 	}
-
-	table, err := c.compileQualifiedTableName(stmt.QualifiedTableName)
-	if err != nil {
-		return command.Delete{}, fmt.Errorf("qualified table name: %w", err)
-	}
 	return command.Delete{
 		Table:  table,
 		Filter: filter,
@@ -331,21 +664,36 @@ func (c *simpleCompiler) compileQualifiedTableName(tableName *ast.QualifiedTable
 }
 
 func (c *simpleCompiler) compileSelect(stmt *ast.SelectStmt) (command.Command, error) {
-	if len(stmt.SelectCore) != 1 {
-		return nil, fmt.Errorf("compound select: %w", ErrUnsupported)
+	if stmt.WithClause != nil && stmt.WithClause.With != nil {
+		// compile the statement without the WITH clause, within which the
+		// common tables are visible
+		withoutWith := *stmt
+		withoutWith.WithClause = nil
+		cmd, err := c.compileWith(stmt.WithClause, func() (command.List, error) {
+			return c.compileSubquery(&withoutWith)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("with: %w", err)
+		}
+		return cmd, nil
 	}
 
 	var cmd command.Command
-	// compile the select core
-	core, err := c.compileSelectCore(stmt.SelectCore[0])
-	if err != nil {
-		return nil, fmt.Errorf("core: %w", err)
-	}
-	cmd = core
-
-	// compile ORDER BY
-	if stmt.Order != nil {
-		return nil, fmt.Errorf("order: %w", ErrUnsupported)
+	if len(stmt.SelectCore) > 1 {
+		compound, err := c.compileCompound(stmt.SelectCore, stmt.OrderingTerm)
+		if err != nil {
+			return nil, fmt.Errorf("compound select: %w", err)
+		}
+		cmd = compound
+	} else {
+		// compile the select core
+		// the ORDER BY clause is compiled together with the core, since it may
+		// reference columns of the core that are not part of the result
+		core, err := c.compileSelectCore(stmt.SelectCore[0], stmt.OrderingTerm)
+		if err != nil {
+			return nil, fmt.Errorf("core: %w", err)
+		}
+		cmd = core
 	}
 
 	// compile LIMIT
@@ -376,15 +724,26 @@ func (c *simpleCompiler) compileSelect(stmt *ast.SelectStmt) (command.Command, e
 	return cmd, nil
 }
 
-func (c *simpleCompiler) compileSelectCore(core *ast.SelectCore) (command.Command, error) {
-	if core.CompoundOperator != nil {
-		return nil, fmt.Errorf("compound statements: %w", ErrUnsupported)
-	}
-
+func (c *simpleCompiler) compileSelectCore(core *ast.SelectCore, order []*ast.OrderingTerm) (command.Command, error) {
 	if core.Values != nil {
-		return c.compileSelectCoreValues(core)
+		values, err := c.compileSelectCoreValues(core)
+		if err != nil || len(order) == 0 {
+			return values, err
+		}
+
+		// the columns of values can only be referenced by their generated
+		// names column1, column2 and so on
+		cols, _ := resultColumns(values)
+		terms, err := c.compileOrderingTerms(order, cols)
+		if err != nil {
+			return nil, fmt.Errorf("order: %w", err)
+		}
+		return command.Sort{
+			Terms: terms,
+			Input: values,
+		}, nil
 	}
-	return c.compileSelectCoreSelect(core)
+	return c.compileSelectCoreSelect(core, order)
 }
 
 func (c *simpleCompiler) compileSelectCoreValues(core *ast.SelectCore) (command.Values, error) {
@@ -411,32 +770,46 @@ func (c *simpleCompiler) compileParenthesizedExpressions(exprs []*ast.Parenthesi
 	return command.Values{Values: datasets}, nil
 }
 
-func (c *simpleCompiler) compileSelectCoreSelect(core *ast.SelectCore) (command.Command, error) {
-	// compile the projection columns
-
-	// cols are the projection columns.
-	var cols []command.Column
-	for _, resultColumn := range core.ResultColumn {
-		col, err := c.compileResultColumn(resultColumn)
-		if err != nil {
-			return nil, fmt.Errorf("result column: %w", err)
-		}
-		cols = append(cols, col)
+func (c *simpleCompiler) compileSelectCoreSelect(core *ast.SelectCore, order []*ast.OrderingTerm) (command.Command, error) {
+	// the tables of this query are visible in all expressions of this query,
+	// including nested queries
+	c.pushScope()
+	defer c.popScope()
+
+	// the named windows of this query are only visible in this query
+	outerWindows := c.windows
+	defer func() {
+		c.windows = outerWindows
+	}()
+	c.windows = make(map[string]*ast.WindowDefn)
+	for _, window := range core.NamedWindow {
+		c.windows[strings.ToLower(window.WindowName.Value())] = window.WindowDefn
 	}
 
 	// selectionInput is the scan or join that is selected from.
 	var selectionInput command.List
-	// if there is only one table to select from, meaning that no join exists
-	if len(core.TableOrSubquery) == 1 {
-		table, err := c.compileTableOrSubquery(core.TableOrSubquery[0])
-		if err != nil {
-			return nil, fmt.Errorf("table or subquery: %w", err)
-		}
+	if len(core.TableOrSubquery) != 0 {
+		// a comma separated list of tables is a cross join of all tables
+		for _, tos := range core.TableOrSubquery {
+			table, err := c.compileTableOrSubquery(tos)
+			if err != nil {
+				return nil, fmt.Errorf("table or subquery: %w", err)
+			}
 
-		selectionInput = command.Scan{
-			Table: table,
+			scan := command.Scan{
+				Table: table,
+			}
+			if selectionInput == nil {
+				selectionInput = scan
+				continue
+			}
+			selectionInput = command.Join{
+				Type:  command.JoinCross,
+				Left:  selectionInput,
+				Right: scan,
+			}
 		}
-	} else if len(core.TableOrSubquery) == 0 {
+	} else {
 		if core.JoinClause == nil {
 			return nil, fmt.Errorf("nothing to select from")
 		}
@@ -446,8 +819,22 @@ func (c *simpleCompiler) compileSelectCoreSelect(core *ast.SelectCore) (command.
 			return nil, fmt.Errorf("join: %w", err)
 		}
 		selectionInput = join
-	} else {
-		return nil, fmt.Errorf("table and join constellation: %w", ErrUnsupported)
+	}
+
+	// compile the projection columns
+
+	// cols are the projection columns.
+	var cols []command.Column
+	for _, resultColumn := range core.ResultColumn {
+		col, err := c.compileResultColumn(resultColumn)
+		if err != nil {
+			return nil, fmt.Errorf("result column: %w", err)
+		}
+		cols = append(cols, col)
+	}
+	cols, err := c.bindResultColumns(cols)
+	if err != nil {
+		return nil, fmt.Errorf("result column: %w", err)
 	}
 
 	// filter is the filter expression extracted from the where clause.
@@ -464,12 +851,49 @@ func (c *simpleCompiler) compileSelectCoreSelect(core *ast.SelectCore) (command.
 	// for the select
 	input := selectionInput
 	if filter != nil {
+		if aggregates, _ := collectAggregates(nil, filter); len(aggregates) != 0 {
+			return nil, fmt.Errorf("where: misuse of aggregate function %v", aggregates[0].Name)
+		}
+		if windows, _ := collectWindows(nil, filter); len(windows) != 0 {
+			return nil, fmt.Errorf("where: misuse of window function %v", windows[0].Name)
+		}
 		input = command.Select{
 			Filter: filter,
 			Input:  input,
 		}
 	}
 
+	// terms are the sort terms from the order by clause. They are sorted
+	// before the projection, so that they can reference columns that are not
+	// part of the result.
+	terms, err := c.compileOrderingTerms(order, cols)
+	if err != nil {
+		return nil, fmt.Errorf("order: %w", err)
+	}
+
+	// wrap input into an aggregate if the query is an aggregate query
+	aggregate, err := c.compileAggregate(core, cols, terms, input)
+	if err != nil {
+		return nil, err
+	}
+	if aggregate != nil {
+		input = aggregate
+	}
+
+	// window functions are computed after grouping, and may reference group
+	// keys and aggregate results
+	input, err = c.compileWindow(cols, terms, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(terms) != 0 {
+		input = command.Sort{
+			Terms: terms,
+			Input: input,
+		}
+	}
+
 	// wrap columns and input into projection
 	var list command.List
 	list = command.Project{
@@ -486,6 +910,147 @@ func (c *simpleCompiler) compileSelectCoreSelect(core *ast.SelectCore) (command.
 	return list, nil
 }
 
+// compileAggregate compiles the GROUP BY and HAVING clause of the given select
+// core. If the core is not an aggregate query, meaning that it has no GROUP BY
+// and HAVING clause, and no aggregate function calls in the result columns or
+// the sort terms, nil is returned.
+func (c *simpleCompiler) compileAggregate(core *ast.SelectCore, cols []command.Column, terms []command.SortTerm, input command.List) (command.List, error) {
+	var groupBy []command.Expr
+	for _, expr := range core.Expr2 {
+		compiled, err := c.compileExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("group by: %w", err)
+		}
+		if aggregates, _ := collectAggregates(nil, compiled); len(aggregates) != 0 {
+			return nil, fmt.Errorf("group by: misuse of aggregate function %v", aggregates[0].Name)
+		}
+		if windows, _ := collectWindows(nil, compiled); len(windows) != 0 {
+			return nil, fmt.Errorf("group by: misuse of window function %v", windows[0].Name)
+		}
+		groupBy = append(groupBy, compiled)
+	}
+
+	var having command.Expr
+	if core.Having != nil {
+		compiled, err := c.compileExpr(core.Expr3)
+		if err != nil {
+			return nil, fmt.Errorf("having: %w", err)
+		}
+		if windows, _ := collectWindows(nil, compiled); len(windows) != 0 {
+			return nil, fmt.Errorf("having: misuse of window function %v", windows[0].Name)
+		}
+		having = compiled
+	}
+
+	var aggregates []command.FunctionExpr
+	var err error
+	for _, col := range cols {
+		aggregates, err = collectAggregates(aggregates, col.Column)
+		if err != nil {
+			return nil, fmt.Errorf("result column: %w", err)
+		}
+	}
+	aggregates, err = collectAggregates(aggregates, having)
+	if err != nil {
+		return nil, fmt.Errorf("having: %w", err)
+	}
+	for _, term := range terms {
+		aggregates, err = collectAggregates(aggregates, term.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("order: %w", err)
+		}
+	}
+
+	if core.Group == nil && having == nil && len(aggregates) == 0 {
+		return nil, nil
+	}
+	return command.Aggregate{
+		GroupBy:    groupBy,
+		Aggregates: aggregates,
+		Having:     having,
+		Input:      input,
+	}, nil
+}
+
+// compileOrderingTerms compiles the given ordering terms of a query with the
+// given result columns. A term that is an integer literal refers to the result
+// column at that 1-based position, and a term that is an unqualified name
+// refers to the result column with that alias, if there is one. Such terms are
+// replaced by the expression of the referenced result column.
+func (c *simpleCompiler) compileOrderingTerms(order []*ast.OrderingTerm, cols []command.Column) ([]command.SortTerm, error) {
+	// ordering terms may reference result columns by their alias
+	outerAliases := c.resultAliases
+	defer func() {
+		c.resultAliases = outerAliases
+	}()
+	c.resultAliases = nil
+	for _, col := range cols {
+		if col.Alias != "" {
+			c.resultAliases = append(c.resultAliases, col.Alias)
+		}
+	}
+
+	var terms []command.SortTerm
+	for _, term := range order {
+		compiled, err := c.compileOrderingTerm(term)
+		if err != nil {
+			return nil, err
+		}
+
+		switch e := compiled.Expr.(type) {
+		case command.LiteralExpr:
+			position, err := strconv.Atoi(e.Value)
+			if err != nil {
+				break
+			}
+			if position < 1 || position > len(cols) {
+				return nil, fmt.Errorf("term %v does not match any result column (1..%d)", position, len(cols))
+			}
+			col := cols[position-1]
+			if lit, ok := col.Column.(command.LiteralExpr); ok && lit.Value == "*" {
+				return nil, fmt.Errorf("term %v refers to *: %w", position, ErrUnsupported)
+			}
+			compiled.Expr = col.Column
+		case command.ColumnRef:
+			if e.Table != "" {
+				break
+			}
+			for _, col := range cols {
+				if col.Alias != "" && strings.EqualFold(col.Alias, e.Column) {
+					compiled.Expr = col.Column
+					break
+				}
+			}
+		}
+		terms = append(terms, compiled)
+	}
+	return terms, nil
+}
+
+// compileOrderingTerm compiles a single ordering term. A COLLATE on the top
+// level of the expression is compiled to the collation of the term.
+func (c *simpleCompiler) compileOrderingTerm(term *ast.OrderingTerm) (command.SortTerm, error) {
+	expr, err := c.compileExpr(term.Expr)
+	if err != nil {
+		return command.SortTerm{}, err
+	}
+
+	var collation string
+	if collate, ok := expr.(command.CollateExpr); ok {
+		expr = collate.Value
+		collation = collate.Collation
+	}
+
+	desc := term.Desc != nil
+	return command.SortTerm{
+		Expr: expr,
+		Desc: desc,
+		// NULL is the smallest value, unless specified otherwise
+		NullsFirst: term.First != nil || (term.Last == nil && !desc),
+		Collation:  collation,
+	}, nil
+}
+
 func (c *simpleCompiler) compileResultColumn(col *ast.ResultColumn) (command.Column, error) {
 	if col.Asterisk != nil {
 		var tableName string
@@ -517,10 +1082,33 @@ func (c *simpleCompiler) compileResultColumn(col *ast.ResultColumn) (command.Col
 func (c *simpleCompiler) compileExpr(expr *ast.Expr) (command.Expr, error) {
 	switch {
 	case expr.LiteralValue != nil:
-		if val := strings.ToLower(expr.LiteralValue.Value()); val == "true" || val == "false" {
-			return command.ConstantBooleanExpr{Value: val == "true"}, nil
+		if isParameter(expr.LiteralValue.Value()) {
+			return c.compileParameter(expr.LiteralValue)
+		}
+		compiled := compileLiteral(expr.LiteralValue)
+		if ref, ok := compiled.(command.ColumnRef); ok {
+			c.bindColumnRef(ref, expr.LiteralValue)
 		}
-		return command.LiteralExpr{Value: expr.LiteralValue.Value()}, nil
+		return compiled, nil
+	case expr.ColumnName != nil:
+		ref := command.ColumnRef{
+			Column: unquoteIdentifier(expr.ColumnName.Value()),
+		}
+		if expr.TableName != nil {
+			ref.Table = unquoteIdentifier(expr.TableName.Value())
+		}
+		if expr.SchemaName != nil {
+			ref.Schema = unquoteIdentifier(expr.SchemaName.Value())
+		}
+		if ref.Table != "" {
+			ref.Depth = c.scopeDepth(ref.Table)
+		}
+		at := expr.ColumnName
+		if expr.TableName != nil {
+			at = expr.TableName
+		}
+		c.bindColumnRef(ref, at)
+		return ref, nil
 	case expr.UnaryOperator != nil:
 		val, err := c.compileExpr(expr.Expr1)
 		if err != nil {
@@ -539,38 +1127,291 @@ func (c *simpleCompiler) compileExpr(expr *ast.Expr) (command.Expr, error) {
 		if err != nil {
 			return nil, fmt.Errorf("expr2: %w", err)
 		}
-		return command.BinaryExpr{
-			Operator: expr.BinaryOperator.Value(),
-			Left:     left,
-			Right:    right,
+		var compiled command.Expr
+		switch expr.BinaryOperator.Value() {
+		case "=", "==":
+			compiled = command.EqualityExpr{
+				Left:  left,
+				Right: right,
+			}
+		case "!=", "<>":
+			compiled = command.EqualityExpr{
+				Left:   left,
+				Right:  right,
+				Invert: true,
+			}
+		default:
+			compiled = command.BinaryExpr{
+				Operator: expr.BinaryOperator.Value(),
+				Left:     left,
+				Right:    right,
+			}
+		}
+		c.checkTypes(expr.BinaryOperator, compiled)
+		return compiled, nil
+	case expr.Between != nil:
+		needle, err := c.compileExpr(expr.Expr1)
+		if err != nil {
+			return nil, fmt.Errorf("expr1: %w", err)
+		}
+		lo, err := c.compileExpr(expr.Expr2)
+		if err != nil {
+			return nil, fmt.Errorf("expr2: %w", err)
+		}
+		hi, err := c.compileExpr(expr.Expr3)
+		if err != nil {
+			return nil, fmt.Errorf("expr3: %w", err)
+		}
+		return command.RangeExpr{
+			Needle: needle,
+			Lo:     lo,
+			Hi:     hi,
+			Invert: expr.Not != nil,
 		}, nil
 	case expr.FunctionName != nil:
-		if !(expr.FilterClause == nil && expr.OverClause == nil) {
-			return nil, fmt.Errorf("filter or over on function: %w", ErrUnsupported)
+		fn := command.FunctionExpr{
+			Name:     expr.FunctionName.Value(),
+			Distinct: expr.Distinct != nil,
 		}
 		if expr.Asterisk != nil {
-			return nil, fmt.Errorf("function_name(*): %w", ErrUnsupported)
+			fn.Args = append(fn.Args, command.LiteralExpr{Value: "*"})
 		}
-
-		var args []command.Expr
 		for _, arg := range expr.Expr {
 			compiledArg, err := c.compileExpr(arg)
 			if err != nil {
 				return nil, fmt.Errorf("expr: %w", err)
 			}
-			args = append(args, compiledArg)
+			fn.Args = append(fn.Args, compiledArg)
+		}
+		if expr.FilterClause != nil {
+			filter, err := c.compileExpr(expr.FilterClause.Expr)
+			if err != nil {
+				return nil, fmt.Errorf("filter: %w", err)
+			}
+			fn.Filter = filter
+		}
+		if expr.OverClause != nil {
+			over, err := c.compileOverClause(expr.OverClause)
+			if err != nil {
+				return nil, fmt.Errorf("over: %w", err)
+			}
+			fn.Over = over
 		}
 
-		return command.FunctionExpr{
-			Name:     expr.FunctionName.Value(),
-			Distinct: expr.Distinct != nil,
-			Args:     args,
+		switch {
+		case fn.Filter != nil && !isAggregate(fn):
+			return nil, fmt.Errorf("FILTER clause may only be used with aggregate functions")
+		case fn.Over != nil && !isAggregate(fn) && !isWindowFunction(fn):
+			return nil, fmt.Errorf("%v may not be used as a window function", fn.Name)
+		case fn.Over != nil && fn.Distinct:
+			return nil, fmt.Errorf("DISTINCT is not supported for window functions")
+		case fn.Over == nil && isWindowFunction(fn):
+			return nil, fmt.Errorf("misuse of window function %v", fn.Name)
+		}
+		c.checkTypes(expr.FunctionName, fn)
+		return fn, nil
+	case expr.Cast != nil:
+		val, err := c.compileExpr(expr.Expr1)
+		if err != nil {
+			return nil, fmt.Errorf("expr1: %w", err)
+		}
+		return command.CastExpr{
+			Value: val,
+			Type:  compileTypeName(expr.TypeName),
+		}, nil
+	case expr.Collate != nil:
+		val, err := c.compileExpr(expr.Expr1)
+		if err != nil {
+			return nil, fmt.Errorf("expr1: %w", err)
+		}
+		return command.CollateExpr{
+			Value:     val,
+			Collation: expr.CollationName.Value(),
+		}, nil
+	case expr.Like != nil, expr.Glob != nil, expr.Regexp != nil, expr.Match != nil:
+		return c.compilePatternExpr(expr)
+	case expr.Isnull != nil, expr.Notnull != nil, expr.Null != nil:
+		val, err := c.compileExpr(expr.Expr1)
+		if err != nil {
+			return nil, fmt.Errorf("expr1: %w", err)
+		}
+		return command.IsExpr{
+			Left:   val,
+			Right:  command.LiteralExpr{Value: "NULL"},
+			Invert: expr.Notnull != nil || expr.Not != nil,
+		}, nil
+	case expr.Is != nil:
+		left, err := c.compileExpr(expr.Expr1)
+		if err != nil {
+			return nil, fmt.Errorf("expr1: %w", err)
+		}
+		right, err := c.compileExpr(expr.Expr2)
+		if err != nil {
+			return nil, fmt.Errorf("expr2: %w", err)
+		}
+		return command.IsExpr{
+			Left:   left,
+			Right:  right,
+			Invert: expr.Not != nil,
 		}, nil
+	case expr.In != nil:
+		return c.compileInExpr(expr)
+	case expr.Exists != nil:
+		input, err := c.compileSubquery(expr.SelectStmt)
+		if err != nil {
+			return nil, fmt.Errorf("exists: %w", err)
+		}
+		return command.ExistsExpr{
+			Input:  input,
+			Invert: expr.Not != nil,
+		}, nil
+	case expr.SelectStmt != nil:
+		if expr.Not != nil {
+			return nil, fmt.Errorf("not: %w", ErrUnsupported)
+		}
+		input, err := c.compileSubquery(expr.SelectStmt)
+		if err != nil {
+			return nil, fmt.Errorf("subquery: %w", err)
+		}
+		return command.SubqueryExpr{
+			Input: input,
+		}, nil
+	case expr.Case != nil:
+		return c.compileCaseExpr(expr)
+	case expr.RaiseFunction != nil:
+		return compileRaiseFunction(expr.RaiseFunction), nil
 	}
 
 	return nil, ErrUnsupported
 }
 
+func (c *simpleCompiler) compilePatternExpr(expr *ast.Expr) (command.PatternExpr, error) {
+	var operator token.Token
+	switch {
+	case expr.Like != nil:
+		operator = expr.Like
+	case expr.Glob != nil:
+		operator = expr.Glob
+	case expr.Regexp != nil:
+		operator = expr.Regexp
+	default:
+		operator = expr.Match
+	}
+
+	val, err := c.compileExpr(expr.Expr1)
+	if err != nil {
+		return command.PatternExpr{}, fmt.Errorf("expr1: %w", err)
+	}
+	pattern, err := c.compileExpr(expr.Expr2)
+	if err != nil {
+		return command.PatternExpr{}, fmt.Errorf("expr2: %w", err)
+	}
+	var escape command.Expr
+	if expr.Escape != nil {
+		escape, err = c.compileExpr(expr.Expr3)
+		if err != nil {
+			return command.PatternExpr{}, fmt.Errorf("escape: %w", err)
+		}
+	}
+	return command.PatternExpr{
+		Operator: strings.ToUpper(operator.Value()),
+		Value:    val,
+		Pattern:  pattern,
+		Escape:   escape,
+		Invert:   expr.Not != nil,
+	}, nil
+}
+
+func (c *simpleCompiler) compileInExpr(expr *ast.Expr) (command.InExpr, error) {
+	val, err := c.compileExpr(expr.Expr1)
+	if err != nil {
+		return command.InExpr{}, fmt.Errorf("expr1: %w", err)
+	}
+	in := command.InExpr{
+		Value:  val,
+		Invert: expr.Not != nil,
+	}
+
+	switch {
+	case expr.TableFunction != nil:
+		return command.InExpr{}, fmt.Errorf("in table function: %w", ErrUnsupported)
+	case expr.TableName != nil:
+		// x IN table is equivalent to x IN (SELECT * FROM table)
+		table := command.SimpleTable{
+			Table: expr.TableName.Value(),
+		}
+		if expr.SchemaName != nil {
+			table.Schema = expr.SchemaName.Value()
+		}
+		in.Input = command.Scan{
+			Table: table,
+		}
+	case expr.SelectStmt != nil:
+		input, err := c.compileSubquery(expr.SelectStmt)
+		if err != nil {
+			return command.InExpr{}, fmt.Errorf("in: %w", err)
+		}
+		in.Input = input
+	default:
+		for _, value := range expr.Expr {
+			compiled, err := c.compileExpr(value)
+			if err != nil {
+				return command.InExpr{}, fmt.Errorf("expr: %w", err)
+			}
+			in.Values = append(in.Values, compiled)
+		}
+	}
+	return in, nil
+}
+
+func (c *simpleCompiler) compileCaseExpr(expr *ast.Expr) (command.CaseExpr, error) {
+	var caseExpr command.CaseExpr
+	if expr.Expr1 != nil {
+		val, err := c.compileExpr(expr.Expr1)
+		if err != nil {
+			return command.CaseExpr{}, fmt.Errorf("expr1: %w", err)
+		}
+		caseExpr.Value = val
+	}
+	for _, whenThen := range expr.WhenThenClause {
+		when, err := c.compileExpr(whenThen.Expr1)
+		if err != nil {
+			return command.CaseExpr{}, fmt.Errorf("when: %w", err)
+		}
+		then, err := c.compileExpr(whenThen.Expr2)
+		if err != nil {
+			return command.CaseExpr{}, fmt.Errorf("then: %w", err)
+		}
+		caseExpr.Cases = append(caseExpr.Cases, command.WhenThen{
+			When: when,
+			Then: then,
+		})
+	}
+	if expr.Else != nil {
+		els, err := c.compileExpr(expr.Expr2)
+		if err != nil {
+			return command.CaseExpr{}, fmt.Errorf("else: %w", err)
+		}
+		caseExpr.Else = els
+	}
+	return caseExpr, nil
+}
+
+// compileSubquery compiles a select statement that is used as part of another
+// statement, for example as input of an IN or EXISTS expression, or as table in
+// a FROM clause.
+func (c *simpleCompiler) compileSubquery(stmt *ast.SelectStmt) (command.List, error) {
+	cmd, err := c.compileSelect(stmt)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := cmd.(command.List)
+	if !ok {
+		return nil, fmt.Errorf("subquery %T is not a list: %w", cmd, ErrUnsupported)
+	}
+	return list, nil
+}
+
 func (c *simpleCompiler) compileJoin(join *ast.JoinClause) (command.List, error) {
 	left, err := c.compileTableOrSubquery(join.TableOrSubquery)
 	if err != nil {
@@ -583,10 +1424,6 @@ func (c *simpleCompiler) compileJoin(join *ast.JoinClause) (command.List, error)
 	}
 
 	for _, part := range join.JoinClausePart {
-		if part.JoinConstraint != nil && part.JoinConstraint.Using != nil {
-			return command.Join{}, fmt.Errorf("using: %w", ErrUnsupported)
-		}
-
 		op := part.JoinOperator
 		// evaluate join type
 		var typ command.JoinType
@@ -602,9 +1439,21 @@ func (c *simpleCompiler) compileJoin(join *ast.JoinClause) (command.List, error)
 			}
 		} else if op.Inner != nil {
 			typ = command.JoinInner
-		} else if op.Cross != nil {
+		} else if op.Cross != nil || op.Comma != nil {
 			typ = command.JoinCross
 		}
+		if natural && part.JoinConstraint != nil && (part.JoinConstraint.On != nil || part.JoinConstraint.Using != nil) {
+			return nil, fmt.Errorf("a NATURAL join may not have an ON or USING clause")
+		}
+
+		var rightStart int
+		if len(c.scopes) != 0 {
+			rightStart = len(c.scopes[len(c.scopes)-1].cols)
+		}
+		table, err := c.compileTableOrSubquery(part.TableOrSubquery)
+		if err != nil {
+			return command.Join{}, fmt.Errorf("table or subquery: %w", err)
+		}
 
 		var filter command.Expr
 		if part.JoinConstraint != nil && part.JoinConstraint.On != nil {
@@ -614,15 +1463,22 @@ func (c *simpleCompiler) compileJoin(join *ast.JoinClause) (command.List, error)
 			}
 		}
 
-		table, err := c.compileTableOrSubquery(part.TableOrSubquery)
-		if err != nil {
-			return command.Join{}, fmt.Errorf("table or subquery: %w", err)
+		var using []string
+		if part.JoinConstraint != nil && part.JoinConstraint.Using != nil {
+			using, err = compileUsing(part.JoinConstraint.ColumnName)
+			if err != nil {
+				return nil, fmt.Errorf("using: %w", err)
+			}
+			c.bindJoinConstraint(rightStart, part.JoinConstraint.ColumnName, false)
+		} else if natural {
+			c.bindJoinConstraint(rightStart, nil, true)
 		}
 
 		prev = command.Join{
 			Natural: natural,
 			Type:    typ,
 			Filter:  filter,
+			Using:   using,
 			Left:    prev,
 			Right: command.Scan{
 				Table: table,
@@ -633,23 +1489,171 @@ func (c *simpleCompiler) compileJoin(join *ast.JoinClause) (command.List, error)
 	return prev, nil
 }
 
-func (c *simpleCompiler) compileTableOrSubquery(tos *ast.TableOrSubquery) (command.Table, error) {
-	if tos.TableName == nil {
-		return nil, fmt.Errorf("not simple table: %w", ErrUnsupported)
+// compileUsing compiles the column names of a USING clause. Every column may
+// only be named once.
+func compileUsing(columnNames []token.Token) ([]string, error) {
+	var using []string
+	for _, columnName := range columnNames {
+		name := columnName.Value()
+		for _, other := range using {
+			if strings.EqualFold(name, other) {
+				return nil, fmt.Errorf("duplicate column %v", name)
+			}
+		}
+		using = append(using, name)
 	}
+	if len(using) == 0 {
+		return nil, fmt.Errorf("no columns")
+	}
+	return using, nil
+}
 
-	var index string
-	if tos.Not == nil && tos.IndexName != nil {
-		index = tos.IndexName.Value()
+// compileTableOrSubquery compiles a single table or subquery, and declares it
+// in the current scope.
+func (c *simpleCompiler) compileTableOrSubquery(tos *ast.TableOrSubquery) (command.Table, error) {
+	var alias string
+	if tos.TableAlias != nil {
+		alias = tos.TableAlias.Value()
 	}
 	var schema string
 	if tos.SchemaName != nil {
 		schema = tos.SchemaName.Value()
 	}
-	return command.SimpleTable{
-		Schema:  schema,
-		Table:   tos.TableName.Value(),
-		Indexed: tos.By != nil,
-		Index:   index,
-	}, nil
+
+	var table command.Table
+	switch {
+	case tos.SelectStmt != nil:
+		input, err := c.compileSubquery(tos.SelectStmt)
+		if err != nil {
+			return nil, fmt.Errorf("subquery: %w", err)
+		}
+		table = command.SubqueryTable{
+			Input: input,
+			Alias: alias,
+		}
+	case tos.TableFunctionName != nil:
+		var args []command.Expr
+		for _, arg := range tos.Expr {
+			compiledArg, err := c.compileExpr(arg)
+			if err != nil {
+				return nil, fmt.Errorf("expr: %w", err)
+			}
+			args = append(args, compiledArg)
+		}
+		table = command.TableFunction{
+			Schema: schema,
+			Name:   tos.TableFunctionName.Value(),
+			Args:   args,
+			Alias:  alias,
+		}
+	case tos.TableName != nil && schema == "" && c.lookupCommonTable(tos.TableName.Value()) != nil:
+		if tos.By != nil || tos.Not != nil {
+			return nil, fmt.Errorf("index on common table: %w", ErrUnsupported)
+		}
+		info := c.lookupCommonTable(tos.TableName.Value())
+		referenceCommonTable(info)
+		table = command.CommonTableRef{
+			Name:  info.name,
+			Alias: alias,
+		}
+	case tos.TableName != nil:
+		var index string
+		if tos.Not == nil && tos.IndexName != nil {
+			index = tos.IndexName.Value()
+		}
+		table = command.SimpleTable{
+			Schema:  schema,
+			Table:   tos.TableName.Value(),
+			Alias:   alias,
+			Indexed: tos.By != nil,
+			Index:   index,
+		}
+	default:
+		return nil, fmt.Errorf("parenthesized join: %w", ErrUnsupported)
+	}
+
+	c.declareTable(table)
+	c.bindTable(table, tos.TableName)
+	return table, nil
+}
+
+func compileTypeName(typeName *ast.TypeName) string {
+	var names []string
+	for _, name := range typeName.Name {
+		names = append(names, name.Value())
+	}
+	typ := strings.Join(names, " ")
+	if typeName.SignedNumber1 != nil {
+		typ += "(" + compileSignedNumber(typeName.SignedNumber1)
+		if typeName.SignedNumber2 != nil {
+			typ += "," + compileSignedNumber(typeName.SignedNumber2)
+		}
+		typ += ")"
+	}
+	return typ
+}
+
+func compileSignedNumber(num *ast.SignedNumber) string {
+	if num.Sign != nil {
+		return num.Sign.Value() + num.NumericLiteral.Value()
+	}
+	return num.NumericLiteral.Value()
+}
+
+func compileRaiseFunction(raise *ast.RaiseFunction) command.RaiseExpr {
+	var action command.RaiseAction
+	switch {
+	case raise.Ignore != nil:
+		action = command.RaiseIgnore
+	case raise.Rollback != nil:
+		action = command.RaiseRollback
+	case raise.Abort != nil:
+		action = command.RaiseAbort
+	case raise.Fail != nil:
+		action = command.RaiseFail
+	}
+	var message string
+	if raise.ErrorMessage != nil {
+		message = raise.ErrorMessage.Value()
+	}
+	return command.RaiseExpr{
+		Action:  action,
+		Message: message,
+	}
+}
+
+// compileLiteral compiles a single literal token. Boolean literals are compiled
+// to constant boolean expressions, identifiers are compiled to unqualified
+// column references. Everything else, such as numbers and strings, is kept as
+// literal expression.
+func compileLiteral(literal token.Token) command.Expr {
+	val := literal.Value()
+	if lower := strings.ToLower(val); lower == "true" || lower == "false" {
+		return command.ConstantBooleanExpr{Value: lower == "true"}
+	}
+	if literal.Type() == token.Literal && isIdentifier(val) {
+		return command.ColumnRef{
+			Column: unquoteIdentifier(val),
+		}
+	}
+	return command.LiteralExpr{Value: val}
+}
+
+// isIdentifier determines whether the value of a literal token is an
+// identifier. Identifiers are either unquoted, or quoted with double quotes.
+// String literals (single quotes) and placeholders are not identifiers.
+func isIdentifier(val string) bool {
+	if val == "" || val[0] == '\'' || isParameter(val) {
+		return false
+	}
+	return !strings.EqualFold(val, "NULL")
+}
+
+// unquoteIdentifier removes the double quotes from a quoted identifier.
+// Unquoted identifiers are returned as they are.
+func unquoteIdentifier(val string) string {
+	if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
+		return strings.ReplaceAll(val[1:len(val)-1], `""`, `"`)
+	}
+	return val
 }
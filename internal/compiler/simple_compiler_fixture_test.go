@@ -7,6 +7,58 @@ func TestCompileGolden(t *testing.T) {
 	t.Run("delete", _TestCompileDelete)
 	t.Run("drop", _TestCompileDrop)
 	t.Run("update", _TestCompileUpdate)
+	t.Run("create", _TestCompileCreate)
+	t.Run("expr", _TestCompileExpr)
+	t.Run("analyze", _TestCompileAnalyze)
+}
+
+func _TestCompileExpr(t *testing.T) {
+	tests := []string{
+		"SELECT CAST(col1 AS VARCHAR(25)) FROM myTable",
+		"SELECT col1 COLLATE NOCASE FROM myTable",
+		"SELECT * FROM myTable WHERE col1 LIKE 'a%'",
+		"SELECT * FROM myTable WHERE col1 NOT LIKE 'a!%' ESCAPE '!'",
+		"SELECT * FROM myTable WHERE col1 GLOB 'a*'",
+		"SELECT * FROM myTable WHERE col1 NOT REGEXP 'a.*'",
+		"SELECT * FROM myTable WHERE col1 MATCH 'a'",
+		"SELECT * FROM myTable WHERE col1 ISNULL",
+		"SELECT * FROM myTable WHERE col1 NOTNULL",
+		"SELECT * FROM myTable WHERE col1 NOT NULL",
+		"SELECT * FROM myTable WHERE col1 IS col2",
+		"SELECT * FROM myTable WHERE col1 IS NOT col2",
+		"SELECT * FROM myTable WHERE col1 IN (1, 2, 3)",
+		"SELECT * FROM myTable WHERE col1 NOT IN (SELECT col2 FROM myOtherTable)",
+		"SELECT * FROM myTable WHERE col1 IN mySchema.myOtherTable",
+		"SELECT * FROM myTable WHERE EXISTS (SELECT * FROM myOtherTable)",
+		"SELECT * FROM myTable WHERE NOT EXISTS (SELECT * FROM myOtherTable WHERE col2 == 5)",
+		"SELECT CASE col1 WHEN 1 THEN 'one' WHEN 2 THEN 'two' ELSE 'many' END FROM myTable",
+		"SELECT CASE WHEN col1 > 5 THEN 'big' END FROM myTable",
+		"SELECT RAISE(IGNORE) FROM myTable",
+		"SELECT RAISE(ABORT, 'error') FROM myTable",
+		"SELECT * FROM myTable WHERE col1 BETWEEN 1 AND 5",
+		"SELECT * FROM myTable WHERE col1 NOT BETWEEN col2 AND col3",
+		"SELECT * FROM myTable WHERE col1 = 1",
+		"SELECT * FROM myTable WHERE col1 != 1",
+		"SELECT * FROM myTable WHERE col1 <> col2",
+	}
+	for _, test := range tests {
+		RunGolden(t, test)
+	}
+}
+
+func _TestCompileCreate(t *testing.T) {
+	tests := []string{
+		"CREATE TABLE myTable (col1, col2)",
+		"CREATE TABLE IF NOT EXISTS mySchema.myTable (col1 VARCHAR(25))",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(25) NOT NULL)",
+		"CREATE TABLE prices (item INTEGER, price DECIMAL(5,2) DEFAULT 0, PRIMARY KEY (item))",
+		"CREATE TABLE myTable (col1 UNIQUE DEFAULT 'none', col2 DEFAULT -1)",
+		"CREATE INDEX myIndex ON myTable (col1)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS mySchema.myIndex ON myTable (col1 DESC, col2)",
+	}
+	for _, test := range tests {
+		RunGolden(t, test)
+	}
 }
 
 func _TestCompileUpdate(t *testing.T) {
@@ -26,6 +78,7 @@ func _TestCompileDelete(t *testing.T) {
 		"DELETE FROM myTable",
 		"DELETE FROM mySchema.myTable",
 		"DELETE FROM myTable WHERE col1 == col2",
+		"DELETE FROM myTable WHERE EXISTS (SELECT * FROM myOtherTable WHERE myOtherTable.col1 == myTable.col1)",
 	}
 	for _, test := range tests {
 		RunGolden(t, test)
@@ -56,6 +109,17 @@ func _TestCompileDrop(t *testing.T) {
 	}
 }
 
+func _TestCompileAnalyze(t *testing.T) {
+	tests := []string{
+		"ANALYZE",
+		"ANALYZE myTable",
+		"ANALYZE mySchema.myTable",
+	}
+	for _, test := range tests {
+		RunGolden(t, test)
+	}
+}
+
 func _TestCompileSelect(t *testing.T) {
 	tests := []string{
 		"SELECT * FROM myTable",
@@ -71,6 +135,43 @@ func _TestCompileSelect(t *testing.T) {
 		"SELECT AVG(price) AS avg_price FROM items LEFT JOIN prices",
 		"SELECT AVG(DISTINCT price) AS avg_price FROM items LEFT JOIN prices",
 		"VALUES (1,2,3),(4,5,6),(7,8,9)",
+		"SELECT t.col1, mySchema.t.col2 FROM t WHERE col3 == 'col4'",
+		"SELECT \"my col\" FROM myTable WHERE \"my col\" == 5",
+		"SELECT * FROM (SELECT col1 FROM myTable) AS sub WHERE sub.col1 > 5",
+		"SELECT * FROM myFunction(1, 'a') AS f",
+		"SELECT col1, (SELECT MAX(col2) FROM myOtherTable) FROM myTable",
+		"SELECT * FROM a WHERE EXISTS (SELECT * FROM b WHERE b.id == a.id)",
+		"SELECT * FROM a AS o WHERE o.col1 IN (SELECT col1 FROM b WHERE b.col2 == o.col2)",
+		"SELECT * FROM a WHERE col1 > (SELECT AVG(c.col1) FROM c WHERE c.id IN (SELECT id FROM b WHERE b.col2 == a.col2))",
+		"SELECT * FROM a JOIN b ON a.id == b.id",
+		"SELECT col1, COUNT(*) FROM myTable GROUP BY col1",
+		"SELECT col1, col2, SUM(col3) AS total FROM myTable WHERE col4 > 5 GROUP BY col1, col2 HAVING SUM(col3) > 10",
+		"SELECT COUNT(DISTINCT col1), MIN(col2), MAX(col2, col3) FROM myTable",
+		"SELECT * FROM myTable GROUP BY col1",
+		"SELECT col1 FROM myTable GROUP BY col1 HAVING MAX(col2) > 5",
+		"SELECT * FROM myTable ORDER BY col1",
+		"SELECT col1, col2 AS c FROM myTable ORDER BY c DESC, 1 NULLS LAST LIMIT 5",
+		"SELECT col1 FROM myTable ORDER BY col2 COLLATE NOCASE DESC NULLS FIRST",
+		"SELECT col1 FROM myTable GROUP BY col1 ORDER BY COUNT(*) DESC",
+		"VALUES (1,2),(3,4) ORDER BY 2 DESC",
+		"SELECT col1 FROM a UNION SELECT col1 FROM b",
+		"SELECT col1, col2 FROM a UNION ALL SELECT col1, col2 FROM b UNION ALL VALUES (1, 2)",
+		"SELECT col1 FROM a INTERSECT SELECT col1 FROM b EXCEPT SELECT col1 FROM c",
+		"SELECT col1 AS c FROM a UNION SELECT col2 FROM b ORDER BY c DESC LIMIT 3",
+		"SELECT col1 FROM a EXCEPT SELECT col1 FROM b ORDER BY 1",
+		"WITH t AS (SELECT col1 FROM a) SELECT * FROM t",
+		"WITH t(c) AS (SELECT col1 FROM a), u AS (SELECT * FROM t) SELECT * FROM t JOIN u ON t.c == u.c",
+		"WITH RECURSIVE cnt(n) AS (VALUES (1) UNION ALL SELECT n * 2 FROM cnt LIMIT 10) SELECT n FROM cnt",
+		"WITH RECURSIVE sub(id) AS (SELECT id FROM emp WHERE id == 1 UNION SELECT emp.id FROM emp JOIN sub ON emp.manager == sub.id) SELECT * FROM sub",
+		"SELECT * FROM a WHERE col1 IN (WITH t AS (SELECT col1 FROM b) SELECT col1 FROM t)",
+		"SELECT col1, row_number() OVER (PARTITION BY col2 ORDER BY col3 DESC) FROM myTable",
+		"SELECT SUM(col1) OVER w, AVG(col1) OVER (w ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING EXCLUDE CURRENT ROW) FROM myTable WINDOW w AS (ORDER BY col2)",
+		"SELECT COUNT(*) FILTER (WHERE col1 > 5) OVER (ORDER BY col2 GROUPS BETWEEN UNBOUNDED PRECEDING AND 1 FOLLOWING EXCLUDE TIES) FROM myTable",
+		"SELECT col1, rank() OVER (ORDER BY SUM(col2) DESC) AS r FROM myTable GROUP BY col1 ORDER BY r",
+		"SELECT COUNT(*) FILTER (WHERE col1 > 5), COUNT(*) FROM myTable",
+		"SELECT * FROM a, b, c WHERE a.id == c.id",
+		"SELECT * FROM a JOIN b USING (id, name)",
+		"SELECT * FROM a NATURAL LEFT JOIN b, c",
 	}
 	for _, test := range tests {
 		RunGolden(t, test)
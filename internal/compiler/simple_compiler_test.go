@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/compiler/optimization"
 	"github.com/tomarrell/lbadd/internal/parser"
 )
 
@@ -21,6 +22,149 @@ func Test_simpleCompiler_Compile_NoOptimizations(t *testing.T) {
 	t.Run("drop", _TestSimpleCompilerCompileDropNoOptimizations)
 	t.Run("update", _TestSimpleCompilerCompileUpdateNoOptimizations)
 	t.Run("insert", _TestSimpleCompilerCompileInsertNoOptimizations)
+	t.Run("create", _TestSimpleCompilerCompileCreateNoOptimizations)
+	t.Run("analyze", _TestSimpleCompilerCompileAnalyzeNoOptimizations)
+}
+
+func _TestSimpleCompilerCompileAnalyzeNoOptimizations(t *testing.T) {
+	tests := []testcase{
+		{
+			"analyze all",
+			"ANALYZE",
+			command.Analyze{},
+			false,
+		},
+		{
+			"analyze table or schema",
+			"ANALYZE myTable",
+			command.Analyze{
+				Name: "myTable",
+			},
+			false,
+		},
+		{
+			"qualified analyze",
+			"ANALYZE mySchema.myTable",
+			command.Analyze{
+				Schema: "mySchema",
+				Name:   "myTable",
+			},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, _TestCompile(tt))
+	}
+}
+
+func _TestSimpleCompilerCompileCreateNoOptimizations(t *testing.T) {
+	tests := []testcase{
+		{
+			"simple create table",
+			"CREATE TABLE myTable (col1, col2)",
+			command.CreateTable{
+				Name: "myTable",
+				Columns: []command.ColumnDef{
+					{Name: "col1"},
+					{Name: "col2"},
+				},
+			},
+			false,
+		},
+		{
+			"create table autoincrement",
+			"CREATE TABLE IF NOT EXISTS mySchema.users (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(25) NOT NULL)",
+			command.CreateTable{
+				IfNotExists: true,
+				Schema:      "mySchema",
+				Name:        "users",
+				Columns: []command.ColumnDef{
+					{
+						Name:          "id",
+						Type:          "INTEGER",
+						PrimaryKey:    true,
+						Autoincrement: true,
+					},
+					{
+						Name:    "name",
+						Type:    "VARCHAR(25)",
+						NotNull: true,
+					},
+				},
+			},
+			false,
+		},
+		{
+			"create table primary key constraint",
+			"CREATE TABLE prices (item INTEGER, price DECIMAL(5,2) DEFAULT 0, PRIMARY KEY (item))",
+			command.CreateTable{
+				Name: "prices",
+				Columns: []command.ColumnDef{
+					{
+						Name:       "item",
+						Type:       "INTEGER",
+						PrimaryKey: true,
+					},
+					{
+						Name:    "price",
+						Type:    "DECIMAL(5,2)",
+						Default: command.LiteralExpr{Value: "0"},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"autoincrement on non integer primary key",
+			"CREATE TABLE users (id VARCHAR(25) PRIMARY KEY AUTOINCREMENT)",
+			nil,
+			true,
+		},
+		{
+			"autoincrement on composite primary key",
+			"CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name, PRIMARY KEY (id, name))",
+			nil,
+			true,
+		},
+		{
+			"create index",
+			"CREATE INDEX myIndex ON myTable (col1)",
+			command.CreateIndex{
+				Name:    "myIndex",
+				Table:   "myTable",
+				Columns: []string{"col1"},
+			},
+			false,
+		},
+		{
+			"create unique index",
+			"CREATE UNIQUE INDEX IF NOT EXISTS mySchema.myIndex ON myTable (col1 DESC, col2 ASC)",
+			command.CreateIndex{
+				Unique:      true,
+				IfNotExists: true,
+				Schema:      "mySchema",
+				Name:        "myIndex",
+				Table:       "myTable",
+				Columns:     []string{"col1", "col2"},
+			},
+			false,
+		},
+		{
+			"partial index",
+			"CREATE INDEX myIndex ON myTable (col1) WHERE col1 > 5",
+			nil,
+			true,
+		},
+		{
+			"index with collation",
+			"CREATE INDEX myIndex ON myTable (col1 COLLATE NOCASE)",
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, _TestCompile(tt))
+	}
 }
 
 func _TestSimpleCompilerCompileInsertNoOptimizations(t *testing.T) {
@@ -117,6 +261,71 @@ func _TestSimpleCompilerCompileInsertNoOptimizations(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"replace",
+			"REPLACE INTO myTable VALUES (1)",
+			command.Insert{
+				InsertOr: command.InsertOrReplace,
+				Table:    command.SimpleTable{Table: "myTable"},
+				Input: command.Values{
+					Values: [][]command.Expr{
+						{command.LiteralExpr{Value: "1"}},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"upsert do nothing",
+			"INSERT INTO myTable VALUES (1) ON CONFLICT DO NOTHING",
+			command.Insert{
+				Table: command.SimpleTable{Table: "myTable"},
+				Input: command.Values{
+					Values: [][]command.Expr{
+						{command.LiteralExpr{Value: "1"}},
+					},
+				},
+				OnConflict: &command.OnConflict{},
+			},
+			false,
+		},
+		{
+			"upsert do update",
+			"INSERT INTO myTable (id, col) VALUES (1, 2) ON CONFLICT (id) DO UPDATE SET col = excluded.col WHERE col < excluded.col",
+			command.Insert{
+				Table: command.SimpleTable{Table: "myTable"},
+				Cols: []command.Column{
+					{Column: command.LiteralExpr{Value: "id"}},
+					{Column: command.LiteralExpr{Value: "col"}},
+				},
+				Input: command.Values{
+					Values: [][]command.Expr{
+						{command.LiteralExpr{Value: "1"}, command.LiteralExpr{Value: "2"}},
+					},
+				},
+				OnConflict: &command.OnConflict{
+					Target: []string{"id"},
+					Updates: []command.UpdateSetter{
+						{
+							Cols:  []string{"col"},
+							Value: command.ColumnRef{Table: "excluded", Column: "col"},
+						},
+					},
+					Filter: command.BinaryExpr{
+						Operator: "<",
+						Left:     command.ColumnRef{Column: "col"},
+						Right:    command.ColumnRef{Table: "excluded", Column: "col"},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"upsert with expression target",
+			"INSERT INTO myTable VALUES (1) ON CONFLICT (lower(col)) DO NOTHING",
+			nil,
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, _TestCompile(tt))
@@ -151,10 +360,9 @@ func _TestSimpleCompilerCompileUpdateNoOptimizations(t *testing.T) {
 				Table: command.SimpleTable{
 					Table: "myTable",
 				},
-				Filter: command.BinaryExpr{
-					Left:     command.LiteralExpr{Value: "myOtherCol"},
-					Operator: "==",
-					Right:    command.LiteralExpr{Value: "9"},
+				Filter: command.EqualityExpr{
+					Left:  command.ColumnRef{Column: "myOtherCol"},
+					Right: command.LiteralExpr{Value: "9"},
 				},
 				Updates: []command.UpdateSetter{
 					{
@@ -173,10 +381,9 @@ func _TestSimpleCompilerCompileUpdateNoOptimizations(t *testing.T) {
 				Table: command.SimpleTable{
 					Table: "myTable",
 				},
-				Filter: command.BinaryExpr{
-					Left:     command.LiteralExpr{Value: "myOtherCol"},
-					Operator: "==",
-					Right:    command.LiteralExpr{Value: "9"},
+				Filter: command.EqualityExpr{
+					Left:  command.ColumnRef{Column: "myOtherCol"},
+					Right: command.LiteralExpr{Value: "9"},
 				},
 				Updates: []command.UpdateSetter{
 					{
@@ -381,10 +588,9 @@ func _TestSimpleCompilerCompileDeleteNoOptimizations(t *testing.T) {
 				Table: command.SimpleTable{
 					Table: "myTable",
 				},
-				Filter: command.BinaryExpr{
-					Left:     command.LiteralExpr{Value: "col1"},
-					Operator: "==",
-					Right:    command.LiteralExpr{Value: "col2"},
+				Filter: command.EqualityExpr{
+					Left:  command.ColumnRef{Column: "col1"},
+					Right: command.ColumnRef{Column: "col2"},
 				},
 			},
 			false,
@@ -558,6 +764,7 @@ func _TestSimpleCompilerCompileSelectNoOptimizations(t *testing.T) {
 				Input: command.Select{
 					Filter: command.ConstantBooleanExpr{Value: true},
 					Input: command.Join{
+						Type: command.JoinCross,
 						Left: command.Scan{
 							Table: command.SimpleTable{
 								Table: "a",
@@ -613,6 +820,7 @@ func _TestSimpleCompilerCompileSelectNoOptimizations(t *testing.T) {
 					Filter: command.ConstantBooleanExpr{Value: true},
 					Input: command.Join{
 						Left: command.Join{
+							Type: command.JoinCross,
 							Left: command.Scan{
 								Table: command.SimpleTable{
 									Table: "a",
@@ -640,13 +848,13 @@ func _TestSimpleCompilerCompileSelectNoOptimizations(t *testing.T) {
 			command.Project{
 				Cols: []command.Column{
 					{
-						Column: command.LiteralExpr{Value: "name"},
+						Column: command.ColumnRef{Column: "name"},
 					},
 					{
 						Column: command.BinaryExpr{
 							Operator: "*",
-							Left:     command.LiteralExpr{Value: "amount"},
-							Right:    command.LiteralExpr{Value: "price"},
+							Left:     command.ColumnRef{Column: "amount"},
+							Right:    command.ColumnRef{Column: "price"},
 						},
 						Alias: "total_price",
 					},
@@ -672,19 +880,30 @@ func _TestSimpleCompilerCompileSelectNoOptimizations(t *testing.T) {
 							Name:     "AVG",
 							Distinct: false,
 							Args: []command.Expr{
-								command.LiteralExpr{Value: "price"},
+								command.ColumnRef{Column: "price"},
 							},
 						},
 						Alias: "avg_price",
 					},
 				},
-				Input: command.Join{
-					Type: command.JoinLeft,
-					Left: command.Scan{
-						Table: command.SimpleTable{Table: "items"},
+				Input: command.Aggregate{
+					Aggregates: []command.FunctionExpr{
+						{
+							Name:     "AVG",
+							Distinct: false,
+							Args: []command.Expr{
+								command.ColumnRef{Column: "price"},
+							},
+						},
 					},
-					Right: command.Scan{
-						Table: command.SimpleTable{Table: "prices"},
+					Input: command.Join{
+						Type: command.JoinLeft,
+						Left: command.Scan{
+							Table: command.SimpleTable{Table: "items"},
+						},
+						Right: command.Scan{
+							Table: command.SimpleTable{Table: "prices"},
+						},
 					},
 				},
 			},
@@ -700,24 +919,344 @@ func _TestSimpleCompilerCompileSelectNoOptimizations(t *testing.T) {
 							Name:     "AVG",
 							Distinct: true,
 							Args: []command.Expr{
-								command.LiteralExpr{Value: "price"},
+								command.ColumnRef{Column: "price"},
 							},
 						},
 						Alias: "avg_price",
 					},
 				},
-				Input: command.Join{
-					Type: command.JoinLeft,
-					Left: command.Scan{
+				Input: command.Aggregate{
+					Aggregates: []command.FunctionExpr{
+						{
+							Name:     "AVG",
+							Distinct: true,
+							Args: []command.Expr{
+								command.ColumnRef{Column: "price"},
+							},
+						},
+					},
+					Input: command.Join{
+						Type: command.JoinLeft,
+						Left: command.Scan{
+							Table: command.SimpleTable{Table: "items"},
+						},
+						Right: command.Scan{
+							Table: command.SimpleTable{Table: "prices"},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"select qualified columns",
+			"SELECT items.name, mySchema.prices.price, \"my col\" FROM items WHERE name == 'name'",
+			command.Project{
+				Cols: []command.Column{
+					{
+						Column: command.ColumnRef{Table: "items", Column: "name"},
+					},
+					{
+						Column: command.ColumnRef{Schema: "mySchema", Table: "prices", Column: "price"},
+					},
+					{
+						Column: command.ColumnRef{Column: "my col"},
+					},
+				},
+				Input: command.Select{
+					Filter: command.EqualityExpr{
+						Left:  command.ColumnRef{Column: "name"},
+						Right: command.LiteralExpr{Value: "'name'"},
+					},
+					Input: command.Scan{
 						Table: command.SimpleTable{Table: "items"},
 					},
-					Right: command.Scan{
-						Table: command.SimpleTable{Table: "prices"},
+				},
+			},
+			false,
+		},
+		{
+			"select correlated subquery",
+			"SELECT * FROM items AS i WHERE EXISTS (SELECT * FROM prices WHERE prices.item == i.id)",
+			command.Project{
+				Cols: []command.Column{
+					{
+						Column: command.LiteralExpr{Value: "*"},
+					},
+				},
+				Input: command.Select{
+					Filter: command.ExistsExpr{
+						Input: command.Project{
+							Cols: []command.Column{
+								{
+									Column: command.LiteralExpr{Value: "*"},
+								},
+							},
+							Input: command.Select{
+								Filter: command.EqualityExpr{
+									Left:  command.ColumnRef{Table: "prices", Column: "item"},
+									Right: command.ColumnRef{Table: "i", Column: "id", Depth: 1},
+								},
+								Input: command.Scan{
+									Table: command.SimpleTable{Table: "prices"},
+								},
+							},
+						},
+					},
+					Input: command.Scan{
+						Table: command.SimpleTable{Table: "items", Alias: "i"},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"select from subquery",
+			"SELECT sub.name FROM (SELECT name FROM items) AS sub",
+			command.Project{
+				Cols: []command.Column{
+					{
+						Column: command.ColumnRef{Table: "sub", Column: "name"},
+					},
+				},
+				Input: command.Scan{
+					Table: command.SubqueryTable{
+						Input: command.Project{
+							Cols: []command.Column{
+								{
+									Column: command.ColumnRef{Column: "name"},
+								},
+							},
+							Input: command.Scan{
+								Table: command.SimpleTable{Table: "items"},
+							},
+						},
+						Alias: "sub",
+					},
+				},
+			},
+			false,
+		},
+		{
+			"select group by",
+			"SELECT name, SUM(amount) FROM items GROUP BY name HAVING COUNT(*) > 1",
+			command.Project{
+				Cols: []command.Column{
+					{
+						Column: command.ColumnRef{Column: "name"},
+					},
+					{
+						Column: command.FunctionExpr{
+							Name: "SUM",
+							Args: []command.Expr{command.ColumnRef{Column: "amount"}},
+						},
+					},
+				},
+				Input: command.Aggregate{
+					GroupBy: []command.Expr{
+						command.ColumnRef{Column: "name"},
+					},
+					Aggregates: []command.FunctionExpr{
+						{
+							Name: "SUM",
+							Args: []command.Expr{command.ColumnRef{Column: "amount"}},
+						},
+						{
+							Name: "COUNT",
+							Args: []command.Expr{command.LiteralExpr{Value: "*"}},
+						},
+					},
+					Having: command.BinaryExpr{
+						Operator: ">",
+						Left: command.FunctionExpr{
+							Name: "COUNT",
+							Args: []command.Expr{command.LiteralExpr{Value: "*"}},
+						},
+						Right: command.LiteralExpr{Value: "1"},
+					},
+					Input: command.Scan{
+						Table: command.SimpleTable{Table: "items"},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"aggregate in where",
+			"SELECT name FROM items WHERE COUNT(*) > 1",
+			nil,
+			true,
+		},
+		{
+			"nested aggregate",
+			"SELECT SUM(COUNT(*)) FROM items",
+			nil,
+			true,
+		},
+		{
+			"order by position out of range",
+			"SELECT name FROM items ORDER BY 2",
+			nil,
+			true,
+		},
+		{
+			"compound column count mismatch",
+			"SELECT name FROM items UNION SELECT name, price FROM prices",
+			nil,
+			true,
+		},
+		{
+			"compound order by unknown column",
+			"SELECT name FROM items UNION SELECT name FROM prices ORDER BY price",
+			nil,
+			true,
+		},
+		{
+			"with column count mismatch",
+			"WITH t(a, b) AS (SELECT name FROM items) SELECT * FROM t",
+			nil,
+			true,
+		},
+		{
+			"with duplicate table name",
+			"WITH t AS (SELECT name FROM items), t AS (SELECT name FROM prices) SELECT * FROM t",
+			nil,
+			true,
+		},
+		{
+			"recursive reference in initial select",
+			"WITH RECURSIVE t(n) AS (SELECT n FROM t UNION SELECT n FROM t) SELECT * FROM t",
+			nil,
+			true,
+		},
+		{
+			"recursive reference without union",
+			"WITH RECURSIVE t(n) AS (SELECT n FROM t) SELECT * FROM t",
+			nil,
+			true,
+		},
+		{
+			"window function without over",
+			"SELECT row_number() FROM items",
+			nil,
+			true,
+		},
+		{
+			"filter on scalar function",
+			"SELECT abs(price) FILTER (WHERE price > 1) FROM items",
+			nil,
+			true,
+		},
+		{
+			"scalar function over window",
+			"SELECT abs(price) OVER (ORDER BY name) FROM items",
+			nil,
+			true,
+		},
+		{
+			"window function in where",
+			"SELECT name FROM items WHERE row_number() OVER () > 1",
+			nil,
+			true,
+		},
+		{
+			"window function in aggregate",
+			"SELECT SUM(row_number() OVER ()) FROM items",
+			nil,
+			true,
+		},
+		{
+			"unknown named window",
+			"SELECT SUM(price) OVER w FROM items",
+			nil,
+			true,
+		},
+		{
+			"override partition of named window",
+			"SELECT SUM(price) OVER (w PARTITION BY name) FROM items WINDOW w AS (ORDER BY price)",
+			nil,
+			true,
+		},
+		{
+			"range offset without order",
+			"SELECT SUM(price) OVER (RANGE 1 PRECEDING) FROM items",
+			nil,
+			true,
+		},
+		{
+			"frame ends before it starts",
+			"SELECT SUM(price) OVER (ORDER BY price ROWS BETWEEN CURRENT ROW AND 1 PRECEDING) FROM items",
+			nil,
+			true,
+		},
+		{
+			"duplicate column in using",
+			"SELECT * FROM a JOIN b USING (id, ID)",
+			nil,
+			true,
+		},
+		{
+			"natural join with using",
+			"SELECT * FROM a NATURAL JOIN b USING (id)",
+			nil,
+			true,
+		},
+		{
+			"natural join with on",
+			"SELECT * FROM a NATURAL JOIN b ON a.id == b.id",
+			nil,
+			true,
+		},
+		{
+			"parameters",
+			"SELECT ?, :a, ?5, @a, :a, ? FROM myTable",
+			command.Project{
+				Cols: []command.Column{
+					{Column: command.ParameterExpr{Index: 1}},
+					{Column: command.ParameterExpr{Name: ":a", Index: 2}},
+					{Column: command.ParameterExpr{Index: 5}},
+					{Column: command.ParameterExpr{Name: "@a", Index: 6}},
+					{Column: command.ParameterExpr{Name: ":a", Index: 2}},
+					{Column: command.ParameterExpr{Index: 7}},
+				},
+				Input: command.Scan{
+					Table: command.SimpleTable{
+						Table: "myTable",
 					},
 				},
 			},
 			false,
 		},
+		{
+			"parameter in filter",
+			"SELECT * FROM myTable WHERE col < $max",
+			command.Project{
+				Cols: []command.Column{
+					{
+						Column: command.LiteralExpr{Value: "*"},
+					},
+				},
+				Input: command.Select{
+					Filter: command.BinaryExpr{
+						Operator: "<",
+						Left:     command.ColumnRef{Column: "col"},
+						Right:    command.ParameterExpr{Name: "$max", Index: 1},
+					},
+					Input: command.Scan{
+						Table: command.SimpleTable{
+							Table: "myTable",
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"parameter index out of range",
+			"SELECT ?0 FROM myTable",
+			nil,
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, _TestCompile(tt))
@@ -744,3 +1283,38 @@ func _TestCompile(tt testcase) func(t *testing.T) {
 		assert.Equal(tt.want, got)
 	}
 }
+
+func Test_simpleCompiler_Compile_Rules(t *testing.T) {
+	assert := assert.New(t)
+
+	// removeDistinct is not a valid optimization, but it fires on every
+	// compiled select distinct
+	removeDistinct := optimization.Rule{
+		Name: "RemoveDistinct",
+		Apply: func(cmd command.Command) (command.Command, bool) {
+			if distinct, ok := cmd.(command.Distinct); ok {
+				return distinct.Input, true
+			}
+			return nil, false
+		},
+	}
+	c := New(OptionEnableRules(removeDistinct, optimization.HalfJoin))
+
+	stmt, errs, ok := parser.New("EXPLAIN SELECT DISTINCT col FROM myTable").Next()
+	assert.True(ok)
+	assert.Len(errs, 0)
+	got, err := c.Compile(stmt)
+	assert.NoError(err)
+	assert.Equal(command.Explain{
+		Command: command.Project{
+			Cols: []command.Column{
+				{Column: command.ColumnRef{Column: "col"}},
+			},
+			Input: command.Scan{
+				Table: command.SimpleTable{Table: "myTable"},
+			},
+		},
+		Optimizations: []string{"RemoveDistinct"},
+	}, got)
+	assert.Equal("explanation[optimizations=RemoveDistinct]: Project[cols=col](Scan[table=myTable]())", got.String())
+}
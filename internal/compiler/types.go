@@ -0,0 +1,185 @@
+package compiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/database/column"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// functionTypes are the result types of all functions, whose result type does
+// not depend on their arguments, by their upper case name.
+var functionTypes = map[string]column.BaseType{
+	"AVG":          column.Decimal,
+	"COUNT":        column.Integer,
+	"DENSE_RANK":   column.Integer,
+	"GROUP_CONCAT": column.Varchar,
+	"LENGTH":       column.Integer,
+	"LOWER":        column.Varchar,
+	"NTILE":        column.Integer,
+	"RANK":         column.Integer,
+	"ROUND":        column.Decimal,
+	"ROW_NUMBER":   column.Integer,
+	"SUBSTR":       column.Varchar,
+	"TOTAL":        column.Decimal,
+	"TYPEOF":       column.Varchar,
+	"UPPER":        column.Varchar,
+}
+
+// typeOf infers the type of the given expression. Column references are
+// resolved with the given function, or against the visible tables if it is
+// nil. If the type can't be inferred, column.Unknown is returned.
+func (c *simpleCompiler) typeOf(expr command.Expr, resolve func(command.ColumnRef) (boundColumn, error)) column.BaseType {
+	if resolve == nil {
+		resolve = c.lookupColumn
+	}
+
+	switch e := expr.(type) {
+	case command.LiteralExpr:
+		return literalType(e.Value)
+	case command.ConstantBooleanExpr:
+		return column.Integer
+	case command.ColumnRef:
+		col, err := resolve(e)
+		if err != nil {
+			return column.Unknown
+		}
+		return col.typ
+	case command.UnaryExpr:
+		if strings.EqualFold(e.Operator, "NOT") || e.Operator == "~" {
+			return column.Integer
+		}
+		return numericType(c.typeOf(e.Value, resolve), column.Integer)
+	case command.BinaryExpr:
+		switch strings.ToUpper(e.Operator) {
+		case "+", "-", "*", "/", "%":
+			return numericType(c.typeOf(e.Left, resolve), c.typeOf(e.Right, resolve))
+		case "||":
+			return column.Varchar
+		}
+		return column.Integer
+	case command.EqualityExpr, command.RangeExpr, command.IsExpr, command.InExpr, command.ExistsExpr, command.PatternExpr:
+		return column.Integer
+	case command.CollateExpr:
+		return c.typeOf(e.Value, resolve)
+	case command.CastExpr:
+		return castType(e.Type)
+	case command.FunctionExpr:
+		if typ, ok := functionTypes[strings.ToUpper(e.Name)]; ok {
+			return typ
+		}
+		switch strings.ToUpper(e.Name) {
+		case "ABS", "SUM":
+			if len(e.Args) != 0 {
+				return numericType(c.typeOf(e.Args[0], resolve), column.Integer)
+			}
+		case "MIN", "MAX", "FIRST_VALUE", "LAST_VALUE", "LAG", "LEAD", "COALESCE", "IFNULL", "NULLIF":
+			if len(e.Args) != 0 {
+				return c.typeOf(e.Args[0], resolve)
+			}
+		}
+	case command.SubqueryExpr:
+		cols, err := c.bindColumns(e.Input)
+		if err == nil && len(cols) != 0 {
+			return cols[0].typ
+		}
+	}
+	return column.Unknown
+}
+
+// checkTypes reports a problem at the given token, if the operands of the
+// given operator or function call have types, that the operator or function
+// can't be applied to. Numbers can't be computed from or compared with texts.
+func (c *simpleCompiler) checkTypes(at token.Token, expr command.Expr) {
+	if c.catalog == nil {
+		return
+	}
+
+	switch e := expr.(type) {
+	case command.BinaryExpr:
+		left, right := c.typeOf(e.Left, nil), c.typeOf(e.Right, nil)
+		switch strings.ToUpper(e.Operator) {
+		case "+", "-", "*", "/", "%":
+			for _, typ := range []column.BaseType{left, right} {
+				if typ == column.Varchar {
+					c.reportProblem(at, fmt.Errorf("%w: cannot apply %v to %v", ErrTypeMismatch, e.Operator, typeName(typ)))
+					return
+				}
+			}
+		case "<", "<=", ">", ">=":
+			c.checkComparison(at, left, right)
+		}
+	case command.EqualityExpr:
+		c.checkComparison(at, c.typeOf(e.Left, nil), c.typeOf(e.Right, nil))
+	case command.FunctionExpr:
+		switch strings.ToUpper(e.Name) {
+		case "SUM", "TOTAL", "AVG", "ABS":
+			if len(e.Args) != 0 && c.typeOf(e.Args[0], nil) == column.Varchar {
+				c.reportProblem(at, fmt.Errorf("%w: cannot compute %v of %v", ErrTypeMismatch, strings.ToUpper(e.Name), typeName(column.Varchar)))
+			}
+		}
+	}
+}
+
+// checkComparison reports a problem at the given token, if a value of one of
+// the given types is a number and the other one is a text.
+func (c *simpleCompiler) checkComparison(at token.Token, left, right column.BaseType) {
+	if (isNumericType(left) && right == column.Varchar) || (left == column.Varchar && isNumericType(right)) {
+		c.reportProblem(at, fmt.Errorf("%w: cannot compare %v with %v", ErrTypeMismatch, typeName(left), typeName(right)))
+	}
+}
+
+// literalType returns the type of the given literal value. Literals that
+// are neither numbers nor texts, such as NULL, have no type.
+func literalType(literal string) column.BaseType {
+	if strings.HasPrefix(literal, "'") {
+		return column.Varchar
+	}
+	if _, err := strconv.ParseInt(literal, 0, 64); err == nil {
+		return column.Integer
+	}
+	if _, err := strconv.ParseFloat(literal, 64); err == nil {
+		return column.Decimal
+	}
+	return column.Unknown
+}
+
+// castType returns the type, that a value is converted to by a CAST with the
+// given type name, which follows the type affinity rules.
+func castType(name string) column.BaseType {
+	name = strings.ToUpper(name)
+	switch {
+	case strings.Contains(name, "INT"):
+		return column.Integer
+	case strings.Contains(name, "CHAR"), strings.Contains(name, "CLOB"), strings.Contains(name, "TEXT"):
+		return column.Varchar
+	case strings.Contains(name, "REAL"), strings.Contains(name, "FLOA"), strings.Contains(name, "DOUB"), strings.Contains(name, "DEC"), strings.Contains(name, "NUM"):
+		return column.Decimal
+	}
+	return column.Unknown
+}
+
+// numericType returns the type of the result of an arithmetic operation on
+// values of the given types. If any of the types is not numeric, the type of
+// the result is unknown.
+func numericType(left, right column.BaseType) column.BaseType {
+	switch {
+	case !isNumericType(left) || !isNumericType(right):
+		return column.Unknown
+	case left == column.Decimal || right == column.Decimal:
+		return column.Decimal
+	}
+	return column.Integer
+}
+
+func isNumericType(typ column.BaseType) bool {
+	return typ == column.Integer || typ == column.Decimal
+}
+
+// typeName returns the upper case name of the given type.
+func typeName(typ column.BaseType) string {
+	return strings.ToUpper(typ.String())
+}
@@ -0,0 +1,259 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+)
+
+// windowFunctions are the names of all known functions, that can only be used
+// as window functions, in upper case.
+var windowFunctions = map[string]struct{}{
+	"DENSE_RANK":  {},
+	"FIRST_VALUE": {},
+	"LAG":         {},
+	"LAST_VALUE":  {},
+	"LEAD":        {},
+	"NTILE":       {},
+	"RANK":        {},
+	"ROW_NUMBER":  {},
+}
+
+// isWindowFunction determines whether the given function call is a call to a
+// function, that can only be used as window function.
+func isWindowFunction(fn command.FunctionExpr) bool {
+	_, ok := windowFunctions[strings.ToUpper(fn.Name)]
+	return ok
+}
+
+// collectWindows appends all window function calls within the given expression
+// to the given windows, if they are not already contained. Window function
+// calls within nested queries belong to the nested query and are not
+// collected. An error is returned if a window function call is nested within
+// another window function call.
+func collectWindows(windows []command.FunctionExpr, expr command.Expr) ([]command.FunctionExpr, error) {
+	if expr == nil {
+		return windows, nil
+	}
+	if fn, ok := expr.(command.FunctionExpr); ok && fn.Over != nil {
+		for _, child := range childExprs(fn) {
+			if nested, _ := collectWindows(nil, child); len(nested) != 0 {
+				return nil, fmt.Errorf("misuse of window function %v", nested[0].Name)
+			}
+		}
+		for _, window := range windows {
+			if window.String() == fn.String() {
+				return windows, nil
+			}
+		}
+		return append(windows, fn), nil
+	}
+
+	var err error
+	for _, child := range childExprs(expr) {
+		windows, err = collectWindows(windows, child)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return windows, nil
+}
+
+// compileWindow wraps the given input into a window, that computes all window
+// function calls of the given result columns and sort terms. If there are no
+// window function calls, the input is returned unchanged.
+func (c *simpleCompiler) compileWindow(cols []command.Column, terms []command.SortTerm, input command.List) (command.List, error) {
+	var windows []command.FunctionExpr
+	var err error
+	for _, col := range cols {
+		windows, err = collectWindows(windows, col.Column)
+		if err != nil {
+			return nil, fmt.Errorf("result column: %w", err)
+		}
+	}
+	for _, term := range terms {
+		windows, err = collectWindows(windows, term.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("order: %w", err)
+		}
+	}
+
+	if len(windows) == 0 {
+		return input, nil
+	}
+	return command.Window{
+		Functions: windows,
+		Input:     input,
+	}, nil
+}
+
+// compileOverClause compiles the window of the given OVER clause. The window
+// may be a named window of the current select core, or be based on one.
+func (c *simpleCompiler) compileOverClause(over *ast.OverClause) (*command.WindowSpec, error) {
+	var window command.WindowSpec
+	var err error
+	if over.WindowName != nil {
+		window, _, err = c.compileNamedWindow(over.WindowName.Value(), nil)
+	} else {
+		window, _, err = c.compileWindowDefn(&ast.WindowDefn{
+			BaseWindowName: over.BaseWindowName,
+			Expr:           over.Expr,
+			OrderingTerm:   over.OrderingTerm,
+			FrameSpec:      over.FrameSpec,
+		}, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// compileNamedWindow compiles the named window with the given name. The given
+// names are the names of the windows that are already being compiled, which
+// must not be referenced again. The returned flag indicates that the window
+// has an explicit frame.
+func (c *simpleCompiler) compileNamedWindow(name string, compiling []string) (command.WindowSpec, bool, error) {
+	for _, other := range compiling {
+		if strings.EqualFold(name, other) {
+			return command.WindowSpec{}, false, fmt.Errorf("circular reference to window %v", name)
+		}
+	}
+	defn, ok := c.windows[strings.ToLower(name)]
+	if !ok {
+		return command.WindowSpec{}, false, fmt.Errorf("no such window: %v", name)
+	}
+	return c.compileWindowDefn(defn, append(compiling, name))
+}
+
+// compileWindowDefn compiles the given window definition. If the definition
+// is based on another window, the partition, the order and the frame are
+// taken from that window, and may only be extended, but not overridden. The
+// returned flag indicates that the window has an explicit frame.
+func (c *simpleCompiler) compileWindowDefn(defn *ast.WindowDefn, compiling []string) (command.WindowSpec, bool, error) {
+	var window command.WindowSpec
+	var explicitFrame bool
+	if defn.BaseWindowName != nil {
+		baseName := defn.BaseWindowName.Value()
+		base, baseFrame, err := c.compileNamedWindow(baseName, compiling)
+		if err != nil {
+			return command.WindowSpec{}, false, err
+		}
+		switch {
+		case len(defn.Expr) != 0:
+			return command.WindowSpec{}, false, fmt.Errorf("cannot override PARTITION clause of window %v", baseName)
+		case len(defn.OrderingTerm) != 0 && len(base.Order) != 0:
+			return command.WindowSpec{}, false, fmt.Errorf("cannot override ORDER BY clause of window %v", baseName)
+		case baseFrame:
+			return command.WindowSpec{}, false, fmt.Errorf("cannot override frame specification of window %v", baseName)
+		}
+		window = base
+	}
+
+	for _, expr := range defn.Expr {
+		compiled, err := c.compileExpr(expr)
+		if err != nil {
+			return command.WindowSpec{}, false, fmt.Errorf("partition: %w", err)
+		}
+		window.Partition = append(window.Partition, compiled)
+	}
+	for _, term := range defn.OrderingTerm {
+		compiled, err := c.compileOrderingTerm(term)
+		if err != nil {
+			return command.WindowSpec{}, false, fmt.Errorf("order: %w", err)
+		}
+		window.Order = append(window.Order, compiled)
+	}
+
+	if defn.FrameSpec != nil {
+		frame, err := c.compileFrameSpec(defn.FrameSpec)
+		if err != nil {
+			return command.WindowSpec{}, false, fmt.Errorf("frame: %w", err)
+		}
+		window.Frame = frame
+		explicitFrame = true
+	} else if window.Frame.Unit == command.FrameUnknown {
+		// the default frame consists of all datasets up to the last peer of
+		// the current dataset
+		window.Frame = command.Frame{
+			Unit:  command.FrameRange,
+			Start: command.FrameBound{Type: command.BoundUnboundedPreceding},
+			End:   command.FrameBound{Type: command.BoundCurrentRow},
+		}
+	}
+
+	if window.Frame.Unit == command.FrameRange && len(window.Order) != 1 &&
+		(window.Frame.Start.Offset != nil || window.Frame.End.Offset != nil) {
+		return command.WindowSpec{}, false, fmt.Errorf("RANGE with offset PRECEDING/FOLLOWING requires one ORDER BY term")
+	}
+	return window, explicitFrame, nil
+}
+
+// compileFrameSpec compiles the given frame specification. If the
+// specification has no end bound, the frame ends at the current dataset.
+func (c *simpleCompiler) compileFrameSpec(spec *ast.FrameSpec) (command.Frame, error) {
+	var frame command.Frame
+	switch {
+	case spec.Rows != nil:
+		frame.Unit = command.FrameRows
+	case spec.Range != nil:
+		frame.Unit = command.FrameRange
+	case spec.Groups != nil:
+		frame.Unit = command.FrameGroups
+	default:
+		return command.Frame{}, fmt.Errorf("missing frame unit")
+	}
+
+	var err error
+	switch {
+	case spec.Unbounded1 != nil:
+		frame.Start.Type = command.BoundUnboundedPreceding
+	case spec.Current1 != nil:
+		frame.Start.Type = command.BoundCurrentRow
+	case spec.Expr1 != nil:
+		frame.Start.Type = command.BoundPreceding
+		if spec.Following1 != nil {
+			frame.Start.Type = command.BoundFollowing
+		}
+		frame.Start.Offset, err = c.compileExpr(spec.Expr1)
+		if err != nil {
+			return command.Frame{}, fmt.Errorf("start: %w", err)
+		}
+	default:
+		return command.Frame{}, fmt.Errorf("missing frame start")
+	}
+
+	switch {
+	case spec.Between == nil, spec.Current2 != nil:
+		frame.End.Type = command.BoundCurrentRow
+	case spec.Unbounded2 != nil:
+		frame.End.Type = command.BoundUnboundedFollowing
+	case spec.Expr2 != nil:
+		frame.End.Type = command.BoundFollowing
+		if spec.Preceding2 != nil {
+			frame.End.Type = command.BoundPreceding
+		}
+		frame.End.Offset, err = c.compileExpr(spec.Expr2)
+		if err != nil {
+			return command.Frame{}, fmt.Errorf("end: %w", err)
+		}
+	default:
+		return command.Frame{}, fmt.Errorf("missing frame end")
+	}
+
+	// a frame must not end before it starts
+	if frame.Start.Type > frame.End.Type {
+		return command.Frame{}, fmt.Errorf("unsupported frame specification %v AND %v", frame.Start, frame.End)
+	}
+
+	switch {
+	case spec.Current3 != nil:
+		frame.Exclude = command.ExcludeCurrentRow
+	case spec.Group != nil:
+		frame.Exclude = command.ExcludeGroup
+	case spec.Ties != nil:
+		frame.Exclude = command.ExcludeTies
+	}
+	return frame, nil
+}
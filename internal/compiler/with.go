@@ -0,0 +1,224 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+)
+
+// commonTable is a common table, that is defined by a WITH clause that
+// encloses the currently compiled statement.
+type commonTable struct {
+	name string
+	// visible indicates that the table can be referenced. A table is not
+	// visible within its own definition, unless it is recursive.
+	visible bool
+	// recursing indicates that the definition of the table is being compiled,
+	// so that references are self-references.
+	recursing bool
+	// refs is the number of references, that are not self-references.
+	refs int
+	// selfRefs is the number of self-references.
+	selfRefs int
+	// cols are the columns of the table. They are only known if the compiler
+	// has a catalog, or if the table has explicit column names.
+	cols []boundColumn
+}
+
+// lookupCommonTable returns the innermost visible common table with the given
+// name, or nil if there is none.
+func (c *simpleCompiler) lookupCommonTable(name string) *commonTable {
+	for i := len(c.commonTables) - 1; i >= 0; i-- {
+		table := c.commonTables[i]
+		if table.visible && strings.EqualFold(table.name, name) {
+			return table
+		}
+	}
+	return nil
+}
+
+// referenceCommonTable counts a reference to the given common table.
+func referenceCommonTable(table *commonTable) {
+	if table.recursing {
+		table.selfRefs++
+	} else {
+		table.refs++
+	}
+}
+
+// compileWith compiles the common tables of the given WITH clause, and the
+// input that is produced by the given function, within which the tables are
+// visible. Tables that are referenced more than once are materialized.
+func (c *simpleCompiler) compileWith(with *ast.WithClause, compileInput func() (command.List, error)) (command.List, error) {
+	defined := len(c.commonTables)
+	defer func() {
+		c.commonTables = c.commonTables[:defined]
+	}()
+
+	var tables []command.CommonTable
+	for _, cte := range with.RecursiveCte {
+		if cte.CteTableName == nil || cte.CteTableName.TableName == nil || cte.SelectStmt == nil {
+			return nil, fmt.Errorf("incomplete common table expression")
+		}
+		name := cte.CteTableName.TableName.Value()
+		for _, other := range c.commonTables[defined:] {
+			if strings.EqualFold(other.name, name) {
+				return nil, fmt.Errorf("duplicate WITH table name: %v", name)
+			}
+		}
+		var cols []string
+		for _, col := range cte.CteTableName.ColumnName {
+			cols = append(cols, col.Value())
+		}
+
+		info := &commonTable{
+			name:      name,
+			visible:   with.Recursive != nil,
+			recursing: true,
+		}
+		for _, col := range cols {
+			info.cols = append(info.cols, boundColumn{name: col})
+		}
+		c.commonTables = append(c.commonTables, info)
+		table, err := c.compileCommonTable(info, cte.SelectStmt)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", name, err)
+		}
+		info.visible = true
+		info.recursing = false
+		c.bindCommonTable(info, table.Input)
+
+		if _, count := resultColumns(table.Input); count != -1 && len(cols) != 0 && count != len(cols) {
+			return nil, fmt.Errorf("table %v has %d values for %d columns", name, count, len(cols))
+		}
+		table.Name = name
+		table.Cols = cols
+		tables = append(tables, table)
+	}
+
+	input, err := compileInput()
+	if err != nil {
+		return nil, err
+	}
+	for i := range tables {
+		tables[i].Materialize = c.commonTables[defined+i].refs > 1
+	}
+	return command.With{
+		Tables: tables,
+		Input:  input,
+	}, nil
+}
+
+// compileCommonTable compiles the definition of the given common table. If
+// the definition references the table itself, it must be a compound select,
+// whose last select is the only one that references the table, and which is
+// connected with UNION or UNION ALL. The other selects produce the initial
+// datasets of the table.
+func (c *simpleCompiler) compileCommonTable(info *commonTable, stmt *ast.SelectStmt) (command.CommonTable, error) {
+	refs := make([]int, len(c.commonTables))
+	for i, table := range c.commonTables {
+		refs[i] = table.refs
+	}
+
+	// the columns of a recursive table are the columns of its initial select,
+	// which have to be known before a self-reference can be bound
+	if c.catalog != nil && len(stmt.SelectCore) > 1 {
+		if compiled, err := c.compileSelectCore(stmt.SelectCore[0], nil); err == nil {
+			c.bindCommonTable(info, compiled.(command.List))
+		}
+		for i, table := range c.commonTables {
+			table.refs = refs[i]
+		}
+		info.selfRefs = 0
+	}
+
+	input, err := c.compileSubquery(stmt)
+	if err != nil {
+		return command.CommonTable{}, err
+	}
+	if info.selfRefs == 0 {
+		return command.CommonTable{
+			Input: input,
+		}, nil
+	}
+
+	last := len(stmt.SelectCore) - 1
+	if last < 1 || stmt.SelectCore[last-1].CompoundOperator == nil || stmt.SelectCore[last-1].CompoundOperator.Union == nil {
+		return command.CommonTable{}, fmt.Errorf("recursive common table must be a compound select with UNION or UNION ALL")
+	}
+	if len(stmt.OrderingTerm) != 0 {
+		return command.CommonTable{}, fmt.Errorf("order in recursive common table: %w", ErrUnsupported)
+	}
+	if stmt.Expr2 != nil {
+		return command.CommonTable{}, fmt.Errorf("offset in recursive common table: %w", ErrUnsupported)
+	}
+
+	// the definition is compiled again in parts, so the references of the
+	// first compilation must not be counted
+	for i, table := range c.commonTables {
+		table.refs = refs[i]
+	}
+	info.selfRefs = 0
+
+	// compile the initial selects, which must not reference the table
+	var initial command.List
+	if last == 1 {
+		compiled, err := c.compileSelectCore(stmt.SelectCore[0], nil)
+		if err != nil {
+			return command.CommonTable{}, fmt.Errorf("initial select: %w", err)
+		}
+		initial = compiled.(command.List)
+	} else {
+		initial, err = c.compileCompound(stmt.SelectCore[:last], nil)
+		if err != nil {
+			return command.CommonTable{}, fmt.Errorf("initial select: %w", err)
+		}
+	}
+	if info.selfRefs != 0 {
+		return command.CommonTable{}, fmt.Errorf("recursive reference in the initial select")
+	}
+
+	// compile the recursive select, which must reference the table once
+	compiled, err := c.compileSelectCore(stmt.SelectCore[last], nil)
+	if err != nil {
+		return command.CommonTable{}, fmt.Errorf("recursive select: %w", err)
+	}
+	if info.selfRefs != 1 {
+		return command.CommonTable{}, fmt.Errorf("recursive select must reference the table exactly once")
+	}
+
+	var limit command.Expr
+	if stmt.Limit != nil {
+		limit, err = c.compileExpr(stmt.Expr1)
+		if err != nil {
+			return command.CommonTable{}, fmt.Errorf("limit: %w", err)
+		}
+	}
+	return command.CommonTable{
+		Input:     initial,
+		Recursive: compiled.(command.List),
+		All:       stmt.SelectCore[last-1].CompoundOperator.All != nil,
+		Limit:     limit,
+	}, nil
+}
+
+// bindCommonTable binds the columns of the given common table to the columns
+// of the given list, which is the definition of the table or its initial
+// select. Explicit column names of the table take precedence over the names
+// of the columns of the list. If the columns of the list are unknown, the
+// columns of the table are left unchanged.
+func (c *simpleCompiler) bindCommonTable(info *commonTable, list command.List) {
+	if c.catalog == nil {
+		return
+	}
+	cols, err := c.bindColumns(list)
+	if err != nil || (info.cols != nil && len(info.cols) != len(cols)) {
+		return
+	}
+	for i := range info.cols {
+		cols[i].name = info.cols[i].name
+	}
+	info.cols = cols
+}
@@ -11,11 +11,12 @@ func _() {
 	_ = x[Unknown-0]
 	_ = x[Decimal-1]
 	_ = x[Varchar-2]
+	_ = x[Integer-3]
 }
 
-const _BaseType_name = "UnknownDecimalVarchar"
+const _BaseType_name = "UnknownDecimalVarcharInteger"
 
-var _BaseType_index = [...]uint8{0, 7, 14, 21}
+var _BaseType_index = [...]uint8{0, 7, 14, 21, 28}
 
 func (i BaseType) String() string {
 	if i >= BaseType(len(_BaseType_index)-1) {
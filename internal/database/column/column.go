@@ -3,6 +3,7 @@ package column
 // Column describes a database column, that consists of a type and multiple
 // attributes, such as nullability, if it is a primary key etc.
 type Column interface {
+	Name() string
 	Type() Type
 	IsNullable() bool
 	IsPrimaryKey() bool
@@ -24,6 +24,7 @@ const (
 	Unknown BaseType = iota
 	Decimal
 	Varchar
+	Integer
 )
 
 // Type describes a type that consists of a base type and zero, one or two
@@ -0,0 +1,48 @@
+package table
+
+import (
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/column"
+)
+
+var (
+	// rowIDNames are the names under which the implicit rowid column of a
+	// table can be referenced, as long as they are not shadowed by a declared
+	// column.
+	rowIDNames = []string{"rowid", "oid", "_rowid_"}
+)
+
+// IsRowIDName determines whether the given name is one of the names, under
+// which the implicit rowid column of a table can be referenced. The comparison
+// is case insensitive.
+func IsRowIDName(name string) bool {
+	for _, rowIDName := range rowIDNames {
+		if strings.EqualFold(name, rowIDName) {
+			return true
+		}
+	}
+	return false
+}
+
+// RowIDAlias returns the index of the column of the given table, that is an
+// alias for the rowid of the table. A column is an alias for the rowid, if it
+// is the only primary key column of the table, and its base type is INTEGER.
+// If the table has no such column, ok=false is returned.
+func RowIDAlias(tbl Table) (index int, ok bool) {
+	index = -1
+	for i, col := range tbl.Columns() {
+		if !col.IsPrimaryKey() {
+			continue
+		}
+		if index != -1 {
+			// more than one primary key column, so none of them is an alias
+			return -1, false
+		}
+		index = i
+	}
+	if index == -1 || tbl.Columns()[index].Type().BaseType() != column.Integer {
+		return -1, false
+	}
+	return index, true
+}
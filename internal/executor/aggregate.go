@@ -0,0 +1,392 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// openAggregate groups the rows of the input of the given aggregate, and
+// creates a cursor over the groups, that match the having filter. The rows of
+// the cursor consist of the last input row of the group, the values of the
+// group keys and the aggregate results. Groups are returned in the order of
+// their keys.
+func (e *simpleExecutor) openAggregate(outer *env, agg command.Aggregate) (cursor, error) {
+	input, err := e.open(outer, agg.Input)
+	if err != nil {
+		return nil, err
+	}
+	inputCols := input.Columns()
+	rows, err := drain(input)
+	if err != nil {
+		return nil, err
+	}
+
+	type group struct {
+		keys        []interface{}
+		row         []interface{}
+		aggregators []aggregator
+	}
+	newGroup := func(keys []interface{}) (*group, error) {
+		g := &group{
+			keys: keys,
+			row:  make([]interface{}, len(inputCols)),
+		}
+		for _, fn := range agg.Aggregates {
+			aggregator, err := newAggregator(fn)
+			if err != nil {
+				return nil, err
+			}
+			g.aggregators = append(g.aggregators, aggregator)
+		}
+		return g, nil
+	}
+
+	var groups []*group
+	groupsByKey := make(map[string]*group)
+	en := newEnv(inputCols, outer)
+	for _, row := range rows {
+		en.row = row
+		keys := make([]interface{}, len(agg.GroupBy))
+		for i, expr := range agg.GroupBy {
+			key, err := e.evaluate(en, expr)
+			if err != nil {
+				return nil, fmt.Errorf("group by: %w", err)
+			}
+			keys[i] = key
+		}
+
+		key := rowKey(keys)
+		g, ok := groupsByKey[key]
+		if !ok {
+			g, err = newGroup(keys)
+			if err != nil {
+				return nil, err
+			}
+			groupsByKey[key] = g
+			groups = append(groups, g)
+		}
+		g.row = row
+
+		for i, fn := range agg.Aggregates {
+			if fn.Filter != nil {
+				pass, err := e.evaluate(en, fn.Filter)
+				if err != nil {
+					return nil, fmt.Errorf("%v: filter: %w", fn.Name, err)
+				}
+				if !isTrue(pass) {
+					continue
+				}
+			}
+			args, err := e.aggregateArgs(en, fn)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", fn.Name, err)
+			}
+			if err := g.aggregators[i].step(args); err != nil {
+				return nil, fmt.Errorf("%v: %w", fn.Name, err)
+			}
+		}
+	}
+	// without GROUP BY, there is always exactly one group
+	if len(agg.GroupBy) == 0 && len(groups) == 0 {
+		g, err := newGroup(nil)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return compareRows(groups[i].keys, groups[j].keys) < 0
+	})
+
+	cols := append([]resultColumn(nil), inputCols...)
+	for _, expr := range agg.GroupBy {
+		cols = append(cols, resultColumn{
+			name:   expr.String(),
+			hidden: true,
+			expr:   expr.String(),
+		})
+	}
+	for _, fn := range agg.Aggregates {
+		cols = append(cols, resultColumn{
+			name:   fn.String(),
+			hidden: true,
+			expr:   fn.String(),
+		})
+	}
+
+	en = newEnv(cols, outer)
+	var result [][]interface{}
+	for _, g := range groups {
+		row := append(append(make([]interface{}, 0, len(cols)), g.row...), g.keys...)
+		for _, aggregator := range g.aggregators {
+			val, err := aggregator.result()
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, val)
+		}
+		if agg.Having != nil {
+			en.row = row
+			pass, err := e.evaluate(en, agg.Having)
+			if err != nil {
+				return nil, fmt.Errorf("having: %w", err)
+			}
+			if !isTrue(pass) {
+				continue
+			}
+		}
+		result = append(result, row)
+	}
+	return &sliceCursor{
+		cols: cols,
+		rows: result,
+	}, nil
+}
+
+// aggregateArgs evaluates the arguments of the given aggregate call. The
+// argument of COUNT(*) is not evaluated, and results in no arguments.
+func (e *simpleExecutor) aggregateArgs(en *env, fn command.FunctionExpr) ([]interface{}, error) {
+	if len(fn.Args) == 1 {
+		if lit, ok := fn.Args[0].(command.LiteralExpr); ok && lit.Value == "*" {
+			return nil, nil
+		}
+	}
+	args := make([]interface{}, len(fn.Args))
+	for i, arg := range fn.Args {
+		val, err := e.evaluate(en, arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+	return args, nil
+}
+
+// compareRows compares the given rows value by value, and returns the result
+// of the first comparison that is not zero.
+func compareRows(a, b []interface{}) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if cmp := compareValues(a[i], b[i], nil); cmp != 0 {
+			return cmp
+		}
+	}
+	return len(a) - len(b)
+}
+
+// aggregator computes the result of an aggregate function over all rows of a
+// group.
+type aggregator interface {
+	// step adds the arguments of a single row to the aggregate.
+	step(args []interface{}) error
+	// result returns the result of the aggregate function.
+	result() (interface{}, error)
+}
+
+// newAggregator creates a new aggregator for the given aggregate call.
+func newAggregator(fn command.FunctionExpr) (aggregator, error) {
+	name := strings.ToUpper(fn.Name)
+	star := len(fn.Args) == 1 && fn.Args[0] == command.Expr(command.LiteralExpr{Value: "*"})
+	if star && name != "COUNT" {
+		return nil, fmt.Errorf("%v(*): %w", fn.Name, ErrUnsupported)
+	}
+
+	var agg aggregator
+	switch {
+	case name == "COUNT" && star:
+		agg = &countAggregator{star: true}
+	case name == "COUNT" && len(fn.Args) == 1:
+		agg = &countAggregator{}
+	case name == "SUM" && len(fn.Args) == 1:
+		agg = &sumAggregator{}
+	case name == "TOTAL" && len(fn.Args) == 1:
+		agg = &sumAggregator{total: true}
+	case name == "AVG" && len(fn.Args) == 1:
+		agg = &avgAggregator{}
+	case (name == "MIN" || name == "MAX") && len(fn.Args) == 1:
+		coll, err := collationOf(fn.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		agg = &minMaxAggregator{
+			max:  name == "MAX",
+			coll: coll,
+		}
+	case name == "GROUP_CONCAT" && (len(fn.Args) == 1 || len(fn.Args) == 2):
+		agg = &groupConcatAggregator{}
+	default:
+		return nil, fmt.Errorf("wrong number of arguments to function %v()", fn.Name)
+	}
+
+	if fn.Distinct {
+		if len(fn.Args) != 1 || star {
+			return nil, fmt.Errorf("DISTINCT aggregates must have exactly one argument")
+		}
+		agg = &distinctAggregator{
+			aggregator: agg,
+			seen:       make(map[string]struct{}),
+		}
+	}
+	return agg, nil
+}
+
+// distinctAggregator passes only distinct arguments to the wrapped aggregator.
+type distinctAggregator struct {
+	aggregator
+	seen map[string]struct{}
+}
+
+func (a *distinctAggregator) step(args []interface{}) error {
+	key := valueKey(args[0])
+	if _, seen := a.seen[key]; seen {
+		return nil
+	}
+	a.seen[key] = struct{}{}
+	return a.aggregator.step(args)
+}
+
+// countAggregator counts the non-NULL arguments, or all rows if it is
+// COUNT(*).
+type countAggregator struct {
+	star  bool
+	count int64
+}
+
+func (a *countAggregator) step(args []interface{}) error {
+	if a.star || args[0] != nil {
+		a.count++
+	}
+	return nil
+}
+
+func (a *countAggregator) result() (interface{}, error) { return a.count, nil }
+
+// sumAggregator computes the sum of all non-NULL arguments. The sum is an
+// integer as long as all arguments are integers, and an integer overflow is an
+// error. The sum of no values is NULL, or 0.0 if this is TOTAL, which always
+// results in a real value.
+type sumAggregator struct {
+	total    bool
+	count    int64
+	isFloat  bool
+	intSum   int64
+	floatSum float64
+	overflow bool
+}
+
+func (a *sumAggregator) step(args []interface{}) error {
+	if args[0] == nil {
+		return nil
+	}
+	a.count++
+	val := normalizeBool(args[0])
+	if i, ok := val.(int64); ok && !a.isFloat {
+		sum := a.intSum + i
+		if (i > 0 && sum < a.intSum) || (i < 0 && sum > a.intSum) {
+			a.overflow = true
+		}
+		a.intSum = sum
+		a.floatSum += float64(i)
+		return nil
+	}
+	a.isFloat = true
+	a.floatSum += toFloat(val)
+	return nil
+}
+
+func (a *sumAggregator) result() (interface{}, error) {
+	if a.total {
+		return a.floatSum, nil
+	}
+	if a.count == 0 {
+		return nil, nil
+	}
+	if a.isFloat {
+		return a.floatSum, nil
+	}
+	if a.overflow {
+		return nil, fmt.Errorf("integer overflow")
+	}
+	return a.intSum, nil
+}
+
+// avgAggregator computes the average of all non-NULL arguments as real value.
+// The average of no values is NULL.
+type avgAggregator struct {
+	count int64
+	sum   float64
+}
+
+func (a *avgAggregator) step(args []interface{}) error {
+	if args[0] == nil {
+		return nil
+	}
+	a.count++
+	a.sum += toFloat(args[0])
+	return nil
+}
+
+func (a *avgAggregator) result() (interface{}, error) {
+	if a.count == 0 {
+		return nil, nil
+	}
+	return a.sum / float64(a.count), nil
+}
+
+// minMaxAggregator computes the minimum or maximum of all non-NULL arguments.
+// The minimum or maximum of no values is NULL.
+type minMaxAggregator struct {
+	max  bool
+	coll collation
+	val  interface{}
+}
+
+func (a *minMaxAggregator) step(args []interface{}) error {
+	if args[0] == nil {
+		return nil
+	}
+	if a.val == nil {
+		a.val = args[0]
+		return nil
+	}
+	cmp := compareValues(args[0], a.val, a.coll)
+	if (a.max && cmp > 0) || (!a.max && cmp < 0) {
+		a.val = args[0]
+	}
+	return nil
+}
+
+func (a *minMaxAggregator) result() (interface{}, error) { return a.val, nil }
+
+// groupConcatAggregator concatenates the text representations of all non-NULL
+// arguments, separated by the separator, which defaults to a comma. The
+// concatenation of no values is NULL.
+type groupConcatAggregator struct {
+	buf   strings.Builder
+	count int64
+}
+
+func (a *groupConcatAggregator) step(args []interface{}) error {
+	if args[0] == nil {
+		return nil
+	}
+	if a.count > 0 {
+		if len(args) == 2 {
+			a.buf.WriteString(toText(args[1]))
+		} else {
+			a.buf.WriteByte(',')
+		}
+	}
+	a.count++
+	a.buf.WriteString(toText(args[0]))
+	return nil
+}
+
+func (a *groupConcatAggregator) result() (interface{}, error) {
+	if a.count == 0 {
+		return nil, nil
+	}
+	return a.buf.String(), nil
+}
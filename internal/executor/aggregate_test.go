@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustQuery(t *testing.T, e *simpleExecutor, sql string) [][]interface{} {
+	t.Helper()
+	res := mustExecute(t, e, sql)
+	require.Implements(t, (*QueryResult)(nil), res, sql)
+	return res.(QueryResult).Rows()
+}
+
+func newAggregateTestExecutor(t *testing.T) *simpleExecutor {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE sales (region VARCHAR(10), product VARCHAR(10), amount INTEGER, price REAL)")
+	mustExecute(t, e, "INSERT INTO sales VALUES "+
+		"('north', 'apple', 3, 1.5), ('north', 'pear', 5, 2.0), ('north', 'apple', 2, 1.5), "+
+		"('south', 'apple', 7, 1.25), ('south', 'plum', 1, 3.0)")
+	mustExecute(t, e, "INSERT INTO sales (region, product) VALUES ('east', 'pear')")
+	return e
+}
+
+func TestAggregate(t *testing.T) {
+	e := newAggregateTestExecutor(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  [][]interface{}
+	}{
+		{
+			"count star",
+			"SELECT COUNT(*) FROM sales",
+			[][]interface{}{{int64(6)}},
+		},
+		{
+			"count ignores NULL",
+			"SELECT COUNT(amount) FROM sales",
+			[][]interface{}{{int64(5)}},
+		},
+		{
+			"group by",
+			"SELECT region, COUNT(*), SUM(amount) FROM sales GROUP BY region",
+			[][]interface{}{
+				{"east", int64(1), nil},
+				{"north", int64(3), int64(10)},
+				{"south", int64(2), int64(8)},
+			},
+		},
+		{
+			"group by multiple keys",
+			"SELECT region, product, SUM(amount) FROM sales GROUP BY region, product",
+			[][]interface{}{
+				{"east", "pear", nil},
+				{"north", "apple", int64(5)},
+				{"north", "pear", int64(5)},
+				{"south", "apple", int64(7)},
+				{"south", "plum", int64(1)},
+			},
+		},
+		{
+			"having",
+			"SELECT region FROM sales GROUP BY region HAVING SUM(amount) > 8",
+			[][]interface{}{{"north"}},
+		},
+		{
+			"having with aggregate not in result",
+			"SELECT region, MAX(amount) FROM sales GROUP BY region HAVING COUNT(*) > 1",
+			[][]interface{}{
+				{"north", int64(5)},
+				{"south", int64(7)},
+			},
+		},
+		{
+			"where before group by",
+			"SELECT region, COUNT(*) FROM sales WHERE amount > 2 GROUP BY region",
+			[][]interface{}{
+				{"north", int64(2)},
+				{"south", int64(1)},
+			},
+		},
+		{
+			"empty input without group by",
+			"SELECT COUNT(*), SUM(amount), TOTAL(amount), AVG(amount), MIN(amount) FROM sales WHERE amount > 100",
+			[][]interface{}{{int64(0), nil, 0.0, nil, nil}},
+		},
+		{
+			"empty input with group by",
+			"SELECT region, COUNT(*) FROM sales WHERE amount > 100 GROUP BY region",
+			[][]interface{}{},
+		},
+		{
+			"min max avg total",
+			"SELECT MIN(amount), MAX(amount), AVG(amount), TOTAL(amount) FROM sales",
+			[][]interface{}{{int64(1), int64(7), 3.6, 18.0}},
+		},
+		{
+			"sum of reals",
+			"SELECT SUM(price) FROM sales",
+			[][]interface{}{{9.25}},
+		},
+		{
+			"min max of texts",
+			"SELECT MIN(product), MAX(product) FROM sales",
+			[][]interface{}{{"apple", "plum"}},
+		},
+		{
+			"group concat",
+			"SELECT region, GROUP_CONCAT(product) FROM sales GROUP BY region",
+			[][]interface{}{
+				{"east", "pear"},
+				{"north", "apple,pear,apple"},
+				{"south", "apple,plum"},
+			},
+		},
+		{
+			"group concat with separator",
+			"SELECT GROUP_CONCAT(region, ';') FROM sales WHERE product = 'apple'",
+			[][]interface{}{{"north;north;south"}},
+		},
+		{
+			"distinct aggregates",
+			"SELECT COUNT(DISTINCT product), GROUP_CONCAT(DISTINCT region) FROM sales",
+			[][]interface{}{{int64(3), "north,south,east"}},
+		},
+		{
+			"distinct sum",
+			"SELECT SUM(DISTINCT price) FROM sales",
+			[][]interface{}{{7.75}},
+		},
+		{
+			"scalar min max",
+			"SELECT region, MAX(amount, 4) FROM sales WHERE region = 'south'",
+			[][]interface{}{
+				{"south", int64(7)},
+				{"south", int64(4)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mustQuery(t, e, tt.query))
+		})
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	e := newAggregateTestExecutor(t)
+	mustExecute(t, e, "CREATE TABLE big (n INTEGER)")
+	mustExecute(t, e, "INSERT INTO big VALUES (9223372036854775807), (1)")
+
+	for _, query := range []string{
+		"SELECT SUM(n) FROM big",
+		"SELECT region FROM sales WHERE COUNT(*) > 1",
+		"SELECT SUM(COUNT(*)) FROM sales",
+		"SELECT COUNT(DISTINCT region, product) FROM sales",
+	} {
+		_, err := execute(e, query)
+		assert.Error(t, err, query)
+	}
+
+	rows := mustQuery(t, e, "SELECT TOTAL(n) FROM big")
+	assert.Equal(t, [][]interface{}{{9223372036854775808.0}}, rows, "total must not overflow")
+}
@@ -0,0 +1,389 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/compiler/optimization"
+	"github.com/tomarrell/lbadd/internal/database/table"
+)
+
+var _ optimization.Statistics = (*simpleExecutor)(nil)
+
+const (
+	// systemTablePrefix is the prefix of the names of all tables, that are
+	// maintained by the executor itself. Tables with such a name can't be
+	// created by commands.
+	systemTablePrefix = "lbadd_"
+	// statTable is the name of the system table, that holds a row with the
+	// amount of rows, the fraction of NULL values and the amount of distinct
+	// values for every column of every analyzed table. For every analyzed
+	// index, it holds a row for every prefix of the indexed columns, named by
+	// the last column of the prefix, with the fraction of keys, that have a
+	// NULL value in the prefix, and the amount of distinct prefixes without
+	// NULL values. The rows of columns have no index. This is the equivalent
+	// of SQLite's sqlite_stat1 table.
+	statTable = "lbadd_stat"
+	// histogramTable is the name of the system table, that holds the buckets
+	// of the equi-depth histograms of the columns of all analyzed tables. This
+	// is the equivalent of SQLite's sqlite_stat4 table.
+	histogramTable = "lbadd_histogram"
+
+	// histogramBuckets is the maximum amount of buckets of a histogram.
+	histogramBuckets = 16
+)
+
+// systemTables are the definitions of the system tables, by their name.
+var systemTables = map[string]command.CreateTable{
+	statTable: {
+		Name: statTable,
+		Columns: []command.ColumnDef{
+			{Name: "schema", Type: "TEXT"},
+			{Name: "tbl", Type: "TEXT"},
+			{Name: "idx", Type: "TEXT"},
+			{Name: "col", Type: "TEXT"},
+			{Name: "nrows", Type: "INTEGER"},
+			{Name: "nullfrac", Type: "REAL"},
+			{Name: "ndistinct", Type: "INTEGER"},
+		},
+	},
+	histogramTable: {
+		Name: histogramTable,
+		Columns: []command.ColumnDef{
+			{Name: "schema", Type: "TEXT"},
+			{Name: "tbl", Type: "TEXT"},
+			{Name: "col", Type: "TEXT"},
+			{Name: "bucket", Type: "INTEGER"},
+			{Name: "upper"},
+			{Name: "nrows", Type: "INTEGER"},
+			{Name: "ndistinct", Type: "INTEGER"},
+		},
+	},
+}
+
+// isSystemTableName determines whether the given table name is reserved for
+// system tables.
+func isSystemTableName(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), systemTablePrefix)
+}
+
+// executeAnalyze collects the statistics of all tables, that are referenced
+// by the given command, and of their indexes, and replaces their previous
+// statistics in the system tables. If the command references an index, only
+// the statistics of that index are collected.
+func (e *simpleExecutor) executeAnalyze(cmd command.Analyze) (Result, error) {
+	key := tableKey(cmd.Schema, cmd.Name)
+	if _, isTable := e.tables[key]; cmd.Name != "" && !isTable {
+		if index, ok := e.indexes[key]; ok {
+			e.analyzeIndex(index)
+			return e.result(0), nil
+		}
+	}
+
+	tables, err := e.analyzedTables(cmd)
+	if err != nil {
+		return nil, err
+	}
+	for _, tbl := range tables {
+		e.analyzeTable(tbl)
+	}
+	return e.result(0), nil
+}
+
+// analyzedTables returns the tables, that are referenced by the given analyze
+// command, ordered by their name. Without a name, all tables except the system
+// tables are analyzed. An unqualified name refers to a table, or if there is
+// no such table, to a schema.
+func (e *simpleExecutor) analyzedTables(cmd command.Analyze) ([]*memTable, error) {
+	if cmd.Name == "" {
+		var tables []*memTable
+		for _, tbl := range e.tables {
+			if tbl.schema != "" || !isSystemTableName(tbl.name) {
+				tables = append(tables, tbl)
+			}
+		}
+		sortTables(tables)
+		return tables, nil
+	}
+
+	if tbl, ok := e.tables[tableKey(cmd.Schema, cmd.Name)]; ok {
+		return []*memTable{tbl}, nil
+	}
+	if cmd.Schema == "" {
+		var tables []*memTable
+		for _, tbl := range e.tables {
+			if strings.EqualFold(tbl.schema, cmd.Name) {
+				tables = append(tables, tbl)
+			}
+		}
+		if len(tables) != 0 {
+			sortTables(tables)
+			return tables, nil
+		}
+	}
+	return nil, fmt.Errorf("%v: %w", cmd.Name, ErrNoSuchTable)
+}
+
+func sortTables(tables []*memTable) {
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].qualifiedName() < tables[j].qualifiedName()
+	})
+}
+
+// analyzeTable computes the statistics of all columns and all indexes of the
+// given table, and replaces the previous statistics of the table in the system
+// tables.
+func (e *simpleExecutor) analyzeTable(tbl *memTable) {
+	type columnStatistics struct {
+		nullFraction float64
+		distinct     int64
+		histogram    [][3]interface{}
+	}
+	stats := make([]columnStatistics, len(tbl.cols))
+	for i := range tbl.cols {
+		var nulls int
+		var values []interface{}
+		for _, row := range tbl.rows {
+			if val := normalizeBool(row.values[i]); val != nil {
+				values = append(values, val)
+			} else {
+				nulls++
+			}
+		}
+		sort.SliceStable(values, func(a, b int) bool {
+			return compareValues(values[a], values[b], nil) < 0
+		})
+
+		if len(tbl.rows) != 0 {
+			stats[i].nullFraction = float64(nulls) / float64(len(tbl.rows))
+		}
+		for _, bucket := range equiDepthHistogram(values, histogramBuckets) {
+			stats[i].distinct += bucket.distinct
+			stats[i].histogram = append(stats[i].histogram, [3]interface{}{bucket.upper, bucket.rows, bucket.distinct})
+		}
+	}
+
+	stat := e.systemTable(statTable)
+	histogram := e.systemTable(histogramTable)
+	deleteStatistics(stat, tbl)
+	deleteStatistics(histogram, tbl)
+	for i, col := range tbl.cols {
+		appendRow(stat, tbl.schema, tbl.name, nil, col.name, int64(len(tbl.rows)), stats[i].nullFraction, stats[i].distinct)
+		for n, bucket := range stats[i].histogram {
+			appendRow(histogram, tbl.schema, tbl.name, col.name, int64(n), bucket[0], bucket[1], bucket[2])
+		}
+	}
+
+	var indexes []*memIndex
+	for _, index := range e.indexes {
+		if index.table == tbl {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].name < indexes[j].name
+	})
+	for _, index := range indexes {
+		e.analyzeIndex(index)
+	}
+}
+
+// analyzeIndex computes the amounts of distinct prefixes of the keys of the
+// given index, and replaces the previous statistics of the index in the
+// statistics table.
+func (e *simpleExecutor) analyzeIndex(index *memIndex) {
+	index.update()
+	stat := e.systemTable(statTable)
+	deleteIndexStatistics(stat, index)
+	for n, col := range index.cols {
+		var nulls, distinct int64
+		var previous []interface{}
+		for _, entry := range index.entries {
+			prefix := entry.key[:n+1]
+			if hasNull(prefix) {
+				nulls++
+				continue
+			}
+			// the entries are sorted by key, so equal prefixes are adjacent
+			if previous == nil || compareKey(prefix, previous) != 0 {
+				distinct++
+			}
+			previous = prefix
+		}
+		var nullFraction float64
+		if len(index.entries) != 0 {
+			nullFraction = float64(nulls) / float64(len(index.entries))
+		}
+		appendRow(stat, index.table.schema, index.table.name, index.name, index.table.cols[col].name, int64(len(index.entries)), nullFraction, distinct)
+	}
+}
+
+// histogramBucket is a bucket of an equi-depth histogram, that covers all
+// values, which are larger than the upper bound of the previous bucket, and
+// smaller than or equal to its own upper bound.
+type histogramBucket struct {
+	upper    interface{}
+	rows     int64
+	distinct int64
+}
+
+// equiDepthHistogram returns the equi-depth histogram of the given sorted
+// values with at most the given amount of buckets. Every bucket contains
+// about the same amount of values, but all equal values are in the same
+// bucket.
+func equiDepthHistogram(values []interface{}, buckets int) []histogramBucket {
+	var histogram []histogramBucket
+	for start, n := 0, 0; start < len(values); n++ {
+		end := (n+1)*len(values)/buckets - 1
+		if end < start {
+			continue
+		}
+		for end+1 < len(values) && compareValues(values[end+1], values[end], nil) == 0 {
+			end++
+		}
+
+		bucket := histogramBucket{
+			upper: values[end],
+			rows:  int64(end - start + 1),
+		}
+		for i := start; i <= end; i++ {
+			if i == start || compareValues(values[i], values[i-1], nil) != 0 {
+				bucket.distinct++
+			}
+		}
+		histogram = append(histogram, bucket)
+		start = end + 1
+	}
+	return histogram
+}
+
+// systemTable returns the system table with the given name, and creates it if
+// it doesn't exist.
+func (e *simpleExecutor) systemTable(name string) *memTable {
+	if tbl, ok := e.tables[tableKey("", name)]; ok {
+		return tbl
+	}
+	tbl := newMemTable(systemTables[name])
+	e.tables[tableKey("", name)] = tbl
+	return tbl
+}
+
+// appendRow appends a row with the given values to the given table, with a
+// rowid that is larger than all rowids in the table.
+func appendRow(tbl *memTable, values ...interface{}) {
+	id, _ := tbl.maxRowID()
+	tbl.put(memRow{
+		id:     id + 1,
+		values: values,
+	})
+}
+
+// deleteStatistics deletes all rows of the given system table, that belong to
+// the given analyzed table.
+func deleteStatistics(system, tbl *memTable) {
+	rows := system.rows[:0]
+	for _, row := range system.rows {
+		if !isStatisticsOf(row, tbl) {
+			rows = append(rows, row)
+		}
+	}
+	system.setRows(rows)
+}
+
+// deleteIndexStatistics deletes all rows of the statistics table, that belong
+// to the given index.
+func deleteIndexStatistics(stat *memTable, index *memIndex) {
+	rows := stat.rows[:0]
+	for _, row := range stat.rows {
+		name, _ := row.values[2].(string)
+		if !isStatisticsOf(row, index.table) || !strings.EqualFold(name, index.name) {
+			rows = append(rows, row)
+		}
+	}
+	stat.setRows(rows)
+}
+
+// isStatisticsOf determines whether the given row of a system table belongs
+// to the given analyzed table. The first two columns of a system table are
+// the schema and the name of the analyzed table.
+func isStatisticsOf(row memRow, tbl *memTable) bool {
+	schema, _ := row.values[0].(string)
+	name, _ := row.values[1].(string)
+	return strings.EqualFold(schema, tbl.schema) && strings.EqualFold(name, tbl.name)
+}
+
+// TableStatistics returns the statistics of the table with the given schema
+// and name, so that the executor can be used as statistics when optimizing
+// commands. The amount of rows is always the current one. The statistics of
+// the columns and the indexes are the ones that were collected by the last
+// analysis of the table or the index. If the table was not analyzed, only the
+// values of the rowid alias of the table are known to be distinct.
+func (e *simpleExecutor) TableStatistics(schemaName, name string) (optimization.TableStatistics, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tbl, ok := e.tables[tableKey(schemaName, name)]
+	if !ok {
+		return optimization.TableStatistics{}, false
+	}
+	stats := optimization.TableStatistics{
+		Rows:    float64(len(tbl.rows)),
+		Columns: make(map[string]optimization.ColumnStatistics),
+	}
+	if index, ok := table.RowIDAlias(tbl); ok {
+		stats.Columns[strings.ToLower(tbl.cols[index].name)] = optimization.ColumnStatistics{
+			Distinct: stats.Rows,
+		}
+	}
+
+	// rows are the amounts of rows of the table, when each column was analyzed
+	rows := make(map[string]float64)
+	if stat, ok := e.tables[tableKey("", statTable)]; ok {
+		// the rows of the prefixes of an index are inserted in ascending
+		// order of their length
+		for _, row := range stat.rows {
+			if !isStatisticsOf(row, tbl) {
+				continue
+			}
+			if row.values[2] != nil {
+				if stats.Indexes == nil {
+					stats.Indexes = make(map[string]optimization.IndexStatistics)
+				}
+				name := strings.ToLower(fmt.Sprint(row.values[2]))
+				indexStats := stats.Indexes[name]
+				indexStats.Distinct = append(indexStats.Distinct, toFloat(row.values[6]))
+				stats.Indexes[name] = indexStats
+				continue
+			}
+			col := strings.ToLower(fmt.Sprint(row.values[3]))
+			rows[col] = toFloat(row.values[4])
+			stats.Columns[col] = optimization.ColumnStatistics{
+				NullFraction: toFloat(row.values[5]),
+				Distinct:     toFloat(row.values[6]),
+			}
+		}
+	}
+	if histogram, ok := e.tables[tableKey("", histogramTable)]; ok {
+		// the rows of the histogram table are ordered by their rowid, and the
+		// buckets of a column are inserted in ascending order
+		for _, row := range histogram.rows {
+			if !isStatisticsOf(row, tbl) {
+				continue
+			}
+			col := strings.ToLower(fmt.Sprint(row.values[2]))
+			colStats, ok := stats.Columns[col]
+			if !ok || rows[col] == 0 {
+				continue
+			}
+			colStats.Histogram = append(colStats.Histogram, optimization.Bucket{
+				Upper:    row.values[4],
+				Fraction: toFloat(row.values[5]) / rows[col],
+				Distinct: toFloat(row.values[6]),
+			})
+			stats.Columns[col] = colStats
+		}
+	}
+	return stats, true
+}
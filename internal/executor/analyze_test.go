@@ -0,0 +1,239 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/compiler"
+	"github.com/tomarrell/lbadd/internal/compiler/optimization"
+	"github.com/tomarrell/lbadd/internal/parser"
+)
+
+func TestAnalyze(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER, s TEXT)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1, 10, 'x'), (2, 20, 'y'), (3, 10, 'x'), (4, 30, 'z')")
+	mustExecute(t, e, "INSERT INTO a (id) VALUES (5)")
+	mustExecute(t, e, "CREATE TABLE b (w)")
+
+	// only the rowid alias is known before analyzing
+	stats, ok := e.TableStatistics("", "a")
+	require.True(t, ok)
+	assert.Equal(optimization.TableStatistics{
+		Rows: 5,
+		Columns: map[string]optimization.ColumnStatistics{
+			"id": {Distinct: 5},
+		},
+	}, stats)
+
+	mustExecute(t, e, "ANALYZE")
+	assert.Equal([][]interface{}{
+		{"", "a", nil, "id", int64(5), 0.0, int64(5)},
+		{"", "a", nil, "s", int64(5), 0.2, int64(3)},
+		{"", "a", nil, "v", int64(5), 0.2, int64(3)},
+		{"", "b", nil, "w", int64(0), 0.0, int64(0)},
+	}, mustQuery(t, e, "SELECT * FROM lbadd_stat ORDER BY tbl, col"))
+	assert.Equal([][]interface{}{
+		{int64(0), int64(10), int64(2), int64(1)},
+		{int64(1), int64(20), int64(1), int64(1)},
+		{int64(2), int64(30), int64(1), int64(1)},
+	}, mustQuery(t, e, "SELECT bucket, upper, nrows, ndistinct FROM lbadd_histogram WHERE col = 'v' ORDER BY bucket"))
+
+	stats, ok = e.TableStatistics("", "a")
+	require.True(t, ok)
+	assert.EqualValues(5, stats.Rows)
+	assert.Equal(optimization.ColumnStatistics{
+		Distinct:     3,
+		NullFraction: 0.2,
+		Histogram: []optimization.Bucket{
+			{Upper: "x", Fraction: 0.4, Distinct: 1},
+			{Upper: "y", Fraction: 0.2, Distinct: 1},
+			{Upper: "z", Fraction: 0.2, Distinct: 1},
+		},
+	}, stats.Columns["s"])
+
+	// analyzing again replaces the statistics of the table
+	mustExecute(t, e, "INSERT INTO b VALUES (1), (1)")
+	mustExecute(t, e, "ANALYZE b")
+	assert.Equal([][]interface{}{
+		{"b", int64(2), int64(1)},
+	}, mustQuery(t, e, "SELECT tbl, nrows, ndistinct FROM lbadd_stat WHERE tbl = 'b'"))
+	assert.Equal([][]interface{}{
+		{"w", int64(0), int64(1), int64(2)},
+	}, mustQuery(t, e, "SELECT col, bucket, upper, nrows FROM lbadd_histogram WHERE tbl = 'b'"))
+
+	// dropping a table removes its statistics
+	mustExecute(t, e, "DROP TABLE a")
+	assert.Equal([][]interface{}{
+		{"b"},
+	}, mustQuery(t, e, "SELECT DISTINCT tbl FROM lbadd_stat"))
+	assert.Equal([][]interface{}{
+		{"b"},
+	}, mustQuery(t, e, "SELECT DISTINCT tbl FROM lbadd_histogram"))
+}
+
+func TestAnalyze_Schema(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE s.a (v)")
+	mustExecute(t, e, "CREATE TABLE s.b (v)")
+	mustExecute(t, e, "CREATE TABLE c (v)")
+
+	mustExecute(t, e, "ANALYZE s")
+	assert.Equal(t, [][]interface{}{
+		{"s", "a"},
+		{"s", "b"},
+	}, mustQuery(t, e, "SELECT schema, tbl FROM lbadd_stat ORDER BY tbl"))
+
+	mustExecute(t, e, "ANALYZE s.a")
+	mustExecute(t, e, "ANALYZE c")
+	assert.Equal(t, [][]interface{}{
+		{"s", "a"},
+		{"s", "b"},
+		{"", "c"},
+	}, mustQuery(t, e, "SELECT schema, tbl FROM lbadd_stat ORDER BY tbl"))
+}
+
+func TestAnalyze_Index(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER, w INTEGER)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1, 10, 1), (2, 10, 2), (3, 10, 2), (4, 20, 1)")
+	mustExecute(t, e, "INSERT INTO a (id, v) VALUES (5, 20)")
+	mustExecute(t, e, "CREATE INDEX a_v_w ON a (v, w)")
+	mustExecute(t, e, "CREATE INDEX a_w ON a (w)")
+
+	// analyzing an index only collects the statistics of that index
+	mustExecute(t, e, "ANALYZE a_v_w")
+	assert.Equal([][]interface{}{
+		{"a_v_w", "v", int64(5), 0.0, int64(2)},
+		{"a_v_w", "w", int64(5), 0.2, int64(3)},
+	}, mustQuery(t, e, "SELECT idx, col, nrows, nullfrac, ndistinct FROM lbadd_stat"))
+	stats, ok := e.TableStatistics("", "a")
+	require.True(t, ok)
+	assert.Equal(map[string]optimization.IndexStatistics{
+		"a_v_w": {Distinct: []float64{2, 3}},
+	}, stats.Indexes)
+
+	// analyzing a table collects the statistics of all of its indexes
+	mustExecute(t, e, "ANALYZE a")
+	assert.Equal([][]interface{}{
+		{"a_v_w", "v", int64(2)},
+		{"a_v_w", "w", int64(3)},
+		{"a_w", "w", int64(2)},
+	}, mustQuery(t, e, "SELECT idx, col, ndistinct FROM lbadd_stat WHERE idx NOTNULL ORDER BY idx, ndistinct"))
+
+	// dropping an index removes its statistics
+	mustExecute(t, e, "DROP INDEX a_v_w")
+	stats, ok = e.TableStatistics("", "a")
+	require.True(t, ok)
+	assert.Equal(map[string]optimization.IndexStatistics{
+		"a_w": {Distinct: []float64{2}},
+	}, stats.Indexes)
+	assert.Len(stats.Columns, 3)
+}
+
+func TestAnalyze_JoinOrder(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER)")
+	mustExecute(t, e, "CREATE TABLE b (id INTEGER, w INTEGER)")
+	var values []string
+	for i := 1; i <= 50; i++ {
+		v := 1
+		if i > 45 {
+			v = i
+		}
+		values = append(values, fmt.Sprintf("(%d, %d)", i, v))
+	}
+	mustExecute(t, e, "INSERT INTO a VALUES "+strings.Join(values, ", "))
+	mustExecute(t, e, "INSERT INTO b VALUES (1, 1), (2, 2), (3, 3), (4, 4), (5, 5), (6, 6), (7, 7), (8, 8), (9, 9), (10, 10)")
+
+	plan := func() string {
+		stmt, errs, ok := parser.New("SELECT a.v, b.w FROM b JOIN a ON a.id = b.id WHERE a.v = 1").Next()
+		require.True(t, ok)
+		require.Len(t, errs, 0)
+		cmd, err := compiler.New(compiler.OptionCatalog(e), compiler.OptionEnableRules(
+			optimization.ReorderJoins(e),
+			optimization.PushDownPredicates,
+		)).Compile(stmt)
+		require.NoError(t, err)
+		return cmd.String()
+	}
+
+	// without statistics, a tenth of the rows of a is expected to pass the
+	// filter, so that a is held in memory
+	assert.Equal(t, "Project[cols=a.v,b.w](Join[filter=a.id==b.id](Scan[table=b](),Select[filter=a.v==1](Scan[table=a]())))", plan())
+	// the histogram shows, that most rows of a pass the filter
+	mustExecute(t, e, "ANALYZE a")
+	assert.Equal(t, "Project[cols=a.v,b.w](Join[filter=a.id==b.id,type=JoinInner](Select[filter=a.v==1](Scan[table=a]()),Scan[table=b]()))", plan())
+}
+
+func TestAnalyze_Errors(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+
+	_, err := execute(e, "ANALYZE missing")
+	assert.True(t, errors.Is(err, ErrNoSuchTable))
+	_, err = execute(e, "ANALYZE s.missing")
+	assert.True(t, errors.Is(err, ErrNoSuchTable))
+	_, err = execute(e, "CREATE TABLE lbadd_stat (v)")
+	assert.True(t, errors.Is(err, ErrReservedName))
+	_, err = execute(e, "CREATE TABLE LBADD_other (v)")
+	assert.True(t, errors.Is(err, ErrReservedName))
+}
+
+func Test_equiDepthHistogram(t *testing.T) {
+	values := func(vals ...interface{}) []interface{} { return vals }
+	tests := []struct {
+		name    string
+		values  []interface{}
+		buckets int
+		want    []histogramBucket
+	}{
+		{
+			"empty",
+			nil,
+			4,
+			nil,
+		},
+		{
+			"fewer values than buckets",
+			values(int64(1), int64(2)),
+			4,
+			[]histogramBucket{
+				{upper: int64(1), rows: 1, distinct: 1},
+				{upper: int64(2), rows: 1, distinct: 1},
+			},
+		},
+		{
+			"equal depth",
+			values(int64(1), int64(2), int64(3), int64(4), int64(5), int64(6), int64(7), int64(8)),
+			4,
+			[]histogramBucket{
+				{upper: int64(2), rows: 2, distinct: 2},
+				{upper: int64(4), rows: 2, distinct: 2},
+				{upper: int64(6), rows: 2, distinct: 2},
+				{upper: int64(8), rows: 2, distinct: 2},
+			},
+		},
+		{
+			"equal values are not split",
+			values(int64(1), int64(2), int64(2), int64(2), int64(2), int64(3), 4.5, "a", "b"),
+			3,
+			[]histogramBucket{
+				{upper: int64(2), rows: 5, distinct: 2},
+				{upper: int64(3), rows: 1, distinct: 1},
+				{upper: "b", rows: 3, distinct: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, equiDepthHistogram(tt.values, tt.buckets))
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database"
+	"github.com/tomarrell/lbadd/internal/database/schema"
+	"github.com/tomarrell/lbadd/internal/database/table"
+)
+
+var _ database.DB = (*simpleExecutor)(nil)
+var _ schema.Schema = (*memSchema)(nil)
+
+// memSchema is a schema, that consists of the tables of an executor with a
+// common schema name. The empty name is the schema of all tables that were
+// created without a schema name.
+type memSchema struct {
+	e    *simpleExecutor
+	name string
+}
+
+// Schema returns the schema with the given name, so that the executor can be
+// used as catalog when compiling commands. A schema exists as long as it has
+// at least one table, except for the schema with the empty name, which always
+// exists.
+func (e *simpleExecutor) Schema(name string) (schema.Schema, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if name != "" {
+		var found bool
+		for _, tbl := range e.tables {
+			if strings.EqualFold(tbl.schema, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return memSchema{
+		e:    e,
+		name: name,
+	}, true
+}
+
+func (s memSchema) Table(name string) (table.Table, bool) {
+	s.e.mu.Lock()
+	defer s.e.mu.Unlock()
+
+	tbl, ok := s.e.tables[tableKey(s.name, name)]
+	if !ok {
+		return nil, false
+	}
+	return tbl, true
+}
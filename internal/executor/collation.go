@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// collation is a collating sequence, that defines how two texts are compared.
+// The nil collation is the BINARY collation.
+type collation func(a, b string) int
+
+var collations = map[string]collation{
+	"BINARY": strings.Compare,
+	"NOCASE": func(a, b string) int {
+		return strings.Compare(foldASCII(a), foldASCII(b))
+	},
+	"RTRIM": func(a, b string) int {
+		return strings.Compare(strings.TrimRight(a, " "), strings.TrimRight(b, " "))
+	},
+}
+
+func (c collation) compare(a, b string) int {
+	if c == nil {
+		return strings.Compare(a, b)
+	}
+	return c(a, b)
+}
+
+// lookupCollation returns the collation with the given name. Collation names
+// are case insensitive.
+func lookupCollation(name string) (collation, error) {
+	coll, ok := collations[strings.ToUpper(name)]
+	if !ok {
+		return nil, fmt.Errorf("no such collation sequence: %v", name)
+	}
+	return coll, nil
+}
+
+// collationOf returns the collation that has to be used when comparing the
+// results of the given expressions. An explicit COLLATE on the left operand
+// takes precedence over one on the right operand. Without any explicit
+// collation, the BINARY collation is used.
+func collationOf(exprs ...command.Expr) (collation, error) {
+	for _, expr := range exprs {
+		if collate, ok := expr.(command.CollateExpr); ok {
+			return lookupCollation(collate.Collation)
+		}
+	}
+	return nil, nil
+}
+
+// foldASCII converts all ASCII upper case letters in the given string to lower
+// case. All other characters are left unchanged.
+func foldASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,180 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// setOperation is the kind of a set operation on two lists.
+type setOperation uint8
+
+const (
+	setUnion setOperation = iota
+	setIntersect
+	setExcept
+)
+
+func (op setOperation) String() string {
+	switch op {
+	case setUnion:
+		return "UNION"
+	case setIntersect:
+		return "INTERSECT"
+	}
+	return "EXCEPT"
+}
+
+// openSetOperation creates a cursor over the result of the given set operation
+// on the left and the right list. Duplicates are detected by hashing the rows,
+// so that the rows of the left list keep their order. For intersections and
+// differences, the right list is held in memory.
+func (e *simpleExecutor) openSetOperation(outer *env, op setOperation, all bool, leftList, rightList command.List) (cursor, error) {
+	left, err := e.open(outer, leftList)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.open(outer, rightList)
+	if err != nil {
+		_ = left.Close()
+		return nil, err
+	}
+
+	c := &setCursor{
+		op:           op,
+		left:         left,
+		right:        right,
+		leftVisible:  visibleColumns(left.Columns()),
+		rightVisible: visibleColumns(right.Columns()),
+	}
+	if len(c.leftVisible) != len(c.rightVisible) {
+		_ = c.Close()
+		return nil, fmt.Errorf("SELECTs to the left and right of %v do not have the same number of result columns", op)
+	}
+	for _, index := range c.leftVisible {
+		col := left.Columns()[index]
+		c.cols = append(c.cols, resultColumn{name: col.name})
+	}
+	if !all {
+		c.seen = make(map[string]struct{})
+	}
+
+	if op != setUnion {
+		// count the occurrences of every row of the right list
+		c.counts = make(map[string]int)
+		for {
+			row, ok, err := right.Next()
+			if err != nil {
+				_ = c.Close()
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			c.counts[rowKey(pick(row, c.rightVisible))]++
+		}
+	}
+	return c, nil
+}
+
+// setCursor is a cursor over the result of a set operation. If seen is not
+// nil, every row is returned at most once.
+type setCursor struct {
+	op           setOperation
+	cols         []resultColumn
+	left, right  cursor
+	leftVisible  []int
+	rightVisible []int
+
+	// onRight indicates that all rows of the left input of a union have been
+	// returned.
+	onRight bool
+	seen    map[string]struct{}
+	// counts are the occurrences of the rows of the right input of an
+	// intersection or difference.
+	counts map[string]int
+}
+
+func (c *setCursor) Columns() []resultColumn { return c.cols }
+
+func (c *setCursor) Next() ([]interface{}, bool, error) {
+	for {
+		input, visible := c.left, c.leftVisible
+		if c.onRight {
+			input, visible = c.right, c.rightVisible
+		}
+		row, ok, err := input.Next()
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			if c.op == setUnion && !c.onRight {
+				c.onRight = true
+				continue
+			}
+			return nil, false, nil
+		}
+
+		row = pick(row, visible)
+		key := rowKey(row)
+		if c.seen != nil {
+			if _, seen := c.seen[key]; seen {
+				continue
+			}
+		}
+
+		// with ALL, every occurrence in the right input matches only a single
+		// occurrence in the left input
+		switch c.op {
+		case setIntersect:
+			if c.counts[key] == 0 {
+				continue
+			}
+			if c.seen == nil {
+				c.counts[key]--
+			}
+		case setExcept:
+			if c.counts[key] > 0 {
+				if c.seen == nil {
+					c.counts[key]--
+				}
+				continue
+			}
+		}
+		if c.seen != nil {
+			c.seen[key] = struct{}{}
+		}
+		return row, true, nil
+	}
+}
+
+func (c *setCursor) Close() error {
+	leftErr := c.left.Close()
+	if err := c.right.Close(); err != nil {
+		return err
+	}
+	return leftErr
+}
+
+// visibleColumns returns the indices of all columns, that are not hidden.
+func visibleColumns(cols []resultColumn) []int {
+	var visible []int
+	for i, col := range cols {
+		if !col.hidden {
+			visible = append(visible, i)
+		}
+	}
+	return visible
+}
+
+// pick returns the values of the given row at the given indices.
+func pick(row []interface{}, indices []int) []interface{} {
+	if len(indices) == len(row) {
+		return row
+	}
+	picked := make([]interface{}, len(indices))
+	for i, index := range indices {
+		picked[i] = row[index]
+	}
+	return picked
+}
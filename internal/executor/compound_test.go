@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+func TestCompound(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (n INTEGER)")
+	mustExecute(t, e, "CREATE TABLE b (n INTEGER)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1), (2), (2), (3), (4)")
+	mustExecute(t, e, "INSERT INTO b VALUES (2), (3), (3), (5)")
+
+	tests := []struct {
+		name  string
+		query string
+		want  [][]interface{}
+	}{
+		{
+			"union",
+			"SELECT n FROM a UNION SELECT n FROM b",
+			[][]interface{}{{int64(1)}, {int64(2)}, {int64(3)}, {int64(4)}, {int64(5)}},
+		},
+		{
+			"union all",
+			"SELECT n FROM a UNION ALL SELECT n FROM b",
+			[][]interface{}{{int64(1)}, {int64(2)}, {int64(2)}, {int64(3)}, {int64(4)}, {int64(2)}, {int64(3)}, {int64(3)}, {int64(5)}},
+		},
+		{
+			"intersect",
+			"SELECT n FROM a INTERSECT SELECT n FROM b",
+			[][]interface{}{{int64(2)}, {int64(3)}},
+		},
+		{
+			"except",
+			"SELECT n FROM a EXCEPT SELECT n FROM b",
+			[][]interface{}{{int64(1)}, {int64(4)}},
+		},
+		{
+			"left to right",
+			"SELECT n FROM a EXCEPT SELECT n FROM b UNION SELECT n FROM b",
+			[][]interface{}{{int64(1)}, {int64(4)}, {int64(2)}, {int64(3)}, {int64(5)}},
+		},
+		{
+			"order and limit",
+			"SELECT n AS v FROM a UNION SELECT n FROM b ORDER BY v DESC LIMIT 2",
+			[][]interface{}{{int64(5)}, {int64(4)}},
+		},
+		{
+			"values",
+			"SELECT n FROM b UNION VALUES (7), (2.0) ORDER BY 1",
+			[][]interface{}{{int64(2)}, {int64(3)}, {int64(5)}, {int64(7)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mustQuery(t, e, tt.query))
+		})
+	}
+
+	_, err := execute(e, "SELECT * FROM a UNION SELECT n, n FROM b")
+	assert.Error(t, err, "column count mismatch")
+}
+
+func TestCompoundAll(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	values := func(ns ...string) command.Values {
+		var values command.Values
+		for _, n := range ns {
+			values.Values = append(values.Values, []command.Expr{command.LiteralExpr{Value: n}})
+		}
+		return values
+	}
+	left, right := values("1", "2", "2", "2", "3"), values("2", "2", "3", "3")
+
+	res, err := e.Execute(command.Intersect{All: true, Left: left, Right: right})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(2)}, {int64(2)}, {int64(3)}}, res.(QueryResult).Rows())
+
+	res, err = e.Execute(command.Except{All: true, Left: left, Right: right})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(1)}, {int64(2)}}, res.(QueryResult).Rows())
+}
@@ -0,0 +1,282 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/table"
+)
+
+// cursor iterates over the rows of an intermediate result of a query.
+type cursor interface {
+	// Columns returns the columns of the rows that are produced by this
+	// cursor.
+	Columns() []resultColumn
+	// Next returns the next row of this cursor, or ok=false if there are no
+	// more rows. The returned row must not be modified.
+	Next() (row []interface{}, ok bool, err error)
+	// Close releases all resources that are held by this cursor.
+	Close() error
+}
+
+// resultColumn is a column of an intermediate result.
+type resultColumn struct {
+	// table is the name or the alias of the table that this column originates
+	// from. May be empty.
+	table string
+	// name is the name of this column.
+	name string
+	// hidden indicates that this column is not part of a star expansion.
+	hidden bool
+	// rowid indicates that this column is the implicit rowid column of a
+	// table. A rowid column is always hidden.
+	rowid bool
+	// coalesced indicates that this column is a column of the right input of
+	// a join, that is merged with the equally named column of the left input,
+	// because the join is a natural join or names the column in its USING
+	// clause. A coalesced column is always hidden, and can only be referenced
+	// by its qualified name.
+	coalesced bool
+	// expr is the string representation of the expression, whose value this
+	// column holds, such as a group key or an aggregate call. Columns with an
+	// expression are hidden, and can only be referenced by an equal
+	// expression, not by name.
+	expr string
+}
+
+// env is the environment in which an expression is evaluated. It consists of
+// the current row of a cursor, and the environment of the enclosing query,
+// which is used to resolve correlated column references.
+type env struct {
+	cols  []resultColumn
+	row   []interface{}
+	outer *env
+	// grouped indicates that some columns hold the values of expressions, so
+	// that expressions have to be looked up before they are evaluated.
+	grouped bool
+	// tables are the common tables of a WITH clause. An environment with
+	// tables has no row, and does not count as a query level.
+	tables map[string]*commonTable
+}
+
+// newEnv creates a new environment for rows with the given columns. The row
+// has to be set before an expression can be evaluated.
+func newEnv(cols []resultColumn, outer *env) *env {
+	en := &env{
+		cols:  cols,
+		outer: outer,
+	}
+	for _, col := range cols {
+		if col.expr != "" {
+			en.grouped = true
+		}
+	}
+	return en
+}
+
+// enclosing returns the environment of the enclosing query level, or nil if
+// there is none.
+func (en *env) enclosing() *env {
+	outer := en.outer
+	for outer != nil && outer.tables != nil {
+		outer = outer.outer
+	}
+	return outer
+}
+
+// lookupCommonTable returns the innermost common table with the given name, or
+// nil if there is none.
+func (en *env) lookupCommonTable(name string) *commonTable {
+	for current := en; current != nil; current = current.outer {
+		if t, ok := current.tables[strings.ToLower(name)]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// lookupColumn returns the index of the column with the given name, that
+// originates from the given table. If the table name is empty, the column may
+// originate from any table. If no such column exists, -1 is returned.
+func (en *env) lookupColumn(tableName, name string) (int, error) {
+	found := -1
+	for i, col := range en.cols {
+		if col.expr != "" || col.rowid || !strings.EqualFold(col.name, name) {
+			continue
+		}
+		if tableName == "" && col.coalesced {
+			continue
+		}
+		if tableName != "" && !strings.EqualFold(col.table, tableName) {
+			continue
+		}
+		if found != -1 {
+			return -1, fmt.Errorf("ambiguous column name: %v", name)
+		}
+		found = i
+	}
+	if found != -1 || !table.IsRowIDName(name) {
+		return found, nil
+	}
+
+	// no declared column with the given name, so look for a rowid column
+	for i, col := range en.cols {
+		if !col.rowid || (tableName != "" && !strings.EqualFold(col.table, tableName)) {
+			continue
+		}
+		if found != -1 {
+			return -1, fmt.Errorf("ambiguous column name: %v", name)
+		}
+		found = i
+	}
+	return found, nil
+}
+
+// lookupExpr returns the index of the column that holds the value of an
+// expression with the given string representation. If no such column exists,
+// -1 is returned.
+func (en *env) lookupExpr(expr string) int {
+	for i, col := range en.cols {
+		if col.expr != "" && col.expr == expr {
+			return i
+		}
+	}
+	return -1
+}
+
+// drain reads all remaining rows from the given cursor and closes it.
+func drain(c cursor) ([][]interface{}, error) {
+	var rows [][]interface{}
+	for {
+		row, ok, err := c.Next()
+		if err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		rows = append(rows, row)
+	}
+	return rows, c.Close()
+}
+
+// sliceCursor is a cursor over rows that are held in memory.
+type sliceCursor struct {
+	cols []resultColumn
+	rows [][]interface{}
+	pos  int
+}
+
+func (c *sliceCursor) Columns() []resultColumn { return c.cols }
+
+func (c *sliceCursor) Next() ([]interface{}, bool, error) {
+	if c.pos >= len(c.rows) {
+		return nil, false, nil
+	}
+	c.pos++
+	return c.rows[c.pos-1], true, nil
+}
+
+func (c *sliceCursor) Close() error { return nil }
+
+// scanCursor is a cursor over the rows of a table. The rows consist of the
+// declared columns, followed by the hidden rowid column.
+type scanCursor struct {
+	cols []resultColumn
+	rows []memRow
+	pos  int
+	// used are the positions of the values of the rows, that are produced by
+	// this cursor. If this is nil, all values are produced.
+	used []int
+}
+
+// newScanCursor creates a cursor over the rows of the given table, whose
+// columns are assigned to the given name. If used is not nil, the rows only
+// consist of the columns with the given names, in the order of the table,
+// followed by the rowid. Names of columns, that the table doesn't have, are
+// ignored.
+func newScanCursor(tbl *memTable, name string, used []string) *scanCursor {
+	c := &scanCursor{
+		// the rows are copied, so that modifications of the table while the
+		// scan is in progress don't affect the scan
+		rows: append([]memRow(nil), tbl.rows...),
+	}
+	var names []string
+	for i, col := range tbl.cols {
+		if used != nil && !containsName(used, col.name) {
+			continue
+		}
+		names = append(names, col.name)
+		if used != nil {
+			c.used = append(c.used, i)
+		}
+	}
+	if used != nil && c.used == nil {
+		c.used = []int{}
+	}
+	c.cols = scanColumns(name, names)
+	return c
+}
+
+// scanColumns returns the columns of a scan of the table with the given name
+// or alias, which consist of the columns with the given names, followed by the
+// hidden rowid column.
+func scanColumns(tableName string, names []string) []resultColumn {
+	cols := make([]resultColumn, 0, len(names)+1)
+	for _, name := range names {
+		cols = append(cols, resultColumn{
+			table: tableName,
+			name:  name,
+		})
+	}
+	return append(cols, resultColumn{
+		table:  tableName,
+		name:   "rowid",
+		hidden: true,
+		rowid:  true,
+	})
+}
+
+func (c *scanCursor) Columns() []resultColumn { return c.cols }
+
+func (c *scanCursor) Next() ([]interface{}, bool, error) {
+	if c.pos >= len(c.rows) {
+		return nil, false, nil
+	}
+	row := c.rows[c.pos]
+	c.pos++
+	if c.used != nil {
+		values := make([]interface{}, 0, len(c.used)+1)
+		for _, i := range c.used {
+			values = append(values, row.values[i])
+		}
+		return append(values, row.id), true, nil
+	}
+	return append(append(make([]interface{}, 0, len(row.values)+1), row.values...), row.id), true, nil
+}
+
+func (c *scanCursor) Close() error { return nil }
+
+// renameCursor is a cursor, that assigns all visible columns of its input to
+// another table.
+type renameCursor struct {
+	cursor
+	cols []resultColumn
+}
+
+func newRenameCursor(input cursor, tableName string) *renameCursor {
+	cols := make([]resultColumn, len(input.Columns()))
+	for i, col := range input.Columns() {
+		if col.expr == "" {
+			col.table = tableName
+		}
+		cols[i] = col
+	}
+	return &renameCursor{
+		cursor: input,
+		cols:   cols,
+	}
+}
+
+func (c *renameCursor) Columns() []resultColumn { return c.cols }
@@ -0,0 +1,39 @@
+package executor
+
+// Error is a helper type for creating constant errors.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+const (
+	// ErrUnsupported indicates that a command or a part of a command is not
+	// supported by the executor. What exactly is unsupported, must be
+	// indicated by a wrapping error.
+	ErrUnsupported Error = "unsupported"
+	// ErrNoSuchTable indicates, that a table that was referenced in a command
+	// does not exist.
+	ErrNoSuchTable Error = "no such table"
+	// ErrTableExists indicates, that a table could not be created, because a
+	// table with the same name already exists.
+	ErrTableExists Error = "table already exists"
+	// ErrNoSuchIndex indicates, that an index that was referenced in a command
+	// does not exist.
+	ErrNoSuchIndex Error = "no such index"
+	// ErrIndexExists indicates, that an index could not be created, because an
+	// index with the same name already exists.
+	ErrIndexExists Error = "index already exists"
+	// ErrReservedName indicates, that a table could not be created, because
+	// its name is reserved for tables that are maintained by the executor.
+	ErrReservedName Error = "object name reserved for internal use"
+	// ErrNoSuchColumn indicates, that a column that was referenced in a
+	// command does not exist.
+	ErrNoSuchColumn Error = "no such column"
+	// ErrConstraint indicates, that a command would have violated a constraint
+	// of a table, such as a unique or not null constraint.
+	ErrConstraint Error = "constraint failed"
+	// ErrDatatypeMismatch indicates, that a value could not be used, because
+	// it has the wrong type, e.g. when a non-integer value is used as rowid.
+	ErrDatatypeMismatch Error = "datatype mismatch"
+	// ErrFull indicates, that no more rowids can be allocated for a table.
+	ErrFull Error = "database or disk is full"
+)
@@ -2,6 +2,7 @@ package executor
 
 import (
 	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
 	"github.com/tomarrell/lbadd/internal/compiler/command"
 )
 
@@ -14,7 +15,9 @@ type Executor interface {
 	Execute(command.Command) (Result, error)
 }
 
-// New creates a new, ready to use Executor.
+// New creates a new, ready to use Executor. Data that has to survive a restart,
+// such as the AUTOINCREMENT sequences of tables, is persisted next to the given
+// database file.
 func New(log zerolog.Logger, databaseFile string) Executor {
-	return newSimpleExecutor(log, databaseFile)
+	return newSimpleExecutor(log, afero.NewOsFs(), databaseFile)
 }
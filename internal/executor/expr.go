@@ -0,0 +1,592 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// evaluateExpr evaluates the given expression to a single value, outside of
+// any query. Values are represented by the Go types nil (NULL), int64,
+// float64, string, []byte and bool.
+func (e *simpleExecutor) evaluateExpr(expr command.Expr) (interface{}, error) {
+	return e.evaluate(nil, expr)
+}
+
+// evaluate evaluates the given expression to a single value, in the given
+// environment, which may be nil. If the environment holds the value of an
+// expression that is equal to the given expression, such as a group key or an
+// aggregate result, that value is returned.
+func (e *simpleExecutor) evaluate(en *env, expr command.Expr) (interface{}, error) {
+	if en != nil && en.grouped {
+		if index := en.lookupExpr(expr.String()); index != -1 {
+			return en.row[index], nil
+		}
+	}
+
+	switch ex := expr.(type) {
+	case command.ConstantBooleanExpr:
+		return ex.Value, nil
+	case command.LiteralExpr:
+		return parseLiteral(ex.Value)
+	case command.ColumnRef:
+		return resolveColumn(en, ex)
+	case command.UnaryExpr:
+		val, err := e.evaluate(en, ex.Value)
+		if err != nil {
+			return nil, err
+		}
+		return evaluateUnary(ex.Operator, val)
+	case command.BinaryExpr:
+		return e.evaluateBinary(en, ex)
+	case command.EqualityExpr:
+		eq, err := e.evaluateComparison(en, "=", ex.Left, ex.Right)
+		if err != nil || !ex.Invert {
+			return eq, err
+		}
+		return not3(eq), nil
+	case command.RangeExpr:
+		return e.evaluateRange(en, ex)
+	case command.IsExpr:
+		return e.evaluateIs(en, ex)
+	case command.InExpr:
+		return e.evaluateIn(en, ex)
+	case command.ExistsExpr:
+		c, err := e.open(en, ex.Input)
+		if err != nil {
+			return nil, err
+		}
+		_, exists, err := c.Next()
+		if err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+		return exists != ex.Invert, c.Close()
+	case command.SubqueryExpr:
+		return e.evaluateSubquery(en, ex.Input)
+	case command.CaseExpr:
+		return e.evaluateCase(en, ex)
+	case command.CastExpr:
+		val, err := e.evaluate(en, ex.Value)
+		if err != nil {
+			return nil, err
+		}
+		return cast(val, ex.Type), nil
+	case command.CollateExpr:
+		// the collation is only relevant when the value is compared
+		if _, err := lookupCollation(ex.Collation); err != nil {
+			return nil, err
+		}
+		return e.evaluate(en, ex.Value)
+	case command.PatternExpr:
+		return e.evaluatePattern(en, ex)
+	case command.FunctionExpr:
+		return e.evaluateFunction(en, ex)
+	case command.RaiseExpr:
+		return nil, fmt.Errorf("raise outside of a trigger: %w", ErrUnsupported)
+	case command.ParameterExpr:
+		if !ex.Bound {
+			return nil, fmt.Errorf("parameter %v is not bound", ex)
+		}
+		return parameterValue(ex.Value)
+	}
+	return nil, fmt.Errorf("expression %T: %w", expr, ErrUnsupported)
+}
+
+// resolveColumn returns the value of the referenced column in the given
+// environment. The column is searched for in the environment of the query,
+// that is referenced by the depth of the column reference, and all
+// environments that enclose it.
+func resolveColumn(en *env, ref command.ColumnRef) (interface{}, error) {
+	start := en
+	for i := 0; i < ref.Depth && start != nil && start.enclosing() != nil; i++ {
+		start = start.enclosing()
+	}
+	for current := start; current != nil; current = current.outer {
+		index, err := current.lookupColumn(ref.Table, ref.Column)
+		if err != nil {
+			return nil, err
+		}
+		if index != -1 {
+			return current.row[index], nil
+		}
+	}
+	return nil, fmt.Errorf("%v: %w", ref, ErrNoSuchColumn)
+}
+
+func (e *simpleExecutor) evaluateRange(en *env, expr command.RangeExpr) (interface{}, error) {
+	lo, err := e.evaluateComparison(en, ">=", expr.Needle, expr.Lo)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := e.evaluateComparison(en, "<=", expr.Needle, expr.Hi)
+	if err != nil {
+		return nil, err
+	}
+	if expr.Invert {
+		return not3(and3(lo, hi)), nil
+	}
+	return and3(lo, hi), nil
+}
+
+func (e *simpleExecutor) evaluateIs(en *env, expr command.IsExpr) (interface{}, error) {
+	left, err := e.evaluate(en, expr.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.evaluate(en, expr.Right)
+	if err != nil {
+		return nil, err
+	}
+	coll, err := collationOf(expr.Left, expr.Right)
+	if err != nil {
+		return nil, err
+	}
+	var equal bool
+	if left == nil || right == nil {
+		equal = left == nil && right == nil
+	} else {
+		equal = compareValues(left, right, coll) == 0
+	}
+	return equal != expr.Invert, nil
+}
+
+// evaluateIn evaluates the given IN expression. The result is true if the
+// value is equal to any of the values, NULL if it isn't but any of the values
+// is NULL, and false otherwise.
+func (e *simpleExecutor) evaluateIn(en *env, expr command.InExpr) (interface{}, error) {
+	val, err := e.evaluate(en, expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	coll, err := collationOf(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []interface{}
+	if expr.Input != nil {
+		c, err := e.open(en, expr.Input)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := drain(c)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			values = append(values, row[0])
+		}
+	} else {
+		for _, valueExpr := range expr.Values {
+			value, err := e.evaluate(en, valueExpr)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+	}
+
+	var result interface{} = false
+	if len(values) != 0 && val == nil {
+		result = nil
+	}
+	for _, value := range values {
+		if val == nil {
+			break
+		}
+		if value == nil {
+			result = nil
+			continue
+		}
+		if compareValues(val, value, coll) == 0 {
+			result = true
+			break
+		}
+	}
+	if expr.Invert {
+		return not3(result), nil
+	}
+	return result, nil
+}
+
+// evaluateSubquery evaluates the given query to the first value of its first
+// row, or to NULL if the query has no rows.
+func (e *simpleExecutor) evaluateSubquery(en *env, input command.List) (interface{}, error) {
+	c, err := e.open(en, input)
+	if err != nil {
+		return nil, err
+	}
+	row, ok, err := c.Next()
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	if !ok || len(row) == 0 {
+		return nil, c.Close()
+	}
+	return row[0], c.Close()
+}
+
+func (e *simpleExecutor) evaluateCase(en *env, expr command.CaseExpr) (interface{}, error) {
+	var base interface{}
+	var coll collation
+	if expr.Value != nil {
+		var err error
+		base, err = e.evaluate(en, expr.Value)
+		if err != nil {
+			return nil, err
+		}
+		coll, err = collationOf(expr.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, whenThen := range expr.Cases {
+		when, err := e.evaluate(en, whenThen.When)
+		if err != nil {
+			return nil, err
+		}
+		var applies bool
+		if expr.Value != nil {
+			applies = base != nil && when != nil && compareValues(base, when, coll) == 0
+		} else {
+			applies = isTrue(when)
+		}
+		if applies {
+			return e.evaluate(en, whenThen.Then)
+		}
+	}
+	if expr.Else == nil {
+		return nil, nil
+	}
+	return e.evaluate(en, expr.Else)
+}
+
+// parseLiteral converts the value of a literal expression to a value. Quoted
+// literals are strings, unquoted literals must either be NULL or a number.
+func parseLiteral(literal string) (interface{}, error) {
+	if literal == "" {
+		return nil, fmt.Errorf("empty literal")
+	}
+	if quote := literal[0]; (quote == '\'' || quote == '"') && len(literal) > 1 && literal[len(literal)-1] == quote {
+		return unquote(literal[1:len(literal)-1], quote), nil
+	}
+	if strings.EqualFold(literal, "NULL") {
+		return nil, nil
+	}
+	if strings.HasPrefix(literal, "0x") || strings.HasPrefix(literal, "0X") {
+		if val, err := strconv.ParseInt(literal[2:], 16, 64); err == nil {
+			return val, nil
+		}
+	}
+	if val, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		return val, nil
+	}
+	if val, err := strconv.ParseFloat(literal, 64); err == nil {
+		return val, nil
+	}
+	return nil, fmt.Errorf("%v: %w", literal, ErrNoSuchColumn)
+}
+
+// unquote removes escape characters from the content of a quoted literal. Both
+// a doubled quote and a backslash escape the character that follows.
+func unquote(content string, quote byte) string {
+	var buf strings.Builder
+	for i := 0; i < len(content); i++ {
+		if (content[i] == '\\' || content[i] == quote) && i+1 < len(content) {
+			i++
+		}
+		buf.WriteByte(content[i])
+	}
+	return buf.String()
+}
+
+func evaluateUnary(operator string, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch strings.ToUpper(operator) {
+	case "+":
+		return val, nil
+	case "-":
+		switch v := toNumeric(val).(type) {
+		case int64:
+			if v == math.MinInt64 {
+				return -float64(v), nil
+			}
+			return -v, nil
+		case float64:
+			return -v, nil
+		}
+	case "~":
+		return ^toInteger(val), nil
+	case "NOT":
+		return !isTrue(val), nil
+	}
+	return nil, fmt.Errorf("unary operator %v: %w", operator, ErrUnsupported)
+}
+
+func (e *simpleExecutor) evaluateBinary(en *env, expr command.BinaryExpr) (interface{}, error) {
+	operator := strings.ToUpper(expr.Operator)
+	switch operator {
+	case "<", "<=", ">", ">=", "=", "==", "!=", "<>":
+		return e.evaluateComparison(en, operator, expr.Left, expr.Right)
+	}
+
+	left, err := e.evaluate(en, expr.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.evaluate(en, expr.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operator {
+	case "AND":
+		return and3(truth(left), truth(right)), nil
+	case "OR":
+		return or3(truth(left), truth(right)), nil
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	switch operator {
+	case "||":
+		return toText(left) + toText(right), nil
+	case "+", "-", "*", "/", "%":
+		return arithmetic(operator, toNumeric(left), toNumeric(right)), nil
+	case "&":
+		return toInteger(left) & toInteger(right), nil
+	case "|":
+		return toInteger(left) | toInteger(right), nil
+	case "<<", ">>":
+		return shift(operator, toInteger(left), toInteger(right)), nil
+	}
+	return nil, fmt.Errorf("binary operator %v: %w", expr.Operator, ErrUnsupported)
+}
+
+// evaluateComparison compares the results of the left and the right
+// expression with the given comparison operator. If any of the results is
+// NULL, the result of the comparison is NULL.
+func (e *simpleExecutor) evaluateComparison(en *env, operator string, leftExpr, rightExpr command.Expr) (interface{}, error) {
+	left, err := e.evaluate(en, leftExpr)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.evaluate(en, rightExpr)
+	if err != nil {
+		return nil, err
+	}
+	coll, err := collationOf(leftExpr, rightExpr)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+
+	cmp := compareValues(left, right, coll)
+	switch operator {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case "!=", "<>":
+		return cmp != 0, nil
+	}
+	return cmp == 0, nil
+}
+
+// arithmetic applies the given arithmetic operator to the given numeric
+// values. Integer operations that overflow are performed on reals instead.
+// Division by zero results in NULL.
+func arithmetic(operator string, left, right interface{}) interface{} {
+	a, aIsInt := left.(int64)
+	b, bIsInt := right.(int64)
+	if aIsInt && bIsInt {
+		switch operator {
+		case "+":
+			if sum := a + b; (sum > a) == (b > 0) {
+				return sum
+			}
+		case "-":
+			if diff := a - b; (diff < a) == (b > 0) {
+				return diff
+			}
+		case "*":
+			if a == 0 || b == 0 {
+				return int64(0)
+			}
+			if product := a * b; product/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64) {
+				return product
+			}
+		case "/":
+			if b == 0 {
+				return nil
+			}
+			if !(a == math.MinInt64 && b == -1) {
+				return a / b
+			}
+		case "%":
+			if b == 0 {
+				return nil
+			}
+			if b == -1 {
+				return int64(0)
+			}
+			return a % b
+		}
+	}
+
+	x, y := toFloat(left), toFloat(right)
+	switch operator {
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "/":
+		if y == 0 {
+			return nil
+		}
+		return x / y
+	}
+	// the modulo of reals is computed on their integer parts
+	a, b = toInteger(left), toInteger(right)
+	if b == 0 {
+		return nil
+	}
+	if b == -1 {
+		return 0.0
+	}
+	return float64(a % b)
+}
+
+// shift shifts the given value by the given amount of bits. A negative amount
+// shifts in the other direction.
+func shift(operator string, val, bits int64) int64 {
+	if bits < 0 {
+		bits = -bits
+		if operator == "<<" {
+			operator = ">>"
+		} else {
+			operator = "<<"
+		}
+	}
+	if operator == "<<" {
+		if bits >= 64 {
+			return 0
+		}
+		return val << uint(bits)
+	}
+	if bits >= 64 {
+		if val < 0 {
+			return -1
+		}
+		return 0
+	}
+	return val >> uint(bits)
+}
+
+// truth converts the given value to a boolean, or nil if the value is NULL.
+func truth(val interface{}) interface{} {
+	if val == nil {
+		return nil
+	}
+	return isTrue(val)
+}
+
+// and3 is the three-valued logical AND of the given booleans, where nil is
+// the unknown value.
+func and3(a, b interface{}) interface{} {
+	if a == false || b == false {
+		return false
+	}
+	if a == nil || b == nil {
+		return nil
+	}
+	return true
+}
+
+// or3 is the three-valued logical OR of the given booleans, where nil is the
+// unknown value.
+func or3(a, b interface{}) interface{} {
+	if a == true || b == true {
+		return true
+	}
+	if a == nil || b == nil {
+		return nil
+	}
+	return false
+}
+
+// not3 is the three-valued logical NOT of the given boolean, where nil is the
+// unknown value.
+func not3(a interface{}) interface{} {
+	if a == nil {
+		return nil
+	}
+	return !isTrue(a)
+}
+
+// cast converts the given value to the affinity of the given type name.
+func cast(val interface{}, typeName string) interface{} {
+	if val == nil {
+		return nil
+	}
+	switch affinityOf(typeName) {
+	case affinityInteger:
+		return toInteger(val)
+	case affinityReal:
+		return toFloat(val)
+	case affinityText:
+		return toText(val)
+	case affinityBlob:
+		return []byte(toText(val))
+	}
+	numeric := toNumeric(val)
+	if f, ok := numeric.(float64); ok && f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		if _, isText := val.(string); isText {
+			return int64(f)
+		}
+	}
+	return numeric
+}
+
+// affinity is the type affinity of a declared type.
+type affinity uint8
+
+const (
+	affinityNumeric affinity = iota
+	affinityInteger
+	affinityText
+	affinityBlob
+	affinityReal
+)
+
+// affinityOf determines the affinity of the given type name.
+func affinityOf(typeName string) affinity {
+	upper := strings.ToUpper(typeName)
+	switch {
+	case strings.Contains(upper, "INT"):
+		return affinityInteger
+	case strings.Contains(upper, "CHAR"), strings.Contains(upper, "CLOB"), strings.Contains(upper, "TEXT"):
+		return affinityText
+	case strings.Contains(upper, "BLOB"), upper == "":
+		return affinityBlob
+	case strings.Contains(upper, "REAL"), strings.Contains(upper, "FLOA"), strings.Contains(upper, "DOUB"):
+		return affinityReal
+	}
+	return affinityNumeric
+}
@@ -0,0 +1,232 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// scalarFunction is a function, that computes a single value from its
+// arguments.
+type scalarFunction struct {
+	// minArgs and maxArgs are the minimum and maximum number of arguments of
+	// this function. A maxArgs of -1 means that there is no maximum.
+	minArgs, maxArgs int
+	call             func(args []interface{}) (interface{}, error)
+}
+
+// scalarFunctions are all known scalar functions, by their upper case name.
+var scalarFunctions = map[string]scalarFunction{
+	"ABS":      {1, 1, abs},
+	"COALESCE": {2, -1, coalesce},
+	"IFNULL":   {2, 2, coalesce},
+	"NULLIF":   {2, 2, nullif},
+	"LENGTH":   {1, 1, length},
+	"LOWER":    {1, 1, lower},
+	"UPPER":    {1, 1, upper},
+	"TYPEOF":   {1, 1, typeOf},
+	"MIN":      {2, -1, minOf},
+	"MAX":      {2, -1, maxOf},
+	"SUBSTR":   {2, 3, substr},
+	"ROUND":    {1, 2, round},
+}
+
+// evaluateFunction evaluates the given call to a scalar function. Aggregate
+// and window function calls can only be evaluated in an environment that holds
+// their result.
+func (e *simpleExecutor) evaluateFunction(en *env, expr command.FunctionExpr) (interface{}, error) {
+	if expr.Over != nil {
+		return nil, fmt.Errorf("misuse of window function %v()", expr.Name)
+	}
+	if isAggregateCall(expr) {
+		return nil, fmt.Errorf("misuse of aggregate function %v()", expr.Name)
+	}
+	fn, ok := scalarFunctions[strings.ToUpper(expr.Name)]
+	if !ok {
+		return nil, fmt.Errorf("no such function: %v", expr.Name)
+	}
+	if len(expr.Args) < fn.minArgs || (fn.maxArgs != -1 && len(expr.Args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments to function %v()", expr.Name)
+	}
+
+	args := make([]interface{}, len(expr.Args))
+	for i, arg := range expr.Args {
+		val, err := e.evaluate(en, arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+	return fn.call(args)
+}
+
+// isAggregateCall determines whether the given function call is a call to an
+// aggregate function. MIN and MAX are aggregate functions only if they are
+// called with a single argument.
+func isAggregateCall(expr command.FunctionExpr) bool {
+	switch strings.ToUpper(expr.Name) {
+	case "AVG", "COUNT", "GROUP_CONCAT", "SUM", "TOTAL":
+		return true
+	case "MIN", "MAX":
+		return len(expr.Args) == 1
+	}
+	return false
+}
+
+func abs(args []interface{}) (interface{}, error) {
+	switch v := toNumeric(args[0]).(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, fmt.Errorf("integer overflow")
+		}
+		if v < 0 {
+			return -v, nil
+		}
+		return v, nil
+	case float64:
+		return math.Abs(v), nil
+	}
+	return nil, nil
+}
+
+func coalesce(args []interface{}) (interface{}, error) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
+
+func nullif(args []interface{}) (interface{}, error) {
+	if args[0] != nil && args[1] != nil && compareValues(args[0], args[1], nil) == 0 {
+		return nil, nil
+	}
+	return args[0], nil
+}
+
+func length(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return int64(len(v)), nil
+	}
+	return int64(utf8.RuneCountInString(toText(args[0]))), nil
+}
+
+func lower(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	return strings.ToLower(toText(args[0])), nil
+}
+
+func upper(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	return strings.ToUpper(toText(args[0])), nil
+}
+
+func typeOf(args []interface{}) (interface{}, error) {
+	return typeName(args[0]), nil
+}
+
+// minOf returns the smallest argument, or NULL if any argument is NULL.
+func minOf(args []interface{}) (interface{}, error) {
+	return extremeOf(args, -1), nil
+}
+
+// maxOf returns the largest argument, or NULL if any argument is NULL.
+func maxOf(args []interface{}) (interface{}, error) {
+	return extremeOf(args, 1), nil
+}
+
+func extremeOf(args []interface{}, sign int) interface{} {
+	result := args[0]
+	for _, arg := range args {
+		if arg == nil {
+			return nil
+		}
+		if compareValues(arg, result, nil)*sign > 0 {
+			result = arg
+		}
+	}
+	return result
+}
+
+// substr returns the substring of the first argument, starting at the 1-based
+// position of the second argument, with the length of the third argument. A
+// negative position is counted from the end of the string.
+func substr(args []interface{}) (interface{}, error) {
+	for _, arg := range args {
+		if arg == nil {
+			return nil, nil
+		}
+	}
+
+	runes := []rune(toText(args[0]))
+	size := int64(len(runes))
+	start := toInteger(args[1])
+	count := size + 1
+	if len(args) == 3 {
+		count = toInteger(args[2])
+	}
+
+	if start < 0 {
+		start += size + 1
+		if start < 1 {
+			count += start - 1
+			start = 1
+		}
+	} else if start == 0 {
+		count--
+		start = 1
+	}
+	if count < 0 {
+		// a negative length selects the characters before the start
+		start += count
+		count = -count
+		if start < 1 {
+			count += start - 1
+			start = 1
+		}
+	}
+	first := start - 1
+	if first > size {
+		first = size
+	}
+	last := first + count
+	if last > size || last < first {
+		last = size
+	}
+	return string(runes[first:last]), nil
+}
+
+// round rounds the first argument to the number of decimal places of the
+// second argument, which defaults to zero. The result is always a real value.
+func round(args []interface{}) (interface{}, error) {
+	if args[0] == nil || (len(args) == 2 && args[1] == nil) {
+		return nil, nil
+	}
+	var digits int64
+	if len(args) == 2 {
+		digits = toInteger(args[1])
+	}
+	if digits < 0 {
+		digits = 0
+	}
+	if digits > 15 {
+		digits = 15
+	}
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(toFloat(args[0]), 'f', int(digits), 64), 64)
+	if err != nil {
+		return nil, err
+	}
+	return rounded, nil
+}
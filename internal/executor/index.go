@@ -0,0 +1,301 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/compiler/optimization"
+)
+
+var _ optimization.Indexes = (*simpleExecutor)(nil)
+
+// memIndex is an index of a memTable. It holds the keys of all rows of the
+// table, which are the values of the rows in the indexed columns. The keys
+// are only computed when the index is used, and are recomputed if the table
+// was modified since.
+type memIndex struct {
+	name  string
+	table *memTable
+	// cols are the positions of the indexed columns in the table, in the
+	// order of the index.
+	cols []int
+	// entries are the keys of all rows of the table, with the rowids of the
+	// rows, sorted by key and rowid.
+	entries []indexEntry
+	// version is the version of the table, from which the entries were
+	// computed.
+	version uint64
+}
+
+// indexEntry is the key of a single row in an index.
+type indexEntry struct {
+	key []interface{}
+	id  int64
+}
+
+func (e *simpleExecutor) executeCreateIndex(cmd command.CreateIndex) (Result, error) {
+	if cmd.Unique {
+		return nil, fmt.Errorf("unique index: %w", ErrUnsupported)
+	}
+	if cmd.Schema == "" && isSystemTableName(cmd.Name) {
+		return nil, fmt.Errorf("%v: %w", cmd.Name, ErrReservedName)
+	}
+
+	key := tableKey(cmd.Schema, cmd.Name)
+	if _, exists := e.indexes[key]; exists {
+		if cmd.IfNotExists {
+			return e.result(0), nil
+		}
+		return nil, fmt.Errorf("%v: %w", cmd.Name, ErrIndexExists)
+	}
+	tbl, ok := e.tables[tableKey(cmd.Schema, cmd.Table)]
+	if !ok {
+		return nil, fmt.Errorf("%v: %w", cmd.Table, ErrNoSuchTable)
+	}
+
+	index := &memIndex{
+		name:  cmd.Name,
+		table: tbl,
+	}
+	for _, name := range cmd.Columns {
+		col, ok := tbl.columnIndex(name)
+		if !ok {
+			return nil, fmt.Errorf("table %v has no column %v: %w", tbl.name, name, ErrNoSuchColumn)
+		}
+		index.cols = append(index.cols, col)
+	}
+	e.indexes[key] = index
+	return e.result(0), nil
+}
+
+func (e *simpleExecutor) executeDropIndex(cmd command.DropIndex) (Result, error) {
+	key := tableKey(cmd.Schema, cmd.Name)
+	index, exists := e.indexes[key]
+	if !exists {
+		if cmd.IfExists {
+			return e.result(0), nil
+		}
+		return nil, fmt.Errorf("%v: %w", cmd.Name, ErrNoSuchIndex)
+	}
+	if stat, ok := e.tables[tableKey("", statTable)]; ok {
+		deleteIndexStatistics(stat, index)
+	}
+	delete(e.indexes, key)
+	return e.result(0), nil
+}
+
+// dropIndexes drops all indexes of the given table.
+func (e *simpleExecutor) dropIndexes(tbl *memTable) {
+	for key, index := range e.indexes {
+		if index.table == tbl {
+			delete(e.indexes, key)
+		}
+	}
+}
+
+// TableIndexes returns the indexes of the table with the given schema and
+// name, so that the executor can provide indexes when optimizing commands.
+func (e *simpleExecutor) TableIndexes(schemaName, name string) []optimization.Index {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tbl, ok := e.tables[tableKey(schemaName, name)]
+	if !ok {
+		return nil
+	}
+	var indexes []optimization.Index
+	for _, index := range e.indexes {
+		if index.table != tbl {
+			continue
+		}
+		cols := make([]string, len(index.cols))
+		for i, col := range index.cols {
+			cols[i] = tbl.cols[col].name
+		}
+		indexes = append(indexes, optimization.Index{
+			Name:    index.name,
+			Columns: cols,
+		})
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+	return indexes
+}
+
+// update recomputes the entries of this index, if the table was modified since
+// they were computed.
+func (i *memIndex) update() {
+	if i.entries != nil && i.version == i.table.version {
+		return
+	}
+	entries := make([]indexEntry, len(i.table.rows))
+	for n, row := range i.table.rows {
+		key := make([]interface{}, len(i.cols))
+		for k, col := range i.cols {
+			key[k] = row.values[col]
+		}
+		entries[n] = indexEntry{
+			key: key,
+			id:  row.id,
+		}
+	}
+	// the rows are sorted by rowid, so a stable sort keeps equal keys ordered
+	// by rowid
+	sort.SliceStable(entries, func(a, b int) bool {
+		return compareKey(entries[a].key, entries[b].key) < 0
+	})
+	i.entries = entries
+	i.version = i.table.version
+}
+
+// compareKey compares the given key with the given bound, which is a prefix
+// of a key, by comparing the prefix of the key with the same length as the
+// bound. Values are compared with the BINARY collation.
+func compareKey(key, bound []interface{}) int {
+	for i := range bound {
+		if cmp := compareValues(key[i], bound[i], nil); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// keyInterval is an interval [start,end) of the positions of the entries of
+// an index, that are in a key range.
+type keyInterval struct {
+	start, end int
+	// bounded is the amount of key columns, that are covered by a bound of the
+	// key range, that the entries are in. Entries with a NULL value in any of
+	// those columns are not in the key range.
+	bounded int
+}
+
+// openIndexScan creates a cursor over the rows of the given table, whose keys
+// in the given index are in any of the key ranges of the index scan. The
+// bounds of the key ranges are evaluated in the given outer environment.
+func (e *simpleExecutor) openIndexScan(outer *env, scan command.IndexScan) (cursor, error) {
+	tbl, err := e.lookupTable(scan.Table)
+	if err != nil {
+		return nil, err
+	}
+	index, ok := e.indexes[tableKey(scan.Table.Schema, scan.Index)]
+	if !ok || index.table != tbl {
+		return nil, fmt.Errorf("%v: %w", scan.Index, ErrNoSuchIndex)
+	}
+	index.update()
+
+	// the ranges may overlap, but every row is only used once
+	seen := make(map[int]bool)
+	var positions []int
+	for _, r := range scan.Ranges {
+		interval, ok, err := e.keyInterval(outer, index, r)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		for pos := interval.start; pos < interval.end; pos++ {
+			if !seen[pos] && !hasNull(index.entries[pos].key[:interval.bounded]) {
+				seen[pos] = true
+				positions = append(positions, pos)
+			}
+		}
+	}
+	sort.Ints(positions)
+
+	name := scan.Table.Table
+	if scan.Table.Alias != "" {
+		name = scan.Table.Alias
+	}
+	names := make([]string, len(tbl.cols))
+	for i, col := range tbl.cols {
+		names[i] = col.name
+	}
+	if scan.Covering {
+		names = make([]string, len(index.cols))
+		for i, col := range index.cols {
+			names[i] = tbl.cols[col].name
+		}
+	}
+
+	c := &scanCursor{
+		cols: scanColumns(name, names),
+		rows: make([]memRow, len(positions)),
+	}
+	for i, pos := range positions {
+		entry := index.entries[pos]
+		if scan.Covering {
+			c.rows[i] = memRow{
+				id:     entry.id,
+				values: entry.key,
+			}
+			continue
+		}
+		row, _ := tbl.search(entry.id)
+		c.rows[i] = tbl.rows[row]
+	}
+	return c, nil
+}
+
+// keyInterval evaluates the bounds of the given key range, and returns the
+// interval of the entries of the given index, that are in the range. If a
+// bound is NULL, the range is empty and ok=false is returned.
+func (e *simpleExecutor) keyInterval(outer *env, index *memIndex, r command.KeyRange) (interval keyInterval, ok bool, err error) {
+	if len(r.Lo) > len(index.cols) || len(r.Hi) > len(index.cols) {
+		return keyInterval{}, false, fmt.Errorf("key range with more than %d columns of index %v", len(index.cols), index.name)
+	}
+	lo, err := e.evaluateBound(outer, r.Lo)
+	if err != nil {
+		return keyInterval{}, false, err
+	}
+	hi, err := e.evaluateBound(outer, r.Hi)
+	if err != nil {
+		return keyInterval{}, false, err
+	}
+	if hasNull(lo) || hasNull(hi) {
+		return keyInterval{}, false, nil
+	}
+
+	entries := index.entries
+	interval.start = sort.Search(len(entries), func(i int) bool {
+		cmp := compareKey(entries[i].key, lo)
+		return cmp > 0 || cmp == 0 && !r.LoExclusive
+	})
+	interval.end = len(entries)
+	if len(hi) != 0 {
+		interval.end = sort.Search(len(entries), func(i int) bool {
+			cmp := compareKey(entries[i].key, hi)
+			return cmp > 0 || cmp == 0 && r.HiExclusive
+		})
+	}
+	interval.bounded = len(lo)
+	if len(hi) > interval.bounded {
+		interval.bounded = len(hi)
+	}
+	return interval, interval.start < interval.end, nil
+}
+
+// evaluateBound evaluates the values of the given bound of a key range.
+func (e *simpleExecutor) evaluateBound(outer *env, bound []command.Expr) ([]interface{}, error) {
+	values := make([]interface{}, len(bound))
+	for i, expr := range bound {
+		val, err := e.evaluate(outer, expr)
+		if err != nil {
+			return nil, fmt.Errorf("key range: %w", err)
+		}
+		values[i] = val
+	}
+	return values, nil
+}
+
+func hasNull(values []interface{}) bool {
+	for _, val := range values {
+		if val == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/compiler"
+	"github.com/tomarrell/lbadd/internal/compiler/optimization"
+	"github.com/tomarrell/lbadd/internal/parser"
+)
+
+func TestUseIndexes(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER, w)")
+	mustExecute(t, e, "CREATE TABLE b (id INTEGER, u)")
+	mustExecute(t, e, "CREATE INDEX a_v ON a (v)")
+	mustExecute(t, e, "CREATE INDEX a_v_w ON a (v, w)")
+	mustExecute(t, e, "CREATE INDEX a_w ON a (w)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1, 10, 'x'), (2, 20, 'y'), (3, 10, 'z'), (4, 30, 1), (5, 10, 2.5)")
+	mustExecute(t, e, "INSERT INTO a (id, w) VALUES (6, 'x')")
+	mustExecute(t, e, "INSERT INTO a (id, v) VALUES (7, 20)")
+	mustExecute(t, e, "INSERT INTO b VALUES (1, 10), (2, 'x'), (3, 40)")
+	mustExecute(t, e, "INSERT INTO b (id) VALUES (4)")
+
+	rules := []optimization.Rule{
+		optimization.PushDownPredicates,
+		optimization.UseIndexes(e, e),
+	}
+	tests := []string{
+		"SELECT * FROM a WHERE v = 10",
+		"SELECT * FROM a WHERE v > 10",
+		"SELECT * FROM a WHERE v < 20",
+		"SELECT * FROM a WHERE 20 >= v",
+		"SELECT * FROM a WHERE v BETWEEN 10 AND 20",
+		"SELECT * FROM a WHERE v NOT BETWEEN 10 AND 20",
+		"SELECT * FROM a WHERE v NOT BETWEEN 20 AND 10",
+		"SELECT * FROM a WHERE v != 10",
+		"SELECT * FROM a WHERE v = 10.0",
+		"SELECT * FROM a WHERE w = 'x'",
+		"SELECT * FROM a WHERE w > 1",
+		"SELECT * FROM a WHERE w < 'y'",
+		"SELECT * FROM a WHERE w = 'X' COLLATE NOCASE",
+		"SELECT v FROM a WHERE v >= 20",
+		"SELECT w FROM (SELECT * FROM a WHERE v = 10) AS s WHERE s.w > 'x'",
+		"SELECT * FROM a AS s WHERE s.v = 30",
+		"SELECT * FROM a INDEXED BY a_w WHERE v = 10",
+		"SELECT * FROM a, b WHERE a.v = b.u",
+		"SELECT * FROM a JOIN b ON a.id = b.id WHERE a.v = 10",
+		"SELECT * FROM b WHERE b.u IN (SELECT w FROM a WHERE v = 10)",
+	}
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			assert.ElementsMatch(t, mustQuery(t, e, sql), mustQueryOptimized(t, e, sql, rules...))
+		})
+	}
+
+	// rows are produced in the order of their keys
+	assert.Equal(t, [][]interface{}{
+		{int64(10)}, {int64(10)}, {int64(10)}, {int64(20)}, {int64(20)}, {int64(30)},
+	}, mustQueryOptimized(t, e, "SELECT v FROM a WHERE v >= 10", rules...))
+
+	// modifications of the table are visible through the index
+	mustExecute(t, e, "INSERT INTO a VALUES (8, 10, 'w')")
+	assert.Equal(t, [][]interface{}{
+		{int64(1)}, {int64(3)}, {int64(5)}, {int64(8)},
+	}, mustQueryOptimized(t, e, "SELECT id FROM a WHERE v = 10", rules...))
+	mustExecute(t, e, "DELETE FROM a")
+	assert.Empty(t, mustQueryOptimized(t, e, "SELECT id FROM a WHERE v = 10", rules...))
+}
+
+func TestUseIndexes_Plan(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER, w)")
+	mustExecute(t, e, "CREATE INDEX a_v ON a (v)")
+
+	plan := func(sql string) string {
+		stmt, errs, ok := parser.New(sql).Next()
+		require.True(t, ok)
+		require.Len(t, errs, 0)
+		cmd, err := compiler.New(compiler.OptionCatalog(e), compiler.OptionEnableRules(
+			optimization.UseIndexes(e, e),
+		)).Compile(stmt)
+		require.NoError(t, err)
+		return cmd.String()
+	}
+
+	assert.Equal(t, "Project[cols=v](IndexScan[table=a,index=a_v,ranges=([10..10]),covering]())", plan("SELECT v FROM a WHERE v = 10"))
+	assert.Equal(t, "Project[cols=a.id,a.v,a.w](IndexScan[table=a,index=a_v,ranges=((..10))]())", plan("SELECT * FROM a WHERE v < 10"))
+	assert.Equal(t, "Project[cols=id](Select[filter=w==1](Scan[table=a]()))", plan("SELECT id FROM a WHERE w = 1"))
+
+	// dropped indexes are not used anymore
+	mustExecute(t, e, "DROP INDEX a_v")
+	assert.Equal(t, "Project[cols=v](Select[filter=v==10](Scan[table=a]()))", plan("SELECT v FROM a WHERE v = 10"))
+}
+
+func TestIndex_Errors(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (v)")
+	mustExecute(t, e, "CREATE INDEX a_v ON a (v)")
+
+	_, err := execute(e, "CREATE INDEX a_v ON a (v)")
+	assert.True(errors.Is(err, ErrIndexExists))
+	mustExecute(t, e, "CREATE INDEX IF NOT EXISTS a_v ON a (v)")
+	_, err = execute(e, "CREATE INDEX b_v ON b (v)")
+	assert.True(errors.Is(err, ErrNoSuchTable))
+	_, err = execute(e, "CREATE INDEX a_w ON a (w)")
+	assert.True(errors.Is(err, ErrNoSuchColumn))
+	_, err = execute(e, "CREATE UNIQUE INDEX a_u ON a (v)")
+	assert.True(errors.Is(err, ErrUnsupported))
+	_, err = execute(e, "CREATE INDEX lbadd_index ON a (v)")
+	assert.True(errors.Is(err, ErrReservedName))
+	_, err = execute(e, "DROP INDEX missing")
+	assert.True(errors.Is(err, ErrNoSuchIndex))
+	mustExecute(t, e, "DROP INDEX IF EXISTS missing")
+
+	// dropping a table drops its indexes
+	mustExecute(t, e, "DROP TABLE a")
+	mustExecute(t, e, "CREATE TABLE a (v)")
+	assert.Empty(e.TableIndexes("", "a"))
+	mustExecute(t, e, "CREATE INDEX a_v ON a (v)")
+	assert.Equal([]optimization.Index{
+		{Name: "a_v", Columns: []string{"v"}},
+	}, e.TableIndexes("", "a"))
+}
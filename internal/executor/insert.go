@@ -0,0 +1,248 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/database/table"
+)
+
+// rowIDTarget is the target index of an insert column, that refers to the
+// implicit rowid column instead of a declared column.
+const rowIDTarget = -1
+
+func (e *simpleExecutor) executeInsert(cmd command.Insert) (Result, error) {
+	tbl, err := e.lookupTable(cmd.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.OnConflict != nil {
+		if err := checkConflictTarget(tbl, cmd.OnConflict); err != nil {
+			return nil, err
+		}
+	}
+
+	var targets []int
+	if !cmd.DefaultValues {
+		targets, err = insertTargets(tbl, cmd.Cols)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	datasets, err := e.insertDatasets(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	sequences, err := e.loadSequences()
+	if err != nil {
+		return nil, err
+	}
+
+	// remember the state before the insertion, so that the statement can be
+	// reverted if a row can not be inserted
+	before := append([]memRow(nil), tbl.rows...)
+	seqBefore := sequences.get(tbl.qualifiedName())
+	seq := seqBefore
+	lastInsertID := e.lastInsertID
+
+	var inserted int64
+	for _, dataset := range datasets {
+		if len(dataset) != len(targets) {
+			err = fmt.Errorf("table %v has %d columns but %d values were supplied", tbl.name, len(targets), len(dataset))
+		} else {
+			var id int64
+			var outcome insertOutcome
+			id, outcome, err = e.insertRow(tbl, cmd, targets, dataset, seq)
+			switch outcome {
+			case rowInserted:
+				inserted++
+				lastInsertID = id
+				if id > seq {
+					seq = id
+				}
+			case rowUpdated:
+				inserted++
+			}
+		}
+		if err != nil {
+			if cmd.InsertOr != command.InsertOrFail {
+				tbl.setRows(before)
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if tbl.autoincrement() && seq != seqBefore {
+		if seqErr := sequences.set(tbl.qualifiedName(), seq); seqErr != nil {
+			tbl.setRows(before)
+			return nil, fmt.Errorf("update sequence: %w", seqErr)
+		}
+	}
+	e.lastInsertID = lastInsertID
+	if err != nil {
+		return nil, err
+	}
+	return e.result(inserted), nil
+}
+
+// insertTargets maps the insert columns to the indices of the declared columns
+// of the given table. If no insert columns are specified, all declared columns
+// are the targets, in declaration order. An insert column, that refers to the
+// implicit rowid column, is mapped to rowIDTarget.
+func insertTargets(tbl *memTable, cols []command.Column) ([]int, error) {
+	if len(cols) == 0 {
+		targets := make([]int, len(tbl.cols))
+		for i := range targets {
+			targets[i] = i
+		}
+		return targets, nil
+	}
+
+	targets := make([]int, len(cols))
+	for i, col := range cols {
+		name := col.Column.String()
+		if index, ok := tbl.columnIndex(name); ok {
+			targets[i] = index
+		} else if table.IsRowIDName(name) {
+			targets[i] = rowIDTarget
+		} else {
+			return nil, fmt.Errorf("table %v has no column %v: %w", tbl.name, name, ErrNoSuchColumn)
+		}
+	}
+	return targets, nil
+}
+
+// insertDatasets evaluates the input of the given insert command to the
+// datasets that have to be inserted. An input other than values is queried
+// completely before any dataset is inserted, so that a query of the table
+// that is inserted into doesn't see its own insertions.
+func (e *simpleExecutor) insertDatasets(cmd command.Insert) ([][]interface{}, error) {
+	if cmd.DefaultValues {
+		return [][]interface{}{nil}, nil
+	}
+
+	values, ok := cmd.Input.(command.Values)
+	if !ok {
+		c, err := e.open(nil, cmd.Input)
+		if err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		rows, err := drain(c)
+		if err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+		visible := visibleColumns(c.Columns())
+		datasets := make([][]interface{}, len(rows))
+		for i, row := range rows {
+			datasets[i] = make([]interface{}, len(visible))
+			for j, index := range visible {
+				datasets[i][j] = normalizeBool(row[index])
+			}
+		}
+		return datasets, nil
+	}
+	datasets := make([][]interface{}, len(values.Values))
+	for i, exprs := range values.Values {
+		for _, expr := range exprs {
+			val, err := e.evaluateExpr(expr)
+			if err != nil {
+				return nil, fmt.Errorf("values: %w", err)
+			}
+			datasets[i] = append(datasets[i], val)
+		}
+	}
+	return datasets, nil
+}
+
+// insertOutcome describes what happened to a dataset, that was inserted into
+// a table.
+type insertOutcome uint8
+
+const (
+	// rowIgnored indicates that the dataset was dropped due to a conflict.
+	rowIgnored insertOutcome = iota
+	// rowInserted indicates that the dataset was inserted as new row.
+	rowInserted
+	// rowUpdated indicates that the dataset conflicted with an existing row,
+	// which was updated by the upsert clause of the insert.
+	rowUpdated
+)
+
+// insertRow inserts a single dataset into the given table, which is the table
+// of the given insert command. The rowid of the row is taken from the dataset
+// if it contains a non-NULL value for the rowid or the rowid alias column,
+// otherwise a new rowid is allocated. If the rowid is already in use, the
+// conflict is resolved by the upsert clause or the InsertOr fallback of the
+// command.
+func (e *simpleExecutor) insertRow(tbl *memTable, cmd command.Insert, targets []int, dataset []interface{}, seq int64) (int64, insertOutcome, error) {
+	values := make([]interface{}, len(tbl.cols))
+	assigned := make([]bool, len(tbl.cols))
+	var explicitID interface{}
+	for i, target := range targets {
+		if target == rowIDTarget {
+			explicitID = dataset[i]
+			continue
+		}
+		values[target] = dataset[i]
+		assigned[target] = true
+	}
+
+	// apply default values to all columns that were not assigned
+	for i, col := range tbl.cols {
+		if assigned[i] || col.dflt == nil {
+			continue
+		}
+		val, err := e.evaluateExpr(col.dflt)
+		if err != nil {
+			return 0, rowIgnored, fmt.Errorf("default value of %v: %w", col.name, err)
+		}
+		values[i] = val
+	}
+
+	alias, hasAlias := table.RowIDAlias(tbl)
+	if hasAlias && values[alias] != nil {
+		explicitID = values[alias]
+	}
+
+	var id int64
+	var err error
+	if explicitID != nil {
+		id, err = toRowID(explicitID)
+	} else {
+		id, err = nextRowID(tbl, seq)
+	}
+	if err != nil {
+		return 0, rowIgnored, err
+	}
+	if hasAlias {
+		values[alias] = id
+	}
+
+	for i, col := range tbl.cols {
+		if col.notNull && values[i] == nil {
+			return 0, rowIgnored, fmt.Errorf("not null %v.%v: %w", tbl.name, col.name, ErrConstraint)
+		}
+	}
+
+	if index, exists := tbl.search(id); exists {
+		switch {
+		case cmd.OnConflict != nil:
+			return e.upsertRow(tbl, cmd, index, values)
+		case cmd.InsertOr == command.InsertOrIgnore:
+			return 0, rowIgnored, nil
+		case cmd.InsertOr == command.InsertOrReplace:
+		default:
+			return 0, rowIgnored, fmt.Errorf("unique %v.rowid: %w", tbl.name, ErrConstraint)
+		}
+	}
+
+	tbl.put(memRow{
+		id:     id,
+		values: values,
+	})
+	return id, rowInserted, nil
+}
@@ -0,0 +1,268 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// openJoin creates a cursor over the join of the left and right input of the
+// given join. The join is performed as nested loop join, with the right input
+// being held in memory.
+func (e *simpleExecutor) openJoin(outer *env, join command.Join) (cursor, error) {
+	left, err := e.open(outer, join.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.open(outer, join.Right)
+	if err != nil {
+		_ = left.Close()
+		return nil, err
+	}
+	rightCols := right.Columns()
+	rightRows, err := drain(right)
+	if err != nil {
+		_ = left.Close()
+		return nil, err
+	}
+	if isSemiOrAntiJoin(join) {
+		if join.Natural || len(join.Using) != 0 {
+			_ = left.Close()
+			return nil, fmt.Errorf("%v with USING: %w", join.Type, ErrUnsupported)
+		}
+		leftEnv := newEnv(left.Columns(), outer)
+		return &semiJoinCursor{
+			e:         e,
+			leftEnv:   leftEnv,
+			rightEnv:  newEnv(rightCols, leftEnv),
+			left:      left,
+			rightRows: rightRows,
+			filter:    join.Filter,
+			anti:      join.Type == command.JoinAnti,
+		}, nil
+	}
+
+	cols := append(append([]resultColumn(nil), left.Columns()...), rightCols...)
+	using, err := usingColumns(join, cols, len(cols)-len(rightCols))
+	if err != nil {
+		_ = left.Close()
+		return nil, err
+	}
+	return &joinCursor{
+		e:         e,
+		en:        newEnv(cols, outer),
+		cols:      cols,
+		left:      left,
+		rightRows: rightRows,
+		rightCols: len(rightCols),
+		filter:    join.Filter,
+		using:     using,
+		outerJoin: join.Type == command.JoinLeft || join.Type == command.JoinLeftOuter,
+	}, nil
+}
+
+// isSemiOrAntiJoin determines whether the given join only produces the rows
+// of its left input.
+func isSemiOrAntiJoin(join command.Join) bool {
+	return join.Type == command.JoinSemi || join.Type == command.JoinAnti
+}
+
+// usingColumns resolves the USING columns of the given join, or the common
+// columns of the left and right input, if the join is a natural one. The given
+// columns are the columns of the left input, followed by the columns of the
+// right input, which start at the given index. For every USING column, the
+// indices of the left and the right column are returned, and the right column
+// is marked as coalesced, so that unqualified references and star expansions
+// only see the left column.
+func usingColumns(join command.Join, cols []resultColumn, rightStart int) ([][2]int, error) {
+	names := join.Using
+	if join.Natural {
+		names = nil
+		for _, col := range cols[rightStart:] {
+			if col.hidden {
+				continue
+			}
+			if indexOfColumn(cols[:rightStart], col.name) != -1 {
+				names = appendName(names, col.name)
+			}
+		}
+	}
+
+	var using [][2]int
+	for _, name := range names {
+		leftIndex := indexOfColumn(cols[:rightStart], name)
+		rightIndex := indexOfColumn(cols[rightStart:], name)
+		if leftIndex == -1 || rightIndex == -1 {
+			return nil, fmt.Errorf("cannot join using column %v - column not present in both tables", name)
+		}
+		rightIndex += rightStart
+		cols[rightIndex].hidden = true
+		cols[rightIndex].coalesced = true
+		using = append(using, [2]int{leftIndex, rightIndex})
+	}
+	return using, nil
+}
+
+// indexOfColumn returns the index of the first visible column with the given
+// name, or -1 if there is no such column.
+func indexOfColumn(cols []resultColumn, name string) int {
+	for i, col := range cols {
+		if !col.hidden && strings.EqualFold(col.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendName appends the given name to the given names, if it is not already
+// contained.
+func appendName(names []string, name string) []string {
+	if containsName(names, name) {
+		return names
+	}
+	return append(names, name)
+}
+
+// containsName determines whether the given names contain the given name,
+// ignoring case.
+func containsName(names []string, name string) bool {
+	for _, other := range names {
+		if strings.EqualFold(other, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinCursor is a cursor over the combinations of the rows of the left input
+// and the right rows, whose USING columns are equal, and for which the filter
+// evaluates to true. If this is an
+// outer join, left rows without any matching right row are combined with a
+// right row, that consists of NULL values only.
+type joinCursor struct {
+	e         *simpleExecutor
+	en        *env
+	cols      []resultColumn
+	left      cursor
+	rightRows [][]interface{}
+	rightCols int
+	filter    command.Expr
+	using     [][2]int
+	outerJoin bool
+
+	leftRow []interface{}
+	pos     int
+	matched bool
+}
+
+func (c *joinCursor) Columns() []resultColumn { return c.cols }
+
+func (c *joinCursor) Next() ([]interface{}, bool, error) {
+	for {
+		if c.leftRow == nil {
+			row, ok, err := c.left.Next()
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			c.leftRow = row
+			c.pos = 0
+			c.matched = false
+		}
+
+		for c.pos < len(c.rightRows) {
+			combined := append(append(make([]interface{}, 0, len(c.cols)), c.leftRow...), c.rightRows[c.pos]...)
+			c.pos++
+			if !c.usingEqual(combined) {
+				continue
+			}
+			if c.filter != nil {
+				c.en.row = combined
+				pass, err := c.e.evaluate(c.en, c.filter)
+				if err != nil {
+					return nil, false, fmt.Errorf("join filter: %w", err)
+				}
+				if !isTrue(pass) {
+					continue
+				}
+			}
+			c.matched = true
+			return combined, true, nil
+		}
+
+		leftRow := c.leftRow
+		c.leftRow = nil
+		if c.outerJoin && !c.matched {
+			return append(append(make([]interface{}, 0, len(c.cols)), leftRow...), make([]interface{}, c.rightCols)...), true, nil
+		}
+	}
+}
+
+// usingEqual determines whether all USING columns of the given combined row
+// are equal. NULL values are never equal.
+func (c *joinCursor) usingEqual(combined []interface{}) bool {
+	for _, pair := range c.using {
+		left, right := combined[pair[0]], combined[pair[1]]
+		if left == nil || right == nil || compareValues(left, right, nil) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *joinCursor) Close() error { return c.left.Close() }
+
+// semiJoinCursor is a cursor over the rows of the left input, for which any of
+// the right rows passes the filter, or, if this is an anti join, for which none
+// of the right rows passes the filter. The filter is evaluated in the
+// environment of the right row, which is enclosed by the environment of the
+// left row, like the filter of a correlated subquery.
+type semiJoinCursor struct {
+	e         *simpleExecutor
+	leftEnv   *env
+	rightEnv  *env
+	left      cursor
+	rightRows [][]interface{}
+	filter    command.Expr
+	anti      bool
+}
+
+func (c *semiJoinCursor) Columns() []resultColumn { return c.leftEnv.cols }
+
+func (c *semiJoinCursor) Next() ([]interface{}, bool, error) {
+	for {
+		row, ok, err := c.left.Next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		matched, err := c.matches(row)
+		if err != nil {
+			return nil, false, err
+		}
+		if matched != c.anti {
+			return row, true, nil
+		}
+	}
+}
+
+// matches determines whether any of the right rows passes the filter for the
+// given left row.
+func (c *semiJoinCursor) matches(leftRow []interface{}) (bool, error) {
+	c.leftEnv.row = leftRow
+	for _, rightRow := range c.rightRows {
+		if c.filter == nil {
+			return true, nil
+		}
+		c.rightEnv.row = rightRow
+		pass, err := c.e.evaluate(c.rightEnv, c.filter)
+		if err != nil {
+			return false, fmt.Errorf("join filter: %w", err)
+		}
+		if isTrue(pass) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c *semiJoinCursor) Close() error { return c.left.Close() }
@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJoinTestExecutor(t *testing.T) *simpleExecutor {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE item (id INTEGER, name VARCHAR(10))")
+	mustExecute(t, e, "INSERT INTO item VALUES (1, 'pen'), (2, 'ink'), (3, 'cap')")
+	mustExecute(t, e, "INSERT INTO item (name) VALUES ('lid')")
+	mustExecute(t, e, "CREATE TABLE price (id INTEGER, amount INTEGER)")
+	mustExecute(t, e, "INSERT INTO price VALUES (1, 5), (2, 7), (4, 9)")
+	mustExecute(t, e, "INSERT INTO price (amount) VALUES (0)")
+	mustExecute(t, e, "CREATE TABLE stock (id INTEGER, name VARCHAR(10), qty INTEGER)")
+	mustExecute(t, e, "INSERT INTO stock VALUES (1, 'pen', 10), (2, 'paper', 3)")
+	return e
+}
+
+func TestJoin(t *testing.T) {
+	e := newJoinTestExecutor(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  [][]interface{}
+	}{
+		{
+			"comma join",
+			"SELECT item.id, amount FROM item, price WHERE item.id == price.id ORDER BY item.id",
+			[][]interface{}{{int64(1), int64(5)}, {int64(2), int64(7)}},
+		},
+		{
+			"comma join of three tables",
+			"SELECT COUNT(*) FROM item, price, stock",
+			[][]interface{}{{int64(32)}},
+		},
+		{
+			"using",
+			"SELECT * FROM item JOIN price USING (id) ORDER BY id",
+			[][]interface{}{{int64(1), "pen", int64(5)}, {int64(2), "ink", int64(7)}},
+		},
+		{
+			"using with qualified column",
+			"SELECT price.id, amount FROM item JOIN price USING (id) ORDER BY amount DESC",
+			[][]interface{}{{int64(2), int64(7)}, {int64(1), int64(5)}},
+		},
+		{
+			"using with table star",
+			"SELECT price.* FROM item JOIN price USING (id) ORDER BY price.id",
+			[][]interface{}{{int64(1), int64(5)}, {int64(2), int64(7)}},
+		},
+		{
+			"using with where",
+			"SELECT name FROM item JOIN price USING (id) WHERE id > 1",
+			[][]interface{}{{"ink"}},
+		},
+		{
+			"left join using",
+			"SELECT id, amount FROM item LEFT JOIN price USING (id) ORDER BY id",
+			[][]interface{}{{nil, nil}, {int64(1), int64(5)}, {int64(2), int64(7)}, {int64(3), nil}},
+		},
+		{
+			"natural join",
+			"SELECT * FROM item NATURAL JOIN stock",
+			[][]interface{}{{int64(1), "pen", int64(10)}},
+		},
+		{
+			"natural join on one common column",
+			"SELECT * FROM price NATURAL JOIN stock ORDER BY id",
+			[][]interface{}{{int64(1), int64(5), "pen", int64(10)}, {int64(2), int64(7), "paper", int64(3)}},
+		},
+		{
+			"natural join after using",
+			"SELECT id, amount, qty FROM item JOIN price USING (id) NATURAL JOIN stock",
+			[][]interface{}{{int64(1), int64(5), int64(10)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mustQuery(t, e, tt.query))
+		})
+	}
+
+	for _, query := range []string{
+		"SELECT * FROM item JOIN price USING (name)",
+		"SELECT name FROM item, stock",
+	} {
+		_, err := execute(e, query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestJoinStarExpansion(t *testing.T) {
+	e := newJoinTestExecutor(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCols []string
+		want     [][]interface{}
+	}{
+		{
+			"duplicate columns",
+			"SELECT * FROM item, stock WHERE item.id == stock.id ORDER BY qty",
+			[]string{"id", "name", "id:1", "name:1", "qty"},
+			[][]interface{}{{int64(2), "ink", int64(2), "paper", int64(3)}, {int64(1), "pen", int64(1), "pen", int64(10)}},
+		},
+		{
+			"duplicate column of subquery",
+			"SELECT \"name:1\" FROM (SELECT * FROM item JOIN stock USING (id)) AS s ORDER BY s.id",
+			[]string{"name:1"},
+			[][]interface{}{{"pen"}, {"paper"}},
+		},
+		{
+			"table star after star",
+			"SELECT *, stock.* FROM item NATURAL JOIN stock",
+			[]string{"id", "name", "qty", "id:1", "name:1", "qty:1"},
+			[][]interface{}{{int64(1), "pen", int64(10), int64(1), "pen", int64(10)}},
+		},
+		{
+			"common table",
+			"WITH t(a, b) AS (SELECT * FROM price WHERE amount > 6) SELECT * FROM t, item WHERE a == id",
+			[]string{"a", "b", "id", "name"},
+			[][]interface{}{{int64(2), int64(7), int64(2), "ink"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := mustExecute(t, e, tt.query)
+			require.Implements(t, (*QueryResult)(nil), res, tt.query)
+			assert.Equal(t, tt.wantCols, res.(QueryResult).Columns())
+			assert.Equal(t, tt.want, res.(QueryResult).Rows())
+		})
+	}
+}
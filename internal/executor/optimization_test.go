@@ -0,0 +1,265 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/compiler"
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/compiler/optimization"
+	"github.com/tomarrell/lbadd/internal/parser"
+)
+
+// optimizationRules are the optimization rules, that must not change the
+// result of any query.
+var optimizationRules = []optimization.Rule{
+	optimization.HalfJoin,
+	optimization.PushDownPredicates,
+	optimization.FoldConstants,
+	optimization.PruneColumns,
+	optimization.DecorrelateSubqueries,
+	optimization.PushDownLimits,
+}
+
+func mustQueryOptimized(t *testing.T, e *simpleExecutor, sql string, rules ...optimization.Rule) [][]interface{} {
+	t.Helper()
+	if rules == nil {
+		rules = optimizationRules
+	}
+	stmt, errs, ok := parser.New(sql).Next()
+	require.True(t, ok, sql)
+	require.Len(t, errs, 0, sql)
+	cmd, err := compiler.New(compiler.OptionCatalog(e), compiler.OptionEnableRules(rules...)).Compile(stmt)
+	require.NoError(t, err, sql)
+	res, err := e.Execute(cmd)
+	require.NoError(t, err, sql)
+	require.Implements(t, (*QueryResult)(nil), res, sql)
+	return res.(QueryResult).Rows()
+}
+
+func TestOptimizationRules(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER)")
+	mustExecute(t, e, "CREATE TABLE b (id INTEGER, w INTEGER)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1, 10), (2, 20), (3, 10)")
+	mustExecute(t, e, "INSERT INTO a (id) VALUES (4)")
+	mustExecute(t, e, "INSERT INTO b VALUES (1, 100), (1, 200), (5, 500)")
+	mustExecute(t, e, "INSERT INTO b (id) VALUES (3)")
+
+	tests := []string{
+		"SELECT * FROM a JOIN b ON a.id = b.id WHERE a.v = 10 ORDER BY a.id, b.w",
+		"SELECT * FROM a JOIN b ON a.id = b.id WHERE b.w > 100",
+		"SELECT * FROM a, b WHERE a.id = b.id",
+		"SELECT * FROM a LEFT JOIN b ON a.id = b.id WHERE b.w ISNULL",
+		"SELECT * FROM a LEFT JOIN b ON a.id = b.id WHERE a.v = 10",
+		"SELECT * FROM a LEFT JOIN b ON b.w > 100",
+		"SELECT * FROM a LEFT JOIN b ON a.v = 10",
+		"SELECT * FROM a JOIN b USING (id) WHERE b.w = 100",
+		"SELECT * FROM (SELECT id AS y, v FROM a) AS s WHERE s.y > 1",
+		"SELECT * FROM (SELECT id AS y, v FROM a) AS s JOIN b ON s.y = b.id WHERE s.v = 10",
+		"SELECT v, COUNT(*) FROM a GROUP BY v HAVING COUNT(*) > 1",
+		"SELECT * FROM a WHERE EXISTS (SELECT * FROM b WHERE b.id = a.id)",
+		"SELECT id FROM a WHERE 1 = 0",
+		"SELECT * FROM a WHERE 1 = 0",
+		"SELECT id FROM a WHERE 1 < 2 ORDER BY id",
+		"SELECT COUNT(*) FROM a WHERE 0",
+		"SELECT id, 2 * 3, -3, 5 / 0, UPPER('abc'), ABS(-2) FROM a",
+		"SELECT id FROM a WHERE v > 5 * 2",
+		"SELECT a.v FROM a JOIN b ON a.id = b.id WHERE b.w > 100",
+		"SELECT b.w FROM a JOIN b USING (id)",
+		"SELECT s.y FROM (SELECT id AS y, v, v * 2 FROM a) AS s",
+		"SELECT 1 FROM (SELECT id, v FROM a) AS s",
+		"SELECT rowid, v FROM a",
+		"SELECT COUNT(*) FROM b",
+		"SELECT v FROM a ORDER BY id DESC LIMIT 2",
+		"SELECT DISTINCT v FROM a",
+		"SELECT id FROM a WHERE v IN (SELECT w / 10 FROM b)",
+		"SELECT id FROM a WHERE EXISTS (SELECT w FROM b WHERE b.id = a.id)",
+		"SELECT id FROM a WHERE NOT EXISTS (SELECT * FROM b WHERE b.id = a.id)",
+		"SELECT id FROM a WHERE EXISTS (SELECT * FROM a AS s WHERE s.v = a.v)",
+		"SELECT id FROM a WHERE EXISTS (SELECT * FROM b WHERE b.w > 300)",
+		"SELECT id FROM a WHERE EXISTS (SELECT * FROM b WHERE EXISTS (SELECT * FROM a AS s WHERE s.id = b.id))",
+		"SELECT id FROM a WHERE EXISTS (SELECT * FROM b WHERE EXISTS (SELECT * FROM a AS s WHERE s.v = a.v))",
+		"SELECT id FROM a WHERE EXISTS (SELECT * FROM (SELECT id, w FROM b) AS s WHERE s.w > 100)",
+		"SELECT id FROM a WHERE id IN (SELECT id FROM b)",
+		"SELECT id FROM a WHERE id NOT IN (SELECT id FROM b)",
+		"SELECT id FROM a WHERE v IN (SELECT w / 10 FROM b WHERE b.id = a.id)",
+		"SELECT id FROM a WHERE v NOT IN (SELECT w / 10 FROM b)",
+		"SELECT id FROM a WHERE v NOT IN (SELECT w FROM b WHERE w > 100)",
+		"SELECT id FROM a WHERE v NOT IN (SELECT w FROM b WHERE b.id = 9)",
+		"SELECT id FROM a WHERE v IN (SELECT v FROM a WHERE a.id > 2)",
+		"SELECT a.id, (SELECT COUNT(*) FROM b WHERE b.id = a.id) FROM a",
+		"SELECT a.id, (SELECT TOTAL(b.w) FROM b WHERE b.id = a.id) AS t FROM a",
+		"SELECT a.id FROM a WHERE a.v < (SELECT MAX(b.w) FROM b WHERE b.id = a.id)",
+		"SELECT a.id, (SELECT COUNT(b.w) FROM b WHERE ABS(a.id) = b.id) FROM a",
+		"SELECT id FROM a LIMIT 2",
+		"SELECT id FROM a LIMIT 2 OFFSET 1",
+		"SELECT id FROM a LIMIT 0",
+		"SELECT id FROM a LIMIT -1 OFFSET 2",
+		"SELECT id, v FROM a ORDER BY v DESC, id LIMIT 2 OFFSET 1",
+		"SELECT id FROM a UNION ALL SELECT w FROM b LIMIT 5",
+		"SELECT id FROM a UNION ALL SELECT w FROM b LIMIT 2 OFFSET 3",
+		"SELECT id FROM a UNION SELECT id FROM b LIMIT 3",
+		"SELECT * FROM (SELECT id, v FROM a ORDER BY id DESC LIMIT 3) AS s WHERE s.v = 10 LIMIT 1",
+		"SELECT a.id, (SELECT COUNT(*) FROM b WHERE b.id = a.id) FROM a LIMIT 2 OFFSET 1",
+	}
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			assert.Equal(t, mustQuery(t, e, sql), mustQueryOptimized(t, e, sql))
+		})
+	}
+}
+
+func TestReorderJoins(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER)")
+	mustExecute(t, e, "CREATE TABLE b (id INTEGER, w INTEGER)")
+	mustExecute(t, e, "CREATE TABLE c (id INTEGER PRIMARY KEY, u INTEGER)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1, 10), (2, 20), (3, 10), (4, 30), (5, 10)")
+	mustExecute(t, e, "INSERT INTO b VALUES (1, 100), (1, 200), (3, 300)")
+	mustExecute(t, e, "INSERT INTO c VALUES (1, 7), (3, 8), (4, 9)")
+
+	rules := append([]optimization.Rule{optimization.ReorderJoins(e)}, optimizationRules...)
+	tests := []string{
+		"SELECT a.v, b.w, c.u FROM a JOIN b ON a.id = b.id, c WHERE b.id = c.id",
+		"SELECT a.v, b.w, c.u FROM a JOIN b ON a.id = b.id JOIN c ON c.id = a.id",
+		"SELECT a.v, b.w FROM a JOIN b ON a.id = b.id WHERE a.v = 10",
+		"SELECT a.v, COUNT(*) FROM a, b, c WHERE a.id = c.id GROUP BY a.v",
+		"SELECT a.id, c.u FROM a LEFT JOIN c ON a.id = c.id, b WHERE b.id = a.id",
+	}
+	for _, sql := range tests {
+		t.Run(sql, func(t *testing.T) {
+			assert.ElementsMatch(t, mustQuery(t, e, sql), mustQueryOptimized(t, e, sql, rules...))
+		})
+	}
+}
+
+func TestPruneColumns(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER, w)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1, 10, 'x'), (2, 20, 'y')")
+
+	stmt, errs, ok := parser.New("SELECT w, rowid FROM a WHERE v > 10").Next()
+	require.True(t, ok)
+	require.Len(t, errs, 0)
+	cmd, err := compiler.New(compiler.OptionCatalog(e), compiler.OptionEnableRules(optimization.PruneColumns)).Compile(stmt)
+	require.NoError(t, err)
+	assert.Equal(t, "Project[cols=w,rowid](Select[filter=v > 10](Scan[table=a,cols=rowid,v,w]()))", cmd.String())
+
+	res, err := e.Execute(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"y", int64(2)}}, res.(QueryResult).Rows())
+}
+
+func TestDecorrelateSubqueries(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER)")
+	mustExecute(t, e, "CREATE TABLE b (id INTEGER, w INTEGER)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1, 10), (2, 20)")
+	mustExecute(t, e, "INSERT INTO a (id) VALUES (3)")
+	mustExecute(t, e, "INSERT INTO b VALUES (1, 10), (1, 30)")
+
+	plan := func(sql string) string {
+		stmt, errs, ok := parser.New(sql).Next()
+		require.True(t, ok)
+		require.Len(t, errs, 0)
+		cmd, err := compiler.New(compiler.OptionCatalog(e), compiler.OptionEnableRules(optimization.DecorrelateSubqueries)).Compile(stmt)
+		require.NoError(t, err)
+		return cmd.String()
+	}
+
+	assert.Equal(t, "Project[cols=id](Join[filter=^v==w OR ^v IS NULL OR w IS NULL,type=JoinAnti](Scan[table=a](),Scan[table=b]()))", plan("SELECT id FROM a WHERE v NOT IN (SELECT w FROM b)"))
+	assert.Equal(t, [][]interface{}{{int64(2)}}, mustQueryOptimized(t, e, "SELECT id FROM a WHERE v NOT IN (SELECT w FROM b)", optimization.DecorrelateSubqueries))
+	assert.Equal(t, [][]interface{}{{int64(1)}, {int64(2)}, {int64(3)}}, mustQueryOptimized(t, e, "SELECT id FROM a WHERE v NOT IN (SELECT w FROM b WHERE b.id = 9)", optimization.DecorrelateSubqueries))
+
+	assert.Contains(t, plan("SELECT a.id, (SELECT COUNT(*) FROM b WHERE b.id = a.id) FROM a"), "type=JoinLeft")
+	assert.Equal(t, [][]interface{}{
+		{int64(1), int64(2)}, {int64(2), int64(0)}, {int64(3), int64(0)},
+	}, mustQueryOptimized(t, e, "SELECT a.id, (SELECT COUNT(*) FROM b WHERE b.id = a.id) FROM a", optimization.DecorrelateSubqueries))
+}
+
+func TestPushDownLimits(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1, 50), (2, 40), (3, 30), (4, 20), (5, 10)")
+
+	stmt, errs, ok := parser.New("SELECT id FROM a ORDER BY v LIMIT 2 OFFSET 1").Next()
+	require.True(t, ok)
+	require.Len(t, errs, 0)
+	cmd, err := compiler.New(compiler.OptionCatalog(e), compiler.OptionEnableRules(optimization.PushDownLimits)).Compile(stmt)
+	require.NoError(t, err)
+	assert.Equal(t, "Project[cols=id](Limit[limit=2](Offset[offset=1](Sort[by=v ASC NULLS FIRST,limit=3](Scan[table=a]()))))", cmd.String())
+
+	res, err := e.Execute(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(4)}, {int64(3)}}, res.(QueryResult).Rows())
+
+	// the composition of limit and offset is not commutative, which the
+	// folded chains must respect
+	stmt, errs, ok = parser.New("SELECT id FROM a").Next()
+	require.True(t, ok)
+	require.Len(t, errs, 0)
+	input, err := compiler.New(compiler.OptionCatalog(e)).Compile(stmt)
+	require.NoError(t, err)
+	optimizer := optimization.Optimizer{Rules: []optimization.Rule{optimization.PushDownLimits}}
+	for _, test := range []struct {
+		cmd  command.List
+		want [][]interface{}
+	}{
+		{
+			command.Limit{
+				Limit: command.LiteralExpr{Value: "3"},
+				Input: command.Offset{Offset: command.LiteralExpr{Value: "1"}, Input: input.(command.List)},
+			},
+			[][]interface{}{{int64(2)}, {int64(3)}, {int64(4)}},
+		},
+		{
+			command.Offset{
+				Offset: command.LiteralExpr{Value: "1"},
+				Input:  command.Limit{Limit: command.LiteralExpr{Value: "3"}, Input: input.(command.List)},
+			},
+			[][]interface{}{{int64(2)}, {int64(3)}},
+		},
+		{
+			command.Offset{
+				Offset: command.LiteralExpr{Value: "1"},
+				Input: command.Limit{
+					Limit: command.LiteralExpr{Value: "3"},
+					Input: command.Offset{Offset: command.LiteralExpr{Value: "1"}, Input: input.(command.List)},
+				},
+			},
+			[][]interface{}{{int64(3)}, {int64(4)}},
+		},
+	} {
+		t.Run(test.cmd.String(), func(t *testing.T) {
+			optimized, _ := optimizer.Optimize(test.cmd)
+			for _, cmd := range []command.Command{test.cmd, optimized} {
+				res, err := e.Execute(cmd)
+				require.NoError(t, err, cmd)
+				assert.Equal(t, test.want, res.(QueryResult).Rows(), cmd)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE a (id INTEGER PRIMARY KEY, v INTEGER)")
+	mustExecute(t, e, "INSERT INTO a VALUES (1, 10), (2, 20)")
+
+	assert.Equal([][]interface{}{
+		{"plan", "Limit[limit=1](Project[cols=id](Scan[table=a]()))"},
+	}, mustQuery(t, e, "EXPLAIN SELECT id FROM a LIMIT 1"))
+	assert.Equal([][]interface{}{
+		{"plan", "Project[cols=id](Limit[limit=1](Scan[table=a,cols=id]()))"},
+		{"rule", "PruneColumns"},
+		{"rule", "PushDownLimits"},
+	}, mustQueryOptimized(t, e, "EXPLAIN SELECT id FROM a LIMIT 1"))
+
+	// the explained command is not executed
+	mustQuery(t, e, "EXPLAIN DELETE FROM a")
+	assert.Equal([]int64{1, 2}, rowIDs(e.tables["a"]))
+}
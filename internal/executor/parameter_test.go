@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/compiler"
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/parser"
+)
+
+func mustPrepare(t *testing.T, e *simpleExecutor, sql string) command.Command {
+	t.Helper()
+	stmt, errs, ok := parser.New(sql).Next()
+	require.True(t, ok, sql)
+	require.Len(t, errs, 0, sql)
+	cmd, err := compiler.New(compiler.OptionCatalog(e)).Compile(stmt)
+	require.NoError(t, err, sql)
+	return cmd
+}
+
+func TestParameters(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+
+	mustExecute(t, e, "CREATE TABLE items (id INTEGER PRIMARY KEY, name VARCHAR(10), price REAL, data BLOB)")
+
+	insert := mustPrepare(t, e, "INSERT INTO items VALUES (?, ?, ?, ?)")
+	assert.Len(command.Parameters(insert), 4)
+	for _, args := range [][]interface{}{
+		{int64(1), "apple", 1.5, []byte{0x01}},
+		{int64(2), "pear", 2.0, nil},
+		{int64(3), nil, 0.5, []byte{}},
+	} {
+		named := make([]driver.NamedValue, len(args))
+		for i, arg := range args {
+			named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+		}
+		cmd, err := command.Bind(insert, named)
+		require.NoError(t, err)
+		_, err = e.Execute(cmd)
+		require.NoError(t, err)
+	}
+
+	// the prepared command is not bound by binding it
+	_, err := e.Execute(insert)
+	assert.Error(err)
+
+	query := mustPrepare(t, e, "SELECT name FROM items WHERE price < :max ORDER BY id LIMIT ?2")
+	params := command.Parameters(query)
+	assert.Len(params, 2)
+	assert.Equal(":max", params[0].Name)
+	cmd, err := command.Bind(query, []driver.NamedValue{
+		{Ordinal: 1, Name: "max", Value: 2.5},
+		{Ordinal: 2, Value: int64(2)},
+	})
+	require.NoError(t, err)
+	res, err := e.Execute(cmd)
+	require.NoError(t, err)
+	assert.Equal([][]interface{}{{"apple"}, {"pear"}}, res.(QueryResult).Rows())
+
+	_, err = command.Bind(query, []driver.NamedValue{{Ordinal: 1, Name: "max", Value: 2.5}})
+	assert.Error(err, "unbound parameter must be an error")
+	_, err = command.Bind(query, []driver.NamedValue{
+		{Ordinal: 1, Name: "min", Value: 2.5},
+		{Ordinal: 2, Value: int64(3)},
+	})
+	assert.Error(err, "unknown named parameter must be an error")
+}
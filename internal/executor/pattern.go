@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+func (e *simpleExecutor) evaluatePattern(en *env, expr command.PatternExpr) (interface{}, error) {
+	val, err := e.evaluate(en, expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := e.evaluate(en, expr.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	var escape interface{}
+	if expr.Escape != nil {
+		escape, err = e.evaluate(en, expr.Escape)
+		if err != nil {
+			return nil, err
+		}
+		if escape == nil {
+			return nil, nil
+		}
+	}
+	if val == nil || pattern == nil {
+		return nil, nil
+	}
+
+	var match bool
+	switch expr.Operator {
+	case "LIKE":
+		var escapeRune rune = -1
+		if escape != nil {
+			text := toText(escape)
+			if utf8.RuneCountInString(text) != 1 {
+				return nil, fmt.Errorf("ESCAPE expression must be a single character")
+			}
+			escapeRune, _ = utf8.DecodeRuneInString(foldASCII(text))
+		}
+		match = like([]rune(foldASCII(toText(pattern))), []rune(foldASCII(toText(val))), escapeRune)
+	case "GLOB":
+		match = glob([]rune(toText(pattern)), []rune(toText(val)))
+	case "REGEXP":
+		re, err := regexp.Compile(toText(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("regexp: %w", err)
+		}
+		match = re.MatchString(toText(val))
+	default:
+		return nil, fmt.Errorf("%v: %w", expr.Operator, ErrUnsupported)
+	}
+	return match != expr.Invert, nil
+}
+
+// like matches the given text against the given LIKE pattern, where '%'
+// matches any sequence of characters, and '_' matches any single character.
+// The escape character, if it is not -1, matches the character that follows
+// it literally.
+func like(pattern, text []rune, escape rune) bool {
+	for len(pattern) > 0 {
+		switch p := pattern[0]; {
+		case p == escape && len(pattern) > 1:
+			if len(text) == 0 || text[0] != pattern[1] {
+				return false
+			}
+			pattern, text = pattern[2:], text[1:]
+		case p == '%':
+			for i := 0; i <= len(text); i++ {
+				if like(pattern[1:], text[i:], escape) {
+					return true
+				}
+			}
+			return false
+		case p == '_':
+			if len(text) == 0 {
+				return false
+			}
+			pattern, text = pattern[1:], text[1:]
+		default:
+			if len(text) == 0 || text[0] != p {
+				return false
+			}
+			pattern, text = pattern[1:], text[1:]
+		}
+	}
+	return len(text) == 0
+}
+
+// glob matches the given text against the given GLOB pattern, where '*'
+// matches any sequence of characters, '?' matches any single character, and
+// '[...]' matches any single character of the given set. Matching is case
+// sensitive.
+func glob(pattern, text []rune) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := 0; i <= len(text); i++ {
+				if glob(pattern[1:], text[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(text) == 0 {
+				return false
+			}
+			pattern, text = pattern[1:], text[1:]
+		case '[':
+			end := strings.IndexRune(string(pattern[1:]), ']')
+			if end == -1 || len(text) == 0 {
+				return false
+			}
+			set := []rune(string(pattern[1:])[:end])
+			if !matchSet(set, text[0]) {
+				return false
+			}
+			pattern, text = pattern[len(set)+2:], text[1:]
+		default:
+			if len(text) == 0 || text[0] != pattern[0] {
+				return false
+			}
+			pattern, text = pattern[1:], text[1:]
+		}
+	}
+	return len(text) == 0
+}
+
+// matchSet determines whether the given character is contained in the given
+// character set of a GLOB pattern. A leading '^' inverts the set, and two
+// characters separated by '-' denote a range.
+func matchSet(set []rune, r rune) bool {
+	invert := len(set) > 0 && set[0] == '^'
+	if invert {
+		set = set[1:]
+	}
+	var found bool
+	for i := 0; i < len(set); i++ {
+		if i+2 < len(set) && set[i+1] == '-' {
+			if set[i] <= r && r <= set[i+2] {
+				found = true
+			}
+			i += 2
+			continue
+		}
+		if set[i] == r {
+			found = true
+		}
+	}
+	return found != invert
+}
@@ -0,0 +1,429 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// executeQuery executes the given list and returns all of its rows as query
+// result. Hidden columns are not part of the result.
+func (e *simpleExecutor) executeQuery(list command.List) (Result, error) {
+	c, err := e.open(nil, list)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := drain(c)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := visibleColumns(c.Columns())
+	names := make([]string, len(visible))
+	for i, index := range visible {
+		names[i] = c.Columns()[index].name
+	}
+	result := queryResult{
+		columns: names,
+		rows:    make([][]interface{}, len(rows)),
+	}
+	for i, row := range rows {
+		values := make([]interface{}, len(visible))
+		for j, index := range visible {
+			values[j] = normalizeBool(row[index])
+		}
+		result.rows[i] = values
+	}
+	return result, nil
+}
+
+// open creates a cursor over the rows of the given list. Correlated column
+// references within the list are resolved in the given outer environment,
+// which may be nil.
+func (e *simpleExecutor) open(outer *env, list command.List) (cursor, error) {
+	switch l := list.(type) {
+	case command.Scan:
+		return e.openTable(outer, l.Table, l.Cols)
+	case command.IndexScan:
+		return e.openIndexScan(outer, l)
+	case command.Values:
+		return e.openValues(outer, l)
+	case command.Empty:
+		return openEmpty(l)
+	case command.Select:
+		input, err := e.open(outer, l.Input)
+		if err != nil {
+			return nil, err
+		}
+		return &selectCursor{
+			e:      e,
+			en:     newEnv(input.Columns(), outer),
+			input:  input,
+			filter: l.Filter,
+		}, nil
+	case command.Project, command.Offset, command.Sort:
+		return e.openLimited(outer, l, -1)
+	case command.Limit:
+		limit, err := e.evaluateCount(outer, l.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("limit: %w", err)
+		}
+		input, err := e.openLimited(outer, l.Input, limit)
+		if err != nil {
+			return nil, err
+		}
+		return &limitCursor{
+			cursor: input,
+			limit:  limit,
+		}, nil
+	case command.Distinct:
+		input, err := e.open(outer, l.Input)
+		if err != nil {
+			return nil, err
+		}
+		return &distinctCursor{
+			cursor: input,
+			seen:   make(map[string]struct{}),
+		}, nil
+	case command.Join:
+		return e.openJoin(outer, l)
+	case command.Aggregate:
+		return e.openAggregate(outer, l)
+	case command.Window:
+		return e.openWindow(outer, l)
+	case command.Union:
+		return e.openSetOperation(outer, setUnion, l.All, l.Left, l.Right)
+	case command.Intersect:
+		return e.openSetOperation(outer, setIntersect, l.All, l.Left, l.Right)
+	case command.Except:
+		return e.openSetOperation(outer, setExcept, l.All, l.Left, l.Right)
+	case command.With:
+		return e.openWith(outer, l)
+	}
+	return nil, fmt.Errorf("list %T: %w", list, ErrUnsupported)
+}
+
+// openLimited creates a cursor over the rows of the given list, of which only
+// the first limit rows are required. A negative limit means that all rows are
+// required. The limit is passed through projections and offsets, so that a
+// sort below them only has to determine the first rows.
+func (e *simpleExecutor) openLimited(outer *env, list command.List, limit int64) (cursor, error) {
+	switch l := list.(type) {
+	case command.Sort:
+		return e.openSort(outer, l, limit)
+	case command.Project:
+		input, err := e.openLimited(outer, l.Input, limit)
+		if err != nil {
+			return nil, err
+		}
+		project, err := newProjectCursor(e, outer, input, l.Cols)
+		if err != nil {
+			_ = input.Close()
+			return nil, err
+		}
+		return project, nil
+	case command.Offset:
+		offset, err := e.evaluateCount(outer, l.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("offset: %w", err)
+		}
+		if offset > 0 && limit >= 0 {
+			limit += offset
+		}
+		input, err := e.openLimited(outer, l.Input, limit)
+		if err != nil {
+			return nil, err
+		}
+		return &offsetCursor{
+			cursor: input,
+			offset: offset,
+		}, nil
+	}
+	return e.open(outer, list)
+}
+
+// openTable creates a cursor over the rows of the given table. The columns of
+// the cursor are assigned to the alias of the table, if it has one. If cols is
+// not nil, a scan of a simple table only produces the columns with the given
+// names.
+func (e *simpleExecutor) openTable(outer *env, tbl command.Table, cols []string) (cursor, error) {
+	switch t := tbl.(type) {
+	case command.SimpleTable:
+		found, err := e.lookupTable(t)
+		if err != nil {
+			return nil, err
+		}
+		name := t.Table
+		if t.Alias != "" {
+			name = t.Alias
+		}
+		return newScanCursor(found, name, cols), nil
+	case command.SubqueryTable:
+		input, err := e.open(outer, t.Input)
+		if err != nil {
+			return nil, err
+		}
+		return newRenameCursor(input, t.Alias), nil
+	case command.CommonTableRef:
+		return e.openCommonTable(outer, t)
+	}
+	return nil, fmt.Errorf("table %T: %w", tbl, ErrUnsupported)
+}
+
+// openValues evaluates all expressions of the given values, and creates a
+// cursor over the results. The columns are named column1, column2 and so on.
+func (e *simpleExecutor) openValues(outer *env, values command.Values) (cursor, error) {
+	var cols []resultColumn
+	if len(values.Values) != 0 {
+		cols = make([]resultColumn, len(values.Values[0]))
+		for i := range cols {
+			cols[i].name = "column" + strconv.Itoa(i+1)
+		}
+	}
+
+	rows := make([][]interface{}, len(values.Values))
+	for i, exprs := range values.Values {
+		if len(exprs) != len(cols) {
+			return nil, fmt.Errorf("all VALUES must have the same number of terms")
+		}
+		rows[i] = make([]interface{}, len(exprs))
+		for j, expr := range exprs {
+			val, err := e.evaluate(outer, expr)
+			if err != nil {
+				return nil, fmt.Errorf("values: %w", err)
+			}
+			rows[i][j] = val
+		}
+	}
+	return &sliceCursor{
+		cols: cols,
+		rows: rows,
+	}, nil
+}
+
+// openEmpty creates a cursor without any rows, whose columns are named like
+// the given columns of a projection. Star columns can't be named, because
+// there is no input.
+func openEmpty(empty command.Empty) (cursor, error) {
+	cols := make([]resultColumn, len(empty.Cols))
+	for i, col := range empty.Cols {
+		if lit, ok := col.Column.(command.LiteralExpr); ok && lit.Value == "*" {
+			return nil, fmt.Errorf("star column of empty list: %w", ErrUnsupported)
+		}
+		cols[i].name = columnName(col)
+	}
+	return &sliceCursor{
+		cols: cols,
+	}, nil
+}
+
+// columnName returns the name of the result column of the given projected
+// column, which is either its alias, the name of the referenced column, or the
+// text of its expression.
+func columnName(col command.Column) string {
+	if col.Alias != "" {
+		return col.Alias
+	}
+	if ref, ok := col.Column.(command.ColumnRef); ok {
+		return ref.Column
+	}
+	return col.Column.String()
+}
+
+// evaluateCount evaluates the expression of a limit or an offset. A negative
+// count is returned as -1.
+func (e *simpleExecutor) evaluateCount(outer *env, expr command.Expr) (int64, error) {
+	val, err := e.evaluate(outer, expr)
+	if err != nil {
+		return 0, err
+	}
+	switch numeric := toNumeric(val).(type) {
+	case int64:
+		if numeric < 0 {
+			return -1, nil
+		}
+		return numeric, nil
+	case float64:
+		if numeric == float64(int64(numeric)) {
+			if numeric < 0 {
+				return -1, nil
+			}
+			return int64(numeric), nil
+		}
+	}
+	return 0, fmt.Errorf("%v: %w", expr, ErrDatatypeMismatch)
+}
+
+// selectCursor is a cursor, that only returns the rows of its input, for which
+// the filter evaluates to true.
+type selectCursor struct {
+	e      *simpleExecutor
+	en     *env
+	input  cursor
+	filter command.Expr
+}
+
+func (c *selectCursor) Columns() []resultColumn { return c.input.Columns() }
+
+func (c *selectCursor) Next() ([]interface{}, bool, error) {
+	for {
+		row, ok, err := c.input.Next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		c.en.row = row
+		pass, err := c.e.evaluate(c.en, c.filter)
+		if err != nil {
+			return nil, false, fmt.Errorf("filter: %w", err)
+		}
+		if isTrue(pass) {
+			return row, true, nil
+		}
+	}
+}
+
+func (c *selectCursor) Close() error { return c.input.Close() }
+
+// projectCursor is a cursor, that evaluates the projected columns for every
+// row of its input.
+type projectCursor struct {
+	e     *simpleExecutor
+	en    *env
+	input cursor
+	cols  []resultColumn
+	// exprs are the expressions of the projected columns. A nil expression
+	// is a column of the input, whose index is stored in indices.
+	exprs   []command.Expr
+	indices []int
+}
+
+func newProjectCursor(e *simpleExecutor, outer *env, input cursor, cols []command.Column) (*projectCursor, error) {
+	c := &projectCursor{
+		e:     e,
+		en:    newEnv(input.Columns(), outer),
+		input: input,
+	}
+	for _, col := range cols {
+		if lit, ok := col.Column.(command.LiteralExpr); ok && lit.Value == "*" {
+			if err := c.expandStar(col.Table); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		c.cols = append(c.cols, resultColumn{name: columnName(col)})
+		c.exprs = append(c.exprs, col.Column)
+		c.indices = append(c.indices, -1)
+	}
+	return c, nil
+}
+
+// expandStar adds all visible columns of the input, that originate from the
+// given table, to the projected columns. If the table is empty, all visible
+// columns are added. Coalesced columns of a join are only added, if the table
+// is not empty.
+func (c *projectCursor) expandStar(tableName string) error {
+	var found bool
+	for i, col := range c.input.Columns() {
+		if tableName != "" && col.table != tableName {
+			continue
+		}
+		if col.hidden && (tableName == "" || !col.coalesced) {
+			continue
+		}
+		found = true
+		c.cols = append(c.cols, resultColumn{
+			table: col.table,
+			name:  col.name,
+		})
+		c.exprs = append(c.exprs, nil)
+		c.indices = append(c.indices, i)
+	}
+	if !found && tableName != "" {
+		return fmt.Errorf("%v: %w", tableName, ErrNoSuchTable)
+	}
+	if !found {
+		return fmt.Errorf("no tables specified")
+	}
+	return nil
+}
+
+func (c *projectCursor) Columns() []resultColumn { return c.cols }
+
+func (c *projectCursor) Next() ([]interface{}, bool, error) {
+	row, ok, err := c.input.Next()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	c.en.row = row
+	projected := make([]interface{}, len(c.cols))
+	for i, expr := range c.exprs {
+		if expr == nil {
+			projected[i] = row[c.indices[i]]
+			continue
+		}
+		val, err := c.e.evaluate(c.en, expr)
+		if err != nil {
+			return nil, false, err
+		}
+		projected[i] = val
+	}
+	return projected, true, nil
+}
+
+func (c *projectCursor) Close() error { return c.input.Close() }
+
+// limitCursor is a cursor, that returns at most limit rows of its input. A
+// negative limit means that there is no limit.
+type limitCursor struct {
+	cursor
+	limit int64
+}
+
+func (c *limitCursor) Next() ([]interface{}, bool, error) {
+	if c.limit == 0 {
+		return nil, false, nil
+	}
+	if c.limit > 0 {
+		c.limit--
+	}
+	return c.cursor.Next()
+}
+
+// offsetCursor is a cursor, that skips the first offset rows of its input.
+type offsetCursor struct {
+	cursor
+	offset int64
+}
+
+func (c *offsetCursor) Next() ([]interface{}, bool, error) {
+	for ; c.offset > 0; c.offset-- {
+		_, ok, err := c.cursor.Next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+	}
+	return c.cursor.Next()
+}
+
+// distinctCursor is a cursor, that skips rows of its input, that are equal to
+// a row that was already returned.
+type distinctCursor struct {
+	cursor
+	seen map[string]struct{}
+}
+
+func (c *distinctCursor) Next() ([]interface{}, bool, error) {
+	for {
+		row, ok, err := c.cursor.Next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		key := rowKey(row)
+		if _, seen := c.seen[key]; !seen {
+			c.seen[key] = struct{}{}
+			return row, true, nil
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package executor
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+var _ ExecResult = (*execResult)(nil)
+var _ QueryResult = (*queryResult)(nil)
 
 // Result describes the result of a command execution. The result is always a
 // table that has a header row. The smallest possible result table is a table
@@ -9,3 +15,68 @@ import "fmt"
 type Result interface {
 	fmt.Stringer
 }
+
+// ExecResult is the result of a command that modified the database instead of
+// querying it, such as an insert or a delete.
+type ExecResult interface {
+	Result
+	// LastInsertID returns the rowid of the last row that was inserted by the
+	// command. If the command didn't insert any rows, this is the rowid of the
+	// last row that was inserted by any previous command.
+	LastInsertID() int64
+	// RowsAffected returns the amount of rows that were inserted, updated or
+	// deleted by the command.
+	RowsAffected() int64
+}
+
+// QueryResult is the result of a query. It consists of the names of the
+// result columns, and the result rows. Values are represented by the Go types
+// nil (NULL), int64, float64, string and []byte.
+type QueryResult interface {
+	Result
+	// Columns returns the names of the result columns.
+	Columns() []string
+	// Rows returns the result rows. Every row has one value per column.
+	Rows() [][]interface{}
+}
+
+type execResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r execResult) LastInsertID() int64 { return r.lastInsertID }
+
+func (r execResult) RowsAffected() int64 { return r.rowsAffected }
+
+func (r execResult) String() string {
+	return fmt.Sprintf("rows affected: %d, last insert id: %d", r.rowsAffected, r.lastInsertID)
+}
+
+type queryResult struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+func (r queryResult) Columns() []string { return r.columns }
+
+func (r queryResult) Rows() [][]interface{} { return r.rows }
+
+func (r queryResult) String() string {
+	var buf strings.Builder
+	buf.WriteString(strings.Join(r.columns, "|"))
+	for _, row := range r.rows {
+		buf.WriteByte('\n')
+		for i, val := range row {
+			if i > 0 {
+				buf.WriteByte('|')
+			}
+			if val != nil {
+				buf.WriteString(toText(val))
+			} else {
+				buf.WriteString("NULL")
+			}
+		}
+	}
+	return buf.String()
+}
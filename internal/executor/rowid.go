@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// nextRowID computes the rowid for a row that is inserted into the given table
+// without an explicit rowid. The given sequence is the largest rowid that was
+// ever used in the table, and is only respected if the table has an
+// AUTOINCREMENT column.
+//
+// Without AUTOINCREMENT, the new rowid is one larger than the largest rowid
+// currently in the table, meaning that rowids of deleted rows may be re-used.
+// If the largest possible rowid is already in use, the smallest unused
+// positive rowid is chosen instead. With AUTOINCREMENT, the new rowid is one
+// larger than the largest rowid that was ever used in the table, and no
+// attempt is made to find unused rowids.
+func nextRowID(tbl *memTable, seq int64) (int64, error) {
+	max, _ := tbl.maxRowID()
+
+	if tbl.autoincrement() {
+		if seq > max {
+			max = seq
+		}
+		if max == math.MaxInt64 {
+			return 0, ErrFull
+		}
+		return max + 1, nil
+	}
+
+	if max < math.MaxInt64 {
+		return max + 1, nil
+	}
+	// the largest possible rowid is in use, search for a gap
+	candidate := int64(1)
+	for _, row := range tbl.rows {
+		if row.id < candidate {
+			continue
+		}
+		if row.id > candidate {
+			return candidate, nil
+		}
+		if candidate == math.MaxInt64 {
+			break
+		}
+		candidate++
+	}
+	return 0, ErrFull
+}
+
+// toRowID converts the given value to a rowid. Integers are used as they are,
+// reals and texts are only accepted if they represent an integer without loss.
+func toRowID(val interface{}) (int64, error) {
+	switch v := val.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), nil
+		}
+	case string:
+		if id, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return id, nil
+		}
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%v can not be used as rowid: %w", val, ErrDatatypeMismatch)
+}
@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/afero"
+)
+
+// sequenceStore holds the largest rowid that was ever used in a table, for
+// every table that has an AUTOINCREMENT column. This is the equivalent of
+// SQLite's sqlite_sequence table. Every modification is persisted to a file
+// immediately, so that rowids are not re-used after a restart.
+type sequenceStore struct {
+	fs   afero.Fs
+	file string
+	seqs map[string]int64
+}
+
+// loadSequences loads the sequences that are persisted in the given file. If
+// the file does not exist, an empty sequence store is returned, which will
+// create the file as soon as it is modified.
+func loadSequences(fs afero.Fs, file string) (*sequenceStore, error) {
+	store := &sequenceStore{
+		fs:   fs,
+		file: file,
+		seqs: make(map[string]int64),
+	}
+
+	data, err := afero.ReadFile(fs, file)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.seqs); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	return store, nil
+}
+
+// get returns the sequence value of the table with the given key, or 0 if no
+// rowid has been allocated for that table yet.
+func (s *sequenceStore) get(table string) int64 {
+	return s.seqs[table]
+}
+
+// set updates the sequence value of the table with the given key and persists
+// the change.
+func (s *sequenceStore) set(table string, seq int64) error {
+	s.seqs[table] = seq
+	return s.persist()
+}
+
+// remove removes the sequence of the table with the given key and persists the
+// change. This is necessary when a table is dropped.
+func (s *sequenceStore) remove(table string) error {
+	if _, ok := s.seqs[table]; !ok {
+		return nil
+	}
+	delete(s.seqs, table)
+	return s.persist()
+}
+
+// persist writes all sequences to a temporary file and then renames that file
+// to the sequence file, so that the sequence file is never left in an
+// incomplete state.
+func (s *sequenceStore) persist() error {
+	data, err := json.Marshal(s.seqs)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	tmp := s.file + ".tmp"
+	if err := afero.WriteFile(s.fs, tmp, data, 0600); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := s.fs.Rename(tmp, s.file); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	return nil
+}
@@ -2,25 +2,224 @@ package executor
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
 	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/database/table"
 )
 
 var _ Executor = (*simpleExecutor)(nil)
 
 type simpleExecutor struct {
 	log          zerolog.Logger
+	fs           afero.Fs
 	databaseFile string
+	// sortBudget is the amount of memory in bytes, that a single sort may
+	// occupy before it spills to temporary files.
+	sortBudget int64
+	// recursionLimit is the maximum amount of rows, that a recursive common
+	// table may produce.
+	recursionLimit int64
+
+	// mu guards all fields below. Commands are executed one after another.
+	mu           sync.Mutex
+	tables       map[string]*memTable
+	indexes      map[string]*memIndex
+	sequences    *sequenceStore
+	lastInsertID int64
 }
 
-func newSimpleExecutor(log zerolog.Logger, databaseFile string) *simpleExecutor {
+func newSimpleExecutor(log zerolog.Logger, fs afero.Fs, databaseFile string) *simpleExecutor {
 	return &simpleExecutor{
-		log:          log,
-		databaseFile: databaseFile,
+		log:            log,
+		fs:             fs,
+		databaseFile:   databaseFile,
+		sortBudget:     defaultSortBudget,
+		recursionLimit: defaultRecursionLimit,
+		tables:         make(map[string]*memTable),
+		indexes:        make(map[string]*memIndex),
 	}
 }
 
 func (e *simpleExecutor) Execute(cmd command.Command) (Result, error) {
-	return nil, fmt.Errorf("unimplemented")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch c := cmd.(type) {
+	case command.CreateTable:
+		return e.executeCreateTable(c)
+	case command.DropTable:
+		return e.executeDropTable(c)
+	case command.CreateIndex:
+		return e.executeCreateIndex(c)
+	case command.DropIndex:
+		return e.executeDropIndex(c)
+	case command.Insert:
+		return e.executeInsert(c)
+	case command.Delete:
+		return e.executeDelete(c)
+	case command.Analyze:
+		return e.executeAnalyze(c)
+	case command.Explain:
+		return e.executeExplain(c)
+	case command.List:
+		return e.executeQuery(c)
+	}
+	return nil, fmt.Errorf("%T: %w", cmd, ErrUnsupported)
+}
+
+func (e *simpleExecutor) executeCreateTable(cmd command.CreateTable) (Result, error) {
+	if cmd.WithoutRowID {
+		return nil, fmt.Errorf("without rowid: %w", ErrUnsupported)
+	}
+
+	if cmd.Schema == "" && isSystemTableName(cmd.Name) {
+		return nil, fmt.Errorf("%v: %w", cmd.Name, ErrReservedName)
+	}
+
+	key := tableKey(cmd.Schema, cmd.Name)
+	if _, exists := e.tables[key]; exists {
+		if cmd.IfNotExists {
+			return e.result(0), nil
+		}
+		return nil, fmt.Errorf("%v: %w", cmd.Name, ErrTableExists)
+	}
+
+	// the rowid is the only uniqueness constraint, that is enforced
+	for _, col := range cmd.Columns {
+		if col.Unique {
+			return nil, fmt.Errorf("unique %v: %w", col.Name, ErrUnsupported)
+		}
+	}
+
+	tbl := newMemTable(cmd)
+	for i, col := range tbl.cols {
+		if alias, ok := table.RowIDAlias(tbl); col.autoincrement && !(ok && alias == i) {
+			return nil, fmt.Errorf("autoincrement on %v, which is not an integer primary key: %w", col.name, ErrUnsupported)
+		}
+	}
+	e.tables[key] = tbl
+	return e.result(0), nil
+}
+
+func (e *simpleExecutor) executeDropTable(cmd command.DropTable) (Result, error) {
+	key := tableKey(cmd.Schema, cmd.Name)
+	tbl, exists := e.tables[key]
+	if !exists {
+		if cmd.IfExists {
+			return e.result(0), nil
+		}
+		return nil, fmt.Errorf("%v: %w", cmd.Name, ErrNoSuchTable)
+	}
+
+	sequences, err := e.loadSequences()
+	if err != nil {
+		return nil, err
+	}
+	if err := sequences.remove(key); err != nil {
+		return nil, fmt.Errorf("remove sequence: %w", err)
+	}
+	for name := range systemTables {
+		if system, ok := e.tables[tableKey("", name)]; ok {
+			deleteStatistics(system, tbl)
+		}
+	}
+	e.dropIndexes(tbl)
+	delete(e.tables, key)
+	return e.result(0), nil
+}
+
+// executeExplain returns the plan of the explained command, without executing
+// it. The first row holds the plan, and is followed by a row for every
+// optimization rule, that rewrote the command, in the order they were applied
+// first.
+func (e *simpleExecutor) executeExplain(cmd command.Explain) (Result, error) {
+	result := queryResult{
+		columns: []string{"kind", "detail"},
+		rows:    [][]interface{}{{"plan", cmd.Command.String()}},
+	}
+	for _, rule := range cmd.Optimizations {
+		result.rows = append(result.rows, []interface{}{"rule", rule})
+	}
+	return result, nil
+}
+
+func (e *simpleExecutor) executeDelete(cmd command.Delete) (Result, error) {
+	tbl, err := e.lookupTable(cmd.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	name := tbl.name
+	if simple, ok := cmd.Table.(command.SimpleTable); ok && simple.Alias != "" {
+		name = simple.Alias
+	}
+
+	// the filter is evaluated against all rows before any of them is deleted,
+	// so that a subquery of the filter sees the table as it was before the
+	// statement
+	scan := newScanCursor(tbl, name, nil)
+	en := newEnv(scan.Columns(), nil)
+	var kept []memRow
+	for i := 0; ; i++ {
+		row, ok, err := scan.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		en.row = row
+		pass, err := e.evaluate(en, cmd.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("filter: %w", err)
+		}
+		if !isTrue(pass) {
+			kept = append(kept, scan.rows[i])
+		}
+	}
+
+	deleted := int64(len(scan.rows) - len(kept))
+	if deleted != 0 {
+		tbl.setRows(kept)
+	}
+	return e.result(deleted), nil
+}
+
+// lookupTable returns the table that is referenced by the given command table.
+func (e *simpleExecutor) lookupTable(tbl command.Table) (*memTable, error) {
+	simple, ok := tbl.(command.SimpleTable)
+	if !ok {
+		return nil, fmt.Errorf("table %T: %w", tbl, ErrUnsupported)
+	}
+	found, ok := e.tables[tableKey(simple.Schema, simple.Table)]
+	if !ok {
+		return nil, fmt.Errorf("%v: %w", simple.Table, ErrNoSuchTable)
+	}
+	return found, nil
+}
+
+// loadSequences returns the sequence store of this executor. The store is
+// loaded from the file system upon first use.
+func (e *simpleExecutor) loadSequences() (*sequenceStore, error) {
+	if e.sequences != nil {
+		return e.sequences, nil
+	}
+	sequences, err := loadSequences(e.fs, e.databaseFile+".seq")
+	if err != nil {
+		return nil, fmt.Errorf("load sequences: %w", err)
+	}
+	e.sequences = sequences
+	return sequences, nil
+}
+
+// result creates an ExecResult with the given amount of affected rows, and the
+// current last insert id.
+func (e *simpleExecutor) result(rowsAffected int64) ExecResult {
+	return execResult{
+		lastInsertID: e.lastInsertID,
+		rowsAffected: rowsAffected,
+	}
 }
@@ -0,0 +1,189 @@
+package executor
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/compiler"
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/parser"
+)
+
+func newTestExecutor(fs afero.Fs) *simpleExecutor {
+	return newSimpleExecutor(zerolog.Nop(), fs, "test.db")
+}
+
+func mustExecute(t *testing.T, e *simpleExecutor, sql string) Result {
+	t.Helper()
+	res, err := execute(e, sql)
+	require.NoError(t, err, sql)
+	return res
+}
+
+func execute(e *simpleExecutor, sql string) (Result, error) {
+	stmt, errs, ok := parser.New(sql).Next()
+	if len(errs) != 0 || !ok {
+		return nil, errs[0]
+	}
+	cmd, err := compiler.New(compiler.OptionCatalog(e)).Compile(stmt)
+	if err != nil {
+		return nil, err
+	}
+	return e.Execute(cmd)
+}
+
+func rowIDs(tbl *memTable) []int64 {
+	var ids []int64
+	for _, row := range tbl.rows {
+		ids = append(ids, row.id)
+	}
+	return ids
+}
+
+func TestRowID(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+
+	mustExecute(t, e, "CREATE TABLE users (name VARCHAR(25))")
+	for i := 1; i <= 3; i++ {
+		res := mustExecute(t, e, "INSERT INTO users VALUES ('user"+strconv.Itoa(i)+"')")
+		assert.EqualValues(i, res.(ExecResult).LastInsertID())
+		assert.EqualValues(1, res.(ExecResult).RowsAffected())
+	}
+
+	res := mustExecute(t, e, "INSERT INTO users (rowid, name) VALUES (10, 'jdoe')")
+	assert.EqualValues(10, res.(ExecResult).LastInsertID())
+	res = mustExecute(t, e, "INSERT INTO users VALUES ('jane'), ('john')")
+	assert.EqualValues(12, res.(ExecResult).LastInsertID())
+	assert.EqualValues(2, res.(ExecResult).RowsAffected())
+	assert.Equal([]int64{1, 2, 3, 10, 11, 12}, rowIDs(e.tables["users"]))
+
+	// rowids are re-used without autoincrement
+	mustExecute(t, e, "DELETE FROM users")
+	res = mustExecute(t, e, "INSERT INTO users VALUES ('jdoe')")
+	assert.EqualValues(1, res.(ExecResult).LastInsertID())
+}
+
+func TestRowIDAlias(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+
+	mustExecute(t, e, "CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(25))")
+	mustExecute(t, e, "INSERT INTO users (name) VALUES ('jdoe')")
+	mustExecute(t, e, "INSERT INTO users VALUES (7, 'jane')")
+	res := mustExecute(t, e, "INSERT INTO users (name) VALUES ('john')")
+	assert.EqualValues(8, res.(ExecResult).LastInsertID())
+
+	tbl := e.tables["users"]
+	assert.Equal([]int64{1, 7, 8}, rowIDs(tbl))
+	for _, row := range tbl.rows {
+		assert.Equal(row.id, row.values[0], "alias column must hold the rowid")
+	}
+
+	_, err := execute(e, "INSERT INTO users VALUES ('abc', 'jdoe')")
+	assert.True(errors.Is(err, ErrDatatypeMismatch), "%v", err)
+	_, err = execute(e, "INSERT INTO users VALUES (7, 'jdoe')")
+	assert.True(errors.Is(err, ErrConstraint), "%v", err)
+	_, err = execute(e, "INSERT INTO users VALUES (20, 'a'), (7, 'b')")
+	assert.True(errors.Is(err, ErrConstraint), "%v", err)
+	assert.Equal([]int64{1, 7, 8}, rowIDs(tbl), "failed insert must be reverted")
+
+	mustExecute(t, e, "INSERT OR IGNORE INTO users VALUES (7, 'jdoe')")
+	assert.Equal("jane", tbl.rows[1].values[1])
+	_, err = e.Execute(command.Insert{
+		InsertOr: command.InsertOrReplace,
+		Table:    command.SimpleTable{Table: "users"},
+		Input: command.Values{
+			Values: [][]command.Expr{
+				{command.LiteralExpr{Value: "7"}, command.LiteralExpr{Value: "'jdoe'"}},
+			},
+		},
+	})
+	assert.NoError(err)
+	assert.Equal("jdoe", tbl.rows[1].values[1])
+
+	// not an alias, since the type is not exactly INTEGER
+	mustExecute(t, e, "CREATE TABLE other (id DECIMAL PRIMARY KEY, name VARCHAR(25))")
+	mustExecute(t, e, "INSERT INTO other VALUES (7, 'jdoe')")
+	assert.Equal([]int64{1}, rowIDs(e.tables["other"]))
+
+	// the rowid is the only enforced uniqueness constraint
+	_, err = execute(e, "CREATE TABLE unique_name (id INTEGER PRIMARY KEY, name VARCHAR(25) UNIQUE)")
+	assert.True(errors.Is(err, ErrUnsupported), "%v", err)
+	_, err = execute(e, "CREATE TABLE unique_name (id INTEGER PRIMARY KEY, name VARCHAR(25), UNIQUE (name))")
+	assert.True(errors.Is(err, ErrUnsupported), "%v", err)
+	assert.NotContains(e.tables, "unique_name")
+}
+
+func TestAutoincrement(t *testing.T) {
+	assert := assert.New(t)
+	fs := afero.NewMemMapFs()
+	e := newTestExecutor(fs)
+
+	mustExecute(t, e, "CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(25))")
+	mustExecute(t, e, "INSERT INTO users (name) VALUES ('a'), ('b'), ('c')")
+	mustExecute(t, e, "DELETE FROM users")
+
+	// rowids are never re-used with autoincrement
+	res := mustExecute(t, e, "INSERT INTO users (name) VALUES ('d')")
+	assert.EqualValues(4, res.(ExecResult).LastInsertID())
+
+	// the sequence survives a restart
+	restarted := newTestExecutor(fs)
+	sequences, err := restarted.loadSequences()
+	assert.NoError(err)
+	assert.EqualValues(4, sequences.get("users"))
+
+	mustExecute(t, e, "INSERT INTO users VALUES ("+strconv.FormatInt(math.MaxInt64, 10)+", 'max')")
+	_, err = execute(e, "INSERT INTO users (name) VALUES ('overflow')")
+	assert.True(errors.Is(err, ErrFull), "%v", err)
+
+	mustExecute(t, e, "DROP TABLE users")
+	sequences, err = newTestExecutor(fs).loadSequences()
+	assert.NoError(err)
+	assert.EqualValues(0, sequences.get("users"))
+}
+
+func TestRowIDExhausted(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+
+	mustExecute(t, e, "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	mustExecute(t, e, "INSERT INTO t VALUES (1), (2), ("+strconv.FormatInt(math.MaxInt64, 10)+")")
+	res := mustExecute(t, e, "INSERT INTO t DEFAULT VALUES")
+	assert.EqualValues(3, res.(ExecResult).LastInsertID(), "smallest unused rowid must be used")
+}
+
+func TestDelete(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+
+	mustExecute(t, e, "CREATE TABLE t (id INTEGER PRIMARY KEY, v INTEGER)")
+	mustExecute(t, e, "INSERT INTO t VALUES (1, 10), (2, 20), (3, 10), (4, 40)")
+
+	res := mustExecute(t, e, "DELETE FROM t WHERE id = 2")
+	assert.EqualValues(1, res.(ExecResult).RowsAffected())
+	assert.Equal([]int64{1, 3, 4}, rowIDs(e.tables["t"]))
+
+	res = mustExecute(t, e, "DELETE FROM t WHERE v = 10")
+	assert.EqualValues(2, res.(ExecResult).RowsAffected())
+	assert.Equal([]int64{4}, rowIDs(e.tables["t"]))
+
+	res = mustExecute(t, e, "DELETE FROM t WHERE 0")
+	assert.EqualValues(0, res.(ExecResult).RowsAffected())
+	assert.Equal([]int64{4}, rowIDs(e.tables["t"]))
+
+	// any true value passes the filter, not only booleans
+	res = mustExecute(t, e, "DELETE FROM t WHERE 1")
+	assert.EqualValues(1, res.(ExecResult).RowsAffected())
+	assert.Empty(rowIDs(e.tables["t"]))
+
+	_, err := execute(e, "DELETE FROM t WHERE u = 1")
+	assert.Error(err)
+}
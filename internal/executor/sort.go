@@ -0,0 +1,374 @@
+package executor
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// defaultSortBudget is the default amount of memory in bytes, that a single
+// sort may occupy before it spills sorted runs to temporary files.
+const defaultSortBudget = 64 << 20
+
+// sortEntry is a row of a sort input, together with the values of the sort
+// terms for that row.
+type sortEntry struct {
+	keys []interface{}
+	row  []interface{}
+	// seq is the position of the row in the sort input, or the index of the
+	// run that the row was read from during a merge. It is used to keep rows
+	// with equal keys in input order.
+	seq int64
+}
+
+// sortOrder compares the keys of sort entries according to the sort terms.
+type sortOrder []sortTermOrder
+
+type sortTermOrder struct {
+	coll       collation
+	desc       bool
+	nullsFirst bool
+}
+
+func newSortOrder(terms []command.SortTerm) (sortOrder, error) {
+	order := make(sortOrder, len(terms))
+	for i, term := range terms {
+		if term.Collation != "" {
+			coll, err := lookupCollation(term.Collation)
+			if err != nil {
+				return nil, err
+			}
+			order[i].coll = coll
+		}
+		order[i].desc = term.Desc
+		order[i].nullsFirst = term.NullsFirst
+	}
+	return order, nil
+}
+
+// compare compares the given keys, and returns a negative number if a has to
+// be sorted before b, a positive number if b has to be sorted before a, and
+// zero if they are equal.
+func (o sortOrder) compare(a, b []interface{}) int {
+	for i, term := range o {
+		var cmp int
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil || b[i] == nil:
+			cmp = 1
+			if (a[i] == nil) == term.nullsFirst {
+				cmp = -1
+			}
+			return cmp
+		default:
+			cmp = compareValues(a[i], b[i], term.coll)
+		}
+		if term.desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// less determines whether the entry a has to be sorted before the entry b.
+// Entries with equal keys are sorted by their sequence number.
+func (o sortOrder) less(a, b sortEntry) bool {
+	if cmp := o.compare(a.keys, b.keys); cmp != 0 {
+		return cmp < 0
+	}
+	return a.seq < b.seq
+}
+
+// openSort creates a cursor over the sorted rows of the input of the given
+// sort. If limit is not negative, only the first limit rows of the sorted
+// input are required, which are then determined with a bounded heap, as long
+// as the heap fits into the sort budget of the executor. Otherwise, the input
+// is sorted with an external merge sort, that spills sorted runs to temporary
+// files once the sort budget is exceeded, and whose output is cut off at the
+// limit. The limit of the sort itself further restricts the required rows.
+func (e *simpleExecutor) openSort(outer *env, s command.Sort, limit int64) (cursor, error) {
+	order, err := newSortOrder(s.Terms)
+	if err != nil {
+		return nil, err
+	}
+	if s.Limit != nil {
+		sortLimit, err := e.evaluateCount(outer, s.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("limit: %w", err)
+		}
+		if sortLimit >= 0 && (limit < 0 || sortLimit < limit) {
+			limit = sortLimit
+		}
+	}
+	input, err := e.open(outer, s.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	sorter := &sorter{
+		e:       e,
+		order:   order,
+		cols:    input.Columns(),
+		limit:   limit,
+		bounded: limit >= 0,
+	}
+	en := newEnv(input.Columns(), outer)
+	for seq := int64(0); ; seq++ {
+		row, ok, err := input.Next()
+		if err == nil && ok {
+			en.row = row
+			var keys []interface{}
+			keys, err = e.sortKeys(en, s.Terms)
+			if err == nil {
+				err = sorter.add(sortEntry{keys: keys, row: row, seq: seq})
+			}
+		}
+		if err != nil {
+			_ = input.Close()
+			_ = sorter.Close()
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+	}
+	if err := input.Close(); err != nil {
+		_ = sorter.Close()
+		return nil, err
+	}
+	if err := sorter.finish(); err != nil {
+		_ = sorter.Close()
+		return nil, err
+	}
+	return sorter, nil
+}
+
+func (e *simpleExecutor) sortKeys(en *env, terms []command.SortTerm) ([]interface{}, error) {
+	keys := make([]interface{}, len(terms))
+	for i, term := range terms {
+		key, err := e.evaluate(en, term.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("order: %w", err)
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// sorter collects the entries of a sort, and is the cursor over the sorted
+// rows, once all entries were added.
+type sorter struct {
+	e     *simpleExecutor
+	order sortOrder
+	cols  []resultColumn
+	limit int64
+	// bounded indicates, that the entries form a heap of at most limit
+	// entries. Once the heap exceeds the sort budget, the entries are sorted
+	// with the external merge sort instead.
+	bounded bool
+
+	// entries are the entries that are held in memory. If the sorter is
+	// bounded, the entries form a heap, whose root is the largest entry.
+	entries []sortEntry
+	size    int64
+	// runs are the spill files, each of which holds a sorted run of entries.
+	runs []*spillFile
+
+	// merge is the heap of the next entry of every run while merging, and pos
+	// is the position of the next entry if no runs were spilled.
+	merge *mergeHeap
+	pos   int
+	// returned is the amount of rows, that were returned by Next.
+	returned int64
+}
+
+func (s *sorter) add(entry sortEntry) error {
+	if s.bounded {
+		if int64(len(s.entries)) < s.limit {
+			heap.Push((*topHeap)(s), entry)
+			s.size += entry.size()
+		} else if len(s.entries) > 0 && s.order.less(entry, s.entries[0]) {
+			s.size += entry.size() - s.entries[0].size()
+			s.entries[0] = entry
+			heap.Fix((*topHeap)(s), 0)
+		}
+		if s.size <= s.e.sortBudget {
+			return nil
+		}
+		// the heap holds all entries, that may be among the first limit
+		// entries, so it can be spilled as the first run
+		s.bounded = false
+		return s.spill()
+	}
+
+	s.entries = append(s.entries, entry)
+	s.size += entry.size()
+	if s.size > s.e.sortBudget {
+		return s.spill()
+	}
+	return nil
+}
+
+// size returns the estimated amount of memory in bytes, that the entry
+// occupies.
+func (entry sortEntry) size() int64 {
+	return valuesSize(entry.keys) + valuesSize(entry.row)
+}
+
+// spill sorts the entries that are held in memory, and writes them to a new
+// run.
+func (s *sorter) spill() error {
+	s.sortEntries()
+	run, err := createSpillFile(s.e.fs)
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+	for _, entry := range s.entries {
+		if err := run.write(entry.keys); err != nil {
+			return err
+		}
+		if err := run.write(entry.row); err != nil {
+			return err
+		}
+	}
+	s.entries = s.entries[:0]
+	s.size = 0
+	return nil
+}
+
+func (s *sorter) sortEntries() {
+	sort.Slice(s.entries, func(i, j int) bool {
+		return s.order.less(s.entries[i], s.entries[j])
+	})
+}
+
+// finish prepares the sorter for reading the sorted rows, after all entries
+// have been added.
+func (s *sorter) finish() error {
+	if len(s.runs) == 0 {
+		s.sortEntries()
+		return nil
+	}
+
+	if len(s.entries) != 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	s.entries = nil
+	s.merge = &mergeHeap{order: s.order}
+	for i, run := range s.runs {
+		if err := run.rewind(); err != nil {
+			return err
+		}
+		if err := s.pushNext(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushNext reads the next entry of the run with the given index, and pushes it
+// onto the merge heap.
+func (s *sorter) pushNext(run int) error {
+	keys, ok, err := s.runs[run].read()
+	if err != nil || !ok {
+		return err
+	}
+	row, ok, err := s.runs[run].read()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("read spill file: incomplete entry")
+	}
+	heap.Push(s.merge, sortEntry{keys: keys, row: row, seq: int64(run)})
+	return nil
+}
+
+func (s *sorter) Columns() []resultColumn { return s.cols }
+
+func (s *sorter) Next() ([]interface{}, bool, error) {
+	if s.limit >= 0 && s.returned >= s.limit {
+		return nil, false, nil
+	}
+	if s.merge == nil {
+		if s.pos >= len(s.entries) {
+			return nil, false, nil
+		}
+		s.pos++
+		s.returned++
+		return s.entries[s.pos-1].row, true, nil
+	}
+
+	if s.merge.Len() == 0 {
+		return nil, false, nil
+	}
+	entry := heap.Pop(s.merge).(sortEntry)
+	if err := s.pushNext(int(entry.seq)); err != nil {
+		return nil, false, err
+	}
+	s.returned++
+	return entry.row, true, nil
+}
+
+// Close removes all spill files of this sorter.
+func (s *sorter) Close() error {
+	var err error
+	for _, run := range s.runs {
+		if removeErr := run.remove(); removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}
+	s.runs = nil
+	return err
+}
+
+// topHeap is the heap of the entries of a sorter with a limit. The root of
+// the heap is the largest entry, which is replaced if a smaller entry is
+// added to a full heap.
+type topHeap sorter
+
+func (h *topHeap) Len() int { return len(h.entries) }
+
+func (h *topHeap) Less(i, j int) bool { return h.order.less(h.entries[j], h.entries[i]) }
+
+func (h *topHeap) Swap(i, j int) { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+
+func (h *topHeap) Push(x interface{}) { h.entries = append(h.entries, x.(sortEntry)) }
+
+func (h *topHeap) Pop() interface{} {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
+}
+
+// mergeHeap is the heap of the next entries of all runs during a merge. The
+// root of the heap is the smallest entry. Since the sequence number of the
+// entries is the index of their run, and runs hold consecutive parts of the
+// input, entries with equal keys are returned in input order.
+type mergeHeap struct {
+	order   sortOrder
+	entries []sortEntry
+}
+
+func (h *mergeHeap) Len() int { return len(h.entries) }
+
+func (h *mergeHeap) Less(i, j int) bool { return h.order.less(h.entries[i], h.entries[j]) }
+
+func (h *mergeHeap) Swap(i, j int) { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+
+func (h *mergeHeap) Push(x interface{}) { h.entries = append(h.entries, x.(sortEntry)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
+}
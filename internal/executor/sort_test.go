@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSort(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE users (name VARCHAR(25), age INTEGER)")
+	mustExecute(t, e, "INSERT INTO users VALUES ('bob', 30), ('alice', 25), ('Carol', 30), ('dave', 41)")
+	mustExecute(t, e, "INSERT INTO users (name) VALUES ('eve')")
+
+	tests := []struct {
+		name  string
+		query string
+		want  [][]interface{}
+	}{
+		{
+			"ascending with nulls first",
+			"SELECT name FROM users ORDER BY age",
+			[][]interface{}{{"eve"}, {"alice"}, {"bob"}, {"Carol"}, {"dave"}},
+		},
+		{
+			"descending with nulls last",
+			"SELECT name FROM users ORDER BY age DESC",
+			[][]interface{}{{"dave"}, {"bob"}, {"Carol"}, {"alice"}, {"eve"}},
+		},
+		{
+			"nulls last",
+			"SELECT name FROM users ORDER BY age NULLS LAST",
+			[][]interface{}{{"alice"}, {"bob"}, {"Carol"}, {"dave"}, {"eve"}},
+		},
+		{
+			"multiple terms",
+			"SELECT name FROM users ORDER BY age DESC, name",
+			[][]interface{}{{"dave"}, {"Carol"}, {"bob"}, {"alice"}, {"eve"}},
+		},
+		{
+			"collation",
+			"SELECT name FROM users ORDER BY name COLLATE NOCASE",
+			[][]interface{}{{"alice"}, {"bob"}, {"Carol"}, {"dave"}, {"eve"}},
+		},
+		{
+			"binary collation",
+			"SELECT name FROM users ORDER BY name",
+			[][]interface{}{{"Carol"}, {"alice"}, {"bob"}, {"dave"}, {"eve"}},
+		},
+		{
+			"result column alias and position",
+			"SELECT age AS a, name FROM users ORDER BY a DESC, 2 DESC",
+			[][]interface{}{{int64(41), "dave"}, {int64(30), "bob"}, {int64(30), "Carol"}, {int64(25), "alice"}, {nil, "eve"}},
+		},
+		{
+			"column that is not part of the result",
+			"SELECT name FROM users WHERE age > 26 ORDER BY age DESC",
+			[][]interface{}{{"dave"}, {"bob"}, {"Carol"}},
+		},
+		{
+			"aggregate",
+			"SELECT age FROM users GROUP BY age ORDER BY COUNT(*) DESC, age",
+			[][]interface{}{{int64(30)}, {nil}, {int64(25)}, {int64(41)}},
+		},
+		{
+			"top n",
+			"SELECT name FROM users ORDER BY age DESC LIMIT 2",
+			[][]interface{}{{"dave"}, {"bob"}},
+		},
+		{
+			"top n with offset",
+			"SELECT name FROM users ORDER BY age DESC LIMIT 2 OFFSET 1",
+			[][]interface{}{{"bob"}, {"Carol"}},
+		},
+		{
+			"limit zero",
+			"SELECT name FROM users ORDER BY age LIMIT 0",
+			[][]interface{}{},
+		},
+		{
+			"values",
+			"VALUES (1, 'b'), (2, 'a') ORDER BY 2",
+			[][]interface{}{{int64(2), "a"}, {int64(1), "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mustQuery(t, e, tt.query))
+		})
+	}
+}
+
+func TestSortSpill(t *testing.T) {
+	assert := assert.New(t)
+	fs := afero.NewMemMapFs()
+	e := newTestExecutor(fs)
+	mustExecute(t, e, "CREATE TABLE numbers (n INTEGER, label VARCHAR(10))")
+
+	const count = 500
+	for i := 0; i < count; i++ {
+		// insert the numbers in a scrambled order, with duplicates
+		n := strconv.Itoa((i * 7919) % (count / 2))
+		mustExecute(t, e, "INSERT INTO numbers VALUES ("+n+", 'row"+strconv.Itoa(i)+"')")
+	}
+	want := mustQuery(t, e, "SELECT n, label FROM numbers ORDER BY n DESC")
+	require.Len(t, want, count)
+
+	e.sortBudget = 1024
+	got := mustQuery(t, e, "SELECT n, label FROM numbers ORDER BY n DESC")
+	assert.Equal(want, got, "spilled sort must be equal to in-memory sort")
+	for i := 1; i < len(got); i++ {
+		assert.GreaterOrEqual(got[i-1][0].(int64), got[i][0].(int64))
+	}
+
+	// all spill files must have been removed
+	entries, err := afero.ReadDir(fs, os.TempDir())
+	if err == nil {
+		assert.Empty(entries)
+	}
+}
+
+// countingFs is a file system, that counts the files that were opened with
+// OpenFile, such as spill files.
+type countingFs struct {
+	afero.Fs
+	opened int
+}
+
+func (fs *countingFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	fs.opened++
+	return fs.Fs.OpenFile(name, flag, perm)
+}
+
+func TestSortSpillWithLimit(t *testing.T) {
+	assert := assert.New(t)
+	fs := &countingFs{Fs: afero.NewMemMapFs()}
+	e := newTestExecutor(fs)
+	mustExecute(t, e, "CREATE TABLE numbers (n INTEGER, label VARCHAR(10))")
+
+	const count = 500
+	for i := 0; i < count; i++ {
+		n := strconv.Itoa((i * 7919) % (count / 2))
+		mustExecute(t, e, "INSERT INTO numbers VALUES ("+n+", 'row"+strconv.Itoa(i)+"')")
+	}
+	want := mustQuery(t, e, "SELECT n, label FROM numbers ORDER BY n DESC")
+	require.Len(t, want, count)
+
+	e.sortBudget = 1024
+	fs.opened = 0
+	got := mustQuery(t, e, "SELECT n, label FROM numbers ORDER BY n DESC LIMIT 3")
+	assert.Equal(want[:3], got)
+	assert.Zero(fs.opened, "a heap, that fits into the sort budget, must not spill")
+
+	got = mustQuery(t, e, "SELECT n, label FROM numbers ORDER BY n DESC LIMIT 1000000000")
+	assert.Equal(want, got, "spilled sort must be equal to in-memory sort")
+	assert.NotZero(fs.opened, "a heap, that exceeds the sort budget, must spill")
+
+	got = mustQuery(t, e, "SELECT n, label FROM numbers ORDER BY n DESC LIMIT 100 OFFSET 50")
+	assert.Equal(want[50:150], got, "spilled sort must be cut off at the limit")
+
+	entries, err := afero.ReadDir(fs, os.TempDir())
+	if err == nil {
+		assert.Empty(entries)
+	}
+}
@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/spf13/afero"
+)
+
+// Value tags of the spill file format. Every value is written as its tag,
+// followed by the payload of the value, if it has one.
+const (
+	tagNull byte = iota
+	tagInteger
+	tagReal
+	tagText
+	tagBlob
+	tagTrue
+	tagFalse
+)
+
+// spillFile is a temporary file, that holds rows which don't fit into memory.
+// Rows are written sequentially, and can be read back in the same order after
+// the file was rewound.
+type spillFile struct {
+	fs   afero.Fs
+	file afero.File
+	w    *bufio.Writer
+	r    *bufio.Reader
+	buf  [binary.MaxVarintLen64]byte
+}
+
+// createSpillFile creates a new temporary spill file in the given file system.
+func createSpillFile(fs afero.Fs) (*spillFile, error) {
+	file, err := afero.TempFile(fs, "", "lbadd-spill-")
+	if err != nil {
+		return nil, fmt.Errorf("create spill file: %w", err)
+	}
+	return &spillFile{
+		fs:   fs,
+		file: file,
+		w:    bufio.NewWriter(file),
+	}, nil
+}
+
+// write appends the given values to the spill file.
+func (f *spillFile) write(values []interface{}) error {
+	f.writeUvarint(uint64(len(values)))
+	for _, val := range values {
+		switch v := val.(type) {
+		case nil:
+			_ = f.w.WriteByte(tagNull)
+		case int64:
+			_ = f.w.WriteByte(tagInteger)
+			n := binary.PutVarint(f.buf[:], v)
+			_, _ = f.w.Write(f.buf[:n])
+		case float64:
+			_ = f.w.WriteByte(tagReal)
+			binary.BigEndian.PutUint64(f.buf[:8], math.Float64bits(v))
+			_, _ = f.w.Write(f.buf[:8])
+		case string:
+			_ = f.w.WriteByte(tagText)
+			f.writeUvarint(uint64(len(v)))
+			_, _ = f.w.WriteString(v)
+		case []byte:
+			_ = f.w.WriteByte(tagBlob)
+			f.writeUvarint(uint64(len(v)))
+			_, _ = f.w.Write(v)
+		case bool:
+			if v {
+				_ = f.w.WriteByte(tagTrue)
+			} else {
+				_ = f.w.WriteByte(tagFalse)
+			}
+		default:
+			return fmt.Errorf("spill value of type %T: %w", val, ErrUnsupported)
+		}
+	}
+	// errors of the buffered writer are sticky, and reported by Flush
+	return nil
+}
+
+func (f *spillFile) writeUvarint(x uint64) {
+	n := binary.PutUvarint(f.buf[:], x)
+	_, _ = f.w.Write(f.buf[:n])
+}
+
+// rewind flushes all written values, and prepares the file for reading from
+// the beginning.
+func (f *spillFile) rewind() error {
+	if err := f.w.Flush(); err != nil {
+		return fmt.Errorf("flush spill file: %w", err)
+	}
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind spill file: %w", err)
+	}
+	f.r = bufio.NewReader(f.file)
+	return nil
+}
+
+// read reads the next values from the spill file. At the end of the file,
+// ok=false is returned.
+func (f *spillFile) read() (values []interface{}, ok bool, err error) {
+	count, err := binary.ReadUvarint(f.r)
+	if err == io.EOF {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read spill file: %w", err)
+	}
+
+	values = make([]interface{}, count)
+	for i := range values {
+		values[i], err = f.readValue()
+		if err != nil {
+			return nil, false, fmt.Errorf("read spill file: %w", err)
+		}
+	}
+	return values, true, nil
+}
+
+func (f *spillFile) readValue() (interface{}, error) {
+	tag, err := f.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tagNull:
+		return nil, nil
+	case tagInteger:
+		return binary.ReadVarint(f.r)
+	case tagReal:
+		if _, err := io.ReadFull(f.r, f.buf[:8]); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(f.buf[:8])), nil
+	case tagText, tagBlob:
+		size, err := binary.ReadUvarint(f.r)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(f.r, data); err != nil {
+			return nil, err
+		}
+		if tag == tagText {
+			return string(data), nil
+		}
+		return data, nil
+	case tagTrue:
+		return true, nil
+	case tagFalse:
+		return false, nil
+	}
+	return nil, fmt.Errorf("unknown value tag %d", tag)
+}
+
+// remove closes and deletes the spill file.
+func (f *spillFile) remove() error {
+	closeErr := f.file.Close()
+	if err := f.fs.Remove(f.file.Name()); err != nil {
+		return fmt.Errorf("remove spill file: %w", err)
+	}
+	return closeErr
+}
+
+// valuesSize estimates the amount of memory in bytes, that is occupied by the
+// given values.
+func valuesSize(values []interface{}) int64 {
+	size := int64(24 + 16*len(values))
+	for _, val := range values {
+		switch v := val.(type) {
+		case string:
+			size += int64(len(v))
+		case []byte:
+			size += int64(len(v))
+		}
+	}
+	return size
+}
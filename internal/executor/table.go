@@ -0,0 +1,211 @@
+package executor
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/database/column"
+	"github.com/tomarrell/lbadd/internal/database/storage"
+	"github.com/tomarrell/lbadd/internal/database/table"
+)
+
+var _ table.Table = (*memTable)(nil)
+var _ column.Column = (*memColumn)(nil)
+var _ column.Type = (*columnType)(nil)
+
+// memTable is a table that holds all of its rows in memory. Rows are kept
+// sorted by their rowid.
+type memTable struct {
+	schema string
+	name   string
+	cols   []*memColumn
+	rows   []memRow
+	// version is incremented whenever the rows of the table are modified, so
+	// that indexes can tell whether they are up to date.
+	version uint64
+}
+
+// memRow is a single row of a memTable, consisting of the rowid and the values
+// of all declared columns, in declaration order.
+type memRow struct {
+	id     int64
+	values []interface{}
+}
+
+// memColumn is a declared column of a memTable.
+type memColumn struct {
+	name          string
+	typ           columnType
+	notNull       bool
+	primaryKey    bool
+	autoincrement bool
+	dflt          command.Expr
+}
+
+// columnType is a column type that was parsed from a declared type name, such
+// as VARCHAR(25).
+type columnType struct {
+	base   column.BaseType
+	params []float64
+}
+
+func newMemTable(cmd command.CreateTable) *memTable {
+	tbl := &memTable{
+		schema: cmd.Schema,
+		name:   cmd.Name,
+	}
+	for _, def := range cmd.Columns {
+		tbl.cols = append(tbl.cols, &memColumn{
+			name:          def.Name,
+			typ:           parseColumnType(def.Type),
+			notNull:       def.NotNull,
+			primaryKey:    def.PrimaryKey,
+			autoincrement: def.Autoincrement,
+			dflt:          def.Default,
+		})
+	}
+	return tbl
+}
+
+func (t *memTable) Schema() string { return t.schema }
+
+func (t *memTable) Name() string { return t.name }
+
+func (t *memTable) Columns() []column.Column {
+	cols := make([]column.Column, len(t.cols))
+	for i, col := range t.cols {
+		cols[i] = col
+	}
+	return cols
+}
+
+// Storage returns nil, since a memTable is not backed by a storage component.
+func (t *memTable) Storage() storage.Storage { return nil }
+
+func (c *memColumn) Name() string              { return c.name }
+func (c *memColumn) Type() column.Type         { return c.typ }
+func (c *memColumn) IsNullable() bool          { return !c.notNull }
+func (c *memColumn) IsPrimaryKey() bool        { return c.primaryKey }
+func (c *memColumn) ShouldAutoincrement() bool { return c.autoincrement }
+
+func (t columnType) BaseType() column.BaseType { return t.base }
+func (t columnType) IsParameterized() bool     { return len(t.params) != 0 }
+
+func (t columnType) FirstParameter() float64 {
+	if len(t.params) < 1 {
+		return 0
+	}
+	return t.params[0]
+}
+
+func (t columnType) SecondParameter() float64 {
+	if len(t.params) < 2 {
+		return 0
+	}
+	return t.params[1]
+}
+
+// columnIndex returns the index of the declared column with the given name.
+// Column names are compared case insensitive.
+func (t *memTable) columnIndex(name string) (int, bool) {
+	for i, col := range t.cols {
+		if strings.EqualFold(col.name, name) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// autoincrement determines whether the rowid alias of this table was declared
+// with AUTOINCREMENT.
+func (t *memTable) autoincrement() bool {
+	alias, ok := table.RowIDAlias(t)
+	return ok && t.cols[alias].autoincrement
+}
+
+// search returns the position of the row with the given rowid, and whether
+// such a row exists. If it doesn't exist, the returned position is the
+// position where a row with that rowid would have to be inserted.
+func (t *memTable) search(id int64) (int, bool) {
+	i := sort.Search(len(t.rows), func(i int) bool {
+		return t.rows[i].id >= id
+	})
+	return i, i < len(t.rows) && t.rows[i].id == id
+}
+
+// put inserts the given row, or replaces the row with the same rowid.
+func (t *memTable) put(row memRow) {
+	t.version++
+	i, exists := t.search(row.id)
+	if exists {
+		t.rows[i] = row
+		return
+	}
+	t.rows = append(t.rows, memRow{})
+	copy(t.rows[i+1:], t.rows[i:])
+	t.rows[i] = row
+}
+
+// setRows replaces all rows of this table with the given rows, which must be
+// sorted by their rowid.
+func (t *memTable) setRows(rows []memRow) {
+	t.version++
+	t.rows = rows
+}
+
+// maxRowID returns the largest rowid in this table, or ok=false if the table
+// is empty.
+func (t *memTable) maxRowID() (id int64, ok bool) {
+	if len(t.rows) == 0 {
+		return 0, false
+	}
+	return t.rows[len(t.rows)-1].id, true
+}
+
+// qualifiedName returns the name of this table, qualified with the schema
+// name, if there is one.
+func (t *memTable) qualifiedName() string {
+	return tableKey(t.schema, t.name)
+}
+
+// tableKey returns the key under which a table with the given schema and name
+// is registered. Table names are case insensitive.
+func tableKey(schema, name string) string {
+	if schema == "" {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(schema + "." + name)
+}
+
+// parseColumnType parses a declared type name such as INTEGER, VARCHAR(25) or
+// DECIMAL(5,2). Unknown type names yield the column.Unknown base type.
+func parseColumnType(declared string) columnType {
+	name := declared
+	var params []float64
+	if paren := strings.IndexByte(declared, '('); paren != -1 {
+		name = declared[:paren]
+		for _, param := range strings.Split(strings.Trim(declared[paren:], "()"), ",") {
+			if val, err := strconv.ParseFloat(strings.TrimSpace(param), 64); err == nil {
+				params = append(params, val)
+			}
+		}
+	}
+
+	var base column.BaseType
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "INTEGER":
+		base = column.Integer
+	case "DECIMAL":
+		base = column.Decimal
+	case "VARCHAR":
+		base = column.Varchar
+	default:
+		base = column.Unknown
+	}
+	return columnType{
+		base:   base,
+		params: params,
+	}
+}
@@ -0,0 +1,132 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+	"github.com/tomarrell/lbadd/internal/database/table"
+)
+
+// checkConflictTarget ensures that the target of the given upsert clause
+// refers to the rowid of the given table, which is the only uniqueness
+// constraint that is enforced by the executor. An upsert clause without a
+// target handles any conflict.
+func checkConflictTarget(tbl *memTable, onConflict *command.OnConflict) error {
+	if len(onConflict.Target) == 0 {
+		return nil
+	}
+	if alias, ok := table.RowIDAlias(tbl); ok && len(onConflict.Target) == 1 && onConflict.TargetFilter == nil &&
+		strings.EqualFold(tbl.cols[alias].name, onConflict.Target[0]) {
+		return nil
+	}
+	return fmt.Errorf("conflict target (%v) of %v: %w", strings.Join(onConflict.Target, ","), tbl.name, ErrUnsupported)
+}
+
+// upsertRow resolves the conflict of the given dataset, which could not be
+// inserted into the given table, with the existing row at the given position,
+// according to the upsert clause of the given insert command. All updates are
+// evaluated against the existing row, and the row is only replaced after all
+// of them succeeded.
+func (e *simpleExecutor) upsertRow(tbl *memTable, cmd command.Insert, index int, excluded []interface{}) (int64, insertOutcome, error) {
+	onConflict := cmd.OnConflict
+	if len(onConflict.Updates) == 0 {
+		return 0, rowIgnored, nil
+	}
+
+	name := tbl.name
+	if simple, ok := cmd.Table.(command.SimpleTable); ok && simple.Alias != "" {
+		name = simple.Alias
+	}
+	existing := tbl.rows[index]
+	en := newEnv(upsertColumns(tbl, name), nil)
+	en.row = make([]interface{}, 0, 2*len(existing.values)+1)
+	en.row = append(append(append(en.row, existing.values...), existing.id), excluded...)
+
+	if onConflict.Filter != nil {
+		pass, err := e.evaluate(en, onConflict.Filter)
+		if err != nil {
+			return 0, rowIgnored, fmt.Errorf("filter: %w", err)
+		}
+		if !isTrue(pass) {
+			return 0, rowIgnored, nil
+		}
+	}
+
+	values := append([]interface{}(nil), existing.values...)
+	id := existing.id
+	alias, hasAlias := table.RowIDAlias(tbl)
+	for _, set := range onConflict.Updates {
+		if len(set.Cols) != 1 {
+			return 0, rowIgnored, fmt.Errorf("update of %d columns with a single value: %w", len(set.Cols), ErrUnsupported)
+		}
+		val, err := e.evaluate(en, set.Value)
+		if err != nil {
+			return 0, rowIgnored, fmt.Errorf("update %v: %w", set.Cols[0], err)
+		}
+		val = normalizeBool(val)
+
+		target, ok := tbl.columnIndex(set.Cols[0])
+		switch {
+		case ok && !(hasAlias && target == alias):
+			values[target] = val
+		case ok || table.IsRowIDName(set.Cols[0]):
+			if id, err = toRowID(val); err != nil {
+				return 0, rowIgnored, err
+			}
+		default:
+			return 0, rowIgnored, fmt.Errorf("table %v has no column %v: %w", tbl.name, set.Cols[0], ErrNoSuchColumn)
+		}
+	}
+	if hasAlias {
+		values[alias] = id
+	}
+
+	for i, col := range tbl.cols {
+		if col.notNull && values[i] == nil {
+			return 0, rowIgnored, fmt.Errorf("not null %v.%v: %w", tbl.name, col.name, ErrConstraint)
+		}
+	}
+	if id != existing.id {
+		if _, exists := tbl.search(id); exists {
+			return 0, rowIgnored, fmt.Errorf("unique %v.rowid: %w", tbl.name, ErrConstraint)
+		}
+		tbl.setRows(append(tbl.rows[:index], tbl.rows[index+1:]...))
+	}
+
+	tbl.put(memRow{
+		id:     id,
+		values: values,
+	})
+	return id, rowUpdated, nil
+}
+
+// upsertColumns returns the columns of the environment, in which the upsert
+// clause of an insert into the given table is evaluated. The existing row is
+// assigned to the given name, followed by its rowid. The dataset that could
+// not be inserted is assigned to the table "excluded", and can only be
+// referenced by qualified names, like coalesced columns.
+func upsertColumns(tbl *memTable, name string) []resultColumn {
+	cols := make([]resultColumn, 0, 2*len(tbl.cols)+1)
+	for _, col := range tbl.cols {
+		cols = append(cols, resultColumn{
+			table: name,
+			name:  col.name,
+		})
+	}
+	cols = append(cols, resultColumn{
+		table:  name,
+		name:   "rowid",
+		hidden: true,
+		rowid:  true,
+	})
+	for _, col := range tbl.cols {
+		cols = append(cols, resultColumn{
+			table:     "excluded",
+			name:      col.name,
+			hidden:    true,
+			coalesced: true,
+		})
+	}
+	return cols
+}
@@ -0,0 +1,59 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpsert(t *testing.T) {
+	assert := assert.New(t)
+	e := newTestExecutor(afero.NewMemMapFs())
+
+	mustExecute(t, e, "CREATE TABLE stock (id INTEGER PRIMARY KEY, name VARCHAR(10), amount INTEGER)")
+	mustExecute(t, e, "INSERT INTO stock VALUES (1, 'apple', 5), (2, 'pear', 3)")
+
+	// do nothing drops the conflicting dataset
+	res := mustExecute(t, e, "INSERT INTO stock VALUES (1, 'plum', 1), (3, 'plum', 1) ON CONFLICT DO NOTHING")
+	assert.EqualValues(1, res.(ExecResult).RowsAffected())
+	assert.EqualValues(3, res.(ExecResult).LastInsertID())
+
+	// do update sets the existing row, using the values of the dataset
+	res = mustExecute(t, e, "INSERT INTO stock VALUES (2, 'pear', 4) ON CONFLICT (id) DO UPDATE SET amount = excluded.amount WHERE amount < excluded.amount")
+	assert.EqualValues(1, res.(ExecResult).RowsAffected())
+	assert.EqualValues(3, res.(ExecResult).LastInsertID(), "update must not change the last insert id")
+	res = mustExecute(t, e, "INSERT INTO stock VALUES (2, 'pear', 1) ON CONFLICT (id) DO UPDATE SET amount = excluded.amount WHERE amount < excluded.amount")
+	assert.EqualValues(0, res.(ExecResult).RowsAffected(), "filtered update must drop the dataset")
+
+	// the updated row may be moved to another rowid
+	mustExecute(t, e, "INSERT INTO stock (id, name) VALUES (3, 'cherry') ON CONFLICT (id) DO UPDATE SET id = 10, name = excluded.name")
+
+	assert.Equal([][]interface{}{
+		{int64(1), "apple", int64(5)},
+		{int64(2), "pear", int64(4)},
+		{int64(10), "cherry", int64(1)},
+	}, mustQuery(t, e, "SELECT * FROM stock"))
+
+	// replace into replaces the conflicting row
+	mustExecute(t, e, "REPLACE INTO stock VALUES (1, 'apricot', 2)")
+	assert.Equal([][]interface{}{{"apricot"}}, mustQuery(t, e, "SELECT name FROM stock WHERE id == 1"))
+
+	// insert select reads all rows before inserting any of them
+	res = mustExecute(t, e, "INSERT INTO stock (name, amount) SELECT name, amount * 2 FROM stock")
+	assert.EqualValues(3, res.(ExecResult).RowsAffected())
+	assert.Equal([][]interface{}{
+		{int64(11), int64(4)},
+		{int64(12), int64(8)},
+		{int64(13), int64(2)},
+	}, mustQuery(t, e, "SELECT id, amount FROM stock WHERE id > 10"))
+
+	// a failing update reverts the whole statement
+	_, err := execute(e, "INSERT INTO stock VALUES (20, 'fig', 1), (1, 'fig', 1) ON CONFLICT (id) DO UPDATE SET id = 2")
+	assert.True(errors.Is(err, ErrConstraint), "%v", err)
+	assert.Equal([][]interface{}{{int64(0)}}, mustQuery(t, e, "SELECT count(*) FROM stock WHERE id == 20"))
+
+	_, err = execute(e, "INSERT INTO stock VALUES (1, 'fig', 1) ON CONFLICT (name) DO NOTHING")
+	assert.True(errors.Is(err, ErrUnsupported), "%v", err)
+}
@@ -0,0 +1,269 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values are represented by the Go types nil (NULL), int64, float64, string,
+// []byte and bool. Booleans are the result of comparisons and logical
+// operators, and behave like the integers 1 and 0 everywhere else.
+
+// storageClass is the storage class of a value. The order of the storage
+// classes is the order in which values of different storage classes are
+// sorted.
+type storageClass uint8
+
+const (
+	classNull storageClass = iota
+	classNumeric
+	classText
+	classBlob
+)
+
+func classOf(val interface{}) storageClass {
+	switch val.(type) {
+	case nil:
+		return classNull
+	case int64, float64, bool:
+		return classNumeric
+	case string:
+		return classText
+	}
+	return classBlob
+}
+
+// timeLayout is the layout, in which time values of bind parameters are
+// stored as text.
+const timeLayout = "2006-01-02 15:04:05.999999999-07:00"
+
+// parameterValue converts the value of a bind parameter, which is one of the
+// types that are allowed by the database/sql/driver package, to a value.
+func parameterValue(val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case nil, int64, float64, string, bool:
+		return v, nil
+	case []byte:
+		// the driver may re-use the buffer of the value
+		return append([]byte{}, v...), nil
+	case time.Time:
+		return v.Format(timeLayout), nil
+	}
+	return nil, fmt.Errorf("parameter value of type %T: %w", val, ErrDatatypeMismatch)
+}
+
+// typeName returns the name of the type of the given value, as returned by the
+// typeof function.
+func typeName(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case int64, bool:
+		return "integer"
+	case float64:
+		return "real"
+	case string:
+		return "text"
+	}
+	return "blob"
+}
+
+// compareValues compares the two given values, and returns a negative number if
+// a is smaller than b, zero if they are equal and a positive number if a is
+// larger than b. NULL is smaller than any numeric value, which is smaller than
+// any text, which is smaller than any blob. Texts are compared with the given
+// collation.
+func compareValues(a, b interface{}, coll collation) int {
+	classA, classB := classOf(a), classOf(b)
+	if classA != classB {
+		return int(classA) - int(classB)
+	}
+	switch classA {
+	case classNumeric:
+		return compareNumeric(normalizeBool(a), normalizeBool(b))
+	case classText:
+		return coll.compare(a.(string), b.(string))
+	case classBlob:
+		return bytes.Compare(a.([]byte), b.([]byte))
+	}
+	return 0
+}
+
+func compareNumeric(a, b interface{}) int {
+	intA, aIsInt := a.(int64)
+	intB, bIsInt := b.(int64)
+	if aIsInt && bIsInt {
+		switch {
+		case intA < intB:
+			return -1
+		case intA > intB:
+			return 1
+		}
+		return 0
+	}
+	floatA, floatB := toFloat(a), toFloat(b)
+	switch {
+	case floatA < floatB:
+		return -1
+	case floatA > floatB:
+		return 1
+	}
+	return 0
+}
+
+// normalizeBool converts a boolean to the integer 1 or 0. All other values are
+// returned unchanged.
+func normalizeBool(val interface{}) interface{} {
+	if b, ok := val.(bool); ok {
+		if b {
+			return int64(1)
+		}
+		return int64(0)
+	}
+	return val
+}
+
+// toNumeric converts the given value to an int64 or a float64. Texts and blobs
+// are converted by parsing their longest numeric prefix, and yield 0 if there
+// is none. NULL is returned as nil.
+func toNumeric(val interface{}) interface{} {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case int64, float64:
+		return v
+	case bool:
+		return normalizeBool(v)
+	case string:
+		return parseNumericPrefix(v)
+	case []byte:
+		return parseNumericPrefix(string(v))
+	}
+	return int64(0)
+}
+
+func parseNumericPrefix(s string) interface{} {
+	s = strings.TrimSpace(s)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	// find the longest prefix that is a valid number
+	for end := len(s) - 1; end > 0; end-- {
+		if i, err := strconv.ParseInt(s[:end], 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(s[:end], 64); err == nil {
+			return f
+		}
+	}
+	return int64(0)
+}
+
+func toFloat(val interface{}) float64 {
+	switch v := toNumeric(val).(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+func toInteger(val interface{}) int64 {
+	switch v := toNumeric(val).(type) {
+	case int64:
+		return v
+	case float64:
+		if v >= math.MaxInt64 {
+			return math.MaxInt64
+		}
+		if v <= math.MinInt64 {
+			return math.MinInt64
+		}
+		return int64(v)
+	}
+	return 0
+}
+
+// toText converts the given value to its text representation. NULL is
+// converted to the empty string.
+func toText(val interface{}) string {
+	switch v := normalizeBool(val).(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatFloat(v)
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(val)
+}
+
+// formatFloat formats a real value with up to 15 significant digits. Integral
+// values keep a trailing ".0", so that they can be distinguished from
+// integers.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', 15, 64)
+	if !strings.ContainsAny(s, ".eEnN") {
+		s += ".0"
+	}
+	return s
+}
+
+// isTrue determines whether the given value is considered true in a boolean
+// context. NULL is not true.
+func isTrue(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	}
+	return toFloat(val) != 0
+}
+
+// valueKey returns a key for the given value, that is equal for two values if
+// and only if the values are equal, and they are in the same storage class.
+// Integers and reals with the same numeric value have the same key.
+func valueKey(val interface{}) string {
+	switch v := normalizeBool(val).(type) {
+	case nil:
+		return "n"
+	case int64:
+		return "i" + strconv.FormatInt(v, 10)
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return "i" + strconv.FormatInt(int64(v), 10)
+		}
+		return "f" + strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return "s" + v
+	case []byte:
+		return "b" + string(v)
+	}
+	return fmt.Sprintf("?%v", val)
+}
+
+// rowKey returns a key for the given row, that is equal for two rows if and
+// only if all values of the rows are pairwise equal in the sense of valueKey.
+func rowKey(row []interface{}) string {
+	var buf strings.Builder
+	for _, val := range row {
+		key := valueKey(val)
+		buf.WriteString(strconv.Itoa(len(key)))
+		buf.WriteByte(':')
+		buf.WriteString(key)
+	}
+	return buf.String()
+}
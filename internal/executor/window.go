@@ -0,0 +1,473 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// openWindow computes the window functions of the given window for every row
+// of its input, and creates a cursor over the input rows, each followed by the
+// results of the window functions. The rows keep the order of the input. All
+// input rows are held in memory.
+func (e *simpleExecutor) openWindow(outer *env, w command.Window) (cursor, error) {
+	input, err := e.open(outer, w.Input)
+	if err != nil {
+		return nil, err
+	}
+	inputCols := input.Columns()
+	rows, err := drain(input)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]interface{}, len(w.Functions))
+	for i, fn := range w.Functions {
+		results[i] = make([]interface{}, len(rows))
+		if err := e.computeWindowFunction(newEnv(inputCols, outer), rows, fn, results[i]); err != nil {
+			return nil, fmt.Errorf("%v: %w", fn.Name, err)
+		}
+	}
+
+	cols := append([]resultColumn(nil), inputCols...)
+	for _, fn := range w.Functions {
+		cols = append(cols, resultColumn{
+			name:   fn.String(),
+			hidden: true,
+			expr:   fn.String(),
+		})
+	}
+	for i, row := range rows {
+		row = append(make([]interface{}, 0, len(cols)), row...)
+		for _, result := range results {
+			row = append(row, result[i])
+		}
+		rows[i] = row
+	}
+	return &sliceCursor{
+		cols: cols,
+		rows: rows,
+	}, nil
+}
+
+// windowPartition is a partition of the rows of a window, in window order.
+type windowPartition struct {
+	// indices are the indices of the rows of this partition in the window
+	// input, and keys are the values of the ordering terms for these rows.
+	indices []int
+	keys    [][]interface{}
+	order   sortOrder
+
+	// group is the index of the peer group of every row, and groupStart and
+	// groupEnd are the first position and the position after the last row of
+	// every peer group.
+	group      []int
+	groupStart []int
+	groupEnd   []int
+}
+
+func (p *windowPartition) Len() int { return len(p.indices) }
+
+func (p *windowPartition) Less(i, j int) bool { return p.order.compare(p.keys[i], p.keys[j]) < 0 }
+
+func (p *windowPartition) Swap(i, j int) {
+	p.indices[i], p.indices[j] = p.indices[j], p.indices[i]
+	p.keys[i], p.keys[j] = p.keys[j], p.keys[i]
+}
+
+// sort orders the rows of this partition, and determines the peer groups.
+// Rows with equal keys keep their input order.
+func (p *windowPartition) sort() {
+	sort.Stable(p)
+	for pos := range p.indices {
+		if pos == 0 || p.order.compare(p.keys[pos-1], p.keys[pos]) != 0 {
+			if pos != 0 {
+				p.groupEnd = append(p.groupEnd, pos)
+			}
+			p.groupStart = append(p.groupStart, pos)
+		}
+		p.group = append(p.group, len(p.groupStart)-1)
+	}
+	p.groupEnd = append(p.groupEnd, len(p.indices))
+}
+
+// computeWindowFunction computes the given window function for all given rows,
+// and stores the results at the index of the rows.
+func (e *simpleExecutor) computeWindowFunction(en *env, rows [][]interface{}, fn command.FunctionExpr, results []interface{}) error {
+	order, err := newSortOrder(fn.Over.Order)
+	if err != nil {
+		return err
+	}
+	frame, err := e.newWindowFrame(en.outer, fn.Over)
+	if err != nil {
+		return err
+	}
+
+	var partitions []*windowPartition
+	partitionsByKey := make(map[string]*windowPartition)
+	for i, row := range rows {
+		en.row = row
+		keys := make([]interface{}, len(fn.Over.Partition))
+		for j, expr := range fn.Over.Partition {
+			key, err := e.evaluate(en, expr)
+			if err != nil {
+				return fmt.Errorf("partition: %w", err)
+			}
+			keys[j] = key
+		}
+		orderKeys, err := e.sortKeys(en, fn.Over.Order)
+		if err != nil {
+			return err
+		}
+
+		key := rowKey(keys)
+		p, ok := partitionsByKey[key]
+		if !ok {
+			p = &windowPartition{order: order}
+			partitionsByKey[key] = p
+			partitions = append(partitions, p)
+		}
+		p.indices = append(p.indices, i)
+		p.keys = append(p.keys, orderKeys)
+	}
+
+	for _, p := range partitions {
+		p.sort()
+		if err := e.computeWindowPartition(en, rows, p, frame, fn, results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// computeWindowPartition computes the given window function for all rows of
+// the given partition.
+func (e *simpleExecutor) computeWindowPartition(en *env, rows [][]interface{}, p *windowPartition, frame *windowFrame, fn command.FunctionExpr, results []interface{}) error {
+	evaluateAt := func(pos int, expr command.Expr) (interface{}, error) {
+		en.row = rows[p.indices[pos]]
+		return e.evaluate(en, expr)
+	}
+	args := func(min, max int) error {
+		if len(fn.Args) < min || len(fn.Args) > max {
+			return fmt.Errorf("wrong number of arguments to function %v()", fn.Name)
+		}
+		return nil
+	}
+
+	switch strings.ToUpper(fn.Name) {
+	case "ROW_NUMBER", "RANK", "DENSE_RANK":
+		if err := args(0, 0); err != nil {
+			return err
+		}
+		for pos, index := range p.indices {
+			switch strings.ToUpper(fn.Name) {
+			case "ROW_NUMBER":
+				results[index] = int64(pos + 1)
+			case "RANK":
+				results[index] = int64(p.groupStart[p.group[pos]] + 1)
+			default:
+				results[index] = int64(p.group[pos] + 1)
+			}
+		}
+	case "NTILE":
+		if err := args(1, 1); err != nil {
+			return err
+		}
+		for pos, index := range p.indices {
+			val, err := evaluateAt(pos, fn.Args[0])
+			if err != nil {
+				return err
+			}
+			tiles, ok := toNumeric(val).(int64)
+			if !ok || tiles <= 0 {
+				return fmt.Errorf("argument of ntile must be a positive integer")
+			}
+			results[index] = ntile(int64(pos), int64(len(p.indices)), tiles)
+		}
+	case "LAG", "LEAD":
+		if err := args(1, 3); err != nil {
+			return err
+		}
+		for pos, index := range p.indices {
+			offset := int64(1)
+			if len(fn.Args) > 1 {
+				val, err := evaluateAt(pos, fn.Args[1])
+				if err != nil {
+					return err
+				}
+				var ok bool
+				if offset, ok = toNumeric(val).(int64); !ok {
+					return fmt.Errorf("second argument to %v must be an integer", fn.Name)
+				}
+			}
+			if strings.EqualFold(fn.Name, "LAG") {
+				offset = -offset
+			}
+
+			var val interface{}
+			var err error
+			if other := int64(pos) + offset; other >= 0 && other < int64(len(p.indices)) {
+				val, err = evaluateAt(int(other), fn.Args[0])
+			} else if len(fn.Args) > 2 {
+				val, err = evaluateAt(pos, fn.Args[2])
+			}
+			if err != nil {
+				return err
+			}
+			results[index] = val
+		}
+	case "FIRST_VALUE", "LAST_VALUE":
+		if err := args(1, 1); err != nil {
+			return err
+		}
+		for pos, index := range p.indices {
+			start, end := frame.bounds(p, pos)
+			found := -1
+			for other := start; other < end; other++ {
+				if !frame.excludes(p, pos, other) {
+					found = other
+					if strings.EqualFold(fn.Name, "FIRST_VALUE") {
+						break
+					}
+				}
+			}
+			var val interface{}
+			if found != -1 {
+				var err error
+				if val, err = evaluateAt(found, fn.Args[0]); err != nil {
+					return err
+				}
+			}
+			results[index] = val
+		}
+	default:
+		if !isAggregateCall(fn) {
+			return fmt.Errorf("no such window function: %v", fn.Name)
+		}
+		return e.computeWindowAggregate(en, rows, p, frame, fn, results)
+	}
+	return nil
+}
+
+// computeWindowAggregate computes the given aggregate function over the frame
+// of every row of the given partition. If the frame always starts at the first
+// row and excludes no rows, the frames of subsequent rows only grow, so that
+// the aggregate is computed incrementally.
+func (e *simpleExecutor) computeWindowAggregate(en *env, rows [][]interface{}, p *windowPartition, frame *windowFrame, fn command.FunctionExpr, results []interface{}) error {
+	incremental := frame.Start.Type == command.BoundUnboundedPreceding && frame.Exclude == command.ExcludeNoOthers
+
+	var agg aggregator
+	var next int
+	for pos, index := range p.indices {
+		start, end := frame.bounds(p, pos)
+		if agg == nil || !incremental {
+			var err error
+			if agg, err = newAggregator(fn); err != nil {
+				return err
+			}
+			next = start
+		}
+		for ; next < end; next++ {
+			if frame.excludes(p, pos, next) {
+				continue
+			}
+			en.row = rows[p.indices[next]]
+			if fn.Filter != nil {
+				pass, err := e.evaluate(en, fn.Filter)
+				if err != nil {
+					return fmt.Errorf("filter: %w", err)
+				}
+				if !isTrue(pass) {
+					continue
+				}
+			}
+			args, err := e.aggregateArgs(en, fn)
+			if err != nil {
+				return err
+			}
+			if err := agg.step(args); err != nil {
+				return err
+			}
+		}
+
+		val, err := agg.result()
+		if err != nil {
+			return err
+		}
+		results[index] = val
+	}
+	return nil
+}
+
+// ntile returns the number of the bucket of the row at the given position,
+// if the given amount of rows is divided into the given amount of buckets.
+// The sizes of the buckets differ by at most one, and larger buckets come
+// first.
+func ntile(pos, rows, tiles int64) int64 {
+	size, larger := rows/tiles, rows%tiles
+	if pos < larger*(size+1) {
+		return pos/(size+1) + 1
+	}
+	return larger + (pos-larger*(size+1))/size + 1
+}
+
+// windowFrame is a frame of a window, whose offsets have been evaluated.
+type windowFrame struct {
+	command.Frame
+	start, end interface{}
+	// desc indicates that the single ordering term of a RANGE frame is
+	// descending.
+	desc bool
+}
+
+func (e *simpleExecutor) newWindowFrame(outer *env, window *command.WindowSpec) (*windowFrame, error) {
+	frame := &windowFrame{
+		Frame: window.Frame,
+	}
+	if len(window.Order) != 0 {
+		frame.desc = window.Order[0].Desc
+	}
+
+	offset := func(bound command.FrameBound, name string) (interface{}, error) {
+		if bound.Offset == nil {
+			return nil, nil
+		}
+		val, err := e.evaluate(outer, bound.Offset)
+		if err != nil {
+			return nil, err
+		}
+		switch v := toNumeric(val).(type) {
+		case int64:
+			if v >= 0 {
+				return v, nil
+			}
+		case float64:
+			if v >= 0 && frame.Unit == command.FrameRange {
+				return v, nil
+			}
+			if v >= 0 && v == float64(int64(v)) {
+				return int64(v), nil
+			}
+		}
+		if frame.Unit == command.FrameRange {
+			return nil, fmt.Errorf("frame %v offset must be a non-negative number", name)
+		}
+		return nil, fmt.Errorf("frame %v offset must be a non-negative integer", name)
+	}
+
+	var err error
+	if frame.start, err = offset(frame.Start, "starting"); err != nil {
+		return nil, err
+	}
+	if frame.end, err = offset(frame.End, "ending"); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// bounds returns the position of the first row of the frame of the row at the
+// given position, and the position after the last row of the frame. Excluded
+// rows are part of the bounds.
+func (f *windowFrame) bounds(p *windowPartition, pos int) (int, int) {
+	start := f.bound(p, pos, f.Start, f.start, false)
+	end := f.bound(p, pos, f.End, f.end, true)
+	if start < 0 {
+		start = 0
+	}
+	if end > len(p.indices) {
+		end = len(p.indices)
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// bound returns the position of the given bound of the frame of the row at the
+// given position. If end is true, the bound is an end bound, and the position
+// after the last row of the bound is returned.
+func (f *windowFrame) bound(p *windowPartition, pos int, bound command.FrameBound, offset interface{}, end bool) int {
+	group := p.group[pos]
+	switch bound.Type {
+	case command.BoundUnboundedPreceding:
+		return 0
+	case command.BoundUnboundedFollowing:
+		return len(p.indices)
+	case command.BoundCurrentRow:
+		if f.Unit == command.FrameRows {
+			if end {
+				return pos + 1
+			}
+			return pos
+		}
+		if end {
+			return p.groupEnd[group]
+		}
+		return p.groupStart[group]
+	}
+
+	preceding := bound.Type == command.BoundPreceding
+	switch f.Unit {
+	case command.FrameRows:
+		n := int(offset.(int64))
+		if preceding {
+			n = -n
+		}
+		if end {
+			return pos + n + 1
+		}
+		return pos + n
+	case command.FrameGroups:
+		n := int(offset.(int64))
+		if preceding {
+			n = -n
+		}
+		switch other := group + n; {
+		case other < 0:
+			return 0
+		case other >= len(p.groupStart):
+			return len(p.indices)
+		case end:
+			return p.groupEnd[other]
+		default:
+			return p.groupStart[other]
+		}
+	}
+
+	// the frame of a row with a NULL key consists of all rows with a NULL key
+	key := p.keys[pos][0]
+	if key == nil {
+		if end {
+			return p.groupEnd[group]
+		}
+		return p.groupStart[group]
+	}
+	operator := "+"
+	if preceding != f.desc {
+		operator = "-"
+	}
+	target := []interface{}{arithmetic(operator, toNumeric(key), offset)}
+	return sort.Search(len(p.indices), func(other int) bool {
+		cmp := p.order[:1].compare(p.keys[other][:1], target)
+		if end {
+			return cmp > 0
+		}
+		return cmp >= 0
+	})
+}
+
+// excludes determines whether the row at the position other is excluded from
+// the frame of the row at the position pos.
+func (f *windowFrame) excludes(p *windowPartition, pos, other int) bool {
+	switch f.Exclude {
+	case command.ExcludeCurrentRow:
+		return other == pos
+	case command.ExcludeGroup:
+		return p.group[other] == p.group[pos]
+	case command.ExcludeTies:
+		return other != pos && p.group[other] == p.group[pos]
+	}
+	return false
+}
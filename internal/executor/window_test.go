@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE t (grp VARCHAR(1), v INTEGER)")
+	mustExecute(t, e, "INSERT INTO t VALUES ('a', 1), ('a', 2), ('b', 3), ('a', 2), ('b', 5), ('a', 4)")
+
+	tests := []struct {
+		name  string
+		query string
+		want  [][]interface{}
+	}{
+		{
+			"ranking",
+			"SELECT v, row_number() OVER (ORDER BY v), rank() OVER (ORDER BY v), dense_rank() OVER (ORDER BY v) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{
+				{int64(1), int64(1), int64(1), int64(1)},
+				{int64(2), int64(2), int64(2), int64(2)},
+				{int64(2), int64(3), int64(2), int64(2)},
+				{int64(4), int64(4), int64(4), int64(3)},
+			},
+		},
+		{
+			"partition",
+			"SELECT grp, v, SUM(v) OVER (PARTITION BY grp) FROM t ORDER BY grp, v",
+			[][]interface{}{
+				{"a", int64(1), int64(9)},
+				{"a", int64(2), int64(9)},
+				{"a", int64(2), int64(9)},
+				{"a", int64(4), int64(9)},
+				{"b", int64(3), int64(8)},
+				{"b", int64(5), int64(8)},
+			},
+		},
+		{
+			"default frame includes peers",
+			"SELECT v, SUM(v) OVER (ORDER BY v) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{{int64(1), int64(1)}, {int64(2), int64(5)}, {int64(2), int64(5)}, {int64(4), int64(9)}},
+		},
+		{
+			"rows frame",
+			"SELECT v, SUM(v) OVER (ORDER BY v ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{{int64(1), int64(3)}, {int64(2), int64(5)}, {int64(2), int64(8)}, {int64(4), int64(6)}},
+		},
+		{
+			"range frame",
+			"SELECT v, SUM(v) OVER (ORDER BY v RANGE BETWEEN 1 PRECEDING AND CURRENT ROW) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{{int64(1), int64(1)}, {int64(2), int64(5)}, {int64(2), int64(5)}, {int64(4), int64(4)}},
+		},
+		{
+			"range frame descending",
+			"SELECT v, SUM(v) OVER (ORDER BY v DESC RANGE BETWEEN CURRENT ROW AND 1 FOLLOWING) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{{int64(1), int64(1)}, {int64(2), int64(5)}, {int64(2), int64(5)}, {int64(4), int64(4)}},
+		},
+		{
+			"groups frame",
+			"SELECT v, SUM(v) OVER (ORDER BY v GROUPS BETWEEN 1 PRECEDING AND CURRENT ROW) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{{int64(1), int64(1)}, {int64(2), int64(5)}, {int64(2), int64(5)}, {int64(4), int64(8)}},
+		},
+		{
+			"exclude current row",
+			"SELECT v, SUM(v) OVER (ORDER BY v ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING EXCLUDE CURRENT ROW) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{{int64(1), int64(8)}, {int64(2), int64(7)}, {int64(2), int64(7)}, {int64(4), int64(5)}},
+		},
+		{
+			"exclude group",
+			"SELECT v, SUM(v) OVER (ORDER BY v ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING EXCLUDE GROUP) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{{int64(1), int64(8)}, {int64(2), int64(5)}, {int64(2), int64(5)}, {int64(4), int64(5)}},
+		},
+		{
+			"exclude ties",
+			"SELECT v, SUM(v) OVER (ORDER BY v ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING EXCLUDE TIES) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{{int64(1), int64(9)}, {int64(2), int64(7)}, {int64(2), int64(7)}, {int64(4), int64(9)}},
+		},
+		{
+			"lag and lead",
+			"SELECT v, lag(v) OVER (ORDER BY v), lead(v, 2, 0) OVER (ORDER BY v) FROM t WHERE grp == 'a' ORDER BY v",
+			[][]interface{}{
+				{int64(1), nil, int64(2)},
+				{int64(2), int64(1), int64(4)},
+				{int64(2), int64(2), int64(0)},
+				{int64(4), int64(2), int64(0)},
+			},
+		},
+		{
+			"first and last value",
+			"SELECT grp, v, first_value(v) OVER (PARTITION BY grp ORDER BY v DESC), last_value(v) OVER (PARTITION BY grp ORDER BY v DESC) FROM t ORDER BY grp, v",
+			[][]interface{}{
+				{"a", int64(1), int64(4), int64(1)},
+				{"a", int64(2), int64(4), int64(2)},
+				{"a", int64(2), int64(4), int64(2)},
+				{"a", int64(4), int64(4), int64(4)},
+				{"b", int64(3), int64(5), int64(3)},
+				{"b", int64(5), int64(5), int64(5)},
+			},
+		},
+		{
+			"ntile",
+			"SELECT v, ntile(4) OVER (ORDER BY v) FROM t ORDER BY v",
+			[][]interface{}{
+				{int64(1), int64(1)},
+				{int64(2), int64(1)},
+				{int64(2), int64(2)},
+				{int64(3), int64(2)},
+				{int64(4), int64(3)},
+				{int64(5), int64(4)},
+			},
+		},
+		{
+			"filter on window aggregate",
+			"SELECT DISTINCT grp, COUNT(*) FILTER (WHERE v > 1) OVER (PARTITION BY grp) FROM t ORDER BY grp",
+			[][]interface{}{{"a", int64(3)}, {"b", int64(2)}},
+		},
+		{
+			"filter on aggregate",
+			"SELECT grp, COUNT(*) FILTER (WHERE v > 1), SUM(v) FILTER (WHERE v < 4) FROM t GROUP BY grp",
+			[][]interface{}{{"a", int64(3), int64(5)}, {"b", int64(2), int64(3)}},
+		},
+		{
+			"window over groups",
+			"SELECT grp, SUM(v), rank() OVER (ORDER BY SUM(v) DESC) FROM t GROUP BY grp ORDER BY grp",
+			[][]interface{}{{"a", int64(9), int64(1)}, {"b", int64(8), int64(2)}},
+		},
+		{
+			"named window",
+			"SELECT v, SUM(v) OVER w, COUNT(*) OVER (w ROWS CURRENT ROW) FROM t WHERE grp == 'b' WINDOW w AS (ORDER BY v) ORDER BY v",
+			[][]interface{}{{int64(3), int64(3), int64(1)}, {int64(5), int64(8), int64(1)}},
+		},
+		{
+			"order by window function",
+			"SELECT v FROM t WHERE grp == 'a' ORDER BY row_number() OVER (ORDER BY v DESC)",
+			[][]interface{}{{int64(4)}, {int64(2)}, {int64(2)}, {int64(1)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mustQuery(t, e, tt.query))
+		})
+	}
+
+	for _, query := range []string{
+		"SELECT ntile(0) OVER () FROM t",
+		"SELECT SUM(v) OVER (ORDER BY v ROWS 1.5 PRECEDING) FROM t",
+		"SELECT lag() OVER () FROM t",
+	} {
+		_, err := execute(e, query)
+		assert.Error(t, err, query)
+	}
+}
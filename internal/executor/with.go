@@ -0,0 +1,249 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/compiler/command"
+)
+
+// defaultRecursionLimit is the default maximum amount of rows, that a
+// recursive common table may produce.
+const defaultRecursionLimit = 1 << 20
+
+// commonTable is the state of a common table during the execution of the query
+// that it is defined for.
+type commonTable struct {
+	def command.CommonTable
+	// en is the environment of the WITH clause, in which the definition of the
+	// table is evaluated.
+	en *env
+
+	// cols and rows are the result of the table, once it has been
+	// materialized. Recursive tables are always materialized.
+	cols         []resultColumn
+	rows         [][]interface{}
+	materialized bool
+	// recursing indicates that the recursive select of the table is being
+	// evaluated, so that a reference to the table yields the working rows,
+	// which are the rows that were added in the previous iteration.
+	recursing bool
+	working   [][]interface{}
+}
+
+// openWith creates a cursor over the input of the given WITH clause, within
+// which the common tables of the clause can be referenced.
+func (e *simpleExecutor) openWith(outer *env, with command.With) (cursor, error) {
+	en := &env{
+		outer:  outer,
+		tables: make(map[string]*commonTable),
+	}
+	for _, def := range with.Tables {
+		en.tables[strings.ToLower(def.Name)] = &commonTable{
+			def: def,
+			en:  en,
+		}
+	}
+	return e.open(en, with.Input)
+}
+
+// openCommonTable creates a cursor over the rows of the referenced common
+// table. Materialized and recursive tables are evaluated on the first
+// reference, all other tables are evaluated on every reference.
+func (e *simpleExecutor) openCommonTable(outer *env, ref command.CommonTableRef) (cursor, error) {
+	t := outer.lookupCommonTable(ref.Name)
+	if t == nil {
+		return nil, fmt.Errorf("%v: %w", ref.Name, ErrNoSuchTable)
+	}
+	name := ref.Name
+	if ref.Alias != "" {
+		name = ref.Alias
+	}
+
+	switch {
+	case t.recursing:
+		return &sliceCursor{
+			cols: renameColumns(t.cols, name),
+			rows: t.working,
+		}, nil
+	case !t.materialized && t.def.Recursive != nil:
+		if err := e.evaluateRecursive(t); err != nil {
+			return nil, fmt.Errorf("%v: %w", t.def.Name, err)
+		}
+	case !t.materialized && t.def.Materialize:
+		input, err := e.open(t.en, t.def.Input)
+		if err != nil {
+			return nil, err
+		}
+		cols, visible, err := commonTableColumns(t.def, input.Columns())
+		if err != nil {
+			_ = input.Close()
+			return nil, err
+		}
+		rows, err := drain(input)
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			rows[i] = pick(row, visible)
+		}
+		t.cols, t.rows, t.materialized = cols, rows, true
+	case !t.materialized:
+		input, err := e.open(t.en, t.def.Input)
+		if err != nil {
+			return nil, err
+		}
+		cols, visible, err := commonTableColumns(t.def, input.Columns())
+		if err != nil {
+			_ = input.Close()
+			return nil, err
+		}
+		return &pickCursor{
+			cursor:  input,
+			cols:    renameColumns(cols, name),
+			indices: visible,
+		}, nil
+	}
+	return &sliceCursor{
+		cols: renameColumns(t.cols, name),
+		rows: t.rows,
+	}, nil
+}
+
+// evaluateRecursive materializes the given recursive table. The result of the
+// initial select is the first working set. The recursive select is evaluated
+// on the working set, and the rows that it produces form the next working set,
+// until no more rows are produced. With UNION, rows that are already part of
+// the result are discarded, so that cycles terminate. The evaluation also
+// stops once the limit of the table is reached, and fails if the recursion
+// limit of the executor is exceeded.
+func (e *simpleExecutor) evaluateRecursive(t *commonTable) error {
+	limit := int64(-1)
+	if t.def.Limit != nil {
+		var err error
+		limit, err = e.evaluateCount(t.en, t.def.Limit)
+		if err != nil {
+			return fmt.Errorf("limit: %w", err)
+		}
+	}
+
+	var seen map[string]struct{}
+	if !t.def.All {
+		seen = make(map[string]struct{})
+	}
+	var next [][]interface{}
+	add := func(row []interface{}) error {
+		if seen != nil {
+			key := rowKey(row)
+			if _, ok := seen[key]; ok {
+				return nil
+			}
+			seen[key] = struct{}{}
+		}
+		if int64(len(t.rows)) >= e.recursionLimit {
+			return fmt.Errorf("recursion produces more than %d rows", e.recursionLimit)
+		}
+		t.rows = append(t.rows, row)
+		next = append(next, row)
+		return nil
+	}
+	full := func() bool {
+		return limit >= 0 && int64(len(t.rows)) >= limit
+	}
+
+	input := t.def.Input
+	for !full() {
+		list, err := e.open(t.en, input)
+		if err != nil {
+			return err
+		}
+		cols, visible, err := commonTableColumns(t.def, list.Columns())
+		if err == nil && t.cols != nil && len(cols) != len(t.cols) {
+			err = fmt.Errorf("SELECTs to the left and right of UNION do not have the same number of result columns")
+		}
+		if err != nil {
+			_ = list.Close()
+			return err
+		}
+		if t.cols == nil {
+			t.cols = cols
+		}
+
+		next = nil
+		for !full() {
+			row, ok, err := list.Next()
+			if err == nil && ok {
+				err = add(pick(row, visible))
+			}
+			if err != nil {
+				_ = list.Close()
+				return err
+			}
+			if !ok {
+				break
+			}
+		}
+		if err := list.Close(); err != nil {
+			return err
+		}
+		if len(next) == 0 {
+			break
+		}
+
+		t.working = next
+		t.recursing = true
+		input = t.def.Recursive
+	}
+
+	t.recursing = false
+	t.working = nil
+	t.materialized = true
+	return nil
+}
+
+// commonTableColumns returns the columns of the given common table, whose
+// definition produces the given columns, as well as the indices of the visible
+// columns of the definition.
+func commonTableColumns(def command.CommonTable, inputCols []resultColumn) ([]resultColumn, []int, error) {
+	visible := visibleColumns(inputCols)
+	if len(def.Cols) != 0 && len(def.Cols) != len(visible) {
+		return nil, nil, fmt.Errorf("table %v has %d values for %d columns", def.Name, len(visible), len(def.Cols))
+	}
+	cols := make([]resultColumn, len(visible))
+	for i, index := range visible {
+		cols[i].name = inputCols[index].name
+		if len(def.Cols) != 0 {
+			cols[i].name = def.Cols[i]
+		}
+	}
+	return cols, visible, nil
+}
+
+// renameColumns returns a copy of the given columns, that are assigned to the
+// table with the given name.
+func renameColumns(cols []resultColumn, tableName string) []resultColumn {
+	renamed := make([]resultColumn, len(cols))
+	for i, col := range cols {
+		col.table = tableName
+		renamed[i] = col
+	}
+	return renamed
+}
+
+// pickCursor is a cursor, that returns the values at the given indices of the
+// rows of its input.
+type pickCursor struct {
+	cursor
+	cols    []resultColumn
+	indices []int
+}
+
+func (c *pickCursor) Columns() []resultColumn { return c.cols }
+
+func (c *pickCursor) Next() ([]interface{}, bool, error) {
+	row, ok, err := c.cursor.Next()
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return pick(row, c.indices), true, nil
+}
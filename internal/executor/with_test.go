@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func newWithTestExecutor(t *testing.T) *simpleExecutor {
+	e := newTestExecutor(afero.NewMemMapFs())
+	mustExecute(t, e, "CREATE TABLE emp (id INTEGER, name VARCHAR(10), manager INTEGER)")
+	mustExecute(t, e, "INSERT INTO emp (id, name) VALUES (1, 'ada')")
+	mustExecute(t, e, "INSERT INTO emp VALUES "+
+		"(2, 'bob', 1), (3, 'cy', 1), (4, 'dee', 2), (5, 'eve', 4), (6, 'fay', 3)")
+	mustExecute(t, e, "CREATE TABLE edge (src INTEGER, dst INTEGER)")
+	mustExecute(t, e, "INSERT INTO edge VALUES (1, 2), (2, 3), (3, 1), (3, 4)")
+	return e
+}
+
+func TestWith(t *testing.T) {
+	e := newWithTestExecutor(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  [][]interface{}
+	}{
+		{
+			"common table",
+			"WITH managers AS (SELECT DISTINCT manager FROM emp WHERE manager > 0) SELECT * FROM managers ORDER BY manager",
+			[][]interface{}{{int64(1)}, {int64(2)}, {int64(3)}, {int64(4)}},
+		},
+		{
+			"column names",
+			"WITH t(i, n) AS (SELECT id, name FROM emp WHERE id < 3) SELECT n FROM t ORDER BY i DESC",
+			[][]interface{}{{"bob"}, {"ada"}},
+		},
+		{
+			"materialized common table",
+			"WITH t(i, m) AS (SELECT id, manager FROM emp) SELECT a.i, b.i FROM t AS a JOIN t AS b ON a.m == b.i WHERE b.i > 2 ORDER BY a.i",
+			[][]interface{}{{int64(5), int64(4)}, {int64(6), int64(3)}},
+		},
+		{
+			"common table referencing a previous one",
+			"WITH a AS (SELECT id FROM emp WHERE id > 2), b AS (SELECT id FROM a WHERE id < 5) SELECT * FROM b",
+			[][]interface{}{{int64(3)}, {int64(4)}},
+		},
+		{
+			"common table in subquery",
+			"SELECT name FROM emp WHERE id IN (WITH t AS (SELECT manager FROM emp) SELECT manager FROM t) ORDER BY name",
+			[][]interface{}{{"ada"}, {"bob"}, {"cy"}, {"dee"}},
+		},
+		{
+			"recursive counter",
+			"WITH RECURSIVE cnt(n) AS (VALUES (1) UNION ALL SELECT n * 2 FROM cnt LIMIT 5) SELECT n FROM cnt",
+			[][]interface{}{{int64(1)}, {int64(2)}, {int64(4)}, {int64(8)}, {int64(16)}},
+		},
+		{
+			"org chart",
+			"WITH RECURSIVE sub(id, name) AS (SELECT id, name FROM emp WHERE id == 2 UNION SELECT emp.id, emp.name FROM emp JOIN sub ON emp.manager == sub.id) SELECT name FROM sub",
+			[][]interface{}{{"bob"}, {"dee"}, {"eve"}},
+		},
+		{
+			"cycle with union",
+			"WITH RECURSIVE reach(node) AS (VALUES (1) UNION SELECT edge.dst FROM edge JOIN reach ON edge.src == reach.node) SELECT node FROM reach ORDER BY node",
+			[][]interface{}{{int64(1)}, {int64(2)}, {int64(3)}, {int64(4)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mustQuery(t, e, tt.query))
+		})
+	}
+}
+
+func TestWithRecursionLimit(t *testing.T) {
+	e := newWithTestExecutor(t)
+	e.recursionLimit = 100
+
+	_, err := execute(e, "WITH RECURSIVE reach(node) AS (VALUES (1) UNION ALL SELECT edge.dst FROM edge JOIN reach ON edge.src == reach.node) SELECT node FROM reach")
+	assert.Error(t, err, "a cycle with UNION ALL must exceed the recursion limit")
+}
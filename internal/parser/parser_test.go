@@ -9099,6 +9099,43 @@ func TestSingleStatementParse(t *testing.T) {
 						},
 					},
 				},
+				{
+					"DELETE with expr with function name with expr with filter and over clause",
+					"DELETE FROM myTable WHERE myFunction (expr1) FILTER (WHERE expr2) OVER myWindow",
+					&ast.SQLStmt{
+						DeleteStmt: &ast.DeleteStmt{
+							Delete: token.New(1, 1, 0, 6, token.KeywordDelete, "DELETE"),
+							From:   token.New(1, 8, 7, 4, token.KeywordFrom, "FROM"),
+							QualifiedTableName: &ast.QualifiedTableName{
+								TableName: token.New(1, 13, 12, 7, token.Literal, "myTable"),
+							},
+							Where: token.New(1, 21, 20, 5, token.KeywordWhere, "WHERE"),
+							Expr: &ast.Expr{
+								FunctionName: token.New(1, 27, 26, 10, token.Literal, "myFunction"),
+								LeftParen:    token.New(1, 38, 37, 1, token.Delimiter, "("),
+								Expr: []*ast.Expr{
+									{
+										LiteralValue: token.New(1, 39, 38, 5, token.Literal, "expr1"),
+									},
+								},
+								RightParen: token.New(1, 44, 43, 1, token.Delimiter, ")"),
+								FilterClause: &ast.FilterClause{
+									Filter:    token.New(1, 46, 45, 6, token.KeywordFilter, "FILTER"),
+									LeftParen: token.New(1, 53, 52, 1, token.Delimiter, "("),
+									Where:     token.New(1, 54, 53, 5, token.KeywordWhere, "WHERE"),
+									Expr: &ast.Expr{
+										LiteralValue: token.New(1, 60, 59, 5, token.Literal, "expr2"),
+									},
+									RightParen: token.New(1, 65, 64, 1, token.Delimiter, ")"),
+								},
+								OverClause: &ast.OverClause{
+									Over:       token.New(1, 67, 66, 4, token.KeywordOver, "OVER"),
+									WindowName: token.New(1, 72, 71, 8, token.Literal, "myWindow"),
+								},
+							},
+						},
+					},
+				},
 				{
 					"DELETE with expr with exprs flanked around binaryOperator, multiple recursion",
 					"DELETE FROM myTable WHERE myExpr1=myExpr2=myExpr3",
@@ -9780,6 +9817,328 @@ func TestSingleStatementParse(t *testing.T) {
 						},
 					},
 				},
+				{
+					"select with subquery and AS table alias",
+					"SELECT * FROM (SELECT myCol FROM myTable) AS myAlias",
+					&ast.SQLStmt{
+						SelectStmt: &ast.SelectStmt{
+							SelectCore: []*ast.SelectCore{
+								{
+									Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+									ResultColumn: []*ast.ResultColumn{
+										{
+											Asterisk: token.New(1, 8, 7, 1, token.BinaryOperator, "*"),
+										},
+									},
+									From: token.New(1, 10, 9, 4, token.KeywordFrom, "FROM"),
+									JoinClause: &ast.JoinClause{
+										TableOrSubquery: &ast.TableOrSubquery{
+											As:         token.New(1, 43, 42, 2, token.KeywordAs, "AS"),
+											TableAlias: token.New(1, 46, 45, 7, token.Literal, "myAlias"),
+											LeftParen:  token.New(1, 15, 14, 1, token.Delimiter, "("),
+											RightParen: token.New(1, 41, 40, 1, token.Delimiter, ")"),
+											SelectStmt: &ast.SelectStmt{
+												SelectCore: []*ast.SelectCore{
+													{
+														Select: token.New(1, 16, 15, 6, token.KeywordSelect, "SELECT"),
+														ResultColumn: []*ast.ResultColumn{
+															{
+																Expr: &ast.Expr{
+																	LiteralValue: token.New(1, 23, 22, 5, token.Literal, "myCol"),
+																},
+															},
+														},
+														From: token.New(1, 29, 28, 4, token.KeywordFrom, "FROM"),
+														JoinClause: &ast.JoinClause{
+															TableOrSubquery: &ast.TableOrSubquery{
+																TableName: token.New(1, 34, 33, 7, token.Literal, "myTable"),
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					"select with subquery and table alias",
+					"SELECT * FROM (SELECT myCol FROM myTable) myAlias",
+					&ast.SQLStmt{
+						SelectStmt: &ast.SelectStmt{
+							SelectCore: []*ast.SelectCore{
+								{
+									Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+									ResultColumn: []*ast.ResultColumn{
+										{
+											Asterisk: token.New(1, 8, 7, 1, token.BinaryOperator, "*"),
+										},
+									},
+									From: token.New(1, 10, 9, 4, token.KeywordFrom, "FROM"),
+									JoinClause: &ast.JoinClause{
+										TableOrSubquery: &ast.TableOrSubquery{
+											TableAlias: token.New(1, 43, 42, 7, token.Literal, "myAlias"),
+											LeftParen:  token.New(1, 15, 14, 1, token.Delimiter, "("),
+											RightParen: token.New(1, 41, 40, 1, token.Delimiter, ")"),
+											SelectStmt: &ast.SelectStmt{
+												SelectCore: []*ast.SelectCore{
+													{
+														Select: token.New(1, 16, 15, 6, token.KeywordSelect, "SELECT"),
+														ResultColumn: []*ast.ResultColumn{
+															{
+																Expr: &ast.Expr{
+																	LiteralValue: token.New(1, 23, 22, 5, token.Literal, "myCol"),
+																},
+															},
+														},
+														From: token.New(1, 29, 28, 4, token.KeywordFrom, "FROM"),
+														JoinClause: &ast.JoinClause{
+															TableOrSubquery: &ast.TableOrSubquery{
+																TableName: token.New(1, 34, 33, 7, token.Literal, "myTable"),
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					"select with table function",
+					"SELECT * FROM myFunction(myExpr1,myExpr2) AS myAlias",
+					&ast.SQLStmt{
+						SelectStmt: &ast.SelectStmt{
+							SelectCore: []*ast.SelectCore{
+								{
+									Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+									ResultColumn: []*ast.ResultColumn{
+										{
+											Asterisk: token.New(1, 8, 7, 1, token.BinaryOperator, "*"),
+										},
+									},
+									From: token.New(1, 10, 9, 4, token.KeywordFrom, "FROM"),
+									JoinClause: &ast.JoinClause{
+										TableOrSubquery: &ast.TableOrSubquery{
+											As:                token.New(1, 43, 42, 2, token.KeywordAs, "AS"),
+											TableAlias:        token.New(1, 46, 45, 7, token.Literal, "myAlias"),
+											TableFunctionName: token.New(1, 15, 14, 10, token.Literal, "myFunction"),
+											LeftParen:         token.New(1, 25, 24, 1, token.Delimiter, "("),
+											Expr: []*ast.Expr{
+												{
+													LiteralValue: token.New(1, 26, 25, 7, token.Literal, "myExpr1"),
+												},
+												{
+													LiteralValue: token.New(1, 34, 33, 7, token.Literal, "myExpr2"),
+												},
+											},
+											RightParen: token.New(1, 41, 40, 1, token.Delimiter, ")"),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					"select with GROUP BY and multiple expr",
+					"SELECT * FROM myTable GROUP BY myExpr1,myExpr2",
+					&ast.SQLStmt{
+						SelectStmt: &ast.SelectStmt{
+							SelectCore: []*ast.SelectCore{
+								{
+									Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+									ResultColumn: []*ast.ResultColumn{
+										{
+											Asterisk: token.New(1, 8, 7, 1, token.BinaryOperator, "*"),
+										},
+									},
+									From: token.New(1, 10, 9, 4, token.KeywordFrom, "FROM"),
+									JoinClause: &ast.JoinClause{
+										TableOrSubquery: &ast.TableOrSubquery{
+											TableName: token.New(1, 15, 14, 7, token.Literal, "myTable"),
+										},
+									},
+									Group: token.New(1, 23, 22, 5, token.KeywordGroup, "GROUP"),
+									By:    token.New(1, 29, 28, 2, token.KeywordBy, "BY"),
+									Expr2: []*ast.Expr{
+										{
+											LiteralValue: token.New(1, 32, 31, 7, token.Literal, "myExpr1"),
+										},
+										{
+											LiteralValue: token.New(1, 40, 39, 7, token.Literal, "myExpr2"),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					"select with GROUP BY and HAVING",
+					"SELECT * FROM myTable GROUP BY myExpr1,myExpr2 HAVING myExpr3",
+					&ast.SQLStmt{
+						SelectStmt: &ast.SelectStmt{
+							SelectCore: []*ast.SelectCore{
+								{
+									Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+									ResultColumn: []*ast.ResultColumn{
+										{
+											Asterisk: token.New(1, 8, 7, 1, token.BinaryOperator, "*"),
+										},
+									},
+									From: token.New(1, 10, 9, 4, token.KeywordFrom, "FROM"),
+									JoinClause: &ast.JoinClause{
+										TableOrSubquery: &ast.TableOrSubquery{
+											TableName: token.New(1, 15, 14, 7, token.Literal, "myTable"),
+										},
+									},
+									Group: token.New(1, 23, 22, 5, token.KeywordGroup, "GROUP"),
+									By:    token.New(1, 29, 28, 2, token.KeywordBy, "BY"),
+									Expr2: []*ast.Expr{
+										{
+											LiteralValue: token.New(1, 32, 31, 7, token.Literal, "myExpr1"),
+										},
+										{
+											LiteralValue: token.New(1, 40, 39, 7, token.Literal, "myExpr2"),
+										},
+									},
+									Having: token.New(1, 48, 47, 6, token.KeywordHaving, "HAVING"),
+									Expr3: &ast.Expr{
+										LiteralValue: token.New(1, 55, 54, 7, token.Literal, "myExpr3"),
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					"select with ORDER BY and multiple ordering terms",
+					"SELECT * FROM myTable ORDER BY myExpr1,myExpr2",
+					&ast.SQLStmt{
+						SelectStmt: &ast.SelectStmt{
+							SelectCore: []*ast.SelectCore{
+								{
+									Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+									ResultColumn: []*ast.ResultColumn{
+										{
+											Asterisk: token.New(1, 8, 7, 1, token.BinaryOperator, "*"),
+										},
+									},
+									From: token.New(1, 10, 9, 4, token.KeywordFrom, "FROM"),
+									JoinClause: &ast.JoinClause{
+										TableOrSubquery: &ast.TableOrSubquery{
+											TableName: token.New(1, 15, 14, 7, token.Literal, "myTable"),
+										},
+									},
+								},
+							},
+							Order: token.New(1, 23, 22, 5, token.KeywordOrder, "ORDER"),
+							By:    token.New(1, 29, 28, 2, token.KeywordBy, "BY"),
+							OrderingTerm: []*ast.OrderingTerm{
+								{
+									Expr: &ast.Expr{
+										LiteralValue: token.New(1, 32, 31, 7, token.Literal, "myExpr1"),
+									},
+								},
+								{
+									Expr: &ast.Expr{
+										LiteralValue: token.New(1, 40, 39, 7, token.Literal, "myExpr2"),
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					"select with ORDER BY, ASC and DESC",
+					"SELECT * FROM myTable ORDER BY myExpr1 ASC,myExpr2 DESC",
+					&ast.SQLStmt{
+						SelectStmt: &ast.SelectStmt{
+							SelectCore: []*ast.SelectCore{
+								{
+									Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+									ResultColumn: []*ast.ResultColumn{
+										{
+											Asterisk: token.New(1, 8, 7, 1, token.BinaryOperator, "*"),
+										},
+									},
+									From: token.New(1, 10, 9, 4, token.KeywordFrom, "FROM"),
+									JoinClause: &ast.JoinClause{
+										TableOrSubquery: &ast.TableOrSubquery{
+											TableName: token.New(1, 15, 14, 7, token.Literal, "myTable"),
+										},
+									},
+								},
+							},
+							Order: token.New(1, 23, 22, 5, token.KeywordOrder, "ORDER"),
+							By:    token.New(1, 29, 28, 2, token.KeywordBy, "BY"),
+							OrderingTerm: []*ast.OrderingTerm{
+								{
+									Expr: &ast.Expr{
+										LiteralValue: token.New(1, 32, 31, 7, token.Literal, "myExpr1"),
+									},
+									Asc: token.New(1, 40, 39, 3, token.KeywordAsc, "ASC"),
+								},
+								{
+									Expr: &ast.Expr{
+										LiteralValue: token.New(1, 44, 43, 7, token.Literal, "myExpr2"),
+									},
+									Desc: token.New(1, 52, 51, 4, token.KeywordDesc, "DESC"),
+								},
+							},
+						},
+					},
+				},
+				{
+					"select with ORDER BY, NULLS FIRST and NULLS LAST",
+					"SELECT * FROM myTable ORDER BY myExpr1 NULLS FIRST,myExpr2 DESC NULLS LAST",
+					&ast.SQLStmt{
+						SelectStmt: &ast.SelectStmt{
+							SelectCore: []*ast.SelectCore{
+								{
+									Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+									ResultColumn: []*ast.ResultColumn{
+										{
+											Asterisk: token.New(1, 8, 7, 1, token.BinaryOperator, "*"),
+										},
+									},
+									From: token.New(1, 10, 9, 4, token.KeywordFrom, "FROM"),
+									JoinClause: &ast.JoinClause{
+										TableOrSubquery: &ast.TableOrSubquery{
+											TableName: token.New(1, 15, 14, 7, token.Literal, "myTable"),
+										},
+									},
+								},
+							},
+							Order: token.New(1, 23, 22, 5, token.KeywordOrder, "ORDER"),
+							By:    token.New(1, 29, 28, 2, token.KeywordBy, "BY"),
+							OrderingTerm: []*ast.OrderingTerm{
+								{
+									Expr: &ast.Expr{
+										LiteralValue: token.New(1, 32, 31, 7, token.Literal, "myExpr1"),
+									},
+									Nulls: token.New(1, 40, 39, 5, token.KeywordNulls, "NULLS"),
+									First: token.New(1, 46, 45, 5, token.KeywordFirst, "FIRST"),
+								},
+								{
+									Expr: &ast.Expr{
+										LiteralValue: token.New(1, 52, 51, 7, token.Literal, "myExpr2"),
+									},
+									Desc:  token.New(1, 60, 59, 4, token.KeywordDesc, "DESC"),
+									Nulls: token.New(1, 65, 64, 5, token.KeywordNulls, "NULLS"),
+									Last:  token.New(1, 71, 70, 4, token.KeywordLast, "LAST"),
+								},
+							},
+						},
+					},
+				},
 	}
 
 	for _, input := range inputs {
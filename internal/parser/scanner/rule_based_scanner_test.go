@@ -249,6 +249,21 @@ func TestRuleBasedScanner(t *testing.T) {
 				token.New(1, 13, 12, 0, token.EOF, ""),
 			},
 		},
+		{
+			"numbered and named placeholders",
+			"?12 :name, @name,$name ?",
+			ruleset.Default,
+			[]token.Token{
+				token.New(1, 1, 0, 3, token.Literal, "?12"),
+				token.New(1, 5, 4, 5, token.Literal, ":name"),
+				token.New(1, 10, 9, 1, token.Delimiter, ","),
+				token.New(1, 12, 11, 5, token.Literal, "@name"),
+				token.New(1, 17, 16, 1, token.Delimiter, ","),
+				token.New(1, 18, 17, 5, token.Literal, "$name"),
+				token.New(1, 24, 23, 1, token.Literal, "?"),
+				token.New(1, 25, 24, 0, token.EOF, ""),
+			},
+		},
 		{
 			"underscore in single unquoted token",
 			"alpha_beta",
@@ -47,6 +47,8 @@ var (
 	defaultBinaryOperator = matcher.String("|*/%<>=&!")
 	defaultDelimiter      = matcher.String("(),")
 	defaultPlaceholder    = matcher.RuneWithDesc("placeholder", '?')
+	// defaultNamedPlaceholder matches the prefixes of named placeholders
+	defaultNamedPlaceholder = matcher.String(":@$")
 	// the order of the rules are important for some cases. Beware
 	defaultRules = []Rule{
 		FuncRule(defaultStatementSeparatorRule),
@@ -70,9 +72,38 @@ func defaultStatementSeparatorRule(s RuneScanner) (token.Type, bool) {
 	return token.Unknown, false
 }
 
+// defaultPlaceholderRule scans placeholders for bind parameters. A placeholder
+// is either a question mark, optionally followed by a number (?, ?NNN), or a
+// name prefixed with a colon, an at sign or a dollar sign (:name, @name,
+// $name). Placeholders are scanned as literals.
 func defaultPlaceholderRule(s RuneScanner) (token.Type, bool) {
-	if next, ok := s.Lookahead(); ok && defaultPlaceholder.Matches(next) {
+	next, ok := s.Lookahead()
+	if !ok {
+		return token.Unknown, false
+	}
+	if defaultPlaceholder.Matches(next) {
+		s.ConsumeRune()
+		for {
+			next, ok := s.Lookahead()
+			if !(ok && defaultNumber.Matches(next)) {
+				break
+			}
+			s.ConsumeRune()
+		}
+		return token.Literal, true
+	}
+	if defaultNamedPlaceholder.Matches(next) {
 		s.ConsumeRune()
+		if next, ok := s.Lookahead(); !(ok && defaultLiteral.Matches(next)) {
+			return token.Unknown, false
+		}
+		for {
+			next, ok := s.Lookahead()
+			if !(ok && defaultLiteral.Matches(next)) {
+				break
+			}
+			s.ConsumeRune()
+		}
 		return token.Literal, true
 	}
 	return token.Unknown, false
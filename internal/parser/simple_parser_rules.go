@@ -1465,29 +1465,30 @@ func (p *simpleParser) parseExpr5(functionName token.Token, r reporter) (expr *a
 		if !ok || next.Type() == token.EOF || next.Type() == token.StatementSeparator {
 			return
 		}
-		// 3 possiblities, Filter OR over clause OR a ')'
-		switch next.Type() {
-		case token.KeywordFilter:
-			expr.FilterClause = p.parseFilterClause(r)
+		// Check whether it was already recorded before.
+		if expr.RightParen == nil {
+			if next.Type() == token.Delimiter && next.Value() == ")" {
+				expr.RightParen = next
+				p.consumeToken()
+			} else {
+				r.unexpectedSingleRuneToken(')')
+			}
 			next, ok = p.optionalLookahead(r)
 			if !ok || next.Type() == token.EOF || next.Type() == token.StatementSeparator {
 				return
 			}
-			if next.Type() == token.KeywordOver {
-				expr.OverClause = p.parseOverClause(r)
+		}
+		// the arguments may be followed by a filter clause, an over clause or
+		// both
+		if next.Type() == token.KeywordFilter {
+			expr.FilterClause = p.parseFilterClause(r)
+			next, ok = p.optionalLookahead(r)
+			if !ok || next.Type() == token.EOF || next.Type() == token.StatementSeparator {
+				return
 			}
-		case token.KeywordOver:
+		}
+		if next.Type() == token.KeywordOver {
 			expr.OverClause = p.parseOverClause(r)
-		default:
-			// Check whether it was already recorded before.
-			if expr.RightParen == nil {
-				if next.Type() == token.Delimiter && next.Value() == ")" {
-					expr.RightParen = next
-					p.consumeToken()
-				} else {
-					r.unexpectedSingleRuneToken(')')
-				}
-			}
 		}
 
 		next, ok := p.optionalLookahead(r)
@@ -3487,8 +3488,8 @@ func (p *simpleParser) parseSelectStmt(withClause *ast.WithClause, r reporter) (
 		}
 		for {
 			stmt.OrderingTerm = append(stmt.OrderingTerm, p.parseOrderingTerm(r))
-			next, ok = p.lookahead(r)
-			if !ok {
+			next, ok = p.optionalLookahead(r)
+			if !ok || next.Type() == token.EOF || next.Type() == token.StatementSeparator {
 				return
 			}
 			if next.Value() == "," {
@@ -3743,8 +3744,8 @@ func (p *simpleParser) parseSelectCore(r reporter) (stmt *ast.SelectCore) {
 				if expression != nil {
 					stmt.Expr2 = append(stmt.Expr2, expression)
 				}
-				next, ok = p.lookahead(r)
-				if !ok {
+				next, ok = p.optionalLookahead(r)
+				if !ok || next.Type() == token.EOF || next.Type() == token.StatementSeparator {
 					return
 				}
 				if next.Value() == "," {
@@ -3754,10 +3755,6 @@ func (p *simpleParser) parseSelectCore(r reporter) (stmt *ast.SelectCore) {
 				}
 			}
 
-			next, ok = p.lookahead(r)
-			if !ok {
-				return
-			}
 			if next.Type() == token.KeywordHaving {
 				stmt.Having = next
 				p.consumeToken()
@@ -4439,8 +4436,7 @@ func (p *simpleParser) parseTableOrSubquery(r reporter) (stmt *ast.TableOrSubque
 				} else {
 					r.unexpectedToken(token.Literal)
 				}
-			}
-			if next.Type() == token.Literal {
+			} else if next.Type() == token.Literal {
 				stmt.TableAlias = next
 				p.consumeToken()
 			}
@@ -4487,7 +4483,13 @@ func (p *simpleParser) parseTableOrSubquery(r reporter) (stmt *ast.TableOrSubque
 			}
 		} else {
 			if next.Value() == "(" {
+				if tableNameOrTableFunctionName == nil {
+					tableNameOrTableFunctionName = schemaOrTableNameOrLeftPar
+				}
+				stmt.TableName = nil
 				stmt.TableFunctionName = tableNameOrTableFunctionName
+				stmt.LeftParen = next
+				p.consumeToken()
 				for {
 					// Since this rule allows an open bracket, we need to check whether an expr
 					// exists before we allow it to look for an expresion to avoid null ptr errors.
@@ -4529,15 +4531,23 @@ func (p *simpleParser) parseTableOrSubquery(r reporter) (stmt *ast.TableOrSubque
 						stmt.TableAlias = next
 						p.consumeToken()
 					}
-				}
-				if next.Type() == token.Literal {
+				} else if next.Type() == token.Literal {
 					stmt.TableAlias = next
 					p.consumeToken()
 				}
 			}
 		}
 	} else if schemaOrTableNameOrLeftPar.Value() == "(" {
-		stmt.SelectStmt = p.parseSelectStmt(nil, r)
+		stmt.LeftParen = schemaOrTableNameOrLeftPar
+		p.consumeToken()
+
+		next, ok := p.lookahead(r)
+		if !ok {
+			return
+		}
+		if next.Type() == token.KeywordSelect || next.Type() == token.KeywordWith || next.Type() == token.KeywordValues {
+			stmt.SelectStmt = p.parseSelectStmt(nil, r)
+		}
 		if stmt.SelectStmt == nil {
 			stmt.JoinClause = p.parseJoinClause(r)
 			if stmt.JoinClause == nil {